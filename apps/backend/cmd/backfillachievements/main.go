@@ -0,0 +1,250 @@
+// Command backfillachievements grants game achievements retroactively by replaying
+// every stored game through the same rules the server applies when a game ends.
+//
+//	go run ./cmd/backfillachievements -dry-run
+//
+// Players are processed in user ID order and their games oldest first, so win
+// streaks and games played are rebuilt as they stood after each game and seasonal
+// achievements are judged by when the game was played. Rating achievements are
+// judged on the standard rating the player held once the game ended, taken from
+// their rating history. Granting is idempotent:
+// achievements a player already has are skipped and GrantAchievement ignores
+// duplicates, so the command can be re-run or resumed with -after at any time.
+//
+// Only what the stored moves and result reveal can be recovered. Games do not
+// record how they ended, so achievements for winning on time are never backfilled.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/achievements"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/chess"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/database"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/logger"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/models"
+)
+
+type options struct {
+	after    string
+	limit    int
+	batch    int
+	progress int
+	dryRun   bool
+}
+
+type stats struct {
+	players  int
+	games    int
+	skipped  int
+	granted  int
+	lastUser string
+}
+
+func main() {
+	opts := options{}
+	flag.StringVar(&opts.after, "after", "", "resume after this user ID (the lastUser of an earlier run)")
+	flag.IntVar(&opts.limit, "limit", 0, "maximum number of players to process (0 = all)")
+	flag.IntVar(&opts.batch, "batch", 500, "players loaded per query")
+	flag.IntVar(&opts.progress, "progress", 100, "log progress every N players")
+	flag.BoolVar(&opts.dryRun, "dry-run", false, "report achievements that would be granted without granting them")
+	flag.Parse()
+
+	_ = godotenv.Load()
+	logger.Configure(os.Getenv("LOG_LEVEL"), false)
+
+	if opts.batch < 1 || opts.progress < 1 || opts.limit < 0 {
+		fmt.Fprintln(os.Stderr, "batch and progress must be positive; limit must not be negative")
+		os.Exit(2)
+	}
+
+	database.InitPostgres()
+	defer database.Close()
+
+	st, err := run(opts)
+	logger.Info("Achievement backfill finished", logger.F(
+		"players", st.players,
+		"games", st.games,
+		"skipped", st.skipped,
+		"granted", st.granted,
+		"dryRun", opts.dryRun,
+		"lastUser", st.lastUser,
+	))
+	if err != nil {
+		logger.Error("Achievement backfill failed", logger.F(
+			"error", err.Error(),
+			"resumeAfter", st.lastUser,
+		))
+		os.Exit(1)
+	}
+}
+
+// run backfills players in batches. stats.lastUser is only advanced once a player is
+// fully processed, so it is always a safe value for -after.
+func run(opts options) (stats, error) {
+	st := stats{lastUser: opts.after}
+
+	for {
+		ids, err := database.ListPlayerIDs(st.lastUser, opts.batch)
+		if err != nil {
+			return st, err
+		}
+		if len(ids) == 0 {
+			return st, nil
+		}
+
+		for _, userID := range ids {
+			if opts.limit > 0 && st.players >= opts.limit {
+				return st, nil
+			}
+			if err := backfillPlayer(userID, opts, &st); err != nil {
+				return st, fmt.Errorf("player %s: %w", userID, err)
+			}
+			st.players++
+			st.lastUser = userID
+
+			if st.players%opts.progress == 0 {
+				logger.Info("Achievement backfill progress", logger.F(
+					"players", st.players,
+					"games", st.games,
+					"granted", st.granted,
+					"resumeAfter", st.lastUser,
+				))
+			}
+		}
+	}
+}
+
+// backfillPlayer replays the player's games in order and grants every achievement
+// they earned that they do not have yet
+func backfillPlayer(userID string, opts options, st *stats) error {
+	games, err := database.GetFinishedGamesByUserID(userID)
+	if err != nil {
+		return err
+	}
+	history, err := database.GetRatingHistoryByUserID(userID, models.RatingPoolStandard)
+	if err != nil {
+		return err
+	}
+	existing, err := database.GetUserAchievementIDs(userID)
+	if err != nil {
+		return err
+	}
+
+	earned := replayGames(userID, games, history, existing, st)
+	if len(earned) == 0 {
+		return nil
+	}
+	if opts.dryRun {
+		ids := make([]string, len(earned))
+		for i, a := range earned {
+			ids[i] = a.ID
+		}
+		st.granted += len(earned)
+		logger.Info("Would grant achievements", logger.F("userID", userID, "achievements", strings.Join(ids, ",")))
+		return nil
+	}
+
+	for _, a := range earned {
+		granted, err := database.GrantAchievement(userID, a.ID, a.Points)
+		if err != nil {
+			return err
+		}
+		if granted {
+			st.granted++
+			logger.Info("Granted achievement", logger.F("userID", userID, "achievementID", a.ID))
+		}
+	}
+	return nil
+}
+
+// replayGames judges the player's games, oldest first, and returns the achievements
+// they earned that are not in existing (which it updates)
+func replayGames(userID string, games []models.Game, history []models.RatingPoint, existing map[string]bool, st *stats) []achievements.Achievement {
+	var earned []achievements.Achievement
+	winStreak := 0
+	for i, g := range games {
+		ctx, err := gameContext(userID, g, history)
+		if err != nil {
+			st.skipped++
+			logger.Warn("Skipped unreplayable game", logger.F("userID", userID, "gameId", g.GameID, "error", err.Error()))
+			continue
+		}
+		st.games++
+
+		if ctx.Won {
+			winStreak++
+		} else {
+			winStreak = 0
+		}
+		facts := achievements.GameFacts(ctx)
+		facts.Counters["games_played"] = i + 1
+		if ctx.Won {
+			facts.Counters["win_streak"] = winStreak
+		}
+
+		for _, a := range achievements.Evaluate(facts, g.CreatedAt) {
+			if !existing[a.ID] {
+				existing[a.ID] = true
+				earned = append(earned, a)
+			}
+		}
+	}
+	return earned
+}
+
+// gameContext rebuilds what the server knew about the game for userID when it ended
+func gameContext(userID string, g models.Game, history []models.RatingPoint) (achievements.GameContext, error) {
+	replay, err := chess.ReplayMoves(g.PGN)
+	if err != nil {
+		return achievements.GameContext{}, err
+	}
+
+	color, startRating := "white", g.PlayerWStartRating
+	if g.PlayerWID != userID {
+		color, startRating = "black", g.PlayerBStartRating
+	}
+
+	var reason string
+	switch _, r := replay.GetOutcome(); r {
+	case chess.ReasonCheckmate, chess.ReasonStalemate:
+		reason = string(r)
+	}
+
+	result := "draw"
+	switch g.Result {
+	case "1-0":
+		result = "white"
+	case "0-1":
+		result = "black"
+	}
+
+	return achievements.GameContext{
+		InnerGame:   replay.InnerGame(),
+		Result:      result,
+		Reason:      reason,
+		PlayerColor: color,
+		MoveCount:   len(replay.Moves()),
+		NewRating:   ratingAfter(history, g.CreatedAt, startRating),
+		Won:         result == color,
+		Drew:        result == "draw",
+	}, nil
+}
+
+// ratingAfter is the rating held once a game that ended at t was rated: the last
+// history entry at or before t, which for a standard game is the entry of the game
+// itself and for a bot game the standard rating it left unchanged. Games older than
+// the history fall back to the rating going into the game.
+func ratingAfter(history []models.RatingPoint, t time.Time, fallback int) int {
+	i := sort.Search(len(history), func(i int) bool { return history[i].CreatedAt.After(t) })
+	if i == 0 {
+		return fallback
+	}
+	return history[i-1].Rating
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/database"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/database/dbtest"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/models"
+)
+
+// foolsMate is won by black in four plies
+const foolsMate = "f2f3 e7e5 g2g4 d8h4"
+
+var day0 = time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+
+func day(n int) time.Time { return day0.AddDate(0, 0, n) }
+
+// blackWin is a game user u1 won as black, starting at rating
+func blackWin(id string, at time.Time, rating int) models.Game {
+	return models.Game{
+		GameID: id, PGN: foolsMate, PlayerWID: "opponent", PlayerBID: "u1",
+		PlayerWStartRating: 1500, PlayerBStartRating: rating, Result: "0-1", CreatedAt: at,
+	}
+}
+
+func earnedIDs(t *testing.T, games []models.Game, history []models.RatingPoint, existing map[string]bool) (map[string]bool, stats) {
+	t.Helper()
+	var st stats
+	if existing == nil {
+		existing = make(map[string]bool)
+	}
+	ids := make(map[string]bool)
+	for _, a := range replayGames("u1", games, history, existing, &st) {
+		ids[a.ID] = true
+	}
+	return ids, st
+}
+
+func TestReplayGames_Rating(t *testing.T) {
+	tests := []struct {
+		name    string
+		game    models.Game
+		history []models.RatingPoint
+		want    bool
+	}{
+		{
+			name: "rating after the game",
+			game: blackWin("g1", day(1), 1590),
+			history: []models.RatingPoint{
+				{Rating: 1500, CreatedAt: day(0)},
+				{Rating: 1610, CreatedAt: day(1)},
+			},
+			want: true,
+		},
+		{
+			name: "rating going into the game",
+			game: blackWin("g1", day(1), 1610),
+			history: []models.RatingPoint{
+				{Rating: 1610, CreatedAt: day(0)},
+				{Rating: 1590, CreatedAt: day(1)},
+			},
+			want: false,
+		},
+		{
+			name: "rating reached in a later game",
+			game: blackWin("g1", day(1), 1500),
+			history: []models.RatingPoint{
+				{Rating: 1500, CreatedAt: day(0)},
+				{Rating: 1550, CreatedAt: day(1)},
+				{Rating: 1650, CreatedAt: day(2)},
+			},
+			want: false,
+		},
+		{
+			name:    "bot game keeps the standard rating",
+			game:    blackWin("g1", day(3), 1700),
+			history: []models.RatingPoint{{Rating: 1620, CreatedAt: day(2)}},
+			want:    true,
+		},
+		{
+			name: "game older than the history",
+			game: blackWin("g1", day(1), 1600),
+			want: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ids, _ := earnedIDs(t, []models.Game{tt.game}, tt.history, nil)
+			if ids["rating_1600"] != tt.want {
+				t.Errorf("rating_1600 earned = %v, want %v (earned %v)", ids["rating_1600"], tt.want, ids)
+			}
+		})
+	}
+}
+
+func TestReplayGames_Counters(t *testing.T) {
+	games := []models.Game{
+		blackWin("g1", day(1), 1500),
+		blackWin("g2", day(2), 1500),
+		{GameID: "bad", PGN: "e2e5", PlayerWID: "u1", PlayerBID: "opponent", Result: "1-0", CreatedAt: day(3)},
+		blackWin("g3", day(4), 1500),
+	}
+
+	ids, st := earnedIDs(t, games, nil, map[string]bool{"first_win": true})
+	if !ids["win_streak_3"] {
+		t.Errorf("win_streak_3 not earned across an unreplayable game: %v", ids)
+	}
+	if ids["first_win"] {
+		t.Error("an achievement the player already has was earned again")
+	}
+	if st.games != 3 || st.skipped != 1 {
+		t.Errorf("stats = %d games, %d skipped; want 3, 1", st.games, st.skipped)
+	}
+
+	// A loss ends the streak
+	games[2] = models.Game{
+		GameID: "loss", PGN: foolsMate, PlayerWID: "u1", PlayerBID: "opponent", Result: "0-1", CreatedAt: day(3),
+	}
+	if ids, _ := earnedIDs(t, games, nil, nil); ids["win_streak_3"] {
+		t.Error("win_streak_3 earned with a loss in the streak")
+	}
+}
+
+func TestRatingAfter(t *testing.T) {
+	history := []models.RatingPoint{
+		{Rating: 1500, CreatedAt: day(0)},
+		{Rating: 1520, CreatedAt: day(2)},
+		{Rating: 1540, CreatedAt: day(2)},
+		{Rating: 1560, CreatedAt: day(4)},
+	}
+	tests := []struct {
+		at   time.Time
+		want int
+	}{
+		{day(-1), 1234},
+		{day(0), 1500},
+		{day(1), 1500},
+		{day(2), 1540},
+		{day(5), 1560},
+	}
+	for _, tt := range tests {
+		if got := ratingAfter(history, tt.at, 1234); got != tt.want {
+			t.Errorf("ratingAfter(%s) = %d, want %d", tt.at.Format(time.DateOnly), got, tt.want)
+		}
+	}
+}
+
+func TestRun_SkipsBots(t *testing.T) {
+	db, rec := dbtest.Open()
+	prev := database.DB
+	database.DB = db
+	t.Cleanup(func() {
+		db.Close()
+		database.DB = prev
+	})
+
+	if _, err := run(options{batch: 10, progress: 100}); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+	s, ok := rec.Find("FROM profiles p")
+	if !ok {
+		t.Fatal("no players were listed")
+	}
+	if !strings.Contains(s.SQL, "NOT p.is_bot") {
+		t.Errorf("player listing includes bots:\n%s", s.SQL)
+	}
+}
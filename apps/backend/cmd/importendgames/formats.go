@@ -0,0 +1,333 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/models"
+)
+
+// Supported file formats
+const (
+	formatCSV  = "csv"
+	formatJSON = "json"
+	formatEPD  = "epd"
+)
+
+// Column positions in the CSV format
+const (
+	colPositionID = iota
+	colFEN
+	colMoves
+	colRating
+	colThemes
+	colInitialEval
+	colDescription
+	colSource
+	colExpectedResult
+	numColumns
+)
+
+var csvHeader = []string{
+	"position_id", "fen", "moves", "rating", "themes",
+	"initial_eval", "description", "source", "expected_result",
+}
+
+// record is one position read from a file, before validation. rating is nil when the
+// file does not give one.
+type record struct {
+	line   int
+	pos    models.EndgamePosition
+	rating *int
+	err    error
+}
+
+// detectFormat returns the format named by flag, or the one implied by the file extension
+func detectFormat(flag, file string) (string, error) {
+	format := strings.ToLower(flag)
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(file)), ".")
+	}
+	switch format {
+	case formatCSV, formatJSON, formatEPD:
+		return format, nil
+	case "":
+		return "", errors.New("cannot tell the format of stdin, pass -format")
+	default:
+		return "", fmt.Errorf("unsupported format %q (want csv, json or epd)", format)
+	}
+}
+
+// readRecords parses every position in the input. Malformed entries are returned with
+// err set so they can be counted as rejected; a non-nil error means the input itself
+// could not be read.
+func readRecords(in io.Reader, format string) ([]record, error) {
+	switch format {
+	case formatJSON:
+		return readJSON(in)
+	case formatEPD:
+		return readEPD(in)
+	default:
+		return readCSV(in)
+	}
+}
+
+func readCSV(in io.Reader) ([]record, error) {
+	r := csv.NewReader(in)
+	r.FieldsPerRecord = -1
+
+	var records []record
+	for line := 1; ; line++ {
+		fields, err := r.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return records, fmt.Errorf("reading CSV: %w", err)
+		}
+		if line == 1 && len(fields) > 0 && fields[colPositionID] == "position_id" {
+			continue // header
+		}
+		rec := record{line: line}
+		rec.pos, rec.rating, rec.err = parseCSVRecord(fields)
+		records = append(records, rec)
+	}
+}
+
+// parseCSVRecord converts a CSV row; moves, rating, themes, initial_eval,
+// description, source and expected_result may be empty
+func parseCSVRecord(fields []string) (models.EndgamePosition, *int, error) {
+	if len(fields) < numColumns {
+		return models.EndgamePosition{}, nil, fmt.Errorf("expected %d columns, got %d", numColumns, len(fields))
+	}
+
+	pos := models.EndgamePosition{
+		PositionID:     strings.TrimSpace(fields[colPositionID]),
+		FEN:            strings.TrimSpace(fields[colFEN]),
+		Moves:          fields[colMoves],
+		Themes:         strings.Fields(fields[colThemes]),
+		Description:    strings.TrimSpace(fields[colDescription]),
+		Source:         strings.TrimSpace(fields[colSource]),
+		ExpectedResult: strings.TrimSpace(fields[colExpectedResult]),
+	}
+
+	var rating *int
+	if s := strings.TrimSpace(fields[colRating]); s != "" {
+		v, err := strconv.Atoi(s)
+		if err != nil {
+			return pos, nil, fmt.Errorf("invalid rating %q", s)
+		}
+		rating = &v
+	}
+	if s := strings.TrimSpace(fields[colInitialEval]); s != "" {
+		eval, err := strconv.Atoi(s)
+		if err != nil {
+			return pos, nil, fmt.Errorf("invalid initial_eval %q", s)
+		}
+		pos.InitialEval = &eval
+	}
+	return pos, rating, nil
+}
+
+// jsonPosition is the JSON format: an array of positions as the API returns them,
+// with rating optional
+type jsonPosition struct {
+	models.EndgamePosition
+	Rating *int `json:"rating"`
+}
+
+func readJSON(in io.Reader) ([]record, error) {
+	var entries []jsonPosition
+	if err := json.NewDecoder(in).Decode(&entries); err != nil {
+		return nil, fmt.Errorf("reading JSON: %w", err)
+	}
+
+	records := make([]record, len(entries))
+	for i, e := range entries {
+		records[i] = record{line: i + 1, pos: e.EndgamePosition, rating: e.Rating}
+	}
+	return records, nil
+}
+
+// readEPD reads one position per line: the four FEN fields followed by opcodes.
+// Recognised opcodes are id, c0 (description), ce (evaluation in centipawns for the
+// side to move), pv (solution in UCI notation), and the extensions rating, themes,
+// source and result (expected result for the side to move). Others are ignored.
+func readEPD(in io.Reader) ([]record, error) {
+	scanner := bufio.NewScanner(in)
+	var records []record
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		rec := record{line: line}
+		rec.pos, rec.rating, rec.err = parseEPDLine(text)
+		records = append(records, rec)
+	}
+	if err := scanner.Err(); err != nil {
+		return records, fmt.Errorf("reading EPD: %w", err)
+	}
+	return records, nil
+}
+
+func parseEPDLine(text string) (models.EndgamePosition, *int, error) {
+	fields := strings.SplitN(text, " ", 5)
+	if len(fields) < 4 {
+		return models.EndgamePosition{}, nil, errors.New("expected four FEN fields")
+	}
+	pos := models.EndgamePosition{FEN: strings.Join(fields[:4], " ") + " 0 1"}
+	if len(fields) == 4 {
+		return pos, nil, nil
+	}
+
+	ops, err := parseEPDOperations(fields[4])
+	if err != nil {
+		return pos, nil, err
+	}
+
+	var rating *int
+	for op, operand := range ops {
+		switch op {
+		case "id":
+			pos.PositionID = operand
+		case "c0":
+			pos.Description = operand
+		case "pv":
+			pos.Moves = operand
+		case "themes":
+			pos.Themes = strings.Fields(operand)
+		case "source":
+			pos.Source = operand
+		case "result":
+			pos.ExpectedResult = operand
+		case "rating":
+			v, err := strconv.Atoi(operand)
+			if err != nil {
+				return pos, nil, fmt.Errorf("invalid rating %q", operand)
+			}
+			rating = &v
+		case "ce":
+			ce, err := strconv.Atoi(operand)
+			if err != nil {
+				return pos, nil, fmt.Errorf("invalid ce %q", operand)
+			}
+			// initial_eval is from white's point of view
+			if fields[1] == "b" {
+				ce = -ce
+			}
+			pos.InitialEval = &ce
+		}
+	}
+	return pos, rating, nil
+}
+
+// parseEPDOperations splits `op operand; op "quoted operand";` into a map
+func parseEPDOperations(s string) (map[string]string, error) {
+	ops := make(map[string]string)
+	for s = strings.TrimSpace(s); s != ""; s = strings.TrimSpace(s) {
+		var op, operand string
+		op, s, _ = strings.Cut(s, " ")
+		if strings.HasSuffix(op, ";") {
+			ops[strings.TrimSuffix(op, ";")] = ""
+			continue
+		}
+		s = strings.TrimLeft(s, " ")
+		if strings.HasPrefix(s, `"`) {
+			end := strings.Index(s[1:], `"`)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string for opcode %s", op)
+			}
+			operand, s = s[1:end+1], s[end+2:]
+			s = strings.TrimLeft(s, " ")
+			if !strings.HasPrefix(s, ";") {
+				return nil, fmt.Errorf("missing ';' after opcode %s", op)
+			}
+			s = s[1:]
+		} else {
+			var found bool
+			operand, s, found = strings.Cut(s, ";")
+			if !found {
+				return nil, fmt.Errorf("missing ';' after opcode %s", op)
+			}
+		}
+		ops[op] = strings.TrimSpace(operand)
+	}
+	return ops, nil
+}
+
+// writePositions writes positions in format
+func writePositions(out io.Writer, format string, positions []models.EndgamePosition) error {
+	switch format {
+	case formatJSON:
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(positions)
+	case formatEPD:
+		w := bufio.NewWriter(out)
+		for _, pos := range positions {
+			fmt.Fprintln(w, formatEPDLine(pos))
+		}
+		return w.Flush()
+	default:
+		w := csv.NewWriter(out)
+		if err := w.Write(csvHeader); err != nil {
+			return err
+		}
+		for _, pos := range positions {
+			eval := ""
+			if pos.InitialEval != nil {
+				eval = strconv.Itoa(*pos.InitialEval)
+			}
+			if err := w.Write([]string{
+				pos.PositionID, pos.FEN, pos.Moves, strconv.Itoa(pos.Rating), strings.Join(pos.Themes, " "),
+				eval, pos.Description, pos.Source, pos.ExpectedResult,
+			}); err != nil {
+				return err
+			}
+		}
+		w.Flush()
+		return w.Error()
+	}
+}
+
+// formatEPDLine encodes a position in the EPD format readEPD accepts
+func formatEPDLine(pos models.EndgamePosition) string {
+	fields := strings.Fields(pos.FEN)
+	if len(fields) > 4 {
+		fields = fields[:4]
+	}
+
+	var b strings.Builder
+	b.WriteString(strings.Join(fields, " "))
+	// EPD strings have no escapes: write the operand as is, minus any double quotes
+	quoted := func(op, operand string) {
+		if operand != "" {
+			fmt.Fprintf(&b, ` %s "%s";`, op, strings.ReplaceAll(operand, `"`, "'"))
+		}
+	}
+	quoted("id", pos.PositionID)
+	fmt.Fprintf(&b, " rating %d;", pos.Rating)
+	quoted("themes", strings.Join(pos.Themes, " "))
+	if pos.InitialEval != nil {
+		ce := *pos.InitialEval
+		if len(fields) > 1 && fields[1] == "b" {
+			ce = -ce
+		}
+		fmt.Fprintf(&b, " ce %d;", ce)
+	}
+	if pos.Moves != "" {
+		fmt.Fprintf(&b, " pv %s;", pos.Moves)
+	}
+	quoted("result", pos.ExpectedResult)
+	quoted("c0", pos.Description)
+	quoted("source", pos.Source)
+	return b.String()
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/models"
+)
+
+func intPtr(v int) *int { return &v }
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		flag, file string
+		want       string
+		wantErr    bool
+	}{
+		{file: "positions.csv", want: formatCSV},
+		{file: "dir/Positions.JSON", want: formatJSON},
+		{file: "suite.epd", want: formatEPD},
+		{flag: "EPD", file: "positions.txt", want: formatEPD},
+		{flag: "csv", file: "positions.json", want: formatCSV},
+		{file: "positions.txt", wantErr: true},
+		{file: "", wantErr: true},
+		{flag: "pgn", file: "positions.csv", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.flag+"/"+tt.file, func(t *testing.T) {
+			got, err := detectFormat(tt.flag, tt.file)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("detectFormat() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("detectFormat() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseEPDOperations(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    map[string]string
+		wantErr string
+	}{
+		{"empty", "", map[string]string{}, ""},
+		{"bare operand", "pv e2e4 e7e5;", map[string]string{"pv": "e2e4 e7e5"}, ""},
+		{"quoted operand", `id "KQ vs K; easy";`, map[string]string{"id": "KQ vs K; easy"}, ""},
+		{
+			"several",
+			`id "a";  ce 120 ;c0 "two words";`,
+			map[string]string{"id": "a", "ce": "120", "c0": "two words"},
+			"",
+		},
+		{"no operand", "draw; id x;", map[string]string{"draw": "", "id": "x"}, ""},
+		{"unterminated string", `id "never closed;`, nil, "unterminated string for opcode id"},
+		{"quoted without ';'", `id "a" ce 5;`, nil, "missing ';' after opcode id"},
+		{"bare without ';'", "ce 120", nil, "missing ';' after opcode ce"},
+		{"opcode alone", "ce", nil, "missing ';' after opcode ce"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseEPDOperations(tt.in)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("parseEPDOperations() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseEPDOperations() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseEPDOperations() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseEPDLine(t *testing.T) {
+	tests := []struct {
+		name       string
+		line       string
+		want       models.EndgamePosition
+		wantRating *int
+		wantErr    bool
+	}{
+		{
+			name: "position only",
+			line: "8/8/8/4k3/8/8/1Q6/K7 w - -",
+			want: models.EndgamePosition{FEN: "8/8/8/4k3/8/8/1Q6/K7 w - - 0 1"},
+		},
+		{
+			name: "all opcodes",
+			line: `8/8/8/4k3/8/8/1Q6/K7 w - - id "kq-1"; rating 900; themes "queen_endgame"; ce 950; ` +
+				`pv b2b5; result "win"; c0 "Queen mate"; source "manual"; bm Qb5;`,
+			want: models.EndgamePosition{
+				PositionID: "kq-1", FEN: "8/8/8/4k3/8/8/1Q6/K7 w - - 0 1", Moves: "b2b5",
+				Themes: []string{"queen_endgame"}, InitialEval: intPtr(950),
+				Description: "Queen mate", Source: "manual", ExpectedResult: "win",
+			},
+			wantRating: intPtr(900),
+		},
+		{
+			name: "ce for black to move",
+			line: "8/8/8/4k3/8/8/1Q6/K7 b - - ce -950;",
+			want: models.EndgamePosition{FEN: "8/8/8/4k3/8/8/1Q6/K7 b - - 0 1", InitialEval: intPtr(950)},
+		},
+		{name: "too few fields", line: "8/8/8/4k3/8/8/1Q6/K7 w -", wantErr: true},
+		{name: "invalid rating", line: "8/8/8/4k3/8/8/1Q6/K7 w - - rating high;", wantErr: true},
+		{name: "invalid ce", line: "8/8/8/4k3/8/8/1Q6/K7 w - - ce +M3;", wantErr: true},
+		{name: "malformed opcode", line: `8/8/8/4k3/8/8/1Q6/K7 w - - id "kq-1`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, rating, err := parseEPDLine(tt.line)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseEPDLine() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseEPDLine() = %+v, want %+v", got, tt.want)
+			}
+			if !reflect.DeepEqual(rating, tt.wantRating) {
+				t.Errorf("parseEPDLine() rating = %v, want %v", rating, tt.wantRating)
+			}
+		})
+	}
+}
+
+func TestReadEPD(t *testing.T) {
+	in := "# endgame suite\n\n" +
+		"8/8/8/4k3/8/8/1Q6/K7 w - - id \"kq-1\";\n" +
+		"8/8/8/4k3/8/8/1Q6/K7 w -\n"
+	records, err := readEPD(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("readEPD() returned %d records, want 2", len(records))
+	}
+	if records[0].line != 3 || records[0].err != nil || records[0].pos.PositionID != "kq-1" {
+		t.Errorf("first record = %+v, want kq-1 on line 3", records[0])
+	}
+	if records[1].line != 4 || records[1].err == nil {
+		t.Errorf("second record = %+v, want an error on line 4", records[1])
+	}
+}
+
+func TestExportRoundTrip(t *testing.T) {
+	positions := []models.EndgamePosition{
+		{
+			PositionID: "kq-1", FEN: "8/8/8/4k3/8/8/1Q6/K7 w - - 0 1", Moves: "b2b5 e5d4",
+			Rating: 900, Themes: []string{"queen_endgame", "mate"}, InitialEval: intPtr(950),
+			Description: `Drive the king back; use "the box"`, Source: `lichess\study`, ExpectedResult: "win",
+		},
+		{
+			PositionID: "kr-2", FEN: "8/8/8/8/8/3k4/3R4/3K4 b - - 0 1",
+			Rating: 1400, Themes: []string{"rook_endgame"}, InitialEval: intPtr(-610),
+			ExpectedResult: "loss",
+		},
+		{
+			PositionID: "kp-3", FEN: "8/8/8/8/8/4k3/4P3/4K3 w - - 0 1",
+			Rating: 1200, Themes: []string{"pawn_endgame"}, ExpectedResult: "draw",
+		},
+	}
+
+	for _, format := range []string{formatCSV, formatJSON, formatEPD} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := writePositions(&buf, format, positions); err != nil {
+				t.Fatal(err)
+			}
+			records, err := readRecords(&buf, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(records) != len(positions) {
+				t.Fatalf("read %d records, want %d", len(records), len(positions))
+			}
+			for i, rec := range records {
+				if rec.err != nil {
+					t.Fatalf("record %d: %v", i, rec.err)
+				}
+				if rec.rating == nil {
+					t.Fatalf("record %d: rating missing", i)
+				}
+				got := rec.pos
+				got.Rating = *rec.rating
+
+				want := positions[i]
+				if format == formatEPD {
+					// Double quotes cannot be escaped in EPD strings
+					want.Description = strings.ReplaceAll(want.Description, `"`, "'")
+				}
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("record %d = %+v, want %+v", i, got, want)
+				}
+			}
+		})
+	}
+}
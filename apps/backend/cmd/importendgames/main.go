@@ -0,0 +1,506 @@
+// Command importendgames loads endgame training positions into the
+// endgame_positions table from CSV, JSON or EPD files, and exports the table in the
+// same formats.
+//
+// CSV has a header row and these columns (all but position_id and fen may be empty;
+// themes are space separated):
+//
+//	position_id,fen,moves,rating,themes,initial_eval,description,source,expected_result
+//
+// JSON is an array of positions as the API returns them. EPD has one position per
+// line with the opcodes id, rating, themes, ce (centipawns for the side to move), pv
+// (UCI moves), result, c0 (description) and source.
+//
+// Usage:
+//
+//	go run ./cmd/importendgames -file positions.epd [-syzygy /path/to/tables]
+//	go run ./cmd/importendgames -export positions.csv
+//	go run ./cmd/importendgames -backfill [-rerate]
+//
+// Every entry is validated (legal position, playable moves, known result) before it
+// is stored. FENs are normalised with their move counters reset, and positions
+// without an ID get one derived from the position. Positions that repeat one already
+// in the file or the table under another ID — ignoring move counters — are
+// rejected; the same ID updates the stored position.
+//
+// Themes are classified with chess.Classify: material themes and endgame families
+// (KRPvKR, KQvKR, opposite-coloured bishops, ...) are derived and replace any given,
+// while technique themes are kept. Positions without a rating whose result is known
+// get an estimated one. -backfill applies the same classification to the stored
+// positions, and -rerate also re-estimates their ratings.
+//
+// With Syzygy tables (-syzygy, default $SYZYGY_PATH), entries whose stated outcome
+// — expected_result, or the one implied by initial_eval — contradicts the
+// tablebase are rejected, and entries without expected_result get the tablebase
+// result.
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/joho/godotenv"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/chess"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/database"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/logger"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/models"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/tablebase"
+)
+
+// Rating bounds accepted for imported positions
+const (
+	minRating = 0
+	maxRating = 4000
+)
+
+// exportPage is the number of positions read per query when exporting
+const exportPage = 1000
+
+type options struct {
+	file          string
+	export        string
+	backfill      bool
+	rerate        bool
+	format        string
+	batch         int
+	defaultRating int
+	dryRun        bool
+	syzygy        string
+}
+
+type stats struct {
+	read       int
+	imported   int
+	rejected   int
+	duplicates int
+	verified   int
+}
+
+func main() {
+	_ = godotenv.Load()
+
+	opts := options{}
+	flag.StringVar(&opts.file, "file", "", "path to the positions file to import, or - for stdin")
+	flag.StringVar(&opts.export, "export", "", "path to export the table to, or - for stdout")
+	flag.BoolVar(&opts.backfill, "backfill", false, "reclassify the stored positions' themes")
+	flag.BoolVar(&opts.rerate, "rerate", false, "with -backfill, also replace ratings with estimates where the result is known")
+	flag.StringVar(&opts.format, "format", "", "csv, json or epd (default: from the file extension)")
+	flag.IntVar(&opts.batch, "batch", 500, "rows per upsert batch")
+	flag.IntVar(&opts.defaultRating, "default-rating", 1200, "rating for positions that do not give one")
+	flag.BoolVar(&opts.dryRun, "dry-run", false, "validate positions without writing to the database")
+	flag.StringVar(&opts.syzygy, "syzygy", os.Getenv("SYZYGY_PATH"), "directory of Syzygy tables to check results against")
+	flag.Parse()
+
+	logger.Configure(os.Getenv("LOG_LEVEL"), false)
+
+	modes := 0
+	for _, set := range []bool{opts.file != "", opts.export != "", opts.backfill} {
+		if set {
+			modes++
+		}
+	}
+	if modes != 1 || opts.batch < 1 {
+		fmt.Fprintln(os.Stderr, "usage: importendgames -file <path|-> [-format F] [-batch N] [-default-rating N] [-dry-run] [-syzygy dir]")
+		fmt.Fprintln(os.Stderr, "       importendgames -export <path|-> [-format F]")
+		fmt.Fprintln(os.Stderr, "       importendgames -backfill [-rerate] [-dry-run] [-syzygy dir]")
+		os.Exit(2)
+	}
+	if opts.defaultRating < minRating || opts.defaultRating > maxRating {
+		fmt.Fprintf(os.Stderr, "default-rating must be between %d and %d\n", minRating, maxRating)
+		os.Exit(2)
+	}
+
+	var format string
+	if !opts.backfill {
+		path := opts.file
+		if opts.export != "" {
+			path = opts.export
+		}
+		if path == "-" {
+			path = ""
+		}
+		f, err := detectFormat(opts.format, path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		format = f
+	}
+
+	if opts.export != "" {
+		database.InitPostgres()
+		defer database.Close()
+		if err := exportPositions(opts.export, format); err != nil {
+			logger.Error("Endgame export failed", logger.F("error", err.Error()))
+			os.Exit(1)
+		}
+		return
+	}
+
+	var prober *tablebase.Prober
+	if opts.syzygy != "" {
+		p, err := tablebase.Open(opts.syzygy)
+		if err != nil {
+			logger.Error("Failed to open Syzygy tables", logger.F("dir", opts.syzygy, "error", err.Error()))
+			os.Exit(1)
+		}
+		defer p.Close()
+		prober = p
+	} else {
+		logger.Warn("No Syzygy tables configured, results are not checked")
+	}
+
+	if opts.backfill {
+		database.InitPostgres()
+		defer database.Close()
+		read, updated, err := backfill(opts, prober)
+		logger.Info("Endgame backfill finished", logger.F(
+			"read", read,
+			"updated", updated,
+			"rerate", opts.rerate,
+			"dryRun", opts.dryRun,
+		))
+		if err != nil {
+			logger.Error("Endgame backfill failed", logger.F("error", err.Error()))
+			os.Exit(1)
+		}
+		return
+	}
+
+	in := os.Stdin
+	if opts.file != "-" {
+		f, err := os.Open(opts.file)
+		if err != nil {
+			logger.Error("Failed to open positions file", logger.F("file", opts.file, "error", err.Error()))
+			os.Exit(1)
+		}
+		defer f.Close()
+		in = f
+	}
+
+	// Positions already stored, by position key, so the file cannot add them again
+	existing := make(map[string]string)
+	if !opts.dryRun {
+		database.InitPostgres()
+		defer database.Close()
+		if err := eachStoredPosition(func(pos models.EndgamePosition) error {
+			if key, err := chess.PositionKey(pos.FEN); err == nil {
+				existing[key] = pos.PositionID
+			}
+			return nil
+		}); err != nil {
+			logger.Error("Failed to load existing endgame positions", logger.F("error", err.Error()))
+			os.Exit(1)
+		}
+	}
+
+	st, err := run(in, format, opts, existing, prober)
+	logger.Info("Endgame import finished", logger.F(
+		"read", st.read,
+		"imported", st.imported,
+		"rejected", st.rejected,
+		"duplicates", st.duplicates,
+		"verified", st.verified,
+		"dryRun", opts.dryRun,
+	))
+	if err != nil {
+		logger.Error("Endgame import failed", logger.F("error", err.Error()))
+		os.Exit(1)
+	}
+}
+
+// errDuplicate marks a position already present under another ID
+var errDuplicate = errors.New("duplicate position")
+
+// run validates the positions in the input and upserts them in batches. existing maps
+// the position keys of stored positions to their IDs.
+func run(in io.Reader, format string, opts options, existing map[string]string, prober *tablebase.Prober) (stats, error) {
+	var st stats
+
+	records, readErr := readRecords(in, format)
+
+	batch := make([]models.EndgamePosition, 0, opts.batch)
+	flush := func() error {
+		if len(batch) > 0 && !opts.dryRun {
+			if err := database.BulkInsertEndgamePositions(batch); err != nil {
+				return err
+			}
+		}
+		st.imported += len(batch)
+		batch = batch[:0]
+		return nil
+	}
+
+	seenIDs := make(map[string]bool)
+	for _, rec := range records {
+		st.read++
+		pos, key, err := rec.pos, "", rec.err
+		if err == nil {
+			key, err = prepare(&pos, rec.rating, opts.defaultRating)
+		}
+		if err == nil {
+			err = checkDuplicate(pos.PositionID, key, seenIDs, existing)
+		}
+		if err == nil && prober != nil {
+			var verified bool
+			verified, err = checkTablebase(prober, &pos)
+			if verified {
+				st.verified++
+			}
+		}
+		if err == nil && rec.rating == nil {
+			if rating, ok := estimateRating(prober, pos); ok {
+				pos.Rating = rating
+			}
+		}
+		if err != nil {
+			if errors.Is(err, errDuplicate) {
+				st.duplicates++
+			} else {
+				st.rejected++
+			}
+			logger.Warn("Rejected endgame position", logger.F("line", rec.line, "error", err.Error()))
+			continue
+		}
+
+		seenIDs[pos.PositionID] = true
+		existing[key] = pos.PositionID
+		batch = append(batch, pos)
+		if len(batch) >= opts.batch {
+			if err := flush(); err != nil {
+				return st, err
+			}
+		}
+	}
+
+	if err := flush(); err != nil {
+		return st, err
+	}
+	return st, readErr
+}
+
+// prepare validates pos and fills in what the file may leave out: the normalised FEN,
+// an ID, the default rating and the classified themes. It returns the position key.
+func prepare(pos *models.EndgamePosition, rating *int, defaultRating int) (string, error) {
+	pos.PositionID = strings.TrimSpace(pos.PositionID)
+	fen, err := chess.NormalizeFEN(pos.FEN)
+	if err != nil {
+		return "", fmt.Errorf("position %q: %w", pos.PositionID, err)
+	}
+	pos.FEN = fen
+	key, err := chess.PositionKey(fen)
+	if err != nil {
+		return "", fmt.Errorf("position %q: %w", pos.PositionID, err)
+	}
+
+	if pos.PositionID == "" {
+		sum := sha1.Sum([]byte(key))
+		pos.PositionID = "eg_" + hex.EncodeToString(sum[:])[:12]
+	}
+
+	moves := chess.SplitMoves(pos.Moves)
+	if len(moves) > 0 {
+		if _, err := chess.ValidateLine(fen, moves); err != nil {
+			return "", fmt.Errorf("position %s: %w", pos.PositionID, err)
+		}
+	}
+	pos.Moves = strings.Join(moves, " ")
+
+	pos.Rating = defaultRating
+	if rating != nil {
+		pos.Rating = *rating
+	}
+	if pos.Rating < minRating || pos.Rating > maxRating {
+		return "", fmt.Errorf("position %s: invalid rating %d", pos.PositionID, pos.Rating)
+	}
+
+	switch pos.ExpectedResult {
+	case "", models.ExpectedWin, models.ExpectedDraw, models.ExpectedLoss:
+	default:
+		return "", fmt.Errorf("position %s: invalid expected_result %q", pos.PositionID, pos.ExpectedResult)
+	}
+
+	themes, err := classifyThemes(fen, pos.Themes)
+	if err != nil {
+		return "", fmt.Errorf("position %s: %w", pos.PositionID, err)
+	}
+	pos.Themes = themes
+
+	return key, nil
+}
+
+// classifyThemes returns the themes Classify derives for fen followed by the given
+// themes it does not derive, such as techniques ("opposition", "lucena")
+func classifyThemes(fen string, given []string) ([]string, error) {
+	c, err := chess.Classify(fen)
+	if err != nil {
+		return nil, err
+	}
+	themes := c.Themes
+	for _, t := range given {
+		if !chess.IsClassifiedTheme(t) && !contains(themes, t) {
+			themes = append(themes, t)
+		}
+	}
+	return themes, nil
+}
+
+// estimateRating estimates the rating of pos from its classification when its
+// result is known, from the tablebase, expected_result or an engine evaluation
+func estimateRating(prober *tablebase.Prober, pos models.EndgamePosition) (int, bool) {
+	if pos.ExpectedResult == "" && pos.InitialEval == nil {
+		return 0, false
+	}
+	c, err := chess.Classify(pos.FEN)
+	if err != nil {
+		return 0, false
+	}
+	var dtz *int
+	if prober != nil {
+		if d, err := prober.ProbeDTZ(pos.FEN); err == nil {
+			dtz = &d
+		}
+	}
+	return chess.EstimateRating(c, pos.ExpectedOutcome(), dtz), true
+}
+
+// checkDuplicate rejects a repeated ID within the file, and a position already in
+// the file or the table under another ID
+func checkDuplicate(id, key string, seenIDs map[string]bool, existing map[string]string) error {
+	if seenIDs[id] {
+		return fmt.Errorf("position %s: %w: ID repeated in the file", id, errDuplicate)
+	}
+	if other, ok := existing[key]; ok && other != id {
+		return fmt.Errorf("position %s: %w of %s", id, errDuplicate, other)
+	}
+	return nil
+}
+
+// checkTablebase rejects a position whose stated outcome contradicts the tablebase
+// and records the tablebase result when none is stated. It reports whether the
+// tables covered the position.
+func checkTablebase(prober *tablebase.Prober, pos *models.EndgamePosition) (bool, error) {
+	wdl, err := prober.ProbeWDL(pos.FEN)
+	switch {
+	case errors.Is(err, tablebase.ErrNotAvailable), errors.Is(err, tablebase.ErrTooManyPieces), errors.Is(err, tablebase.ErrCastling):
+		return false, nil
+	case err != nil:
+		return false, fmt.Errorf("position %s: %w", pos.PositionID, err)
+	}
+
+	// ExpectedOutcome falls back to a win when nothing is stated, so only a supplied
+	// result or evaluation is checked
+	if pos.ExpectedResult != "" || pos.InitialEval != nil {
+		if stated := pos.ExpectedOutcome(); stated != wdl.Result() {
+			return true, fmt.Errorf("position %s: stated outcome %q contradicts tablebase %q (%s)",
+				pos.PositionID, stated, wdl.Result(), wdl)
+		}
+	}
+	pos.ExpectedResult = wdl.Result()
+	return true, nil
+}
+
+// backfill reclassifies the themes of every stored position and, with -rerate,
+// replaces ratings with estimates. It returns the number of positions read and
+// changed.
+func backfill(opts options, prober *tablebase.Prober) (int, int, error) {
+	var positions []models.EndgamePosition
+	if err := eachStoredPosition(func(pos models.EndgamePosition) error {
+		positions = append(positions, pos)
+		return nil
+	}); err != nil {
+		return 0, 0, err
+	}
+
+	updated := 0
+	for _, pos := range positions {
+		themes, err := classifyThemes(pos.FEN, pos.Themes)
+		if err != nil {
+			logger.Warn("Skipping unclassifiable endgame position", logger.F("positionID", pos.PositionID, "error", err.Error()))
+			continue
+		}
+		rating := pos.Rating
+		if opts.rerate {
+			if r, ok := estimateRating(prober, pos); ok {
+				rating = r
+			}
+		}
+		if rating == pos.Rating && strings.Join(themes, " ") == strings.Join(pos.Themes, " ") {
+			continue
+		}
+
+		logger.Info("Reclassified endgame position", logger.F(
+			"positionID", pos.PositionID,
+			"themes", strings.Join(themes, " "),
+			"rating", rating,
+		))
+		if !opts.dryRun {
+			if err := database.UpdateEndgameClassification(pos.PositionID, themes, rating); err != nil {
+				return len(positions), updated, err
+			}
+		}
+		updated++
+	}
+	return len(positions), updated, nil
+}
+
+// exportPositions writes the whole table to path in format
+func exportPositions(path, format string) error {
+	var positions []models.EndgamePosition
+	if err := eachStoredPosition(func(pos models.EndgamePosition) error {
+		positions = append(positions, pos)
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	out := os.Stdout
+	if path != "-" {
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	if err := writePositions(out, format, positions); err != nil {
+		return err
+	}
+	logger.Info("Endgame export finished", logger.F("positions", len(positions), "format", format))
+	return nil
+}
+
+// eachStoredPosition calls fn for every stored position in ID order
+func eachStoredPosition(fn func(models.EndgamePosition) error) error {
+	after := ""
+	for {
+		page, err := database.ListEndgamePositions(after, exportPage)
+		if err != nil {
+			return err
+		}
+		for _, pos := range page {
+			if err := fn(pos); err != nil {
+				return err
+			}
+		}
+		if len(page) < exportPage {
+			return nil
+		}
+		after = page[len(page)-1].PositionID
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/models"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/tablebase"
+)
+
+// openKQvKProber opens a synthetic KQvK WDL table in which white to move always wins
+// and black to move always loses
+func openKQvKProber(t *testing.T) *tablebase.Prober {
+	t.Helper()
+	table := []byte{
+		0x71, 0xE8, 0x23, 0x5D, // WDL magic
+		0x01,             // split: both sides to move
+		0x00,             // group order
+		0x66, 0x55, 0xEE, // white king, white queen, black king
+		0x00,       // alignment
+		0x80, 0x04, // white to move: single value, win
+		0x80, 0x00, // black to move: single value, loss
+	}
+	table = append(table, make([]byte, 64-len(table))...)
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "KQvK.rtbw"), table, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	p, err := tablebase.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { p.Close() })
+	return p
+}
+
+func TestCheckTablebase(t *testing.T) {
+	prober := openKQvKProber(t)
+
+	const (
+		whiteToMove = "8/8/8/4k3/8/8/1Q6/K7 w - - 0 1"
+		blackToMove = "8/8/8/4k3/8/8/1Q6/K7 b - - 0 1"
+	)
+	tests := []struct {
+		name        string
+		pos         models.EndgamePosition
+		wantCovered bool
+		wantResult  string
+		wantErr     string
+	}{
+		{"nothing stated", models.EndgamePosition{FEN: whiteToMove}, true, models.ExpectedWin, ""},
+		{"nothing stated, losing side to move", models.EndgamePosition{FEN: blackToMove}, true, models.ExpectedLoss, ""},
+		{"basic mate theme alone", models.EndgamePosition{FEN: blackToMove, Themes: []string{"basicMate"}}, true, models.ExpectedLoss, ""},
+		{"stated result agrees", models.EndgamePosition{FEN: blackToMove, ExpectedResult: models.ExpectedLoss}, true, models.ExpectedLoss, ""},
+		{"evaluation agrees", models.EndgamePosition{FEN: blackToMove, InitialEval: intPtr(900)}, true, models.ExpectedLoss, ""},
+		{"stated result contradicts", models.EndgamePosition{FEN: whiteToMove, ExpectedResult: models.ExpectedDraw}, true, "", "contradicts tablebase"},
+		{"evaluation contradicts", models.EndgamePosition{FEN: whiteToMove, InitialEval: intPtr(0)}, true, "", "contradicts tablebase"},
+		{"material without a table", models.EndgamePosition{FEN: "8/8/8/4k3/8/8/1R6/K7 w - - 0 1"}, false, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.pos.PositionID = "p1"
+			covered, err := checkTablebase(prober, &tt.pos)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("checkTablebase() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || covered != tt.wantCovered {
+				t.Fatalf("checkTablebase() = %v, %v; want %v, nil", covered, err, tt.wantCovered)
+			}
+			if tt.pos.ExpectedResult != tt.wantResult {
+				t.Errorf("ExpectedResult = %q, want %q", tt.pos.ExpectedResult, tt.wantResult)
+			}
+		})
+	}
+}
@@ -0,0 +1,210 @@
+// Command importmiddlegames loads middlegame training positions from a CSV file
+// into the middlegame_positions table. The curated seed in
+// db/seeds/middlegame_positions_curated.sql covers development; this command is
+// for larger sets.
+//
+// The CSV has a header row and these columns (moves, initial_eval, description
+// and source may be empty; themes are space separated):
+//
+//	position_id,fen,moves,rating,themes,initial_eval,description,source
+//
+// Usage:
+//
+//	go run ./cmd/importmiddlegames -file positions.csv
+//
+// Every row is validated (legal FEN, playable moves, known themes) before it is
+// stored. Existing positions with the same ID are updated.
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/joho/godotenv"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/chess"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/database"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/logger"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/models"
+)
+
+// Column positions in the import CSV
+const (
+	colPositionID = iota
+	colFEN
+	colMoves
+	colRating
+	colThemes
+	colInitialEval
+	colDescription
+	colSource
+	numColumns
+)
+
+type stats struct {
+	read     int
+	imported int
+	rejected int
+}
+
+func main() {
+	file := flag.String("file", "", "path to the positions CSV, or - for stdin")
+	batchSize := flag.Int("batch", 500, "rows per upsert batch")
+	dryRun := flag.Bool("dry-run", false, "validate rows without writing to the database")
+	flag.Parse()
+
+	_ = godotenv.Load()
+	logger.Configure(os.Getenv("LOG_LEVEL"), false)
+
+	if *file == "" || *batchSize < 1 {
+		fmt.Fprintln(os.Stderr, "usage: importmiddlegames -file <path|-> [-batch N] [-dry-run]")
+		os.Exit(2)
+	}
+
+	in := os.Stdin
+	if *file != "-" {
+		f, err := os.Open(*file)
+		if err != nil {
+			logger.Error("Failed to open positions file", logger.F("file", *file, "error", err.Error()))
+			os.Exit(1)
+		}
+		defer f.Close()
+		in = f
+	}
+
+	if !*dryRun {
+		database.InitPostgres()
+		defer database.Close()
+	}
+
+	st, err := run(in, *batchSize, *dryRun)
+	logger.Info("Middlegame import finished", logger.F(
+		"read", st.read,
+		"imported", st.imported,
+		"rejected", st.rejected,
+		"dryRun", *dryRun,
+	))
+	if err != nil {
+		logger.Error("Middlegame import failed", logger.F("error", err.Error()))
+		os.Exit(1)
+	}
+}
+
+// run reads the CSV and upserts valid rows in batches
+func run(in io.Reader, batchSize int, dryRun bool) (stats, error) {
+	var st stats
+
+	r := csv.NewReader(in)
+	r.FieldsPerRecord = -1
+
+	batch := make([]models.MiddlegamePosition, 0, batchSize)
+	flush := func() error {
+		if len(batch) > 0 && !dryRun {
+			if err := database.BulkInsertMiddlegamePositions(batch); err != nil {
+				return err
+			}
+		}
+		st.imported += len(batch)
+		batch = batch[:0]
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	row := 0
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return st, fmt.Errorf("reading CSV: %w", err)
+		}
+		row++
+		if row == 1 && len(record) > 0 && record[colPositionID] == "position_id" {
+			continue // header
+		}
+
+		st.read++
+		pos, err := parseRecord(record)
+		if err == nil && seen[pos.PositionID] {
+			err = fmt.Errorf("duplicate position ID %s", pos.PositionID)
+		}
+		if err != nil {
+			st.rejected++
+			logger.Warn("Rejected middlegame row", logger.F("row", row, "error", err.Error()))
+			continue
+		}
+		seen[pos.PositionID] = true
+		batch = append(batch, pos)
+
+		if len(batch) >= batchSize {
+			if err := flush(); err != nil {
+				return st, err
+			}
+		}
+	}
+
+	return st, flush()
+}
+
+// parseRecord validates a CSV row and converts it into a middlegame position
+func parseRecord(record []string) (models.MiddlegamePosition, error) {
+	if len(record) < numColumns {
+		return models.MiddlegamePosition{}, fmt.Errorf("expected %d columns, got %d", numColumns, len(record))
+	}
+
+	id := strings.TrimSpace(record[colPositionID])
+	if id == "" {
+		return models.MiddlegamePosition{}, errors.New("missing position ID")
+	}
+
+	fen := strings.TrimSpace(record[colFEN])
+	if _, err := chess.NewGameFromFEN(fen); err != nil {
+		return models.MiddlegamePosition{}, fmt.Errorf("position %s: %w", id, err)
+	}
+
+	moves := chess.SplitMoves(record[colMoves])
+	if len(moves) > 0 {
+		if _, err := chess.ValidateLine(fen, moves); err != nil {
+			return models.MiddlegamePosition{}, fmt.Errorf("position %s: %w", id, err)
+		}
+	}
+
+	rating, err := strconv.Atoi(strings.TrimSpace(record[colRating]))
+	if err != nil || rating < 0 || rating > 4000 {
+		return models.MiddlegamePosition{}, fmt.Errorf("position %s: invalid rating %q", id, record[colRating])
+	}
+
+	themes := strings.Fields(record[colThemes])
+	if len(themes) == 0 {
+		return models.MiddlegamePosition{}, fmt.Errorf("position %s: no themes", id)
+	}
+	for _, theme := range themes {
+		if !models.IsMiddlegameTheme(theme) {
+			return models.MiddlegamePosition{}, fmt.Errorf("position %s: unknown theme %q", id, theme)
+		}
+	}
+
+	pos := models.MiddlegamePosition{
+		PositionID:  id,
+		FEN:         fen,
+		Moves:       strings.Join(moves, " "),
+		Rating:      rating,
+		Themes:      themes,
+		Description: strings.TrimSpace(record[colDescription]),
+		Source:      strings.TrimSpace(record[colSource]),
+	}
+	if s := strings.TrimSpace(record[colInitialEval]); s != "" {
+		eval, err := strconv.Atoi(s)
+		if err != nil {
+			return models.MiddlegamePosition{}, fmt.Errorf("position %s: invalid initial_eval %q", id, s)
+		}
+		pos.InitialEval = &eval
+	}
+	return pos, nil
+}
@@ -0,0 +1,259 @@
+// Command importpuzzles loads puzzles from the Lichess puzzle database CSV
+// (https://database.lichess.org/#puzzles) into the puzzles table.
+//
+// The Lichess export is zstd-compressed; decompress it on the fly:
+//
+//	zstd -dc lichess_db_puzzle.csv.zst | go run ./cmd/importpuzzles -file -
+//
+// Every row is validated by replaying its moves before it is stored. Progress is
+// logged with the offset to pass to -offset when an interrupted import is resumed.
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/joho/godotenv"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/chess"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/database"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/logger"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/models"
+)
+
+// Column positions in the Lichess puzzle CSV
+const (
+	colPuzzleID = iota
+	colFEN
+	colMoves
+	colRating
+	colRatingDeviation
+	colPopularity
+	colNbPlays
+	colThemes
+	colGameURL
+	colOpeningTags
+)
+
+// minColumns is the column count of older exports, which have no OpeningTags column
+const minColumns = colOpeningTags
+
+type options struct {
+	file     string
+	batch    int
+	offset   int
+	limit    int
+	progress int
+	dryRun   bool
+}
+
+type stats struct {
+	read     int
+	imported int
+	rejected int
+}
+
+func main() {
+	opts := options{}
+	flag.StringVar(&opts.file, "file", "", "path to the Lichess puzzle CSV, or - for stdin")
+	flag.IntVar(&opts.batch, "batch", 1000, "rows per upsert batch")
+	flag.IntVar(&opts.offset, "offset", 0, "number of data rows to skip (resume an interrupted import)")
+	flag.IntVar(&opts.limit, "limit", 0, "maximum number of data rows to read after the offset (0 = all)")
+	flag.IntVar(&opts.progress, "progress", 10000, "log progress every N rows")
+	flag.BoolVar(&opts.dryRun, "dry-run", false, "validate rows without writing to the database")
+	flag.Parse()
+
+	_ = godotenv.Load()
+	logger.Configure(os.Getenv("LOG_LEVEL"), false)
+
+	if opts.file == "" {
+		fmt.Fprintln(os.Stderr, "usage: importpuzzles -file <path|-> [-batch N] [-offset N] [-limit N] [-dry-run]")
+		os.Exit(2)
+	}
+	if opts.batch < 1 || opts.offset < 0 || opts.limit < 0 || opts.progress < 1 {
+		fmt.Fprintln(os.Stderr, "batch and progress must be positive; offset and limit must not be negative")
+		os.Exit(2)
+	}
+
+	in := os.Stdin
+	if opts.file != "-" {
+		f, err := os.Open(opts.file)
+		if err != nil {
+			logger.Error("Failed to open puzzle file", logger.F("file", opts.file, "error", err.Error()))
+			os.Exit(1)
+		}
+		defer f.Close()
+		in = f
+	}
+
+	if !opts.dryRun {
+		database.InitPostgres()
+		defer database.Close()
+	}
+
+	st, err := run(in, opts)
+	logger.Info("Puzzle import finished", logger.F(
+		"read", st.read,
+		"imported", st.imported,
+		"rejected", st.rejected,
+		"dryRun", opts.dryRun,
+		"nextOffset", opts.offset+st.read,
+	))
+	if err != nil {
+		logger.Error("Puzzle import failed", logger.F(
+			"error", err.Error(),
+			"resumeOffset", opts.offset+st.read,
+		))
+		os.Exit(1)
+	}
+}
+
+// run streams the CSV and upserts valid rows in batches. Only rows belonging to a
+// committed batch are counted in stats.read, so offset+read is a safe resume point.
+func run(in io.Reader, opts options) (stats, error) {
+	var st stats
+
+	r := csv.NewReader(in)
+	r.FieldsPerRecord = -1
+	r.ReuseRecord = true
+
+	batch := make([]models.Puzzle, 0, opts.batch)
+	seen := make(map[string]bool, opts.batch)
+	pending, pendingRejected := 0, 0
+	row := 0
+
+	flush := func() error {
+		if len(batch) > 0 && !opts.dryRun {
+			if err := database.BulkUpsertPuzzles(batch); err != nil {
+				return err
+			}
+		}
+		st.imported += len(batch)
+		st.read += pending
+		st.rejected += pendingRejected
+		batch = batch[:0]
+		clear(seen)
+		pending, pendingRejected = 0, 0
+		return nil
+	}
+
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return st, fmt.Errorf("reading CSV: %w", err)
+		}
+		if row == 0 && len(record) > 0 && record[colPuzzleID] == "PuzzleId" {
+			continue // header
+		}
+		row++
+		if row <= opts.offset {
+			continue
+		}
+		if opts.limit > 0 && st.read+pending >= opts.limit {
+			break
+		}
+
+		pending++
+		puzzle, err := parseRecord(record)
+		if err != nil {
+			pendingRejected++
+			logger.Debug("Rejected puzzle row", logger.F("row", row, "error", err.Error()))
+		} else if !seen[puzzle.PuzzleID] {
+			seen[puzzle.PuzzleID] = true
+			batch = append(batch, puzzle)
+		}
+
+		if pending >= opts.batch {
+			if err := flush(); err != nil {
+				return st, err
+			}
+		}
+		if (st.read+pending)%opts.progress == 0 {
+			logger.Info("Puzzle import progress", logger.F(
+				"read", st.read+pending,
+				"imported", st.imported,
+				"rejected", st.rejected+pendingRejected,
+				"resumeOffset", opts.offset+st.read,
+			))
+		}
+	}
+
+	return st, flush()
+}
+
+// parseRecord converts a Lichess CSV row into a puzzle. In the Lichess format the FEN
+// is the position before the opponent's last move and the first move in Moves is that
+// move; it is applied here so the stored FEN is the position the solver sees.
+func parseRecord(record []string) (models.Puzzle, error) {
+	if len(record) < minColumns {
+		return models.Puzzle{}, fmt.Errorf("expected at least %d columns, got %d", minColumns, len(record))
+	}
+
+	id := strings.TrimSpace(record[colPuzzleID])
+	if id == "" {
+		return models.Puzzle{}, errors.New("missing puzzle ID")
+	}
+
+	moves := chess.SplitMoves(record[colMoves])
+	if len(moves) < 2 || len(moves)%2 != 0 {
+		return models.Puzzle{}, fmt.Errorf("puzzle %s: expected an even number of moves, got %d", id, len(moves))
+	}
+
+	g, err := chess.NewGameFromFEN(record[colFEN])
+	if err != nil {
+		return models.Puzzle{}, fmt.Errorf("puzzle %s: %w", id, err)
+	}
+	if result := g.TryUCIMove(moves[0]); !result.Valid {
+		return models.Puzzle{}, fmt.Errorf("puzzle %s: setup move %s is illegal", id, moves[0])
+	}
+	fen := g.FEN()
+	solution := moves[1:]
+	if _, err := chess.ValidateLine(fen, solution); err != nil {
+		return models.Puzzle{}, fmt.Errorf("puzzle %s: %w", id, err)
+	}
+
+	rating, err := strconv.Atoi(record[colRating])
+	if err != nil || rating < 0 || rating > 4000 {
+		return models.Puzzle{}, fmt.Errorf("puzzle %s: invalid rating %q", id, record[colRating])
+	}
+
+	p := models.Puzzle{
+		PuzzleID:        id,
+		FEN:             fen,
+		Moves:           strings.Join(solution, " "),
+		Rating:          rating,
+		RatingDeviation: atoiOrZero(record[colRatingDeviation]),
+		Popularity:      atoiOrZero(record[colPopularity]),
+		NbPlays:         atoiOrZero(record[colNbPlays]),
+		Themes:          nonNil(strings.Fields(record[colThemes])),
+		GameURL:         strings.TrimSpace(record[colGameURL]),
+		OpeningTags:     []string{},
+	}
+	if len(record) > colOpeningTags {
+		p.OpeningTags = nonNil(strings.Fields(record[colOpeningTags]))
+	}
+	return p, nil
+}
+
+func atoiOrZero(s string) int {
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/database"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/database/dbtest"
+)
+
+const header = "PuzzleId,FEN,Moves,Rating,RatingDeviation,Popularity,NbPlays,Themes,GameUrl,OpeningTags"
+
+// Rows from the Lichess export: the FEN is before the opponent's move, the first move
+const (
+	rowMate = "00sHx,q3k1nr/1pp1nQpp/3p4/1P2p3/4P3/B1PP1b2/B5PP/5K2 b k - 0 17,e8d7 a2e6 d7d8 f7f8," +
+		"1760,80,83,72,mate mateIn2 middlegame short,https://lichess.org/yyznGmXs/black#34," +
+		"Italian_Game Italian_Game_Classical_Variation"
+	rowCrushing = "00008,r6k/pp2r2p/4Rp1Q/3p4/8/1N1P2R1/PqP2bPP/7K b - - 0 24,f2g3 e6e7 b2b1 b3c1 b1c1 h6c1," +
+		"1913,75,94,6230,crushing hangingPiece long middlegame,https://lichess.org/787zsVup/black#47,"
+)
+
+func record(row string) []string { return strings.Split(row, ",") }
+
+// withField returns row with one column replaced
+func withField(row string, col int, value string) []string {
+	fields := record(row)
+	fields[col] = value
+	return fields
+}
+
+func TestParseRecord(t *testing.T) {
+	p, err := parseRecord(record(rowMate))
+	if err != nil {
+		t.Fatalf("parseRecord() error = %v", err)
+	}
+	// The setup move e8d7 is applied, leaving the solver to move
+	if p.FEN != "q5nr/1ppknQpp/3p4/1P2p3/4P3/B1PP1b2/B5PP/5K2 w - - 1 18" {
+		t.Errorf("FEN = %s, want the position after e8d7", p.FEN)
+	}
+	if p.PuzzleID != "00sHx" || p.Moves != "a2e6 d7d8 f7f8" || p.Rating != 1760 {
+		t.Errorf("parseRecord() = %s %q %d; want 00sHx \"a2e6 d7d8 f7f8\" 1760", p.PuzzleID, p.Moves, p.Rating)
+	}
+	if p.RatingDeviation != 80 || p.Popularity != 83 || p.NbPlays != 72 {
+		t.Errorf("deviation, popularity, plays = %d, %d, %d; want 80, 83, 72", p.RatingDeviation, p.Popularity, p.NbPlays)
+	}
+	if want := []string{"mate", "mateIn2", "middlegame", "short"}; !reflect.DeepEqual(p.Themes, want) {
+		t.Errorf("Themes = %v, want %v", p.Themes, want)
+	}
+	if want := []string{"Italian_Game", "Italian_Game_Classical_Variation"}; !reflect.DeepEqual(p.OpeningTags, want) {
+		t.Errorf("OpeningTags = %v, want %v", p.OpeningTags, want)
+	}
+
+	// Older exports have no OpeningTags column
+	p, err = parseRecord(record(rowCrushing)[:minColumns])
+	if err != nil {
+		t.Fatalf("parseRecord() without opening tags error = %v", err)
+	}
+	if p.OpeningTags == nil || len(p.OpeningTags) != 0 || p.Moves != "e6e7 b2b1 b3c1 b1c1 h6c1" {
+		t.Errorf("parseRecord() = %q, %#v; want five moves and empty tags", p.Moves, p.OpeningTags)
+	}
+}
+
+func TestParseRecord_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		record  []string
+		wantErr string
+	}{
+		{"too few columns", record(rowMate)[:minColumns-1], "expected at least 9 columns"},
+		{"missing ID", withField(rowMate, colPuzzleID, " "), "missing puzzle ID"},
+		{"no solution", withField(rowMate, colMoves, "e8d7"), "expected an even number of moves, got 1"},
+		{"odd move count", withField(rowMate, colMoves, "e8d7 a2e6 d7d8"), "expected an even number of moves, got 3"},
+		{"invalid FEN", withField(rowMate, colFEN, "not a fen"), "puzzle 00sHx:"},
+		{"illegal setup move", withField(rowMate, colMoves, "e8e7 a2e6 d7d8 f7f8"), "setup move e8e7 is illegal"},
+		{"illegal solution", withField(rowMate, colMoves, "e8d7 a2e6 d7d8 f7f1"), "puzzle 00sHx:"},
+		{"rating not a number", withField(rowMate, colRating, "high"), `invalid rating "high"`},
+		{"rating out of range", withField(rowMate, colRating, "4001"), `invalid rating "4001"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseRecord(tt.record)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("parseRecord() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	// Six data rows: a duplicate and one that fails validation
+	csv := strings.Join([]string{
+		header,
+		rowMate,
+		rowCrushing,
+		rowMate,
+		"bad,8/8/8/8/8/8/8/8 w - - 0 1,e2e4 e7e5,1500,75,90,10,short,,",
+		strings.Replace(rowCrushing, "00008", "00009", 1),
+		strings.Replace(rowMate, "00sHx", "00sHy", 1),
+	}, "\n") + "\n"
+
+	tests := []struct {
+		name string
+		opts options
+		want stats
+	}{
+		{"everything", options{batch: 10}, stats{read: 6, imported: 4, rejected: 1}},
+		{"one batch per row", options{batch: 1}, stats{read: 6, imported: 5, rejected: 1}},
+		{"limit", options{batch: 10, limit: 2}, stats{read: 2, imported: 2}},
+		{"resume", options{batch: 10, offset: 3}, stats{read: 3, imported: 2, rejected: 1}},
+		{"resume with limit", options{batch: 2, offset: 1, limit: 3}, stats{read: 3, imported: 2, rejected: 1}},
+		{"offset past the end", options{batch: 10, offset: 6}, stats{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.progress = 1000
+			tt.opts.dryRun = true
+			got, err := run(strings.NewReader(csv), tt.opts)
+			if err != nil || got != tt.want {
+				t.Errorf("run() = %+v, %v; want %+v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRun_Batches(t *testing.T) {
+	db, rec := dbtest.Open()
+	prev := database.DB
+	database.DB = db
+	t.Cleanup(func() {
+		db.Close()
+		database.DB = prev
+	})
+
+	// The duplicate falls in the first batch of two; the upsert fails on a row that
+	// appears twice in one statement
+	csv := strings.Join([]string{rowMate, rowMate, rowCrushing}, "\n")
+	st, err := run(strings.NewReader(csv), options{batch: 2, progress: 1000})
+	if err != nil || st != (stats{read: 3, imported: 2}) {
+		t.Fatalf("run() = %+v, %v; want 3 read, 2 imported", st, err)
+	}
+
+	var batches [][]string
+	for _, s := range rec.Statements() {
+		if !strings.Contains(s.SQL, "INSERT INTO puzzles") {
+			continue
+		}
+		var ids []string
+		for i := 0; i < len(s.Args); i += 10 {
+			ids = append(ids, s.Args[i].(string))
+		}
+		batches = append(batches, ids)
+	}
+	if want := [][]string{{"00sHx"}, {"00008"}}; !reflect.DeepEqual(batches, want) {
+		t.Errorf("upserted batches = %v, want %v", batches, want)
+	}
+}
@@ -12,16 +12,22 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/joho/godotenv"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/achievements"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/analysis"
 	"github.com/tmcarmichael/nxtchess/apps/backend/internal/auth"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/computer"
 	"github.com/tmcarmichael/nxtchess/apps/backend/internal/config"
 	"github.com/tmcarmichael/nxtchess/apps/backend/internal/controllers"
 	"github.com/tmcarmichael/nxtchess/apps/backend/internal/database"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/engine"
 	"github.com/tmcarmichael/nxtchess/apps/backend/internal/httpx"
 	"github.com/tmcarmichael/nxtchess/apps/backend/internal/logger"
 	"github.com/tmcarmichael/nxtchess/apps/backend/internal/metrics"
 	"github.com/tmcarmichael/nxtchess/apps/backend/internal/middleware"
 	"github.com/tmcarmichael/nxtchess/apps/backend/internal/migrate"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/models"
 	"github.com/tmcarmichael/nxtchess/apps/backend/internal/sessions"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/tablebase"
 	"github.com/tmcarmichael/nxtchess/apps/backend/internal/ws"
 )
 
@@ -56,18 +62,36 @@ func main() {
 
 	sessions.InitRedis()
 	auth.InitOAuthProviders(cfg)
+	tablebase.Init(cfg.SyzygyPath)
+	engine.Init(engine.Options{
+		Path:        cfg.EnginePath,
+		Size:        cfg.EnginePoolSize,
+		MaxDepth:    cfg.EngineMaxDepth,
+		MaxMoveTime: cfg.EngineMaxMoveTime,
+	})
+	analysis.Init(engine.Default, cfg.AnalysisWorkers, cfg.AnalysisDepth)
+	computer.Init(engine.Default)
+
+	if err := achievements.Init(cfg.AchievementsFile); err != nil {
+		logger.Error("Invalid achievement definitions", logger.F("error", err.Error()))
+		os.Exit(1)
+	}
+	logger.Info("Achievements loaded", logger.F("count", len(achievements.All)))
 
 	// Initialize WebSocket hub with connection limiter
 	connLimit, onDisconnect := ws.NewConnectionLimiterForHub()
 	wsHub := ws.NewHub(onDisconnect)
 	globalWsHub = wsHub // Store for health check (set before server starts)
 	go wsHub.Run()
+	sessions.OnRevoke(wsHub.DisconnectSessions)
 	wsHandler := ws.NewHandler(wsHub, cfg, connLimit)
+	botHandler := ws.NewBotHandler(wsHub)
 	logger.Info("WebSocket hub started")
 
 	// Create rate limiters with config for trusted proxy validation
 	authRateLimiter := middleware.NewAuthRateLimiter(cfg)
 	apiRateLimiter := middleware.NewAPIRateLimiter(cfg)
+	botRateLimiter := middleware.NewBotRateLimiter(cfg)
 
 	metrics.Register()
 
@@ -88,6 +112,8 @@ func main() {
 	r.Group(func(internal chi.Router) {
 		internal.Use(middleware.InternalOnly(cfg))
 		internal.Handle("/metrics", promhttp.Handler())
+		internal.Put("/admin/puzzle/daily", controllers.SetDailyPuzzleHandler)
+		internal.Delete("/admin/users/{userID}/sessions", controllers.RevokeUserSessionsHandler)
 	})
 
 	// WebSocket endpoint for multiplayer games (no body limit needed)
@@ -104,11 +130,21 @@ func main() {
 		ar.Get("/auth/discord/callback", auth.CallbackHandler("Discord", cfg))
 	})
 
-	// Logout endpoint (rate limited, no session required)
+	// Account linking starts from a signed-in session and completes in the callbacks above
+	r.Group(func(lk chi.Router) {
+		lk.Use(authRateLimiter.RedirectMiddleware)
+		lk.Use(middleware.Session)
+		lk.Get("/auth/google/link", auth.LinkHandler("Google", cfg))
+		lk.Get("/auth/github/link", auth.LinkHandler("GitHub", cfg))
+		lk.Get("/auth/discord/link", auth.LinkHandler("Discord", cfg))
+	})
+
+	// Logout and mobile token endpoints (rate limited, no session required)
 	r.Group(func(lr chi.Router) {
 		lr.Use(authRateLimiter.Middleware)
 		lr.Use(middleware.SmallBodyLimit) // 64KB limit for JSON
 		lr.Post("/auth/logout", controllers.LogoutHandler(cfg))
+		lr.Post("/auth/token", auth.TokenHandler(cfg))
 	})
 
 	// Public API routes (no session required)
@@ -118,13 +154,21 @@ func main() {
 		pub.Get("/api/profile/{username}", controllers.UserProfileHandler)
 		pub.Get("/api/profile/{username}/rating-history", controllers.UserRatingHistoryHandler)
 		pub.Get("/api/profile/{username}/recent-games", controllers.UserRecentGamesHandler)
-		pub.Get("/api/profile/{username}/achievements", controllers.UserAchievementsHandler)
 		pub.Get("/api/achievements", controllers.AchievementCatalogHandler)
+		pub.Get("/api/games/{id}", controllers.GameHandler)
+		pub.Get("/api/games/{id}/analysis", controllers.GameAnalysisHandler)
+		pub.Get("/api/chess/opening", controllers.OpeningHandler)
 
 		// Training API routes
-		pub.Get("/api/training/endgame/random", controllers.GetRandomEndgamePosition)
 		pub.Get("/api/training/endgame/themes", controllers.GetEndgameThemes)
 		pub.Get("/api/training/endgame/stats", controllers.GetEndgameStats)
+		pub.Get("/api/training/middlegame/random", controllers.GetRandomMiddlegamePosition)
+		pub.Get("/api/training/middlegame/themes", controllers.GetMiddlegameThemes)
+		pub.Get("/api/training/tablebase", controllers.ProbeTablebaseHandler)
+
+		// Puzzle API routes
+		pub.Get("/api/puzzle/random", controllers.GetRandomPuzzleHandler)
+		pub.Get("/api/puzzle/sprint/leaderboard", controllers.GetPuzzleSprintLeaderboardHandler)
 	})
 
 	// Optional session routes (returns different response for anon vs logged in)
@@ -133,6 +177,38 @@ func main() {
 		opt.Use(middleware.SmallBodyLimit)
 		opt.Use(middleware.OptionalSession)
 		opt.Get("/check-username", controllers.CheckUsernameHandler)
+		opt.Get("/api/profile/{username}/achievements", controllers.UserAchievementsHandler)
+		opt.Get("/api/puzzle/daily", controllers.GetDailyPuzzleHandler)
+		opt.Get("/api/training/endgame/random", controllers.GetRandomEndgamePosition)
+	})
+
+	// Protected routes that personal API tokens may also call, each within a scope
+	r.Group(func(tok chi.Router) {
+		tok.Use(apiRateLimiter.Middleware)
+		tok.Use(middleware.SmallBodyLimit) // 64KB limit for JSON API
+		tok.Use(middleware.Session)
+		tok.With(middleware.RequireScope(models.ScopeGamesRead)).Get("/api/me/games", controllers.MyGamesHandler)
+		tok.Group(func(pz chi.Router) {
+			pz.Use(middleware.RequireScope(models.ScopePuzzlesSubmit))
+			pz.Get("/api/puzzle/next", controllers.GetNextPuzzleHandler)
+			pz.Post("/api/puzzle/submit", controllers.SubmitPuzzleHandler)
+			pz.Post("/api/puzzle/daily/submit", controllers.SubmitDailyPuzzleHandler)
+		})
+	})
+
+	// Bot API, called with a personal API token that has the bot:play scope
+	r.Group(func(bot chi.Router) {
+		bot.Use(botRateLimiter.TokenMiddleware)
+		bot.Use(middleware.SmallBodyLimit)
+		bot.Use(middleware.Session)
+		bot.Use(middleware.RequireScope(models.ScopeBotPlay))
+		bot.Post("/api/bot/account/upgrade", botHandler.UpgradeAccount)
+		bot.Get("/api/bot/stream", botHandler.Stream)
+		bot.Post("/api/bot/seek", botHandler.Seek)
+		bot.Post("/api/bot/challenge/{gameID}/accept", botHandler.AcceptChallenge)
+		bot.Post("/api/bot/challenge/{gameID}/decline", botHandler.DeclineChallenge)
+		bot.Post("/api/bot/game/{gameID}/move/{move}", botHandler.Move)
+		bot.Post("/api/bot/game/{gameID}/resign", botHandler.Resign)
 	})
 
 	// Protected session routes with API rate limiting
@@ -140,9 +216,31 @@ func main() {
 		pr.Use(apiRateLimiter.Middleware)
 		pr.Use(middleware.SmallBodyLimit) // 64KB limit for JSON API
 		pr.Use(middleware.Session)
+		pr.Use(middleware.DenyAPITokens)
+		pr.Get("/api/me", controllers.MyProfileHandler)
+		pr.Patch("/api/me", controllers.UpdateMyProfileHandler)
+		pr.Get("/api/profile/myprofile", controllers.MyProfileHandler)
+		pr.Patch("/api/profile/myprofile", controllers.UpdateMyProfileHandler)
+		pr.Get("/api/me/identities", controllers.MyIdentitiesHandler)
+		pr.Delete("/api/me/identities/{provider}", controllers.UnlinkIdentityHandler)
+		pr.Get("/api/me/sessions", controllers.MySessionsHandler)
+		pr.Delete("/api/me/sessions", controllers.RevokeOtherSessionsHandler)
+		pr.Delete("/api/me/sessions/{id}", controllers.RevokeSessionHandler)
+		pr.Get("/api/me/tokens", controllers.MyAPITokensHandler)
+		pr.Post("/api/me/tokens", controllers.CreateAPITokenHandler)
+		pr.Delete("/api/me/tokens/{tokenID}", controllers.RevokeAPITokenHandler)
+		// Deprecated: superseded by PATCH /api/me
 		pr.Post("/set-username", controllers.SetUsernameHandler)
 		pr.Post("/set-profile-icon", controllers.SetProfileIconHandler)
+		// Deprecated: records nothing; superseded by POST /api/puzzle/submit
 		pr.Post("/api/puzzle/result", controllers.SubmitPuzzleResultHandler)
+		pr.Post("/api/puzzle/sprint", controllers.StartPuzzleSprintHandler)
+		pr.Post("/api/puzzle/sprint/{sprintID}/submit", controllers.SubmitPuzzleSprintHandler)
+		pr.Get("/api/puzzle/sprint/history", controllers.GetPuzzleSprintHistoryHandler)
+		pr.Post("/api/training/endgame/attempt", controllers.SubmitEndgameAttemptHandler)
+		pr.Post("/api/training/endgame/submit", controllers.SubmitEndgameHandler)
+		pr.Get("/api/training/endgame/progress", controllers.GetEndgameProgressHandler)
+		pr.Post("/api/analysis", controllers.AnalysisHandler)
 	})
 
 	addr := ":" + cfg.Port
@@ -179,6 +277,11 @@ func main() {
 		logger.Error("Server forced to shutdown", logger.F("error", err.Error()))
 	}
 
+	// Stop engine processes
+	if engine.Default != nil {
+		engine.Default.Close()
+	}
+
 	// Close database connection
 	if err := database.Close(); err != nil {
 		logger.Error("Error closing database", logger.F("error", err.Error()))
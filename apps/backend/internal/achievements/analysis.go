@@ -1,25 +1,58 @@
 package achievements
 
 import (
+	"sort"
+
 	"github.com/notnil/chess"
 )
 
+// GameFlags are the patterns found in a game from one player's point of view: move
+// patterns count only that player's moves, and mate patterns are set only when the
+// player delivered the mate
 type GameFlags struct {
-	HasPromotion      bool
-	HasUnderpromotion bool
-	HasEnPassant      bool
-	IsBackRankMate    bool
-	IsScholarsMate    bool
-	MoveCount         int
+	HasPromotion         bool
+	HasUnderpromotion    bool
+	HasEnPassant         bool
+	HasDoubleCheck       bool
+	HasCastlingCheck     bool
+	IsBackRankMate       bool
+	IsScholarsMate       bool
+	IsSmotheredMate      bool
+	IsAnastasiasMate     bool
+	IsArabianMate        bool
+	IsQueenSacrificeMate bool
+	MoveCount            int
 }
 
-func AnalyzeGame(g *chess.Game, winnerColor string) GameFlags {
+// queenSacrificePlies is how soon after losing the queen the player must mate for the
+// loss to count as a sacrifice: at most three of the player's own moves
+const queenSacrificePlies = 6
+
+func AnalyzeGame(g *chess.Game, playerColor string) GameFlags {
 	moves := g.Moves()
+	positions := g.Positions()
 	flags := GameFlags{
 		MoveCount: len(moves),
 	}
 
-	for _, move := range moves {
+	player := chess.White
+	if playerColor == "black" {
+		player = chess.Black
+	}
+
+	// queenLostAt is the ply the player's queen was taken for nothing, enemyQueenTakenAt
+	// the last ply the player took the opponent's queen
+	queenLostAt, enemyQueenTakenAt := -1, -2
+	for i, move := range moves {
+		mover := positions[i].Turn()
+		if mover != player {
+			// Recapturing after the player took the queen first makes it a trade
+			if captured := positions[i].Board().Piece(move.S2()); captured.Type() == chess.Queen && captured.Color() == player && enemyQueenTakenAt != i-1 {
+				queenLostAt = i
+			}
+			continue
+		}
+
 		if move.HasTag(chess.EnPassant) {
 			flags.HasEnPassant = true
 		}
@@ -29,48 +62,246 @@ func AnalyzeGame(g *chess.Game, winnerColor string) GameFlags {
 				flags.HasUnderpromotion = true
 			}
 		}
+		if move.HasTag(chess.Check) {
+			if move.HasTag(chess.KingSideCastle) || move.HasTag(chess.QueenSideCastle) {
+				flags.HasCastlingCheck = true
+			}
+			if len(checkers(positions[i+1].Board(), mover.Other())) >= 2 {
+				flags.HasDoubleCheck = true
+			}
+		}
+		if captured := positions[i].Board().Piece(move.S2()); captured.Type() == chess.Queen && captured.Color() != player {
+			// Winning the queen back makes it a trade rather than a sacrifice
+			queenLostAt, enemyQueenTakenAt = -1, i
+		}
+	}
+
+	if g.Method() != chess.Checkmate || len(moves) == 0 || g.Position().Turn() == player {
+		return flags
 	}
 
-	if g.Method() == chess.Checkmate {
-		flags.IsBackRankMate = checkBackRankMate(g)
-		flags.IsScholarsMate = checkScholarsMate(moves)
+	board := g.Position().Board()
+	loser := player.Other()
+	king, ok := kingSquare(board, loser)
+	if !ok {
+		return flags
 	}
+	attackers := checkers(board, loser)
+	if len(attackers) == 0 {
+		return flags
+	}
+	// The piece that just moved is the mating piece unless the mate was discovered
+	checker := attackers[0]
+	for _, sq := range attackers {
+		if sq == moves[len(moves)-1].S2() {
+			checker = sq
+		}
+	}
+	checkerType := board.Piece(checker).Type()
+
+	flags.IsBackRankMate = checkBackRankMate(board, king, checker, loser)
+	flags.IsSmotheredMate = len(attackers) == 1 && checkerType == chess.Knight && checkSmothered(board, king, loser)
+	flags.IsAnastasiasMate = checkAnastasiasMate(board, king, checker, loser)
+	flags.IsArabianMate = checkArabianMate(board, king, checker, loser)
+	flags.IsScholarsMate = checkScholarsMate(g, moves, player)
+	flags.IsQueenSacrificeMate = queenLostAt >= 0 && len(moves)-1-queenLostAt <= queenSacrificePlies
 
 	return flags
 }
 
-func checkBackRankMate(g *chess.Game) bool {
-	pos := g.Position()
-	board := pos.Board()
+// checkBackRankMate reports a king mated on its back rank by a rook or queen along
+// that rank
+func checkBackRankMate(board *chess.Board, king, checker chess.Square, loser chess.Color) bool {
+	backRank := chess.Rank1
+	if loser == chess.Black {
+		backRank = chess.Rank8
+	}
+	t := board.Piece(checker).Type()
+	return king.Rank() == backRank && checker.Rank() == backRank && (t == chess.Rook || t == chess.Queen)
+}
 
-	loserColor := pos.Turn()
+// checkSmothered reports a king whose every neighbouring square holds one of its own
+// pieces
+func checkSmothered(board *chess.Board, king chess.Square, loser chess.Color) bool {
+	for _, sq := range neighbours(king) {
+		if p := board.Piece(sq); p == chess.NoPiece || p.Color() != loser {
+			return false
+		}
+	}
+	return true
+}
 
-	var backRank chess.Rank
-	if loserColor == chess.White {
-		backRank = chess.Rank1
-	} else {
-		backRank = chess.Rank8
+// checkAnastasiasMate reports a king on the a- or h-file, short of the corner, mated by a rook or queen
+// along that file while a knight takes its flight squares on the next file
+func checkAnastasiasMate(board *chess.Board, king, checker chess.Square, loser chess.Color) bool {
+	if king.File() != chess.FileA && king.File() != chess.FileH || isCorner(king) {
+		return false
 	}
+	if t := board.Piece(checker).Type(); checker.File() != king.File() || (t != chess.Rook && t != chess.Queen) {
+		return false
+	}
+	for _, sq := range neighbours(king) {
+		if sq.File() == king.File() || board.Piece(sq) != chess.NoPiece {
+			continue
+		}
+		if attackedBy(board, sq, loser.Other(), chess.Knight) {
+			return true
+		}
+	}
+	return false
+}
 
-	var kingPiece chess.Piece
-	if loserColor == chess.White {
-		kingPiece = chess.WhiteKing
-	} else {
-		kingPiece = chess.BlackKing
+// checkArabianMate reports a cornered king mated by an adjacent rook that a knight
+// defends
+func checkArabianMate(board *chess.Board, king, checker chess.Square, loser chess.Color) bool {
+	if !isCorner(king) || board.Piece(checker).Type() != chess.Rook || distance(king, checker) != 1 {
+		return false
 	}
+	return attackedBy(board, checker, loser.Other(), chess.Knight)
+}
 
-	sqMap := board.SquareMap()
-	kingOnBackRank := false
-	for sq, piece := range sqMap {
-		if piece == kingPiece && sq.Rank() == backRank {
-			kingOnBackRank = true
-			break
+// checkScholarsMate reports the queen taking on f7 (f2 for Black) with mate inside
+// the player's first four moves, supported by a bishop on the a2-g8 (a7-g1) diagonal
+func checkScholarsMate(g *chess.Game, moves []*chess.Move, player chess.Color) bool {
+	target := chess.F7
+	if player == chess.Black {
+		target = chess.F2
+	}
+	ownMoves := (len(moves) + 1) / 2
+	if player == chess.Black {
+		ownMoves = len(moves) / 2
+	}
+	if ownMoves > 4 {
+		return false
+	}
+	last := moves[len(moves)-1]
+	board := g.Position().Board()
+	if last.S2() != target || !last.HasTag(chess.Capture) || board.Piece(target).Type() != chess.Queen {
+		return false
+	}
+	return attackedBy(board, target, player, chess.Bishop)
+}
+
+// checkers returns the squares of the pieces giving check to color's king, in square
+// order
+func checkers(board *chess.Board, color chess.Color) []chess.Square {
+	king, ok := kingSquare(board, color)
+	if !ok {
+		return nil
+	}
+	var result []chess.Square
+	for sq, p := range board.SquareMap() {
+		if p.Color() != color && attacks(board, sq, king) {
+			result = append(result, sq)
 		}
 	}
+	sort.Slice(result, func(i, j int) bool { return result[i] < result[j] })
+	return result
+}
 
-	return kingOnBackRank
+// attackedBy reports whether a piece of type t and color c attacks sq
+func attackedBy(board *chess.Board, sq chess.Square, c chess.Color, t chess.PieceType) bool {
+	for from, p := range board.SquareMap() {
+		if p.Color() == c && p.Type() == t && attacks(board, from, sq) {
+			return true
+		}
+	}
+	return false
 }
 
-func checkScholarsMate(moves []*chess.Move) bool {
-	return len(moves) <= 7 && len(moves) >= 4
+func kingSquare(board *chess.Board, color chess.Color) (chess.Square, bool) {
+	for sq, p := range board.SquareMap() {
+		if p.Type() == chess.King && p.Color() == color {
+			return sq, true
+		}
+	}
+	return chess.NoSquare, false
+}
+
+// attacks reports whether the piece on from attacks to
+func attacks(board *chess.Board, from, to chess.Square) bool {
+	p := board.Piece(from)
+	df := int(to.File()) - int(from.File())
+	dr := int(to.Rank()) - int(from.Rank())
+	switch p.Type() {
+	case chess.Pawn:
+		forward := 1
+		if p.Color() == chess.Black {
+			forward = -1
+		}
+		return dr == forward && abs(df) == 1
+	case chess.Knight:
+		return abs(df)*abs(dr) == 2
+	case chess.King:
+		return distance(from, to) == 1
+	case chess.Bishop:
+		return abs(df) == abs(dr) && df != 0 && rayClear(board, from, df, dr)
+	case chess.Rook:
+		return (df == 0) != (dr == 0) && rayClear(board, from, df, dr)
+	case chess.Queen:
+		return (abs(df) == abs(dr) && df != 0 || (df == 0) != (dr == 0)) && rayClear(board, from, df, dr)
+	}
+	return false
+}
+
+// rayClear reports whether the squares strictly between from and from+(df, dr) are
+// empty
+func rayClear(board *chess.Board, from chess.Square, df, dr int) bool {
+	steps := abs(df)
+	if abs(dr) > steps {
+		steps = abs(dr)
+	}
+	for i := 1; i < steps; i++ {
+		f := int(from.File()) + i*sign(df)
+		r := int(from.Rank()) + i*sign(dr)
+		if board.Piece(chess.NewSquare(chess.File(f), chess.Rank(r))) != chess.NoPiece {
+			return false
+		}
+	}
+	return true
+}
+
+func isCorner(sq chess.Square) bool {
+	return (sq.File() == chess.FileA || sq.File() == chess.FileH) &&
+		(sq.Rank() == chess.Rank1 || sq.Rank() == chess.Rank8)
+}
+
+func neighbours(sq chess.Square) []chess.Square {
+	var result []chess.Square
+	for df := -1; df <= 1; df++ {
+		for dr := -1; dr <= 1; dr++ {
+			f, r := int(sq.File())+df, int(sq.Rank())+dr
+			if (df != 0 || dr != 0) && f >= 0 && f < 8 && r >= 0 && r < 8 {
+				result = append(result, chess.NewSquare(chess.File(f), chess.Rank(r)))
+			}
+		}
+	}
+	return result
+}
+
+// distance is the number of king moves between two squares
+func distance(a, b chess.Square) int {
+	df := abs(int(a.File()) - int(b.File()))
+	dr := abs(int(a.Rank()) - int(b.Rank()))
+	if df > dr {
+		return df
+	}
+	return dr
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func sign(n int) int {
+	switch {
+	case n > 0:
+		return 1
+	case n < 0:
+		return -1
+	}
+	return 0
 }
@@ -0,0 +1,150 @@
+package achievements
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/notnil/chess"
+	ichess "github.com/tmcarmichael/nxtchess/apps/backend/internal/chess"
+)
+
+// play returns the game reached by playing UCI moves from fen, or from the start
+// when fen is empty
+func play(t *testing.T, fen, moves string) *chess.Game {
+	t.Helper()
+	g := ichess.NewGame()
+	if fen != "" {
+		var err error
+		if g, err = ichess.NewGameFromFEN(fen); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, uci := range strings.Fields(moves) {
+		if result := g.TryUCIMove(uci); !result.Valid {
+			t.Fatalf("move %s is illegal", uci)
+		}
+	}
+	return g.InnerGame()
+}
+
+func TestAnalyzeGame(t *testing.T) {
+	tests := []struct {
+		name   string
+		fen    string
+		moves  string
+		player string
+		want   GameFlags
+	}{
+		{
+			name:   "scholar's mate",
+			moves:  "e2e4 e7e5 f1c4 b8c6 d1h5 g8f6 h5f7",
+			player: "white",
+			want:   GameFlags{IsScholarsMate: true},
+		},
+		{
+			name:   "scholar's mate seen by the loser",
+			moves:  "e2e4 e7e5 f1c4 b8c6 d1h5 g8f6 h5f7",
+			player: "black",
+		},
+		{
+			name:   "fool's mate is not scholar's mate",
+			moves:  "f2f3 e7e5 g2g4 d8h4",
+			player: "black",
+		},
+		{
+			name:   "smothered mate",
+			moves:  "e2e4 c7c6 d2d4 d7d5 b1c3 d5e4 c3e4 b8d7 d1e2 g8f6 e4d6",
+			player: "white",
+			want:   GameFlags{IsSmotheredMate: true},
+		},
+		{
+			name:   "anastasia's mate",
+			fen:    "8/4N1pk/8/8/8/R7/8/K7 w - - 0 1",
+			moves:  "a3h3",
+			player: "white",
+			want:   GameFlags{IsAnastasiasMate: true},
+		},
+		{
+			name:   "arabian mate",
+			fen:    "7k/1R6/5N2/8/8/8/8/K7 w - - 0 1",
+			moves:  "b7h7",
+			player: "white",
+			want:   GameFlags{IsArabianMate: true},
+		},
+		{
+			name:   "back rank mate",
+			fen:    "6k1/5ppp/8/8/8/8/8/K2R4 w - - 0 1",
+			moves:  "d1d8",
+			player: "white",
+			want:   GameFlags{IsBackRankMate: true},
+		},
+		{
+			name:   "queen sacrifice (Legal's mate)",
+			moves:  "e2e4 e7e5 g1f3 d7d6 f1c4 c8g4 b1c3 g7g6 f3e5 g4d1 c4f7 e8e7 c3d5",
+			player: "white",
+			want:   GameFlags{IsQueenSacrificeMate: true},
+		},
+		{
+			name:   "queen trade started by the player",
+			fen:    "3q2k1/5ppp/2n5/8/8/8/5PPP/3QR1K1 w - - 0 1",
+			moves:  "d1d8 c6d8 e1e8",
+			player: "white",
+			want:   GameFlags{IsBackRankMate: true},
+		},
+		{
+			name:   "queen trade started by the opponent",
+			fen:    "3q2k1/p4ppp/8/8/8/8/5PPP/3QR1K1 b - - 0 1",
+			moves:  "d8d1 e1d1 a7a6 d1d8",
+			player: "white",
+			want:   GameFlags{IsBackRankMate: true},
+		},
+		{
+			name:   "double check",
+			fen:    "4k3/8/8/8/4N3/8/8/K3R3 w - - 0 1",
+			moves:  "e4f6",
+			player: "white",
+			want:   GameFlags{HasDoubleCheck: true},
+		},
+		{
+			name:   "castling with check",
+			fen:    "5k2/8/8/8/8/8/8/4K2R w K - 0 1",
+			moves:  "e1g1",
+			player: "white",
+			want:   GameFlags{HasCastlingCheck: true},
+		},
+		{
+			name:   "castling with check by the opponent",
+			fen:    "5k2/8/8/8/8/8/8/4K2R w K - 0 1",
+			moves:  "e1g1",
+			player: "black",
+		},
+		{
+			name:   "en passant",
+			moves:  "e2e4 a7a6 e4e5 d7d5 e5d6",
+			player: "white",
+			want:   GameFlags{HasEnPassant: true},
+		},
+		{
+			name:   "en passant by the opponent",
+			moves:  "e2e4 a7a6 e4e5 d7d5 e5d6",
+			player: "black",
+		},
+		{
+			name:   "underpromotion",
+			fen:    "8/1P5k/8/8/8/8/8/K7 w - - 0 1",
+			moves:  "b7b8n",
+			player: "white",
+			want:   GameFlags{HasPromotion: true, HasUnderpromotion: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := play(t, tt.fen, tt.moves)
+			got := AnalyzeGame(g, tt.player)
+			tt.want.MoveCount = len(g.Moves())
+			if got != tt.want {
+				t.Errorf("AnalyzeGame() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -24,274 +24,210 @@ type PuzzleContext struct {
 	NewRating int
 }
 
-func CheckGameAchievements(userID string, ctx GameContext) []AchievementUnlock {
-	existing, err := database.GetUserAchievementIDs(userID)
-	if err != nil {
-		logger.Error("Failed to get existing achievements", logger.F("userID", userID, "error", err.Error()))
-		return nil
-	}
+type TrainingContext struct {
+	Success bool
+	// Goal is the chess.TrainingGoal of the position ("mate", "win" or "draw")
+	Goal      string
+	NewRating int
+}
 
-	var unlocked []AchievementUnlock
+// ComputerContext is a finished game against the computer, from the player's side
+type ComputerContext struct {
+	Level int
+	Won   bool
+	Drew  bool
+	// NewRating is the player's computer rating after a rated game; 0 for unrated games
+	NewRating int
+}
 
-	grant := func(id string) {
-		if existing[id] {
-			return
-		}
-		a, ok := All[id]
-		if !ok {
-			return
-		}
-		granted, err := database.GrantAchievement(userID, id, a.Points)
-		if err != nil {
-			logger.Error("Failed to grant achievement", logger.F("userID", userID, "achievementID", id, "error", err.Error()))
-			return
-		}
-		if granted {
-			unlocked = append(unlocked, ToUnlock(a))
-			existing[id] = true
-		}
-	}
+func CheckGameAchievements(userID string, ctx GameContext) []AchievementUnlock {
+	facts := GameFacts(ctx)
 
-	if ctx.Won {
-		winStreak, err := database.UpdateWinStreak(userID, true)
-		if err != nil {
-			logger.Error("Failed to update win streak", logger.F("userID", userID, "error", err.Error()))
-		} else {
-			if winStreak >= 3 {
-				grant("win_streak_3")
-			}
-			if winStreak >= 5 {
-				grant("win_streak_5")
-			}
-			if winStreak >= 10 {
-				grant("win_streak_10")
-			}
-			if winStreak >= 20 {
-				grant("win_streak_20")
-			}
-		}
-	} else {
-		database.UpdateWinStreak(userID, false)
+	winStreak, err := database.UpdateWinStreak(userID, ctx.Won)
+	if err != nil {
+		logger.Error("Failed to update win streak", logger.F("userID", userID, "error", err.Error()))
+	} else if ctx.Won {
+		facts.Counters["win_streak"] = winStreak
 	}
 
-	ratingThresholds := []struct {
-		threshold int
-		id        string
-	}{
-		{1600, "rating_1600"},
-		{1800, "rating_1800"},
-		{2000, "rating_2000"},
-		{2200, "rating_2200"},
-		{2400, "rating_2400"},
-		{2600, "rating_2600"},
-		{2800, "rating_2800"},
-		{3000, "rating_3000"},
-	}
-	for _, rt := range ratingThresholds {
-		if ctx.NewRating >= rt.threshold {
-			grant(rt.id)
-		}
+	if gamesPlayed, err := database.GetGamesPlayedCount(userID); err == nil {
+		facts.Counters["games_played"] = gamesPlayed
 	}
 
-	if ctx.Won {
-		grant("first_win")
+	return grantMatching(userID, facts)
+}
+
+// GameFacts returns what a finished game says about one player: the rating and
+// per-game counters, and the result and move patterns. Counters kept across games
+// (win_streak, games_played) are left for the caller.
+func GameFacts(ctx GameContext) Facts {
+	facts := Facts{
+		Event: EventGame,
+		Counters: map[string]int{
+			"rating": ctx.NewRating,
+		},
+		Patterns: map[string]bool{
+			"won":     ctx.Won,
+			"drew":    ctx.Drew,
+			"timeout": ctx.Reason == "timeout",
+		},
 	}
 
 	if ctx.InnerGame != nil {
 		flags := AnalyzeGame(ctx.InnerGame, ctx.PlayerColor)
+		facts.Counters["plies"] = flags.MoveCount
+		facts.Patterns["en_passant"] = flags.HasEnPassant
+		facts.Patterns["promotion"] = flags.HasPromotion
+		facts.Patterns["underpromotion"] = flags.HasUnderpromotion
+		facts.Patterns["back_rank_mate"] = flags.IsBackRankMate
+		facts.Patterns["scholars_mate"] = flags.IsScholarsMate
+		facts.Patterns["smothered_mate"] = flags.IsSmotheredMate
+		facts.Patterns["anastasias_mate"] = flags.IsAnastasiasMate
+		facts.Patterns["arabian_mate"] = flags.IsArabianMate
+		facts.Patterns["queen_sacrifice_mate"] = flags.IsQueenSacrificeMate
+		facts.Patterns["double_check"] = flags.HasDoubleCheck
+		facts.Patterns["castling_check"] = flags.HasCastlingCheck
+
+		if ctx.Reason == "stalemate" {
+			stalematedColor := ctx.InnerGame.Position().Turn()
+			playerIsWhite := ctx.PlayerColor == "white"
+			playerWasStalemated := (playerIsWhite && stalematedColor == chess.White) ||
+				(!playerIsWhite && stalematedColor == chess.Black)
+			facts.Patterns["stalemate_received"] = playerWasStalemated
+			facts.Patterns["stalemate_delivered"] = !playerWasStalemated
+		}
+	}
+
+	return facts
+}
 
-		if flags.HasEnPassant {
-			grant("en_passant")
-		}
-		if flags.HasPromotion {
-			grant("promotion")
-		}
-		if flags.HasUnderpromotion {
-			grant("underpromotion")
-		}
-		if ctx.Won && flags.IsBackRankMate {
-			grant("back_rank_mate")
-		}
-		if ctx.Won && flags.IsScholarsMate {
-			grant("scholars_mate")
-		}
-		if flags.MoveCount >= 200 {
-			grant("marathon_game")
-		}
-	}
-
-	if ctx.Reason == "stalemate" && ctx.InnerGame != nil {
-		stalematedColor := ctx.InnerGame.Position().Turn()
-		playerIsWhite := ctx.PlayerColor == "white"
-		playerWasStalemated := (playerIsWhite && stalematedColor == chess.White) ||
-			(!playerIsWhite && stalematedColor == chess.Black)
-		if playerWasStalemated {
-			grant("stalemate_receive")
-		} else {
-			grant("stalemate_deliver")
-		}
+func CheckPuzzleAchievements(userID string, ctx PuzzleContext) []AchievementUnlock {
+	facts := Facts{
+		Event: EventPuzzle,
+		Counters: map[string]int{
+			"puzzle_rating": ctx.NewRating,
+		},
+		Patterns: map[string]bool{
+			"solved": ctx.Solved,
+		},
 	}
 
-	if ctx.Won && ctx.Reason == "timeout" {
-		grant("win_on_time")
+	puzzleStreak, err := database.RefreshPuzzleStreak(userID)
+	if err != nil {
+		logger.Error("Failed to refresh puzzle streak", logger.F("userID", userID, "error", err.Error()))
+	} else {
+		facts.Counters["puzzle_streak"] = puzzleStreak
 	}
 
-	gamesPlayed, err := database.GetGamesPlayedCount(userID)
-	if err == nil {
-		if gamesPlayed >= 10 {
-			grant("games_10")
-		}
-		if gamesPlayed >= 50 {
-			grant("games_50")
-		}
-		if gamesPlayed >= 100 {
-			grant("games_100")
-		}
-		if gamesPlayed >= 500 {
-			grant("games_500")
-		}
-		if gamesPlayed >= 1000 {
-			grant("games_1000")
-		}
+	if puzzlesSolved, err := database.GetPuzzlesSolvedCount(userID); err == nil {
+		facts.Counters["puzzles_solved"] = puzzlesSolved
 	}
 
-	return unlocked
+	return grantMatching(userID, facts)
 }
 
-func CheckPuzzleAchievements(userID string, ctx PuzzleContext) []AchievementUnlock {
-	existing, err := database.GetUserAchievementIDs(userID)
-	if err != nil {
-		logger.Error("Failed to get existing achievements for puzzle check", logger.F("userID", userID, "error", err.Error()))
-		return nil
-	}
+// CheckSprintAchievements grants sprint milestones for the current sprint score.
+// It is called after every solved sprint puzzle so milestones unlock mid-run.
+func CheckSprintAchievements(userID string, score int) []AchievementUnlock {
+	return grantMatching(userID, Facts{
+		Event:    EventSprint,
+		Counters: map[string]int{"sprint_score": score},
+	})
+}
 
-	var unlocked []AchievementUnlock
+// CheckDailyPuzzleAchievements grants daily puzzle streak milestones
+func CheckDailyPuzzleAchievements(userID string, streak int) []AchievementUnlock {
+	return grantMatching(userID, Facts{
+		Event:    EventDailyPuzzle,
+		Counters: map[string]int{"daily_streak": streak},
+	})
+}
 
-	grant := func(id string) {
-		if existing[id] {
-			return
-		}
-		a, ok := All[id]
-		if !ok {
-			return
-		}
-		granted, err := database.GrantAchievement(userID, id, a.Points)
+// CheckTrainingAchievements grants endgame training milestones after a judged attempt
+func CheckTrainingAchievements(userID string, ctx TrainingContext) []AchievementUnlock {
+	facts := Facts{
+		Event: EventTraining,
+		Counters: map[string]int{
+			"training_rating": ctx.NewRating,
+		},
+		Patterns: map[string]bool{
+			"success":   ctx.Success,
+			"goal_draw": ctx.Goal == "draw",
+		},
+	}
+
+	if ctx.Success {
+		solved, err := database.GetEndgameSuccessCount(userID)
 		if err != nil {
-			logger.Error("Failed to grant puzzle achievement", logger.F("userID", userID, "achievementID", id, "error", err.Error()))
-			return
-		}
-		if granted {
-			unlocked = append(unlocked, ToUnlock(a))
-			existing[id] = true
+			logger.Error("Failed to count endgame successes", logger.F("userID", userID, "error", err.Error()))
+		} else {
+			facts.Counters["endgame_successes"] = solved
 		}
 	}
 
-	puzzleStreak, err := database.UpdatePuzzleStreak(userID, ctx.Solved)
-	if err != nil {
-		logger.Error("Failed to update puzzle streak", logger.F("userID", userID, "error", err.Error()))
-	} else if ctx.Solved {
-		if puzzleStreak >= 3 {
-			grant("puzzle_streak_3")
-		}
-		if puzzleStreak >= 5 {
-			grant("puzzle_streak_5")
-		}
-		if puzzleStreak >= 10 {
-			grant("puzzle_streak_10")
-		}
-		if puzzleStreak >= 20 {
-			grant("puzzle_streak_20")
-		}
-	}
+	return grantMatching(userID, facts)
+}
 
-	puzzleRatingThresholds := []struct {
-		threshold int
-		id        string
-	}{
-		{1400, "puzzle_rating_1400"},
-		{1600, "puzzle_rating_1600"},
-		{1800, "puzzle_rating_1800"},
-		{2000, "puzzle_rating_2000"},
-		{2200, "puzzle_rating_2200"},
-		{2400, "puzzle_rating_2400"},
-		{2600, "puzzle_rating_2600"},
-		{2800, "puzzle_rating_2800"},
-		{3000, "puzzle_rating_3000"},
-	}
-	for _, rt := range puzzleRatingThresholds {
-		if ctx.NewRating >= rt.threshold {
-			grant(rt.id)
-		}
-	}
+// CheckComputerAchievements grants milestones for a game against the computer: the
+// level beaten when the player won, and the computer rating after rated games
+func CheckComputerAchievements(userID string, ctx ComputerContext) []AchievementUnlock {
+	return grantMatching(userID, ComputerFacts(ctx))
+}
 
-	if ctx.Solved {
-		grant("first_puzzle")
+// ComputerFacts returns what a game against the computer says about the player
+func ComputerFacts(ctx ComputerContext) Facts {
+	facts := Facts{
+		Event:    EventComputer,
+		Counters: map[string]int{},
+		Patterns: map[string]bool{
+			"won":  ctx.Won,
+			"drew": ctx.Drew,
+		},
 	}
-
-	puzzlesSolved, err := database.GetPuzzlesSolvedCount(userID)
-	if err == nil {
-		if puzzlesSolved >= 10 {
-			grant("puzzles_10")
-		}
-		if puzzlesSolved >= 50 {
-			grant("puzzles_50")
-		}
-		if puzzlesSolved >= 100 {
-			grant("puzzles_100")
-		}
-		if puzzlesSolved >= 500 {
-			grant("puzzles_500")
-		}
+	if ctx.Won {
+		facts.Counters["computer_level"] = ctx.Level
 	}
-
-	return unlocked
+	if ctx.NewRating > 0 {
+		facts.Counters["computer_rating"] = ctx.NewRating
+	}
+	return facts
 }
 
 func CheckLoyaltyAchievements(userID string, createdAt time.Time) []AchievementUnlock {
+	return grantMatching(userID, Facts{
+		Event:    EventLoyalty,
+		Counters: map[string]int{"membership_days": int(time.Since(createdAt) / (24 * time.Hour))},
+	})
+}
+
+// grantMatching grants every achievement the user has not earned yet whose trigger
+// matches facts
+func grantMatching(userID string, facts Facts) []AchievementUnlock {
+	matched := Evaluate(facts, time.Now())
+	if len(matched) == 0 {
+		return nil
+	}
+
 	existing, err := database.GetUserAchievementIDs(userID)
 	if err != nil {
-		logger.Error("Failed to get existing achievements for loyalty check", logger.F("userID", userID, "error", err.Error()))
+		logger.Error("Failed to get existing achievements", logger.F("userID", userID, "event", facts.Event, "error", err.Error()))
 		return nil
 	}
 
 	var unlocked []AchievementUnlock
-
-	grant := func(id string) {
-		if existing[id] {
-			return
-		}
-		a, ok := All[id]
-		if !ok {
-			return
+	for _, a := range matched {
+		if existing[a.ID] {
+			continue
 		}
-		granted, err := database.GrantAchievement(userID, id, a.Points)
+		granted, err := database.GrantAchievement(userID, a.ID, a.Points)
 		if err != nil {
-			logger.Error("Failed to grant loyalty achievement", logger.F("userID", userID, "achievementID", id, "error", err.Error()))
-			return
+			logger.Error("Failed to grant achievement", logger.F("userID", userID, "achievementID", a.ID, "error", err.Error()))
+			continue
 		}
 		if granted {
 			unlocked = append(unlocked, ToUnlock(a))
-			existing[id] = true
-		}
-	}
-
-	membershipDuration := time.Since(createdAt)
-
-	loyaltyMilestones := []struct {
-		duration time.Duration
-		id       string
-	}{
-		{365 * 24 * time.Hour, "loyalty_1yr"},
-		{2 * 365 * 24 * time.Hour, "loyalty_2yr"},
-		{3 * 365 * 24 * time.Hour, "loyalty_3yr"},
-		{5 * 365 * 24 * time.Hour, "loyalty_5yr"},
-	}
-
-	for _, lm := range loyaltyMilestones {
-		if membershipDuration >= lm.duration {
-			grant(lm.id)
+			existing[a.ID] = true
 		}
 	}
-
 	return unlocked
 }
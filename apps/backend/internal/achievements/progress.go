@@ -0,0 +1,73 @@
+package achievements
+
+import (
+	"time"
+
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/database"
+)
+
+// Progress is how far a user is towards a locked achievement
+type Progress struct {
+	Current int `json:"current"`
+	Target  int `json:"target"`
+}
+
+// privateCounters measure activity only the user should see: streaks and how much
+// they practise. Ratings, games played and membership are on the public profile.
+var privateCounters = map[string]bool{
+	"win_streak":        true,
+	"puzzle_streak":     true,
+	"daily_streak":      true,
+	"puzzles_solved":    true,
+	"endgame_successes": true,
+	"sprint_score":      true,
+}
+
+// counterValues names the stored counters as triggers do. Per-game counters such as
+// plies and computer_level have no stored value, so achievements on them report no
+// progress.
+func counterValues(c database.AchievementCounters) map[string]int {
+	return map[string]int{
+		"rating":            c.Rating,
+		"puzzle_rating":     c.PuzzleRating,
+		"training_rating":   c.TrainingRating,
+		"computer_rating":   c.ComputerRating,
+		"win_streak":        c.WinStreak,
+		"puzzle_streak":     c.PuzzleStreak,
+		"daily_streak":      c.DailyStreak,
+		"membership_days":   c.MembershipDays,
+		"games_played":      c.GamesPlayed,
+		"puzzles_solved":    c.PuzzlesSolved,
+		"endgame_successes": c.EndgameSuccesses,
+		"sprint_score":      c.BestSprintScore,
+	}
+}
+
+// ComputeProgress returns the progress towards every active achievement that is
+// earned on a counter and not yet unlocked, keyed by achievement ID. Achievements on
+// private counters are left out unless includePrivate is set.
+func ComputeProgress(c database.AchievementCounters, unlocked map[string]bool, includePrivate bool, now time.Time) map[string]Progress {
+	values := counterValues(c)
+	progress := make(map[string]Progress)
+	for _, a := range ordered {
+		counter := a.Trigger.Counter
+		if counter == "" || unlocked[a.ID] || !a.Active(now) {
+			continue
+		}
+		if privateCounters[counter] && !includePrivate {
+			continue
+		}
+		current, ok := values[counter]
+		if !ok {
+			continue
+		}
+		if current > a.Trigger.Threshold {
+			current = a.Trigger.Threshold
+		}
+		if current < 0 {
+			current = 0
+		}
+		progress[a.ID] = Progress{Current: current, Target: a.Trigger.Threshold}
+	}
+	return progress
+}
@@ -0,0 +1,43 @@
+package achievements
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/database"
+)
+
+func TestComputeProgress(t *testing.T) {
+	counters := database.AchievementCounters{
+		Rating:      1700,
+		GamesPlayed: 37,
+		WinStreak:   4,
+	}
+	unlocked := map[string]bool{"games_10": true}
+	now := time.Now()
+
+	public := ComputeProgress(counters, unlocked, false, now)
+	if got := public["games_50"]; got != (Progress{Current: 37, Target: 50}) {
+		t.Errorf("games_50 progress = %+v, want 37/50", got)
+	}
+	if got := public["rating_1600"]; got != (Progress{Current: 1600, Target: 1600}) {
+		t.Errorf("rating_1600 progress = %+v, want capped at 1600/1600", got)
+	}
+	if _, ok := public["games_10"]; ok {
+		t.Error("progress reported for an unlocked achievement")
+	}
+	if _, ok := public["win_streak_5"]; ok {
+		t.Error("private win streak progress visible to others")
+	}
+	if _, ok := public["marathon_game"]; ok {
+		t.Error("progress reported for a per-game counter")
+	}
+	if _, ok := public["first_win"]; ok {
+		t.Error("progress reported for a pattern-only achievement")
+	}
+
+	owner := ComputeProgress(counters, unlocked, true, now)
+	if got := owner["win_streak_5"]; got != (Progress{Current: 4, Target: 5}) {
+		t.Errorf("win_streak_5 progress = %+v, want 4/5", got)
+	}
+}
@@ -0,0 +1,141 @@
+package achievements
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Event is what happened to a user that may unlock achievements
+type Event string
+
+const (
+	EventGame        Event = "game"
+	EventPuzzle      Event = "puzzle"
+	EventSprint      Event = "sprint"
+	EventDailyPuzzle Event = "daily_puzzle"
+	EventTraining    Event = "training"
+	EventComputer    Event = "computer"
+	EventLoyalty     Event = "loyalty"
+)
+
+// eventFacts lists the counters and patterns each event supplies, so definitions
+// can be checked against them at startup
+var eventFacts = map[Event]struct {
+	counters []string
+	patterns []string
+}{
+	EventGame: {
+		counters: []string{"win_streak", "rating", "games_played", "plies"},
+		patterns: []string{
+			"won", "drew", "timeout", "en_passant", "promotion", "underpromotion",
+			"back_rank_mate", "scholars_mate", "smothered_mate", "anastasias_mate", "arabian_mate",
+			"queen_sacrifice_mate", "double_check", "castling_check",
+			"stalemate_delivered", "stalemate_received",
+		},
+	},
+	EventPuzzle: {
+		counters: []string{"puzzle_streak", "puzzle_rating", "puzzles_solved"},
+		patterns: []string{"solved"},
+	},
+	EventSprint: {
+		counters: []string{"sprint_score"},
+	},
+	EventDailyPuzzle: {
+		counters: []string{"daily_streak"},
+	},
+	EventTraining: {
+		counters: []string{"endgame_successes", "training_rating"},
+		patterns: []string{"success", "goal_draw"},
+	},
+	EventComputer: {
+		counters: []string{"computer_rating", "computer_level"},
+		patterns: []string{"won", "drew"},
+	},
+	EventLoyalty: {
+		counters: []string{"membership_days"},
+	},
+}
+
+// Trigger describes when an achievement unlocks: on Event, once Counter reaches
+// Threshold (when set) and every pattern in Patterns holds
+type Trigger struct {
+	Event     Event    `json:"event"`
+	Counter   string   `json:"counter,omitempty"`
+	Threshold int      `json:"threshold,omitempty"`
+	Patterns  []string `json:"patterns,omitempty"`
+}
+
+func (t Trigger) validate() error {
+	facts, ok := eventFacts[t.Event]
+	if !ok {
+		return fmt.Errorf("unknown event %q", t.Event)
+	}
+	if t.Counter == "" && len(t.Patterns) == 0 {
+		return errors.New("trigger needs a counter or a pattern")
+	}
+	if t.Counter != "" {
+		if !contains(facts.counters, t.Counter) {
+			return fmt.Errorf("event %s has no counter %q", t.Event, t.Counter)
+		}
+		if t.Threshold <= 0 {
+			return errors.New("counter threshold must be positive")
+		}
+	} else if t.Threshold != 0 {
+		return errors.New("threshold without a counter")
+	}
+	for _, p := range t.Patterns {
+		if !contains(facts.patterns, p) {
+			return fmt.Errorf("event %s has no pattern %q", t.Event, p)
+		}
+	}
+	return nil
+}
+
+// Facts is what is known about an event when achievements are evaluated. A counter
+// that is absent (e.g., because it could not be loaded) satisfies no threshold.
+type Facts struct {
+	Event    Event
+	Counters map[string]int
+	Patterns map[string]bool
+}
+
+// Matches reports whether the trigger's conditions hold for facts
+func (t Trigger) Matches(f Facts) bool {
+	if t.Event != f.Event {
+		return false
+	}
+	if t.Counter != "" {
+		v, ok := f.Counters[t.Counter]
+		if !ok || v < t.Threshold {
+			return false
+		}
+	}
+	for _, p := range t.Patterns {
+		if !f.Patterns[p] {
+			return false
+		}
+	}
+	return true
+}
+
+// Evaluate returns the active achievements whose triggers match facts at now, in
+// definition order
+func Evaluate(f Facts, now time.Time) []Achievement {
+	var matched []Achievement
+	for _, a := range ordered {
+		if a.Active(now) && a.Trigger.Matches(f) {
+			matched = append(matched, a)
+		}
+	}
+	return matched
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+package achievements
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuiltInDefinitions(t *testing.T) {
+	if len(All) == 0 || len(All) != len(ordered) {
+		t.Fatalf("loaded %d achievements, %d in order", len(All), len(ordered))
+	}
+	if _, ok := GetByID("win_streak_5"); !ok {
+		t.Error("win_streak_5 not defined")
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	valid := `{"id": "a", "name": "A", "description": "d", "category": "c", "rarity": "common", "points": 1,
+		"trigger": {"event": "game", "counter": "games_played", "threshold": 5}}`
+
+	tests := []struct {
+		name string
+		json string
+		want string
+	}{
+		{"empty", `[]`, "no achievements"},
+		{"duplicate id", `[` + valid + `,` + valid + `]`, "duplicate id"},
+		{"unknown rarity", `[` + strings.Replace(valid, `"common"`, `"mythic"`, 1) + `]`, "unknown rarity"},
+		{"unknown event", `[` + strings.Replace(valid, `"game"`, `"tournament"`, 1) + `]`, "unknown event"},
+		{"counter of another event", `[` + strings.Replace(valid, `"games_played"`, `"sprint_score"`, 1) + `]`, "no counter"},
+		{"zero threshold", `[` + strings.Replace(valid, `"threshold": 5`, `"threshold": 0`, 1) + `]`, "threshold must be positive"},
+		{"unknown pattern", `[{"id": "a", "name": "A", "description": "d", "category": "c", "rarity": "common", "points": 1,
+			"trigger": {"event": "puzzle", "patterns": ["checkmate"]}}]`, "no pattern"},
+		{"empty trigger", `[{"id": "a", "name": "A", "description": "d", "category": "c", "rarity": "common", "points": 1,
+			"trigger": {"event": "puzzle"}}]`, "counter or a pattern"},
+		{"season ends first", `[` + strings.Replace(valid, `"points": 1,`,
+			`"points": 1, "start": "2026-12-31T00:00:00Z", "end": "2026-12-01T00:00:00Z",`, 1) + `]`, "start must be before end"},
+		{"unknown field", `[` + strings.Replace(valid, `"points": 1,`, `"points": 1, "hidden": true,`, 1) + `]`, "unknown field"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.json))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Parse() error = %v, want containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestTriggerMatches(t *testing.T) {
+	trigger := Trigger{Event: EventGame, Counter: "win_streak", Threshold: 5, Patterns: []string{"won"}}
+
+	tests := []struct {
+		name  string
+		facts Facts
+		want  bool
+	}{
+		{"reached", Facts{Event: EventGame, Counters: map[string]int{"win_streak": 5}, Patterns: map[string]bool{"won": true}}, true},
+		{"below threshold", Facts{Event: EventGame, Counters: map[string]int{"win_streak": 4}, Patterns: map[string]bool{"won": true}}, false},
+		{"missing counter", Facts{Event: EventGame, Patterns: map[string]bool{"won": true}}, false},
+		{"pattern false", Facts{Event: EventGame, Counters: map[string]int{"win_streak": 9}}, false},
+		{"other event", Facts{Event: EventPuzzle, Counters: map[string]int{"win_streak": 9}, Patterns: map[string]bool{"won": true}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := trigger.Matches(tt.facts); got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	defs, err := Parse([]byte(`[
+		{"id": "sprint_5", "name": "S", "description": "d", "category": "volume", "rarity": "common", "points": 1,
+			"trigger": {"event": "sprint", "counter": "sprint_score", "threshold": 5}},
+		{"id": "sprint_10", "name": "S", "description": "d", "category": "volume", "rarity": "common", "points": 1,
+			"trigger": {"event": "sprint", "counter": "sprint_score", "threshold": 10}},
+		{"id": "winter_sprint", "name": "W", "description": "d", "category": "seasonal", "rarity": "rare", "points": 3,
+			"start": "2026-12-01T00:00:00Z", "end": "2027-01-01T00:00:00Z",
+			"trigger": {"event": "sprint", "counter": "sprint_score", "threshold": 5}}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved, savedOrdered := All, ordered
+	use(defs)
+	t.Cleanup(func() { All, ordered = saved, savedOrdered })
+
+	facts := Facts{Event: EventSprint, Counters: map[string]int{"sprint_score": 7}}
+	ids := func(as []Achievement) string {
+		var s []string
+		for _, a := range as {
+			s = append(s, a.ID)
+		}
+		return strings.Join(s, ",")
+	}
+
+	if got := ids(Evaluate(facts, time.Date(2026, 11, 15, 0, 0, 0, 0, time.UTC))); got != "sprint_5" {
+		t.Errorf("Evaluate() before season = %q, want sprint_5", got)
+	}
+	if got := ids(Evaluate(facts, time.Date(2026, 12, 24, 0, 0, 0, 0, time.UTC))); got != "sprint_5,winter_sprint" {
+		t.Errorf("Evaluate() in season = %q, want sprint_5,winter_sprint", got)
+	}
+	if got := ids(Evaluate(facts, time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC))); got != "sprint_5" {
+		t.Errorf("Evaluate() at season end = %q, want sprint_5", got)
+	}
+}
+
+func TestComputerFacts(t *testing.T) {
+	now := time.Now()
+	ids := func(ctx ComputerContext) string {
+		var s []string
+		for _, a := range Evaluate(ComputerFacts(ctx), now) {
+			s = append(s, a.ID)
+		}
+		return strings.Join(s, ",")
+	}
+
+	if got := ids(ComputerContext{Level: 6, Won: true}); got != "computer_win_level_1,computer_win_level_5" {
+		t.Errorf("unrated win at level 6 = %q", got)
+	}
+	if got := ids(ComputerContext{Level: 10, Drew: true, NewRating: 1650}); got != "computer_rating_1600" {
+		t.Errorf("rated draw at level 10 = %q", got)
+	}
+	if got := ids(ComputerContext{Level: 10, NewRating: 1500}); got != "" {
+		t.Errorf("rated loss = %q, want nothing", got)
+	}
+}
@@ -0,0 +1,148 @@
+package analysis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/chess"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/database"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/engine"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/logger"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/models"
+)
+
+const (
+	// Games waiting in memory; more stay pending in the database until restart
+	queueSize = 1000
+
+	// Time allowed to evaluate one position, including waiting for a free engine
+	positionTimeout = 30 * time.Second
+)
+
+// Default is the process-wide queue set by Init; nil when analysis is disabled
+var Default *Queue
+
+// Queue analyzes games in the background. Queued games are recorded in the
+// database first, so games still waiting at shutdown are analyzed after a restart.
+type Queue struct {
+	pool  *engine.Pool
+	depth int
+	jobs  chan string
+}
+
+// Init starts Default with workers analyzing at depth, and requeues the games left
+// unfinished by the previous run. A nil pool disables analysis.
+func Init(pool *engine.Pool, workers, depth int) {
+	if pool == nil {
+		logger.Info("Game analysis disabled (no engine configured)")
+		return
+	}
+	Default = NewQueue(pool, workers, depth)
+
+	ids, err := database.GetUnfinishedGameAnalyses()
+	if err != nil {
+		logger.Error("Failed to load unfinished game analyses", logger.F("error", err.Error()))
+	}
+	for _, id := range ids {
+		Default.push(id)
+	}
+	logger.Info("Game analysis started", logger.F("workers", workers, "depth", depth, "requeued", len(ids)))
+}
+
+// NewQueue creates a queue and starts its workers
+func NewQueue(pool *engine.Pool, workers, depth int) *Queue {
+	q := &Queue{pool: pool, depth: depth, jobs: make(chan string, queueSize)}
+	for i := 0; i < max(1, workers); i++ {
+		go q.work()
+	}
+	return q
+}
+
+// Enqueue queues a stored game on Default. It does nothing when analysis is disabled.
+func Enqueue(gameID string) {
+	if Default == nil {
+		return
+	}
+	if err := database.CreateGameAnalysis(gameID); err != nil {
+		return
+	}
+	Default.push(gameID)
+}
+
+func (q *Queue) push(gameID string) {
+	select {
+	case q.jobs <- gameID:
+	default:
+		logger.Warn("Game analysis queue full; game stays pending", logger.F("gameId", gameID))
+	}
+}
+
+func (q *Queue) work() {
+	for gameID := range q.jobs {
+		q.run(gameID)
+	}
+}
+
+// run analyzes one game and stores the outcome
+func (q *Queue) run(gameID string) {
+	start := time.Now()
+	if err := database.StartGameAnalysis(gameID); err != nil {
+		return
+	}
+
+	result, err := q.analyze(gameID)
+	if err != nil {
+		logger.Warn("Game analysis failed", logger.F("gameId", gameID, "error", err.Error()))
+		database.FailGameAnalysis(gameID, err.Error())
+		return
+	}
+	if err := database.CompleteGameAnalysis(result); err != nil {
+		return
+	}
+	logger.Info("Game analyzed", logger.F(
+		"gameId", gameID,
+		"plies", len(result.Moves),
+		"durationMs", time.Since(start).Milliseconds(),
+	))
+}
+
+// analyze evaluates every position of a game
+func (q *Queue) analyze(gameID string) (*models.GameAnalysis, error) {
+	game, err := database.GetGameByID(gameID)
+	if err != nil {
+		return nil, fmt.Errorf("loading game: %w", err)
+	}
+	if game == nil {
+		return nil, fmt.Errorf("game not found")
+	}
+
+	moves := chess.SplitMoves(game.PGN)
+	g := chess.NewGame()
+	evals := make([]Eval, 0, len(moves)+1)
+	for i := 0; i <= len(moves); i++ {
+		if i > 0 {
+			if result := g.TryUCIMove(moves[i-1]); !result.Valid {
+				return nil, fmt.Errorf("move %d (%s) is illegal", i, moves[i-1])
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), positionTimeout)
+		a, err := q.pool.Analyze(ctx, "", moves[:i], engine.Limits{Depth: q.depth})
+		cancel()
+		if err != nil {
+			return nil, fmt.Errorf("evaluating ply %d: %w", i, err)
+		}
+		evals = append(evals, evalOf(a, g))
+	}
+
+	results, white, black := Report(moves, evals)
+	return &models.GameAnalysis{
+		GameID: gameID,
+		Status: models.AnalysisDone,
+		Depth:  q.depth,
+		White:  &white,
+		Black:  &black,
+		Moves:  results,
+	}, nil
+}
@@ -0,0 +1,159 @@
+// Package analysis runs computer analysis of finished games. A background queue
+// evaluates every position of a game with the engine pool, then classifies each
+// move by how much it lowered the mover's winning chances and summarizes each
+// player's accuracy and average centipawn loss.
+package analysis
+
+import (
+	"math"
+
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/chess"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/engine"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/models"
+)
+
+// mateCP is the centipawn value a forced mate counts as. Losses are capped at it
+// too, so one collapse cannot dominate a player's average.
+const mateCP = 1000
+
+// Minimum drop in win percentage for each classification
+const (
+	inaccuracyLoss = 5.0
+	mistakeLoss    = 10.0
+	blunderLoss    = 15.0
+)
+
+// Eval is the evaluation of one position from White's point of view
+type Eval struct {
+	CP       int  // centipawns, with mates counted as ±mateCP
+	Mate     *int // moves to mate, when the engine found one
+	BestMove string
+}
+
+// evalOf converts the engine's best line into an Eval. Positions without lines are
+// game over: checkmate counts as a mate for the side that delivered it, anything
+// else as a draw.
+func evalOf(a *engine.Analysis, g *chess.Game) Eval {
+	if len(a.Lines) == 0 {
+		zero := 0
+		switch result, _ := g.GetOutcome(); result {
+		case chess.ResultWhiteWins:
+			return Eval{CP: mateCP, Mate: &zero}
+		case chess.ResultBlackWins:
+			return Eval{CP: -mateCP, Mate: &zero}
+		default:
+			return Eval{}
+		}
+	}
+
+	best := a.Lines[0]
+	e := Eval{BestMove: a.BestMove, Mate: best.Mate}
+	switch {
+	case best.Mate != nil && *best.Mate >= 0:
+		e.CP = mateCP
+	case best.Mate != nil:
+		e.CP = -mateCP
+	case best.CP != nil:
+		e.CP = clampCP(*best.CP)
+	}
+	return e
+}
+
+func clampCP(cp int) int {
+	return max(-mateCP, min(mateCP, cp))
+}
+
+// WinPercent is the chance of winning, 0-100, for the side with an advantage of cp
+func WinPercent(cp int) float64 {
+	return 50 + 50*(2/(1+math.Exp(-0.00368208*float64(cp)))-1)
+}
+
+// MoveAccuracy rates a move 0-100 from the mover's win percentage before and after it
+func MoveAccuracy(winBefore, winAfter float64) float64 {
+	if winAfter >= winBefore {
+		return 100
+	}
+	accuracy := 103.1668*math.Exp(-0.04354*(winBefore-winAfter)) - 3.1669
+	return max(0, min(100, accuracy))
+}
+
+// Classify names a move by the drop in the mover's win percentage, or returns ""
+// for a move that is not an error
+func Classify(winLoss float64) string {
+	switch {
+	case winLoss >= blunderLoss:
+		return models.MoveBlunder
+	case winLoss >= mistakeLoss:
+		return models.MoveMistake
+	case winLoss >= inaccuracyLoss:
+		return models.MoveInaccuracy
+	default:
+		return ""
+	}
+}
+
+// Report evaluates each move from the evaluations of the positions around it;
+// evals holds one more entry than moves, starting with the initial position.
+// A player's accuracy is the mean of their move accuracies. A player without moves
+// has full accuracy.
+func Report(moves []string, evals []Eval) (results []models.MoveAnalysis, white, black models.PlayerAnalysis) {
+	results = make([]models.MoveAnalysis, len(moves))
+	var accuracySum, lossSum [2]float64
+	var counts [2]int
+
+	for i, move := range moves {
+		side, sign := i%2, 1
+		if side == 1 {
+			sign = -1
+		}
+		before, after := evals[i], evals[i+1]
+
+		// Both from the mover's point of view
+		cpBefore, cpAfter := sign*before.CP, sign*after.CP
+		loss := min(mateCP, max(0, cpBefore-cpAfter))
+		winBefore, winAfter := WinPercent(cpBefore), WinPercent(cpAfter)
+		accuracy := MoveAccuracy(winBefore, winAfter)
+
+		r := models.MoveAnalysis{
+			Ply:      i + 1,
+			Move:     move,
+			Eval:     after.CP,
+			Mate:     after.Mate,
+			BestMove: before.BestMove,
+			CPLoss:   loss,
+			Accuracy: round1(accuracy),
+		}
+		if move != before.BestMove {
+			r.Classification = Classify(winBefore - winAfter)
+		}
+		results[i] = r
+
+		accuracySum[side] += accuracy
+		lossSum[side] += float64(loss)
+		counts[side]++
+	}
+
+	summary := func(side int) models.PlayerAnalysis {
+		p := models.PlayerAnalysis{Accuracy: 100}
+		if counts[side] > 0 {
+			p.Accuracy = round1(accuracySum[side] / float64(counts[side]))
+			p.ACPL = int(math.Round(lossSum[side] / float64(counts[side])))
+		}
+		for i := side; i < len(results); i += 2 {
+			switch results[i].Classification {
+			case models.MoveInaccuracy:
+				p.Inaccuracies++
+			case models.MoveMistake:
+				p.Mistakes++
+			case models.MoveBlunder:
+				p.Blunders++
+			}
+		}
+		return p
+	}
+	return results, summary(0), summary(1)
+}
+
+func round1(x float64) float64 {
+	return math.Round(x*10) / 10
+}
@@ -0,0 +1,138 @@
+package analysis
+
+import (
+	"math"
+	"testing"
+
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/chess"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/engine"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/models"
+)
+
+func TestWinPercent(t *testing.T) {
+	if got := WinPercent(0); got != 50 {
+		t.Errorf("WinPercent(0) = %v, want 50", got)
+	}
+	if up, down := WinPercent(300), WinPercent(-300); math.Abs(up+down-100) > 1e-9 {
+		t.Errorf("expected symmetric win percentages, got %v and %v", up, down)
+	}
+	if got := WinPercent(mateCP); got < 95 {
+		t.Errorf("expected a mate to be nearly certain, got %v", got)
+	}
+}
+
+func TestMoveAccuracy(t *testing.T) {
+	if got := MoveAccuracy(60, 60); got != 100 {
+		t.Errorf("expected a move keeping the evaluation to score 100, got %v", got)
+	}
+	if got := MoveAccuracy(60, 70); got != 100 {
+		t.Errorf("expected an improving move to score 100, got %v", got)
+	}
+	if got := MoveAccuracy(100, 0); got != 0 {
+		t.Errorf("expected throwing away a won game to score 0, got %v", got)
+	}
+	if a, b := MoveAccuracy(60, 55), MoveAccuracy(60, 40); a <= b {
+		t.Errorf("expected smaller drops to score higher, got %v and %v", a, b)
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		loss float64
+		want string
+	}{
+		{0, ""},
+		{4.9, ""},
+		{5, models.MoveInaccuracy},
+		{10, models.MoveMistake},
+		{15, models.MoveBlunder},
+		{60, models.MoveBlunder},
+	}
+	for _, tt := range tests {
+		if got := Classify(tt.loss); got != tt.want {
+			t.Errorf("Classify(%v) = %q, want %q", tt.loss, got, tt.want)
+		}
+	}
+}
+
+func TestReport(t *testing.T) {
+	moves := []string{"e2e4", "e7e5", "d1h5", "g8f6"}
+	evals := []Eval{
+		{CP: 30, BestMove: "e2e4"},
+		{CP: 30, BestMove: "e7e5"},
+		{CP: 25, BestMove: "g1f3"},
+		{CP: -10, BestMove: "b8c6"},
+		{CP: 600, BestMove: "h5f7"},
+	}
+
+	results, white, black := Report(moves, evals)
+
+	if len(results) != 4 {
+		t.Fatalf("expected 4 moves, got %d", len(results))
+	}
+	// e2e4 and e7e5 are the engine's choices
+	if results[0].Classification != "" || results[1].Classification != "" {
+		t.Errorf("expected best moves unclassified, got %q and %q", results[0].Classification, results[1].Classification)
+	}
+	// Qh5 loses 35cp: less than five percent of winning chances
+	if results[2].CPLoss != 35 || results[2].Classification != "" {
+		t.Errorf("expected Qh5 to lose 35cp without a classification, got %+v", results[2])
+	}
+	// Nf6 hangs f7: -10 to +600 from White's side is a 610cp loss for Black
+	if results[3].CPLoss != 610 || results[3].Classification != models.MoveBlunder {
+		t.Errorf("expected Nf6 to be a 610cp blunder, got %+v", results[3])
+	}
+	if results[3].BestMove != "b8c6" || results[3].Eval != 600 {
+		t.Errorf("expected best move and resulting eval recorded, got %+v", results[3])
+	}
+
+	if white.ACPL != 18 || white.Blunders != 0 {
+		t.Errorf("unexpected white summary %+v", white)
+	}
+	if black.ACPL != 305 || black.Blunders != 1 {
+		t.Errorf("unexpected black summary %+v", black)
+	}
+	if white.Accuracy <= black.Accuracy {
+		t.Errorf("expected white to be more accurate, got %v and %v", white.Accuracy, black.Accuracy)
+	}
+}
+
+func TestReport_CapsMateLoss(t *testing.T) {
+	mate := 1
+	results, white, _ := Report([]string{"e2e4"}, []Eval{
+		{CP: mateCP, Mate: &mate, BestMove: "d1h5"},
+		{CP: -mateCP},
+	})
+	if results[0].CPLoss != mateCP {
+		t.Errorf("expected loss capped at %d, got %d", mateCP, results[0].CPLoss)
+	}
+	if white.Blunders != 1 {
+		t.Errorf("expected a blunder, got %+v", white)
+	}
+}
+
+func TestReport_PlayerWithoutMoves(t *testing.T) {
+	_, _, black := Report([]string{"e2e4"}, []Eval{{CP: 30, BestMove: "e2e4"}, {CP: 30}})
+	if black.Accuracy != 100 || black.ACPL != 0 {
+		t.Errorf("expected full accuracy without moves, got %+v", black)
+	}
+}
+
+func TestEvalOf(t *testing.T) {
+	cp, mate := 2500, -3
+	if e := evalOf(&engine.Analysis{Lines: []engine.Line{{Score: engine.Score{CP: &cp}}}}, chess.NewGame()); e.CP != mateCP {
+		t.Errorf("expected centipawns clamped to %d, got %d", mateCP, e.CP)
+	}
+	if e := evalOf(&engine.Analysis{Lines: []engine.Line{{Score: engine.Score{Mate: &mate}}}}, chess.NewGame()); e.CP != -mateCP || e.Mate == nil {
+		t.Errorf("expected black mating to count as -%d, got %+v", mateCP, e)
+	}
+
+	// Fool's mate: no lines, and black has won
+	g, err := chess.ReplayMoves("f2f3 e7e5 g2g4 d8h4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e := evalOf(&engine.Analysis{}, g); e.CP != -mateCP {
+		t.Errorf("expected checkmate by black to count as -%d, got %+v", mateCP, e)
+	}
+}
@@ -0,0 +1,49 @@
+// Package apitokens issues and resolves personal API tokens.
+package apitokens
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/database"
+)
+
+// Prefix starts every personal API token, telling them apart from session tokens and
+// making leaked tokens easy to find with secret scanners
+const Prefix = "nxt_"
+
+// displayLength is how much of a token is kept to identify it in listings
+const displayLength = len(Prefix) + 6
+
+// Generate returns a new token, its hash for storage and its display prefix
+func Generate() (token, hash, display string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", "", err
+	}
+	token = Prefix + base64.RawURLEncoding.EncodeToString(b)
+	return token, Hash(token), token[:displayLength], nil
+}
+
+// Hash is the stored form of a token
+func Hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// IsToken reports whether a bearer token is a personal API token
+func IsToken(token string) bool {
+	return strings.HasPrefix(token, Prefix)
+}
+
+// Lookup returns the owner and scopes of a live token and records its use
+func Lookup(token string) (userID string, scopes []string, ok bool) {
+	userID, scopes, err := database.UseAPIToken(Hash(token))
+	if err != nil || userID == "" {
+		return "", nil, false
+	}
+	return userID, scopes, true
+}
@@ -0,0 +1,42 @@
+package apitokens
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	token, hash, display, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsToken(token) {
+		t.Errorf("generated token %q is not recognised as an API token", token)
+	}
+	if hash != Hash(token) {
+		t.Errorf("Generate() hash = %q, want Hash(token) = %q", hash, Hash(token))
+	}
+	if strings.Contains(hash, token) || len(hash) != 64 {
+		t.Errorf("hash %q should be a 64-character digest not containing the token", hash)
+	}
+	if !strings.HasPrefix(token, display) || len(display) != displayLength {
+		t.Errorf("display %q should be the first %d characters of %q", display, displayLength, token)
+	}
+
+	other, _, _, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other == token {
+		t.Error("Generate() returned the same token twice")
+	}
+}
+
+func TestIsToken(t *testing.T) {
+	if IsToken("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk=") {
+		t.Error("session tokens must not be treated as API tokens")
+	}
+	if !IsToken(Prefix + "abc") {
+		t.Error("tokens with the API token prefix should be recognised")
+	}
+}
@@ -3,9 +3,11 @@ package auth
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
 
@@ -15,6 +17,7 @@ import (
 	"github.com/tmcarmichael/nxtchess/apps/backend/internal/database"
 	"github.com/tmcarmichael/nxtchess/apps/backend/internal/httpx"
 	"github.com/tmcarmichael/nxtchess/apps/backend/internal/logger"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/middleware"
 	"github.com/tmcarmichael/nxtchess/apps/backend/internal/sessions"
 	"github.com/tmcarmichael/nxtchess/apps/backend/internal/utils"
 )
@@ -46,6 +49,15 @@ func GetProvider(name string) *OAuthProvider {
 	return providers[name]
 }
 
+// IdentityProvider is the provider name stored with linked identities, e.g. "github"
+func (p *OAuthProvider) IdentityProvider() string {
+	return strings.ToLower(p.Name)
+}
+
+// linkSuffix marks the OAuth state of a request that links an account to the signed-in
+// user instead of signing in
+const linkSuffix = ":link"
+
 // LoginHandler creates a login handler for any OAuth provider
 func LoginHandler(providerName string, cfg *config.Config) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -61,24 +73,71 @@ func LoginHandler(providerName string, cfg *config.Config) http.HandlerFunc {
 			return
 		}
 
-		// Encode mobile flag in state so callback can redirect to custom scheme
+		// Encode mobile flag in state so callback can redirect to custom scheme.
+		// Mobile logins use PKCE: the app later proves it started this login when
+		// exchanging the one-time code from the redirect for tokens.
 		isMobile := r.URL.Query().Get("mobile") == "true"
 		cookieState := state
 		if isMobile {
+			challenge := r.URL.Query().Get("code_challenge")
+			if !validCodeChallenge(challenge, r.URL.Query().Get("code_challenge_method")) {
+				httpx.WriteJSONError(w, http.StatusBadRequest, "Mobile login requires an S256 code_challenge")
+				return
+			}
+			if err := sessions.StorePKCEChallenge(state, challenge); err != nil {
+				httpx.WriteJSONError(w, http.StatusInternalServerError, "Failed to start login")
+				return
+			}
 			state = state + ":mobile"
 		}
 
-		http.SetCookie(w, httpx.NewSecureCookie(cfg, provider.StateCookie, cookieState, 600))
+		logger.Info("Redirecting to OAuth", logger.F("provider", providerName, "mobile", isMobile))
+		redirectToProvider(w, r, provider, cfg, cookieState, state)
+	}
+}
 
-		w.Header().Set("Cache-Control", "no-store")
+// LinkHandler creates a handler that links an account of the provider to the
+// signed-in user. It must be behind middleware.Session.
+func LinkHandler(providerName string, cfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok || userID == "" {
+			httpx.WriteJSONError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
 
-		authURL := provider.OAuthConfig.AuthCodeURL(state, provider.AuthCodeOpts...)
-		logger.Info("Redirecting to OAuth", logger.F("provider", providerName, "mobile", isMobile))
+		provider := GetProvider(providerName)
+		if provider == nil || provider.OAuthConfig == nil {
+			httpx.WriteJSONError(w, http.StatusInternalServerError, fmt.Sprintf("%s OAuth not configured", providerName))
+			return
+		}
+
+		state, err := utils.GenerateRandomString(16)
+		if err != nil {
+			httpx.WriteJSONError(w, http.StatusInternalServerError, "Failed to generate state parameter")
+			return
+		}
+		if err := sessions.StoreLinkRequest(state, userID); err != nil {
+			httpx.WriteJSONError(w, http.StatusInternalServerError, "Failed to start account linking")
+			return
+		}
 
-		http.Redirect(w, r, authURL, http.StatusTemporaryRedirect)
+		logger.Info("Redirecting to OAuth to link account", logger.F("provider", providerName, "userID", userID))
+		redirectToProvider(w, r, provider, cfg, state, state+linkSuffix)
 	}
 }
 
+// redirectToProvider stores cookieState in the provider's state cookie and sends the
+// browser to the provider's consent page with state
+func redirectToProvider(w http.ResponseWriter, r *http.Request, provider *OAuthProvider, cfg *config.Config, cookieState, state string) {
+	http.SetCookie(w, httpx.NewSecureCookie(cfg, provider.StateCookie, cookieState, 600))
+
+	w.Header().Set("Cache-Control", "no-store")
+
+	authURL := provider.OAuthConfig.AuthCodeURL(state, provider.AuthCodeOpts...)
+	http.Redirect(w, r, authURL, http.StatusTemporaryRedirect)
+}
+
 // CallbackHandler creates a callback handler for any OAuth provider
 func CallbackHandler(providerName string, cfg *config.Config) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -98,11 +157,15 @@ func CallbackHandler(providerName string, cfg *config.Config) http.HandlerFunc {
 			return
 		}
 
-		// Detect mobile flag encoded in state
+		// Detect mobile and link flags encoded in state
 		isMobile := strings.HasSuffix(stateParam, ":mobile")
 		if isMobile {
 			stateParam = strings.TrimSuffix(stateParam, ":mobile")
 		}
+		isLink := strings.HasSuffix(stateParam, linkSuffix)
+		if isLink {
+			stateParam = strings.TrimSuffix(stateParam, linkSuffix)
+		}
 
 		stateCookie, err := r.Cookie(provider.StateCookie)
 		if err != nil {
@@ -155,8 +218,8 @@ func CallbackHandler(providerName string, cfg *config.Config) http.HandlerFunc {
 			return
 		}
 
-		// Extract user ID using provider-specific logic
-		userID, err := provider.ExtractUserID(bodyBytes)
+		// Extract the provider's user ID using provider-specific logic
+		subject, err := provider.ExtractUserID(bodyBytes)
 		if err != nil {
 			logger.Error("OAuth user ID extraction failed", logger.F(
 				"provider", providerName,
@@ -166,32 +229,15 @@ func CallbackHandler(providerName string, cfg *config.Config) http.HandlerFunc {
 			return
 		}
 
-		// Create session
-		sessionToken, err := sessions.GenerateSessionToken()
-		if err != nil {
-			logger.Error("Session token generation failed", logger.F("error", err.Error()))
-			utils.AuthRedirectWithError(w, r, "Authentication failed. Please try again.", http.StatusInternalServerError, cfg)
+		if isLink {
+			completeLink(w, r, provider, stateParam, subject, cfg)
 			return
 		}
 
-		if err := sessions.StoreSession(sessionToken, userID); err != nil {
-			logger.Error("Session storage failed", logger.F(
-				"userID", userID,
-				"error", err.Error(),
-			))
-			utils.AuthRedirectWithError(w, r, "Authentication failed. Please try again.", http.StatusInternalServerError, cfg)
-			return
-		}
-
-		http.SetCookie(w, httpx.NewSecureCookie(cfg, "session_token", sessionToken, 86400))
-
-		// Upsert user profile (reuse the OAuth context for timeout)
-		err = database.CreateProfileWithContext(ctx, userID)
+		// Resolve the provider account to the internal user ID, creating the profile on
+		// first sign-in (reuse the OAuth context for timeout)
+		userID, created, err := database.ResolveIdentityWithContext(ctx, provider.IdentityProvider(), subject)
 		if err != nil {
-			logger.Error("Profile creation failed", logger.F(
-				"userID", userID,
-				"error", err.Error(),
-			))
 			utils.AuthRedirectWithError(w, r, "Authentication failed. Please try again.", http.StatusInternalServerError, cfg)
 			return
 		}
@@ -210,17 +256,40 @@ func CallbackHandler(providerName string, cfg *config.Config) http.HandlerFunc {
 		logger.Info("OAuth login successful", logger.F(
 			"provider", providerName,
 			"userID", userID,
+			"newAccount", created,
 			"hasUsername", hasUsername,
 			"mobile", isMobile,
 		))
 
 		if isMobile {
-			// Redirect to custom URL scheme for mobile app
-			redirectURL := fmt.Sprintf("nxtchess://callback?token=%s&has_username=%t", sessionToken, hasUsername)
-			http.Redirect(w, r, redirectURL, http.StatusSeeOther)
+			redirectToApp(w, r, stateParam, userID, hasUsername, cfg)
+			return
+		}
+
+		// Create session
+		sessionToken, err := sessions.GenerateSessionToken()
+		if err != nil {
+			logger.Error("Session token generation failed", logger.F("error", err.Error()))
+			utils.AuthRedirectWithError(w, r, "Authentication failed. Please try again.", http.StatusInternalServerError, cfg)
 			return
 		}
 
+		meta := sessions.Metadata{
+			IP:        httpx.GetClientIP(r, cfg),
+			UserAgent: r.UserAgent(),
+			Platform:  sessions.PlatformWeb,
+		}
+		if err := sessions.StoreSession(sessionToken, userID, meta); err != nil {
+			logger.Error("Session storage failed", logger.F(
+				"userID", userID,
+				"error", err.Error(),
+			))
+			utils.AuthRedirectWithError(w, r, "Authentication failed. Please try again.", http.StatusInternalServerError, cfg)
+			return
+		}
+
+		http.SetCookie(w, httpx.NewSecureCookie(cfg, "session_token", sessionToken, int(sessions.MaxLifetime.Seconds())))
+
 		if hasUsername {
 			http.Redirect(w, r, cfg.FrontendURL+"/", http.StatusSeeOther)
 		} else {
@@ -229,6 +298,58 @@ func CallbackHandler(providerName string, cfg *config.Config) http.HandlerFunc {
 	}
 }
 
+// redirectToApp sends a mobile login back to the app with a one-time code. The app
+// exchanges it at POST /auth/token with the PKCE verifier of the login, so the code
+// is useless to anything else that intercepts the redirect.
+func redirectToApp(w http.ResponseWriter, r *http.Request, state, userID string, hasUsername bool, cfg *config.Config) {
+	challenge, ok := sessions.TakePKCEChallenge(state)
+	if !ok {
+		utils.AuthRedirectWithError(w, r, "Authentication session expired", http.StatusBadRequest, cfg)
+		return
+	}
+
+	code, err := sessions.GenerateSessionToken()
+	if err != nil {
+		logger.Error("Authorization code generation failed", logger.F("error", err.Error()))
+		utils.AuthRedirectWithError(w, r, "Authentication failed. Please try again.", http.StatusInternalServerError, cfg)
+		return
+	}
+	if err := sessions.StoreAuthCode(code, userID, challenge); err != nil {
+		utils.AuthRedirectWithError(w, r, "Authentication failed. Please try again.", http.StatusInternalServerError, cfg)
+		return
+	}
+
+	redirectURL := fmt.Sprintf("nxtchess://callback?code=%s&has_username=%t", url.QueryEscape(code), hasUsername)
+	http.Redirect(w, r, redirectURL, http.StatusSeeOther)
+}
+
+// completeLink links the provider account to the user who started linking under state
+// and sends the browser back to the frontend
+func completeLink(w http.ResponseWriter, r *http.Request, provider *OAuthProvider, state, subject string, cfg *config.Config) {
+	userID, ok := sessions.TakeLinkRequest(state)
+	if !ok {
+		utils.AuthRedirectWithError(w, r, "Account linking expired. Please try again.", http.StatusBadRequest, cfg)
+		return
+	}
+
+	name := provider.IdentityProvider()
+	err := database.LinkIdentity(userID, name, subject)
+	switch {
+	case errors.Is(err, database.ErrIdentityLinked):
+		utils.AuthRedirectWithError(w, r, fmt.Sprintf("This %s account is already linked to another user", provider.Name), http.StatusConflict, cfg)
+		return
+	case errors.Is(err, database.ErrProviderLinked):
+		utils.AuthRedirectWithError(w, r, fmt.Sprintf("A %s account is already linked", provider.Name), http.StatusConflict, cfg)
+		return
+	case err != nil:
+		utils.AuthRedirectWithError(w, r, "Failed to link account. Please try again.", http.StatusInternalServerError, cfg)
+		return
+	}
+
+	logger.Info("OAuth account linked", logger.F("provider", provider.Name, "userID", userID))
+	http.Redirect(w, r, cfg.FrontendURL+"/?linked="+name, http.StatusSeeOther)
+}
+
 // readBody reads the response body
 func readBody(resp *http.Response) ([]byte, error) {
 	return io.ReadAll(resp.Body)
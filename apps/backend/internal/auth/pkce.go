@@ -0,0 +1,28 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"regexp"
+)
+
+// pkceValue matches a PKCE code verifier (RFC 7636: 43-128 unreserved characters).
+// An S256 code challenge is always 43 characters of the same alphabet.
+var pkceValue = regexp.MustCompile(`^[A-Za-z0-9\-._~]{43,128}$`)
+
+// validCodeChallenge reports whether challenge is a well-formed S256 code challenge.
+// The plain method is not supported.
+func validCodeChallenge(challenge, method string) bool {
+	return method == "S256" && len(challenge) == 43 && pkceValue.MatchString(challenge)
+}
+
+// verifyCodeVerifier reports whether verifier hashes to the S256 challenge
+func verifyCodeVerifier(verifier, challenge string) bool {
+	if !pkceValue.MatchString(verifier) {
+		return false
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	computed := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(computed), []byte(challenge)) == 1
+}
@@ -0,0 +1,41 @@
+package auth
+
+import "testing"
+
+// Example values from RFC 7636 Appendix B
+const (
+	rfcVerifier  = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	rfcChallenge = "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+)
+
+func TestValidCodeChallenge(t *testing.T) {
+	tests := []struct {
+		challenge string
+		method    string
+		want      bool
+	}{
+		{rfcChallenge, "S256", true},
+		{rfcChallenge, "plain", false},
+		{rfcChallenge, "", false},
+		{rfcChallenge[:42], "S256", false},
+		{rfcChallenge[:42] + "=", "S256", false},
+		{"", "S256", false},
+	}
+	for _, tt := range tests {
+		if got := validCodeChallenge(tt.challenge, tt.method); got != tt.want {
+			t.Errorf("validCodeChallenge(%q, %q) = %v, want %v", tt.challenge, tt.method, got, tt.want)
+		}
+	}
+}
+
+func TestVerifyCodeVerifier(t *testing.T) {
+	if !verifyCodeVerifier(rfcVerifier, rfcChallenge) {
+		t.Error("expected the RFC 7636 verifier to match its challenge")
+	}
+	if verifyCodeVerifier(rfcVerifier+"x", rfcChallenge) {
+		t.Error("expected a different verifier not to match")
+	}
+	if verifyCodeVerifier("short", rfcChallenge) {
+		t.Error("expected a too-short verifier to be rejected")
+	}
+}
@@ -0,0 +1,94 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/config"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/httpx"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/logger"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/sessions"
+)
+
+// TokenHandler issues bearer tokens to the mobile app
+// POST /auth/token
+//
+// {"grant_type": "authorization_code", "code": "...", "code_verifier": "..."} exchanges
+// the one-time code from a mobile login redirect and starts a session;
+// {"grant_type": "refresh_token", "refresh_token": "..."} rotates the session's tokens.
+func TokenHandler(cfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-store")
+
+		var req struct {
+			GrantType    string `json:"grant_type"`
+			Code         string `json:"code"`
+			CodeVerifier string `json:"code_verifier"`
+			RefreshToken string `json:"refresh_token"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			httpx.WriteJSONError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+
+		switch req.GrantType {
+		case "authorization_code":
+			exchangeAuthCode(w, r, req.Code, req.CodeVerifier, cfg)
+		case "refresh_token":
+			refreshTokens(w, req.RefreshToken)
+		default:
+			httpx.WriteJSONError(w, http.StatusBadRequest, "grant_type must be authorization_code or refresh_token")
+		}
+	}
+}
+
+func exchangeAuthCode(w http.ResponseWriter, r *http.Request, code, verifier string, cfg *config.Config) {
+	if code == "" || verifier == "" {
+		httpx.WriteJSONError(w, http.StatusBadRequest, "code and code_verifier are required")
+		return
+	}
+
+	userID, challenge, ok := sessions.TakeAuthCode(code)
+	if !ok {
+		httpx.WriteJSONError(w, http.StatusBadRequest, "Invalid or expired code")
+		return
+	}
+	if !verifyCodeVerifier(verifier, challenge) {
+		logger.Warn("PKCE verification failed", logger.F("userID", userID))
+		httpx.WriteJSONError(w, http.StatusBadRequest, "Invalid or expired code")
+		return
+	}
+
+	meta := sessions.Metadata{
+		IP:        httpx.GetClientIP(r, cfg),
+		UserAgent: r.UserAgent(),
+		Platform:  sessions.PlatformMobile,
+	}
+	pair, err := sessions.CreateTokenSession(userID, meta)
+	if err != nil {
+		httpx.WriteJSONError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	logger.Info("Mobile session started", logger.F("userID", userID))
+	httpx.WriteJSON(w, http.StatusOK, pair)
+}
+
+func refreshTokens(w http.ResponseWriter, refreshToken string) {
+	if refreshToken == "" {
+		httpx.WriteJSONError(w, http.StatusBadRequest, "refresh_token is required")
+		return
+	}
+
+	pair, ok, err := sessions.RefreshTokens(refreshToken)
+	if err != nil {
+		httpx.WriteJSONError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	if !ok {
+		httpx.WriteJSONError(w, http.StatusUnauthorized, "Invalid or expired refresh token")
+		return
+	}
+
+	httpx.WriteJSON(w, http.StatusOK, pair)
+}
@@ -70,6 +70,18 @@ func NewGameFromFEN(fen string) (*Game, error) {
 	}, nil
 }
 
+// ReplayMoves plays space-separated UCI moves, the form games are stored in, from
+// the starting position
+func ReplayMoves(moves string) (*Game, error) {
+	g := NewGame()
+	for i, uci := range SplitMoves(moves) {
+		if result := g.TryUCIMove(uci); !result.Valid {
+			return nil, fmt.Errorf("move %d (%s) is illegal", i+1, uci)
+		}
+	}
+	return g, nil
+}
+
 // FEN returns the current position in FEN notation
 func (g *Game) FEN() string {
 	return g.game.FEN()
@@ -143,6 +155,43 @@ func (g *Game) TryMove(from, to, promotion string) MoveResult {
 	return result
 }
 
+// TryUCIMove attempts a move given in UCI notation (e.g., "e2e4", "e7e8q")
+func (g *Game) TryUCIMove(uci string) MoveResult {
+	from, to, promotion, err := ParseUCI(uci)
+	if err != nil {
+		return MoveResult{
+			Valid:    false,
+			NewFEN:   g.FEN(),
+			MoveNum:  g.MoveNumber(),
+			ErrorMsg: err.Error(),
+		}
+	}
+	return g.TryMove(from, to, promotion)
+}
+
+// ParseUCI splits a UCI move into from/to squares and an optional promotion piece
+func ParseUCI(uci string) (from, to, promotion string, err error) {
+	uci = strings.ToLower(strings.TrimSpace(uci))
+	if len(uci) != 4 && len(uci) != 5 {
+		return "", "", "", fmt.Errorf("invalid UCI move: %q", uci)
+	}
+	from, to = uci[0:2], uci[2:4]
+	if !isSquare(from) || !isSquare(to) {
+		return "", "", "", fmt.Errorf("invalid UCI move: %q", uci)
+	}
+	if len(uci) == 5 {
+		promotion = uci[4:5]
+		if !strings.Contains("qrbn", promotion) {
+			return "", "", "", fmt.Errorf("invalid promotion piece in UCI move: %q", uci)
+		}
+	}
+	return from, to, promotion, nil
+}
+
+func isSquare(s string) bool {
+	return len(s) == 2 && s[0] >= 'a' && s[0] <= 'h' && s[1] >= '1' && s[1] <= '8'
+}
+
 // findMove finds a legal move matching from/to/promotion
 func (g *Game) findMove(from, to, promotion string) *chess.Move {
 	from = strings.ToLower(from)
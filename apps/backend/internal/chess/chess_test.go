@@ -327,3 +327,20 @@ func TestMovesHistory(t *testing.T) {
 		}
 	}
 }
+
+func TestReplayMoves(t *testing.T) {
+	g, err := ReplayMoves("e2e4 e7e5 d1h5 b8c6 f1c4 g8f6 h5f7")
+	if err != nil {
+		t.Fatalf("ReplayMoves() error = %v", err)
+	}
+	if !g.IsGameOver() || len(g.Moves()) != 7 {
+		t.Errorf("ReplayMoves() = %d moves, game over %v; want 7, true", len(g.Moves()), g.IsGameOver())
+	}
+
+	if _, err := ReplayMoves("e2e4 e2e4"); err == nil {
+		t.Error("ReplayMoves() accepted an illegal move")
+	}
+	if g, err := ReplayMoves(""); err != nil || len(g.Moves()) != 0 {
+		t.Errorf("ReplayMoves(\"\") = %v, %v; want an empty game", g, err)
+	}
+}
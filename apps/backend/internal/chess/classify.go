@@ -0,0 +1,202 @@
+package chess
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/notnil/chess"
+)
+
+// Endgame families recognised from the material signature
+const (
+	ThemeQueenVsRook      = "queenVsRook"
+	ThemeQueenVsPawn      = "queenVsPawn"
+	ThemeRookPawnVsRook   = "rookPawnVsRook"
+	ThemeRookVsBishop     = "rookVsBishop"
+	ThemeRookVsKnight     = "rookVsKnight"
+	ThemeBishopKnightMate = "bishopKnightMate"
+	ThemeOppositeBishops  = "oppositeColoredBishops"
+	ThemeKingPawnVsKing   = "kingPawnVsKing"
+)
+
+// Technique themes, tagged by hand
+const (
+	ThemeOpposition = "opposition"
+	ThemeZugzwang   = "zugzwang"
+	ThemeLucena     = "lucena"
+	ThemePhilidor   = "philidor"
+)
+
+// familyBySignature maps material signatures to the family they belong to
+var familyBySignature = map[string]string{
+	"KQvKR":  ThemeQueenVsRook,
+	"KQvKP":  ThemeQueenVsPawn,
+	"KRPvKR": ThemeRookPawnVsRook,
+	"KRvKB":  ThemeRookVsBishop,
+	"KRvKN":  ThemeRookVsKnight,
+	"KBNvK":  ThemeBishopKnightMate,
+	"KPvK":   ThemeKingPawnVsKing,
+}
+
+// techniqueThemes are kept on reclassification but never derived
+var techniqueThemes = map[string]bool{
+	ThemeOpposition: true,
+	ThemeZugzwang:   true,
+	ThemeLucena:     true,
+	ThemePhilidor:   true,
+}
+
+// IsEndgameTheme reports whether theme is one the endgame trainer knows: derived
+// from material, an endgame family, or a technique
+func IsEndgameTheme(theme string) bool {
+	return IsClassifiedTheme(theme) || techniqueThemes[theme]
+}
+
+// IsClassifiedTheme reports whether Classify derives theme, so a stored copy of it
+// can be replaced by a fresh classification
+func IsClassifiedTheme(theme string) bool {
+	if IsMaterialTheme(theme) || theme == ThemeOppositeBishops {
+		return true
+	}
+	for _, family := range familyBySignature {
+		if family == theme {
+			return true
+		}
+	}
+	return false
+}
+
+// pieceValues orders material when deciding which side of a signature is stronger
+var pieceValues = map[chess.PieceType]int{
+	chess.Queen:  9,
+	chess.Rook:   5,
+	chess.Bishop: 3,
+	chess.Knight: 3,
+	chess.Pawn:   1,
+}
+
+// signatureOrder is the order pieces are listed in within a signature
+const signatureOrder = "KQRBNP"
+
+// Classification describes an endgame position by its material
+type Classification struct {
+	// Signature lists the stronger side's pieces, then the other side's (e.g., "KRPvKR")
+	Signature string
+	// Themes are the material themes followed by any family themes
+	Themes []string
+	// Pieces counts the pieces other than kings and pawns; Pawns counts pawns
+	Pieces int
+	Pawns  int
+}
+
+// Classify computes the material signature of fen and the endgame families it
+// belongs to, such as KRPvKR, KQvKR or opposite-coloured bishops
+func Classify(fen string) (Classification, error) {
+	themes, err := MaterialThemes(fen)
+	if err != nil {
+		return Classification{}, err
+	}
+	g, err := NewGameFromFEN(fen)
+	if err != nil {
+		return Classification{}, err
+	}
+	board := g.game.Position().Board()
+
+	c := Classification{Signature: MaterialSignature(board), Themes: themes}
+	bishopColors := make(map[chess.Color][]int)
+	for sq, p := range board.SquareMap() {
+		switch p.Type() {
+		case chess.King:
+		case chess.Pawn:
+			c.Pawns++
+		case chess.Bishop:
+			bishopColors[p.Color()] = append(bishopColors[p.Color()], (int(sq.File())+int(sq.Rank()))%2)
+			c.Pieces++
+		default:
+			c.Pieces++
+		}
+	}
+
+	if family, ok := familyBySignature[c.Signature]; ok {
+		c.Themes = append(c.Themes, family)
+	}
+	white, black := bishopColors[chess.White], bishopColors[chess.Black]
+	if len(white) == 1 && len(black) == 1 && white[0] != black[0] {
+		c.Themes = append(c.Themes, ThemeOppositeBishops)
+	}
+	return c, nil
+}
+
+// MaterialSignature lists the pieces of each side in KQRBNP order, stronger side
+// first (white on equal material), e.g., "KRPvKR"
+func MaterialSignature(board *chess.Board) string {
+	var letters [2][]byte
+	var value [2]int
+	for _, p := range board.SquareMap() {
+		side := 0
+		if p.Color() == chess.Black {
+			side = 1
+		}
+		letters[side] = append(letters[side], strings.ToUpper(p.Type().String())[0])
+		value[side] += pieceValues[p.Type()]
+	}
+	for _, l := range letters {
+		sort.Slice(l, func(i, j int) bool {
+			return strings.IndexByte(signatureOrder, l[i]) < strings.IndexByte(signatureOrder, l[j])
+		})
+	}
+
+	if value[1] > value[0] {
+		letters[0], letters[1] = letters[1], letters[0]
+	}
+	return string(letters[0]) + "v" + string(letters[1])
+}
+
+// familyRatings is how much harder a family is to play than its material suggests
+var familyRatings = map[string]int{
+	ThemeBishopKnightMate: 800,
+	ThemeQueenVsRook:      700,
+	ThemeRookPawnVsRook:   500,
+	ThemeRookVsBishop:     400,
+	ThemeRookVsKnight:     300,
+	ThemeQueenVsPawn:      300,
+	ThemeOppositeBishops:  100,
+	ThemeBasicMate:        -200,
+}
+
+// Bounds of EstimateRating
+const (
+	minEstimatedRating = 200
+	maxEstimatedRating = 3000
+)
+
+// EstimateRating estimates how hard it is to play a classified position to its
+// result, on the training rating scale. result is the expected result for the side
+// to move ("win", "draw" or "loss"); dtz, when known, is the tablebase distance to
+// zeroing in plies. Holding a draw, more material, a long road to progress and the
+// technical families (KBNvK, KQvKR, ...) all make a position harder.
+func EstimateRating(c Classification, result string, dtz *int) int {
+	rating := 600 + 60*c.Pieces + 40*c.Pawns
+	if result != "win" {
+		rating += 250
+	}
+	for _, t := range c.Themes {
+		rating += familyRatings[t]
+	}
+	if dtz != nil {
+		plies := abs(*dtz)
+		if plies > 100 {
+			plies = 100
+		}
+		rating += 5 * plies
+	}
+
+	switch {
+	case rating < minEstimatedRating:
+		return minEstimatedRating
+	case rating > maxEstimatedRating:
+		return maxEstimatedRating
+	default:
+		return rating
+	}
+}
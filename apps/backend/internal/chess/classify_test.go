@@ -0,0 +1,86 @@
+package chess
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name      string
+		fen       string
+		signature string
+		themes    []string
+	}{
+		{"lucena material", "1K1k4/1P6/8/8/8/8/r7/2R5 w - - 0 1", "KRPvKR", []string{ThemeRookEndgame, ThemeRookPawnVsRook}},
+		{"black is stronger", "8/8/3k4/8/3pK3/8/r7/7R w - - 0 1", "KRPvKR", []string{ThemeRookEndgame, ThemeRookPawnVsRook}},
+		{"queen against rook", "8/8/3k4/8/4K3/8/r7/7Q w - - 0 1", "KQvKR", []string{ThemeQueenRookEndgame, ThemeQueenVsRook}},
+		{"bishop and knight mate", "8/8/8/3k4/8/3K4/8/5BN1 w - - 0 1", "KBNvK",
+			[]string{ThemeBishopEndgame, ThemeKnightEndgame, ThemeBasicMate, ThemeBishopKnightMate}},
+		{"opposite bishops", "8/8/3k1b2/8/3PK3/8/8/7B w - - 0 1", "KBPvKB", []string{ThemeBishopEndgame, ThemeOppositeBishops}},
+		{"same coloured bishops", "8/8/3k2b1/8/3PK3/8/8/7B w - - 0 1", "KBPvKB", []string{ThemeBishopEndgame}},
+		{"king and pawn", "8/8/8/3k4/8/3K4/3P4/8 w - - 0 1", "KPvK", []string{ThemePawnEndgame, ThemeKingPawnVsKing}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := Classify(tt.fen)
+			if err != nil {
+				t.Fatalf("Classify() error = %v", err)
+			}
+			if c.Signature != tt.signature {
+				t.Errorf("Signature = %q, want %q", c.Signature, tt.signature)
+			}
+			if !reflect.DeepEqual(c.Themes, tt.themes) {
+				t.Errorf("Themes = %v, want %v", c.Themes, tt.themes)
+			}
+		})
+	}
+
+	if _, err := Classify("not a fen"); err == nil {
+		t.Error("Classify() accepted an invalid FEN")
+	}
+}
+
+func TestIsEndgameTheme(t *testing.T) {
+	for theme, want := range map[string]bool{
+		ThemeRookEndgame:     true,
+		ThemeQueenVsRook:     true,
+		ThemeOppositeBishops: true,
+		ThemeLucena:          true,
+		"fork":               false,
+		"":                   false,
+	} {
+		if got := IsEndgameTheme(theme); got != want {
+			t.Errorf("IsEndgameTheme(%q) = %v, want %v", theme, got, want)
+		}
+	}
+	if IsClassifiedTheme(ThemeOpposition) {
+		t.Error("IsClassifiedTheme(opposition) = true, want false")
+	}
+}
+
+func TestEstimateRating(t *testing.T) {
+	basic, _ := Classify("6k1/8/5K2/8/8/8/8/7Q w - - 0 1")
+	queenRook, _ := Classify("8/8/3k4/8/4K3/8/r7/7Q w - - 0 1")
+	rookPawn, _ := Classify("1K1k4/1P6/8/8/8/8/r7/2R5 w - - 0 1")
+
+	basicRating := EstimateRating(basic, "win", nil)
+	if basicRating >= EstimateRating(queenRook, "win", nil) {
+		t.Error("basic mate rated at least as hard as queen against rook")
+	}
+	if EstimateRating(rookPawn, "win", nil) >= EstimateRating(rookPawn, "draw", nil) {
+		t.Error("winning rated at least as hard as holding the draw")
+	}
+	long, short := 60, 4
+	if EstimateRating(basic, "win", &short) >= EstimateRating(basic, "win", &long) {
+		t.Error("short DTZ rated at least as hard as long DTZ")
+	}
+
+	huge := Classification{Pieces: 30, Pawns: 16, Themes: []string{ThemeQueenVsRook}}
+	if got := EstimateRating(huge, "draw", &long); got != maxEstimatedRating {
+		t.Errorf("EstimateRating() = %d, want cap %d", got, maxEstimatedRating)
+	}
+	if basicRating < minEstimatedRating {
+		t.Errorf("EstimateRating() = %d, below floor %d", basicRating, minEstimatedRating)
+	}
+}
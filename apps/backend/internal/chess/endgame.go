@@ -0,0 +1,227 @@
+package chess
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/notnil/chess"
+)
+
+// Endgame themes derived from the material on the board
+const (
+	ThemePawnEndgame      = "pawnEndgame"
+	ThemeRookEndgame      = "rookEndgame"
+	ThemeBishopEndgame    = "bishopEndgame"
+	ThemeKnightEndgame    = "knightEndgame"
+	ThemeQueenEndgame     = "queenEndgame"
+	ThemeQueenRookEndgame = "queenRookEndgame"
+	ThemeBasicMate        = "basicMate"
+)
+
+// materialThemes lists every theme MaterialThemes can return
+var materialThemes = map[string]bool{
+	ThemePawnEndgame:      true,
+	ThemeRookEndgame:      true,
+	ThemeBishopEndgame:    true,
+	ThemeKnightEndgame:    true,
+	ThemeQueenEndgame:     true,
+	ThemeQueenRookEndgame: true,
+	ThemeBasicMate:        true,
+}
+
+// IsMaterialTheme reports whether theme is derived from material rather than
+// describing a technique (e.g., "opposition", "lucena")
+func IsMaterialTheme(theme string) bool {
+	return materialThemes[theme]
+}
+
+// MaterialThemes derives the endgame themes of a position from its pieces:
+// pawnEndgame with kings and pawns only, the piece family otherwise (rookEndgame,
+// queenRookEndgame, one theme per piece type for mixed material), and basicMate
+// when a side without pawns has mating material against a lone king.
+func MaterialThemes(fen string) ([]string, error) {
+	g, err := NewGameFromFEN(fen)
+	if err != nil {
+		return nil, err
+	}
+	board := g.game.Position().Board()
+
+	present := make(map[chess.PieceType]bool)
+	pawns := make(map[chess.Color]bool)
+	for _, p := range board.SquareMap() {
+		switch p.Type() {
+		case chess.King:
+		case chess.Pawn:
+			pawns[p.Color()] = true
+		default:
+			present[p.Type()] = true
+		}
+	}
+
+	var themes []string
+	switch {
+	case len(present) == 0:
+		themes = append(themes, ThemePawnEndgame)
+	case len(present) == 2 && present[chess.Queen] && present[chess.Rook]:
+		themes = append(themes, ThemeQueenRookEndgame)
+	default:
+		for _, family := range []struct {
+			piece chess.PieceType
+			theme string
+		}{
+			{chess.Queen, ThemeQueenEndgame},
+			{chess.Rook, ThemeRookEndgame},
+			{chess.Bishop, ThemeBishopEndgame},
+			{chess.Knight, ThemeKnightEndgame},
+		} {
+			if present[family.piece] {
+				themes = append(themes, family.theme)
+			}
+		}
+	}
+
+	for _, side := range []chess.Color{chess.White, chess.Black} {
+		if !pawns[side] && hasForcedMateMaterial(board, side) {
+			themes = append(themes, ThemeBasicMate)
+		}
+	}
+	return themes, nil
+}
+
+// NormalizeFEN validates fen with ValidatePosition and returns it as the move
+// generator encodes it, with the halfmove clock and fullmove number reset to "0 1"
+func NormalizeFEN(fen string) (string, error) {
+	if err := ValidatePosition(fen); err != nil {
+		return "", err
+	}
+	key, err := PositionKey(fen)
+	if err != nil {
+		return "", err
+	}
+	return key + " 0 1", nil
+}
+
+// PositionKey identifies a position regardless of its move counters: the first four
+// FEN fields (placement, side to move, castling rights, en passant square)
+func PositionKey(fen string) (string, error) {
+	g, err := NewGameFromFEN(fen)
+	if err != nil {
+		return "", err
+	}
+	fields := strings.Fields(g.FEN())
+	if len(fields) < 4 {
+		return "", fmt.Errorf("invalid FEN: %q", fen)
+	}
+	return strings.Join(fields[:4], " "), nil
+}
+
+// ValidatePosition checks that fen is a legal position with play left: one king per
+// side, no pawns on the first or last rank, the side not to move not in check, and
+// at least one legal move
+func ValidatePosition(fen string) error {
+	g, err := NewGameFromFEN(fen)
+	if err != nil {
+		return err
+	}
+	pos := g.game.Position()
+
+	kings := make(map[chess.Color]chess.Square)
+	for sq, p := range pos.Board().SquareMap() {
+		switch p.Type() {
+		case chess.King:
+			if _, dup := kings[p.Color()]; dup {
+				return fmt.Errorf("%s has more than one king", p.Color().Name())
+			}
+			kings[p.Color()] = sq
+		case chess.Pawn:
+			if sq.Rank() == chess.Rank1 || sq.Rank() == chess.Rank8 {
+				return fmt.Errorf("pawn on %s", sq)
+			}
+		}
+	}
+	if len(kings) != 2 {
+		return errors.New("each side needs exactly one king")
+	}
+
+	if attacked(pos.Board(), kings[pos.Turn().Other()], pos.Turn()) {
+		return errors.New("side not to move is in check")
+	}
+	if len(pos.ValidMoves()) == 0 {
+		return errors.New("position has no legal moves")
+	}
+	return nil
+}
+
+// attacked reports whether any piece of color by attacks target
+func attacked(board *chess.Board, target chess.Square, by chess.Color) bool {
+	tf, tr := int(target.File()), int(target.Rank())
+	for sq, p := range board.SquareMap() {
+		if p.Color() != by {
+			continue
+		}
+		df, dr := tf-int(sq.File()), tr-int(sq.Rank())
+		adf, adr := abs(df), abs(dr)
+
+		switch p.Type() {
+		case chess.Pawn:
+			forward := 1
+			if by == chess.Black {
+				forward = -1
+			}
+			if dr == forward && adf == 1 {
+				return true
+			}
+		case chess.Knight:
+			if (adf == 1 && adr == 2) || (adf == 2 && adr == 1) {
+				return true
+			}
+		case chess.King:
+			if adf <= 1 && adr <= 1 {
+				return true
+			}
+		default:
+			straight := df == 0 || dr == 0
+			diagonal := adf == adr
+			if (straight && p.Type() == chess.Bishop) || (diagonal && p.Type() == chess.Rook) ||
+				(!straight && !diagonal) || (df == 0 && dr == 0) {
+				continue
+			}
+			if rayClear(board, sq, target) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// rayClear reports whether the squares strictly between from and to (on one line) are empty
+func rayClear(board *chess.Board, from, to chess.Square) bool {
+	stepF, stepR := sign(int(to.File())-int(from.File())), sign(int(to.Rank())-int(from.Rank()))
+	f, r := int(from.File())+stepF, int(from.Rank())+stepR
+	for f != int(to.File()) || r != int(to.Rank()) {
+		if board.Piece(chess.Square(r*8+f)) != chess.NoPiece {
+			return false
+		}
+		f, r = f+stepF, r+stepR
+	}
+	return true
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func sign(v int) int {
+	switch {
+	case v > 0:
+		return 1
+	case v < 0:
+		return -1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,96 @@
+package chess
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMaterialThemes(t *testing.T) {
+	tests := []struct {
+		name string
+		fen  string
+		want []string
+	}{
+		{"king and pawn", "8/8/8/3k4/8/3K4/3P4/8 w - - 0 1", []string{ThemePawnEndgame}},
+		{"bare kings", "8/8/8/3k4/8/3K4/8/8 w - - 0 1", []string{ThemePawnEndgame}},
+		{"rook ending", "8/8/3k4/8/3PK3/8/r7/7R w - - 0 1", []string{ThemeRookEndgame}},
+		{"queen and rook", "8/8/3k4/8/3qK3/8/r7/7R w - - 0 1", []string{ThemeQueenRookEndgame}},
+		{"bishop versus knight", "8/8/3k1n2/8/3PK3/8/8/7B w - - 0 1", []string{ThemeBishopEndgame, ThemeKnightEndgame}},
+		{"basic queen mate", "6k1/8/5K2/8/8/8/8/7Q w - - 0 1", []string{ThemeQueenEndgame, ThemeBasicMate}},
+		{"black mates", "6K1/8/5k2/8/8/8/8/7r b - - 0 1", []string{ThemeRookEndgame, ThemeBasicMate}},
+		{"bishop and knight mate", "8/8/8/3k4/8/3K4/8/5BN1 w - - 0 1", []string{ThemeBishopEndgame, ThemeKnightEndgame, ThemeBasicMate}},
+		{"lone knight", "8/8/8/3k4/8/3K4/8/6N1 w - - 0 1", []string{ThemeKnightEndgame}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MaterialThemes(tt.fen)
+			if err != nil {
+				t.Fatalf("MaterialThemes() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MaterialThemes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidatePosition(t *testing.T) {
+	tests := []struct {
+		name    string
+		fen     string
+		wantErr bool
+	}{
+		{"valid", "8/8/8/3k4/8/3K4/3P4/8 w - - 0 1", false},
+		{"checked side to move", "3k4/8/8/8/8/8/8/3RK3 b - - 0 1", false},
+		{"not a FEN", "not a fen", true},
+		{"missing king", "8/8/8/8/8/3K4/3P4/8 w - - 0 1", true},
+		{"two kings", "8/8/3k4/8/3k4/3K4/8/8 w - - 0 1", true},
+		{"pawn on the last rank", "3P4/8/8/3k4/8/3K4/8/8 w - - 0 1", true},
+		{"side not to move in check", "3k4/8/8/8/8/8/8/3RK3 w - - 0 1", true},
+		{"in check from a knight", "8/8/8/3k4/5N2/3K4/8/8 w - - 0 1", true},
+		{"checking ray blocked", "3k4/8/3p4/8/8/8/8/3RK3 w - - 0 1", false},
+		{"checkmated", "6k1/6Q1/6K1/8/8/8/8/8 b - - 0 1", true},
+		{"stalemated", "k7/8/1QK5/8/8/8/8/8 b - - 0 1", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidatePosition(tt.fen)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidatePosition() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNormalizeFEN(t *testing.T) {
+	got, err := NormalizeFEN("8/8/8/3k4/8/3K4/3P4/8 w - - 17 42")
+	if err != nil {
+		t.Fatalf("NormalizeFEN() error = %v", err)
+	}
+	if want := "8/8/8/3k4/8/3K4/3P4/8 w - - 0 1"; got != want {
+		t.Errorf("NormalizeFEN() = %q, want %q", got, want)
+	}
+
+	if _, err := NormalizeFEN("8/8/8/8/8/3K4/3P4/8 w - - 0 1"); err == nil {
+		t.Error("NormalizeFEN() accepted a position without a black king")
+	}
+}
+
+func TestPositionKey(t *testing.T) {
+	a, err := PositionKey("8/8/8/3k4/8/3K4/3P4/8 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := PositionKey("8/8/8/3k4/8/3K4/3P4/8 w - - 12 60")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a != b {
+		t.Errorf("keys differ by move counters: %q, %q", a, b)
+	}
+
+	c, _ := PositionKey("8/8/8/3k4/8/3K4/3P4/8 b - - 0 1")
+	if a == c {
+		t.Error("keys equal for different sides to move")
+	}
+}
@@ -0,0 +1,115 @@
+package chess
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+
+	"github.com/notnil/chess"
+)
+
+// Opening is a named line of the Encyclopaedia of Chess Openings
+type Opening struct {
+	ECO  string `json:"eco"`  // A00 to E99
+	Name string `json:"name"` // family, then variation after a colon
+}
+
+// openingsTSV is the ECO table (eco, name, uci, epd), from the lichess chess-openings
+// dataset
+//
+//go:embed openings.tsv
+var openingsTSV string
+
+var (
+	// openingBook maps the position each line reaches to its opening, so a game is
+	// named by where it got to rather than the move order it took
+	openingBook map[string]Opening
+
+	// openingMaxPlies is the length of the longest line; no later position is in the book
+	openingMaxPlies int
+)
+
+func init() {
+	book, maxPlies, err := parseOpenings(openingsTSV)
+	if err != nil {
+		panic("chess: invalid built-in opening book: " + err.Error())
+	}
+	openingBook, openingMaxPlies = book, maxPlies
+}
+
+func parseOpenings(data string) (map[string]Opening, int, error) {
+	lines := strings.Split(strings.TrimSpace(data), "\n")
+	book := make(map[string]Opening, len(lines))
+	maxPlies := 0
+	for i, line := range lines[1:] {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 4 {
+			return nil, 0, fmt.Errorf("line %d: expected 4 fields, got %d", i+2, len(fields))
+		}
+		key, ok := openingKey(fields[3])
+		if !ok {
+			return nil, 0, fmt.Errorf("line %d: invalid position %q", i+2, fields[3])
+		}
+		if _, dup := book[key]; dup {
+			return nil, 0, fmt.Errorf("line %d: position of %s already named", i+2, fields[1])
+		}
+		book[key] = Opening{ECO: fields[0], Name: fields[1]}
+		maxPlies = max(maxPlies, len(strings.Fields(fields[2])))
+	}
+	return book, maxPlies, nil
+}
+
+// openingKey identifies a position for the opening book by placement, side to move
+// and castling rights. The en passant square is left out: the book records it only
+// when a capture is possible, and it never changes an opening's name.
+func openingKey(fen string) (string, bool) {
+	fields := strings.Fields(fen)
+	if len(fields) < 3 {
+		return "", false
+	}
+	return strings.Join(fields[:3], " "), true
+}
+
+// LookupOpening returns the opening whose line reaches fen, if any
+func LookupOpening(fen string) (Opening, bool, error) {
+	g, err := NewGameFromFEN(fen)
+	if err != nil {
+		return Opening{}, false, err
+	}
+	key, _ := openingKey(g.FEN())
+	opening, ok := openingBook[key]
+	return opening, ok, nil
+}
+
+// ClassifyOpening names the opening of a game from its UCI moves: the last position
+// reached that is in the book, however the game got there. It stops at the first
+// illegal move and reports false when no position is in the book.
+func ClassifyOpening(moves []string) (Opening, bool) {
+	var (
+		found   Opening
+		matched bool
+	)
+	pos := chess.StartingPosition()
+	for i, uci := range moves {
+		if i >= openingMaxPlies {
+			break
+		}
+		var played *chess.Move
+		for _, m := range pos.ValidMoves() {
+			if m.String() == uci {
+				played = m
+				break
+			}
+		}
+		if played == nil {
+			break
+		}
+		pos = pos.Update(played)
+
+		key, _ := openingKey(pos.String())
+		if opening, ok := openingBook[key]; ok {
+			found, matched = opening, true
+		}
+	}
+	return found, matched
+}
@@ -0,0 +1,69 @@
+package chess
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOpeningBook(t *testing.T) {
+	codes := make(map[string]bool)
+	for _, line := range strings.Split(strings.TrimSpace(openingsTSV), "\n")[1:] {
+		fields := strings.Split(line, "\t")
+		codes[fields[0]] = true
+
+		// Every line must reach the position it is filed under
+		got, ok := ClassifyOpening(strings.Fields(fields[2]))
+		if !ok || got.Name != fields[1] {
+			t.Errorf("ClassifyOpening(%s) = %+v, %v; want %s", fields[2], got, ok, fields[1])
+		}
+	}
+	if !codes["A00"] || !codes["E99"] || len(codes) < 450 {
+		t.Errorf("expected the book to cover A00 to E99, got %d codes", len(codes))
+	}
+}
+
+func TestClassifyOpening(t *testing.T) {
+	tests := []struct {
+		name  string
+		moves string
+		want  Opening
+	}{
+		{"single move", "e2e4 c7c5", Opening{"B20", "Sicilian Defense"}},
+		{"transposition", "g1f3 d7d5 d2d4", Opening{"D02", "Queen's Pawn Game: Zukertort Variation"}},
+		{
+			"leaves the book",
+			"e2e4 c7c5 g1f3 d7d6 d2d4 c5d4 f3d4 g8f6 b1c3 a7a6 a2a3 h7h6 b2b3",
+			Opening{"B90", "Sicilian Defense: Najdorf Variation"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ClassifyOpening(strings.Fields(tt.moves))
+			if !ok || got != tt.want {
+				t.Errorf("ClassifyOpening() = %+v, %v; want %+v", got, ok, tt.want)
+			}
+		})
+	}
+
+	if _, ok := ClassifyOpening(nil); ok {
+		t.Error("expected a game without moves to be unclassified")
+	}
+	if got, ok := ClassifyOpening([]string{"e2e4", "c7c5", "e1e8"}); !ok || got.ECO != "B20" {
+		t.Errorf("expected classification to stop at an illegal move, got %+v, %v", got, ok)
+	}
+}
+
+func TestLookupOpening(t *testing.T) {
+	// Reached by 1.d4 d5 2.Nf3 and 1.Nf3 d5 2.d4; move counters do not matter
+	got, ok, err := LookupOpening("rnbqkbnr/ppp1pppp/8/3p4/3P4/5N2/PPP1PPPP/RNBQKB1R b KQkq - 3 7")
+	if err != nil || !ok || got.ECO != "D02" {
+		t.Errorf("LookupOpening() = %+v, %v, %v; want D02", got, ok, err)
+	}
+
+	if _, ok, err := LookupOpening("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"); err != nil || ok {
+		t.Errorf("expected the starting position to have no opening, got %v, %v", ok, err)
+	}
+	if _, _, err := LookupOpening("not a fen"); err == nil {
+		t.Error("expected an error for an invalid FEN")
+	}
+}
@@ -0,0 +1,101 @@
+package chess
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// PuzzleStatus describes where a submitted line stands against a puzzle solution
+type PuzzleStatus string
+
+const (
+	PuzzleSolved     PuzzleStatus = "solved"
+	PuzzleFailed     PuzzleStatus = "failed"
+	PuzzleIncomplete PuzzleStatus = "continue"
+)
+
+// ErrIllegalPuzzleMove is returned when a submitted move is not legal in the position
+var ErrIllegalPuzzleMove = errors.New("illegal move")
+
+// PuzzleCheck is the result of checking a played line against a puzzle solution
+type PuzzleCheck struct {
+	Status PuzzleStatus
+	// Reply is the opponent's answer to the last played move when Status is PuzzleIncomplete
+	Reply string
+	// FailedAt is the index (into the played moves) of the first wrong move when Status is PuzzleFailed
+	FailedAt int
+}
+
+// SplitMoves splits a space-separated UCI move list, ignoring extra whitespace
+func SplitMoves(moves string) []string {
+	return strings.Fields(moves)
+}
+
+// CheckPuzzleLine replays the solver's moves from the puzzle FEN and compares them
+// with the solution line. The solution alternates solver and opponent moves, starting
+// with the solver; played holds only the solver's moves. A move that differs from the
+// solution but delivers checkmate is accepted, since any mate solves the puzzle.
+func CheckPuzzleLine(fen string, solution []string, played []string) (PuzzleCheck, error) {
+	if len(solution) == 0 {
+		return PuzzleCheck{}, errors.New("puzzle has no solution")
+	}
+	solverMoves := (len(solution) + 1) / 2
+	if len(played) == 0 || len(played) > solverMoves {
+		return PuzzleCheck{}, fmt.Errorf("expected between 1 and %d moves, got %d", solverMoves, len(played))
+	}
+
+	g, err := NewGameFromFEN(fen)
+	if err != nil {
+		return PuzzleCheck{}, err
+	}
+
+	for i, uci := range played {
+		expected := strings.ToLower(solution[2*i])
+		result := g.TryUCIMove(uci)
+		if !result.Valid {
+			return PuzzleCheck{}, fmt.Errorf("%w: %s", ErrIllegalPuzzleMove, uci)
+		}
+
+		moves := g.Moves()
+		if moves[len(moves)-1] != expected {
+			if result.GameOver && result.Reason == ReasonCheckmate {
+				return PuzzleCheck{Status: PuzzleSolved}, nil
+			}
+			return PuzzleCheck{Status: PuzzleFailed, FailedAt: i}, nil
+		}
+
+		replyIdx := 2*i + 1
+		if replyIdx >= len(solution) {
+			return PuzzleCheck{Status: PuzzleSolved}, nil
+		}
+
+		reply := solution[replyIdx]
+		if i == len(played)-1 {
+			return PuzzleCheck{Status: PuzzleIncomplete, Reply: reply}, nil
+		}
+		if r := g.TryUCIMove(reply); !r.Valid {
+			return PuzzleCheck{}, fmt.Errorf("solution move %q is illegal", reply)
+		}
+	}
+
+	return PuzzleCheck{Status: PuzzleIncomplete}, nil
+}
+
+// ValidateLine replays a list of UCI moves from a FEN and returns the final position.
+// It is used to validate imported puzzle and training lines before storing them.
+func ValidateLine(fen string, moves []string) (string, error) {
+	g, err := NewGameFromFEN(fen)
+	if err != nil {
+		return "", err
+	}
+	for i, uci := range moves {
+		if g.IsGameOver() {
+			return "", fmt.Errorf("move %d (%s) played after the game ended", i+1, uci)
+		}
+		if result := g.TryUCIMove(uci); !result.Valid {
+			return "", fmt.Errorf("%w: move %d (%s)", ErrIllegalPuzzleMove, i+1, uci)
+		}
+	}
+	return g.FEN(), nil
+}
@@ -0,0 +1,116 @@
+package chess
+
+import (
+	"errors"
+	"testing"
+)
+
+// Ladder mate: 1. Ra7 Kf8 2. Rb8#
+const ladderFEN = "6k1/8/8/8/8/8/8/RR4K1 w - - 0 1"
+
+var ladderSolution = []string{"a1a7", "g8f8", "b1b8"}
+
+func TestCheckPuzzleLine_Solved(t *testing.T) {
+	check, err := CheckPuzzleLine(ladderFEN, ladderSolution, []string{"a1a7", "b1b8"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if check.Status != PuzzleSolved {
+		t.Errorf("Status = %q, want %q", check.Status, PuzzleSolved)
+	}
+}
+
+func TestCheckPuzzleLine_Incomplete(t *testing.T) {
+	check, err := CheckPuzzleLine(ladderFEN, ladderSolution, []string{"a1a7"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if check.Status != PuzzleIncomplete {
+		t.Fatalf("Status = %q, want %q", check.Status, PuzzleIncomplete)
+	}
+	if check.Reply != "g8f8" {
+		t.Errorf("Reply = %q, want %q", check.Reply, "g8f8")
+	}
+}
+
+func TestCheckPuzzleLine_WrongMove(t *testing.T) {
+	check, err := CheckPuzzleLine(ladderFEN, ladderSolution, []string{"b1b7"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if check.Status != PuzzleFailed {
+		t.Errorf("Status = %q, want %q", check.Status, PuzzleFailed)
+	}
+	if check.FailedAt != 0 {
+		t.Errorf("FailedAt = %d, want 0", check.FailedAt)
+	}
+}
+
+func TestCheckPuzzleLine_AlternativeMate(t *testing.T) {
+	// Both Ra8# and Rb8# mate; the solution only lists Ra8#
+	fen := "6k1/8/6K1/8/8/8/8/RR6 w - - 0 1"
+	check, err := CheckPuzzleLine(fen, []string{"a1a8"}, []string{"b1b8"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if check.Status != PuzzleSolved {
+		t.Errorf("Status = %q, want %q (alternative mate should be accepted)", check.Status, PuzzleSolved)
+	}
+}
+
+func TestCheckPuzzleLine_IllegalMove(t *testing.T) {
+	_, err := CheckPuzzleLine(ladderFEN, ladderSolution, []string{"a1h8"})
+	if !errors.Is(err, ErrIllegalPuzzleMove) {
+		t.Errorf("expected ErrIllegalPuzzleMove, got %v", err)
+	}
+}
+
+func TestCheckPuzzleLine_TooManyMoves(t *testing.T) {
+	_, err := CheckPuzzleLine(ladderFEN, ladderSolution, []string{"a1a7", "b1b8", "g1g2"})
+	if err == nil {
+		t.Error("expected error when more moves are submitted than the solution has")
+	}
+}
+
+func TestParseUCI(t *testing.T) {
+	tests := []struct {
+		uci       string
+		from, to  string
+		promotion string
+		wantErr   bool
+	}{
+		{"e2e4", "e2", "e4", "", false},
+		{"E7E8Q", "e7", "e8", "q", false},
+		{"a7a8n", "a7", "a8", "n", false},
+		{"e2e9", "", "", "", true},
+		{"e7e8k", "", "", "", true},
+		{"e2", "", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.uci, func(t *testing.T) {
+			from, to, promo, err := ParseUCI(tt.uci)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseUCI(%q) error = %v, wantErr %v", tt.uci, err, tt.wantErr)
+			}
+			if from != tt.from || to != tt.to || promo != tt.promotion {
+				t.Errorf("ParseUCI(%q) = (%q, %q, %q), want (%q, %q, %q)",
+					tt.uci, from, to, promo, tt.from, tt.to, tt.promotion)
+			}
+		})
+	}
+}
+
+func TestValidateLine(t *testing.T) {
+	fen, err := ValidateLine(ladderFEN, ladderSolution)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fen == ladderFEN {
+		t.Error("expected final FEN to differ from the starting FEN")
+	}
+
+	if _, err := ValidateLine(ladderFEN, []string{"a1a7", "g8g7", "b1b8", "g7g6"}); !errors.Is(err, ErrIllegalPuzzleMove) {
+		t.Errorf("expected ErrIllegalPuzzleMove for illegal line, got %v", err)
+	}
+}
@@ -0,0 +1,285 @@
+package chess
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/notnil/chess"
+)
+
+// TrainingGoal is what the side to move has to achieve in a training position
+type TrainingGoal string
+
+const (
+	// GoalMate requires delivering checkmate
+	GoalMate TrainingGoal = "mate"
+	// GoalWin requires checkmate or converting to a lone king against mating material
+	GoalWin TrainingGoal = "win"
+	// GoalDraw requires reaching a drawn result or surviving the move limit
+	GoalDraw TrainingGoal = "draw"
+)
+
+// TrainingStatus describes where a played training line stands
+type TrainingStatus string
+
+const (
+	TrainingSuccess    TrainingStatus = "success"
+	TrainingFailure    TrainingStatus = "failure"
+	TrainingIncomplete TrainingStatus = "continue"
+)
+
+// Training-specific reasons, alongside the GameEndReason values for finished games
+const (
+	ReasonConverted GameEndReason = "converted"
+	ReasonHeld      GameEndReason = "held"
+	ReasonMated     GameEndReason = "mated"
+	ReasonMoveLimit GameEndReason = "move_limit"
+	// ReasonBlunder is a trainee move that gave away the result the oracle expected
+	ReasonBlunder GameEndReason = "blunder"
+)
+
+// Oracle knows the exact result of some positions, such as endgame tablebases.
+// Value returns 1 (won), 0 (drawn) or -1 (lost) for the side to move in fen, and
+// false when the position is not covered. Distance returns the signed distance to
+// the next capture or pawn move with best play (tablebase DTZ): positive when the
+// side to move wins, negative when it loses.
+type Oracle interface {
+	Value(fen string) (int, bool)
+	Distance(fen string) (int, bool)
+}
+
+// TrainingOutcome is the judged result of a line played from a training position
+type TrainingOutcome struct {
+	Status TrainingStatus
+	Reason GameEndReason
+	// TraineeMoves is the number of moves the side to move played
+	TraineeMoves int
+	// FEN is the position after the last move
+	FEN string
+	// DefenceChecked is set when every opponent reply in the line was rated among the
+	// best available by the oracle or chosen by the server. Only such lines show what
+	// the trainee achieved against real resistance.
+	DefenceChecked bool
+	// Reply is a best opponent reply to the trainee's last move, when the line is
+	// still going and the oracle covers the position
+	Reply string
+}
+
+// JudgeTrainingLine replays moves (both sides, UCI, starting with the side to move)
+// from fen and decides whether the side to move achieved goal within moveLimit of its
+// own moves. A line that goes on after the outcome was decided is rejected.
+//
+//   - Checkmate by the trainee succeeds for every goal; being mated fails.
+//   - A draw by rule (stalemate, insufficient material, or a claimable threefold
+//     repetition or fifty-move draw) succeeds for GoalDraw and fails otherwise.
+//   - GoalWin also succeeds when, after the opponent's reply, the opponent has a lone
+//     king and the trainee keeps enough material to force mate.
+//   - GoalMate fails when the trainee's moveLimit-th move is not mate; GoalWin fails
+//     and GoalDraw succeeds when the opponent's reply to that move decides nothing.
+//   - With an oracle (may be nil), a trainee move fails as soon as it turns the
+//     position into a draw or loss for GoalMate and GoalWin, or a loss for GoalDraw.
+//     Opponent replies are checked against the oracle's best defence, or else with
+//     served (may be nil), which reports whether the last move of a line is a reply
+//     the server chose itself; see TrainingOutcome.DefenceChecked.
+func JudgeTrainingLine(fen string, moves []string, goal TrainingGoal, moveLimit int, oracle Oracle, served func(line []string) bool) (TrainingOutcome, error) {
+	if goal != GoalMate && goal != GoalWin && goal != GoalDraw {
+		return TrainingOutcome{}, fmt.Errorf("unknown training goal %q", goal)
+	}
+	if moveLimit < 1 {
+		return TrainingOutcome{}, errors.New("move limit must be positive")
+	}
+	if len(moves) > 2*moveLimit {
+		return TrainingOutcome{}, fmt.Errorf("expected at most %d moves, got %d", 2*moveLimit, len(moves))
+	}
+
+	g, err := NewGameFromFEN(fen)
+	if err != nil {
+		return TrainingOutcome{}, err
+	}
+	trainee := g.game.Position().Turn()
+
+	outcome := TrainingOutcome{Status: TrainingIncomplete, FEN: g.FEN(), DefenceChecked: true}
+	for i, uci := range moves {
+		if outcome.Status != TrainingIncomplete {
+			return TrainingOutcome{}, fmt.Errorf("line continues after the outcome was decided at move %d", i)
+		}
+		if i%2 == 1 && outcome.DefenceChecked {
+			best, ok := bestDefences(g, oracle)
+			outcome.DefenceChecked = ok && slices.Contains(best, strings.ToLower(strings.TrimSpace(uci))) ||
+				served != nil && served(moves[:i+1])
+		}
+
+		result := g.TryUCIMove(uci)
+		if !result.Valid {
+			return TrainingOutcome{}, fmt.Errorf("%w: %s", ErrIllegalPuzzleMove, uci)
+		}
+		traineeMoved := i%2 == 0
+		if traineeMoved {
+			outcome.TraineeMoves++
+		}
+		outcome.FEN = result.NewFEN
+		outcome.Status, outcome.Reason = judgeTrainingPly(g, result, goal, trainee, traineeMoved)
+		if outcome.Status == TrainingIncomplete && traineeMoved && oracle != nil && gaveAwayResult(oracle, result.NewFEN, goal) {
+			outcome.Status, outcome.Reason = TrainingFailure, ReasonBlunder
+		}
+
+		if outcome.Status == TrainingIncomplete && outcome.TraineeMoves == moveLimit {
+			switch {
+			case goal == GoalMate && traineeMoved, goal == GoalWin && !traineeMoved:
+				outcome.Status, outcome.Reason = TrainingFailure, ReasonMoveLimit
+			case goal == GoalDraw && !traineeMoved:
+				outcome.Status, outcome.Reason = TrainingSuccess, ReasonHeld
+			}
+		}
+	}
+
+	if outcome.Status == TrainingIncomplete && len(moves)%2 == 1 {
+		if best, ok := bestDefences(g, oracle); ok {
+			outcome.Reply = best[0]
+		}
+	}
+	return outcome, nil
+}
+
+// judgeTrainingPly decides the outcome after one move of a training line, if any
+func judgeTrainingPly(g *Game, result MoveResult, goal TrainingGoal, trainee chess.Color, traineeMoved bool) (TrainingStatus, GameEndReason) {
+	if result.GameOver {
+		if result.Reason == ReasonCheckmate {
+			if traineeMoved {
+				return TrainingSuccess, ReasonCheckmate
+			}
+			return TrainingFailure, ReasonMated
+		}
+		return drawFor(goal), result.Reason
+	}
+
+	for _, method := range g.game.EligibleDraws() {
+		switch method {
+		case chess.ThreefoldRepetition:
+			return drawFor(goal), ReasonThreefoldRepetition
+		case chess.FiftyMoveRule:
+			return drawFor(goal), ReasonFiftyMoveRule
+		}
+	}
+
+	// Judged after the opponent's reply so a piece left en prise does not count
+	if goal == GoalWin && !traineeMoved && hasForcedMateMaterial(g.game.Position().Board(), trainee) {
+		return TrainingSuccess, ReasonConverted
+	}
+	return TrainingIncomplete, ReasonNone
+}
+
+// gaveAwayResult reports whether the oracle judges fen, with the opponent to move,
+// short of goal for the trainee
+func gaveAwayResult(oracle Oracle, fen string, goal TrainingGoal) bool {
+	value, ok := oracle.Value(fen)
+	if !ok {
+		return false
+	}
+	if goal == GoalDraw {
+		return -value < 0
+	}
+	return -value < 1
+}
+
+// defenceScore rates an opponent reply by the position it leaves the trainee in:
+// the trainee's outcome, then the distance for decided positions
+type defenceScore struct {
+	value    int
+	distance int
+}
+
+// better reports whether s is a better defence than o: a worse outcome for the
+// trainee, or the same decided outcome with a larger distance, which delays a
+// trainee's win or hastens its loss
+func (s defenceScore) better(o defenceScore) bool {
+	if s.value != o.value {
+		return s.value < o.value
+	}
+	return s.value != 0 && s.distance > o.distance
+}
+
+// bestDefences returns the opponent's best replies (UCI) in g's position, and false
+// when the oracle cannot rate every reply
+func bestDefences(g *Game, oracle Oracle) ([]string, bool) {
+	if oracle == nil {
+		return nil, false
+	}
+	pos := g.game.Position()
+	var (
+		best      []string
+		bestScore defenceScore
+	)
+	for _, m := range pos.ValidMoves() {
+		score, ok := replyScore(pos.Update(m), oracle)
+		if !ok {
+			return nil, false
+		}
+		switch {
+		case best == nil || score.better(bestScore):
+			best, bestScore = []string{m.String()}, score
+		case !bestScore.better(score):
+			best = append(best, m.String())
+		}
+	}
+	return best, len(best) > 0
+}
+
+// replyScore rates the position after an opponent reply, with the trainee to move
+func replyScore(next *chess.Position, oracle Oracle) (defenceScore, bool) {
+	switch next.Status() {
+	case chess.Checkmate:
+		return defenceScore{value: -1}, true
+	case chess.Stalemate:
+		return defenceScore{}, true
+	}
+	fen := next.String()
+	value, ok := oracle.Value(fen)
+	if !ok {
+		return defenceScore{}, false
+	}
+	if value == 0 {
+		return defenceScore{}, true
+	}
+	distance, ok := oracle.Distance(fen)
+	return defenceScore{value: value, distance: distance}, ok
+}
+
+func drawFor(goal TrainingGoal) TrainingStatus {
+	if goal == GoalDraw {
+		return TrainingSuccess
+	}
+	return TrainingFailure
+}
+
+// hasForcedMateMaterial reports whether side can force mate against a lone king:
+// the opponent has nothing but its king, and side has a queen, a rook, bishops on
+// both square colours, or a bishop and a knight
+func hasForcedMateMaterial(board *chess.Board, side chess.Color) bool {
+	var heavy, knights int
+	var lightBishop, darkBishop bool
+	for sq, p := range board.SquareMap() {
+		if p.Color() != side {
+			if p.Type() != chess.King {
+				return false
+			}
+			continue
+		}
+		switch p.Type() {
+		case chess.Queen, chess.Rook:
+			heavy++
+		case chess.Knight:
+			knights++
+		case chess.Bishop:
+			if (int(sq.File())+int(sq.Rank()))%2 == 0 {
+				darkBishop = true
+			} else {
+				lightBishop = true
+			}
+		}
+	}
+	bishops := lightBishop || darkBishop
+	return heavy > 0 || (lightBishop && darkBishop) || (bishops && knights > 0)
+}
@@ -0,0 +1,224 @@
+package chess
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestJudgeTrainingLine(t *testing.T) {
+	tests := []struct {
+		name       string
+		fen        string
+		moves      []string
+		goal       TrainingGoal
+		limit      int
+		wantStatus TrainingStatus
+		wantReason GameEndReason
+	}{
+		{"mate delivered", ladderFEN, []string{"a1a7", "g8f8", "b1b8"}, GoalMate, 10, TrainingSuccess, ReasonCheckmate},
+		{"mate counts as win", "6k1/7p/8/8/8/8/8/RR4K1 w - - 0 1", []string{"a1a7", "g8f8", "b1b8"}, GoalWin, 10, TrainingSuccess, ReasonCheckmate},
+		{"in progress", ladderFEN, []string{"a1a7"}, GoalMate, 10, TrainingIncomplete, ReasonNone},
+		{"mate not found within limit", ladderFEN, []string{"a1a2"}, GoalMate, 1, TrainingFailure, ReasonMoveLimit},
+		// Qxe2+ leaves a lone king against king and queen
+		{"win converted", "4k3/8/8/8/8/8/4r3/4QK2 w - - 0 1", []string{"e1e2", "e8d7"}, GoalWin, 10, TrainingSuccess, ReasonConverted},
+		{"conversion waits for the reply", "4k3/8/8/8/8/8/4r3/4QK2 w - - 0 1", []string{"e1e2"}, GoalWin, 10, TrainingIncomplete, ReasonNone},
+		{"win not converted within limit", "4k3/8/8/8/8/8/4r3/4QK2 w - - 0 1", []string{"e1d1", "e2a2"}, GoalWin, 1, TrainingFailure, ReasonMoveLimit},
+		{"conversion is not mate", "4k3/8/8/8/8/8/4r3/4QK2 w - - 0 1", []string{"e1e2"}, GoalMate, 10, TrainingIncomplete, ReasonNone},
+		// Rxc2+ Kxc2 leaves bare kings
+		{"win thrown away", "8/8/8/8/8/2k5/2r5/K1R5 w - - 0 1", []string{"c1c2", "c3c2"}, GoalWin, 10, TrainingFailure, ReasonInsufficientMaterial},
+		{"draw by rule holds", "8/8/8/8/8/2k5/2r5/K1R5 w - - 0 1", []string{"c1c2", "c3c2"}, GoalDraw, 10, TrainingSuccess, ReasonInsufficientMaterial},
+		{"draw held to the limit", "8/8/8/4k3/8/8/4P3/4K3 b - - 0 1", []string{"e5e4", "e1f2"}, GoalDraw, 1, TrainingSuccess, ReasonHeld},
+		{"draw not yet held", "8/8/8/4k3/8/8/4P3/4K3 b - - 0 1", []string{"e5e4"}, GoalDraw, 1, TrainingIncomplete, ReasonNone},
+		// Black defends but walks into Rb8#
+		{"defender mated", "6k1/R7/8/8/8/8/8/1R4K1 b - - 0 1", []string{"g8h8", "b1b8"}, GoalDraw, 10, TrainingFailure, ReasonMated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outcome, err := JudgeTrainingLine(tt.fen, tt.moves, tt.goal, tt.limit, nil, nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if outcome.Status != tt.wantStatus || outcome.Reason != tt.wantReason {
+				t.Errorf("outcome = (%q, %q), want (%q, %q)", outcome.Status, outcome.Reason, tt.wantStatus, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestJudgeTrainingLine_Errors(t *testing.T) {
+	if _, err := JudgeTrainingLine(ladderFEN, []string{"a1h8"}, GoalMate, 10, nil, nil); !errors.Is(err, ErrIllegalPuzzleMove) {
+		t.Errorf("expected ErrIllegalPuzzleMove, got %v", err)
+	}
+	if _, err := JudgeTrainingLine("4k3/8/8/8/8/8/4r3/4QK2 w - - 0 1", []string{"e1e2", "e8d7", "e2d2", "d7c7"}, GoalWin, 10, nil, nil); err == nil {
+		t.Error("expected error for moves after the win was converted")
+	}
+	if _, err := JudgeTrainingLine(ladderFEN, []string{"a1a7"}, TrainingGoal("lose"), 10, nil, nil); err == nil {
+		t.Error("expected error for unknown goal")
+	}
+	if _, err := JudgeTrainingLine(ladderFEN, []string{"a1a7", "g8f8", "a7a6"}, GoalMate, 1, nil, nil); err == nil {
+		t.Error("expected error for more moves than the limit allows")
+	}
+}
+
+func TestJudgeTrainingLine_CountsTraineeMoves(t *testing.T) {
+	outcome, err := JudgeTrainingLine(ladderFEN, []string{"a1a7", "g8f8", "b1b8"}, GoalMate, 10, nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if outcome.TraineeMoves != 2 {
+		t.Errorf("TraineeMoves = %d, want 2", outcome.TraineeMoves)
+	}
+}
+
+// mapOracle knows the values listed for positions, keyed by FEN
+type mapOracle map[string]int
+
+func (o mapOracle) Value(fen string) (int, bool) {
+	v, ok := o[fen]
+	return v, ok
+}
+
+func (o mapOracle) Distance(string) (int, bool) {
+	return 0, false
+}
+
+// dtzOracle knows the value and distance listed for positions, keyed by FEN
+type dtzOracle map[string][2]int
+
+func (o dtzOracle) Value(fen string) (int, bool) {
+	v, ok := o[fen]
+	return v[0], ok
+}
+
+func (o dtzOracle) Distance(fen string) (int, bool) {
+	v, ok := o[fen]
+	return v[1], ok
+}
+
+// after plays moves from fen and returns the position reached
+func after(t *testing.T, fen string, moves ...string) string {
+	t.Helper()
+	g, err := NewGameFromFEN(fen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, uci := range moves {
+		if result := g.TryUCIMove(uci); !result.Valid {
+			t.Fatalf("illegal move %s", uci)
+		}
+	}
+	return g.FEN()
+}
+
+func TestJudgeTrainingLine_Oracle(t *testing.T) {
+	const fen = "8/8/8/4k3/8/8/4P3/4K3 w - - 0 1"
+	oracle := mapOracle{
+		// After Kf2 black is lost; after Kd1 black holds
+		"8/8/8/4k3/8/8/4PK2/8 b - - 1 1":  -1,
+		"8/8/8/4k3/8/8/4P3/3K4 b - - 1 1": 0,
+	}
+
+	tests := []struct {
+		name       string
+		fen        string
+		moves      []string
+		goal       TrainingGoal
+		wantStatus TrainingStatus
+		wantReason GameEndReason
+	}{
+		{"winning move", fen, []string{"e1f2"}, GoalWin, TrainingIncomplete, ReasonNone},
+		{"win thrown away", fen, []string{"e1d1"}, GoalWin, TrainingFailure, ReasonBlunder},
+		{"unknown position", fen, []string{"e2e3"}, GoalWin, TrainingIncomplete, ReasonNone},
+		{"drawing move keeps the draw", fen, []string{"e1d1"}, GoalDraw, TrainingIncomplete, ReasonNone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outcome, err := JudgeTrainingLine(tt.fen, tt.moves, tt.goal, 10, oracle, nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if outcome.Status != tt.wantStatus || outcome.Reason != tt.wantReason {
+				t.Errorf("outcome = (%q, %q), want (%q, %q)", outcome.Status, outcome.Reason, tt.wantStatus, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestJudgeTrainingLine_Defence(t *testing.T) {
+	// After Qxe2+ the black king has four replies, all lost; Kd8 holds out longest
+	const fen = "4k3/8/8/8/8/8/4r3/4QK2 w - - 0 1"
+	oracle := dtzOracle{
+		after(t, fen, "e1e2", "e8d8"): {1, 20},
+		after(t, fen, "e1e2", "e8d7"): {1, 12},
+		after(t, fen, "e1e2", "e8f8"): {1, 12},
+		after(t, fen, "e1e2", "e8f7"): {1, 10},
+	}
+
+	outcome, err := JudgeTrainingLine(fen, []string{"e1e2"}, GoalWin, 10, oracle, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if outcome.Reply != "e8d8" {
+		t.Errorf("Reply = %q, want the longest defence e8d8", outcome.Reply)
+	}
+
+	// servedAfter stands for a server that handed out one reply, after e1e2
+	servedAfter := func(reply string) func([]string) bool {
+		return func(line []string) bool {
+			return slices.Equal(line, []string{"e1e2", reply})
+		}
+	}
+
+	tests := []struct {
+		name        string
+		oracle      Oracle
+		served      func([]string) bool
+		reply       string
+		wantChecked bool
+	}{
+		{"best defence", oracle, nil, "e8d8", true},
+		{"weaker defence", oracle, nil, "e8f7", false},
+		{"no oracle", nil, nil, "e8d8", false},
+		{"reply not covered", dtzOracle{after(t, fen, "e1e2", "e8d8"): {1, 20}}, nil, "e8d8", false},
+		{"served reply", nil, servedAfter("e8f7"), "e8f7", true},
+		{"reply the server did not choose", nil, servedAfter("e8d8"), "e8f7", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outcome, err := JudgeTrainingLine(fen, []string{"e1e2", tt.reply}, GoalWin, 10, tt.oracle, tt.served)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if outcome.Status != TrainingSuccess || outcome.Reason != ReasonConverted {
+				t.Errorf("outcome = (%q, %q), want a conversion", outcome.Status, outcome.Reason)
+			}
+			if outcome.DefenceChecked != tt.wantChecked {
+				t.Errorf("DefenceChecked = %v, want %v", outcome.DefenceChecked, tt.wantChecked)
+			}
+		})
+	}
+}
+
+func TestDefenceScore(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b defenceScore
+		want bool
+	}{
+		{"draw beats a trainee win", defenceScore{0, 0}, defenceScore{1, 30}, true},
+		{"trainee loss beats a draw", defenceScore{-1, -5}, defenceScore{0, 0}, true},
+		{"longer trainee win is better", defenceScore{1, 30}, defenceScore{1, 10}, true},
+		{"faster trainee loss is better", defenceScore{-1, -3}, defenceScore{-1, -9}, true},
+		{"mate is the fastest loss", defenceScore{-1, 0}, defenceScore{-1, -1}, true},
+		{"draws are equal", defenceScore{0, 0}, defenceScore{0, 0}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.a.better(tt.b); got != tt.want {
+				t.Errorf("better() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
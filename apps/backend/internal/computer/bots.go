@@ -0,0 +1,110 @@
+package computer
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand/v2"
+
+	"github.com/notnil/chess"
+)
+
+// materialValues are the piece values the material bot counts
+var materialValues = map[chess.PieceType]int{
+	chess.Queen:  9,
+	chess.Rook:   5,
+	chess.Bishop: 3,
+	chess.Knight: 3,
+	chess.Pawn:   1,
+}
+
+// RandomOpponent plays a random legal move at every level
+type RandomOpponent struct{}
+
+// Move picks one of the legal moves
+func (RandomOpponent) Move(_ context.Context, moves []string, _ int) (string, error) {
+	pos, err := replay(moves)
+	if err != nil {
+		return "", err
+	}
+	valid := pos.ValidMoves()
+	if len(valid) == 0 {
+		return "", ErrNoMove
+	}
+	return valid[rand.IntN(len(valid))].String(), nil
+}
+
+// MaterialOpponent plays at every level the move that keeps the most material after
+// the opponent's best reply, mating when it can. Equally good moves are chosen at random.
+type MaterialOpponent struct{}
+
+// Move searches two plies deep, counting material only
+func (MaterialOpponent) Move(_ context.Context, moves []string, _ int) (string, error) {
+	pos, err := replay(moves)
+	if err != nil {
+		return "", err
+	}
+	side := pos.Turn()
+
+	var best []*chess.Move
+	bestScore := math.MinInt
+	for _, m := range pos.ValidMoves() {
+		next := pos.Update(m)
+		replies := next.ValidMoves()
+		if len(replies) == 0 && next.Status() == chess.Checkmate {
+			return m.String(), nil
+		}
+
+		// Stalemate counts as even material
+		score := 0
+		if len(replies) > 0 {
+			score = math.MaxInt
+			for _, r := range replies {
+				score = min(score, material(next.Update(r).Board(), side))
+			}
+		}
+
+		switch {
+		case score > bestScore:
+			best, bestScore = []*chess.Move{m}, score
+		case score == bestScore:
+			best = append(best, m)
+		}
+	}
+	if len(best) == 0 {
+		return "", ErrNoMove
+	}
+	return best[rand.IntN(len(best))].String(), nil
+}
+
+// replay plays moves (UCI) from the starting position, rejecting illegal moves
+func replay(moves []string) (*chess.Position, error) {
+	pos := chess.StartingPosition()
+	for i, s := range moves {
+		var played *chess.Move
+		for _, m := range pos.ValidMoves() {
+			if m.String() == s {
+				played = m
+				break
+			}
+		}
+		if played == nil {
+			return nil, fmt.Errorf("computer: move %d (%s) is illegal", i+1, s)
+		}
+		pos = pos.Update(played)
+	}
+	return pos, nil
+}
+
+// material is side's material minus the opponent's
+func material(board *chess.Board, side chess.Color) int {
+	balance := 0
+	for _, p := range board.SquareMap() {
+		if p.Color() == side {
+			balance += materialValues[p.Type()]
+		} else {
+			balance -= materialValues[p.Type()]
+		}
+	}
+	return balance
+}
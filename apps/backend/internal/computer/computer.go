@@ -0,0 +1,103 @@
+// Package computer chooses the computer's moves in server-run games against it. An
+// Opponent plays at one of the difficulty levels the client offers: the engine
+// opponent searches with the UCI engine pool at the level's strength, and the
+// built-in random and material bots need no engine.
+package computer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/engine"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/logger"
+)
+
+// Difficulty levels
+const (
+	MinLevel = 1
+	MaxLevel = 10
+)
+
+// ErrNoMove is returned when the side to move has no legal move
+var ErrNoMove = errors.New("computer: no legal move")
+
+// Level is how the computer plays at one difficulty level
+type Level struct {
+	Elo      int           // playing strength, also the computer's rating in rated games
+	Depth    int           // search depth limit in plies; 0 for none
+	MoveTime time.Duration // time to think per move
+}
+
+// levels match the client's difficulty levels: deeper searches at the top levels
+// keep the strength limit meaningful. Stockfish's UCI_Elo starts at 1320; below it
+// the engine plays at its lowest Skill Level, so the weaker levels are told apart
+// by how deep they search.
+var levels = [MaxLevel]Level{
+	{Elo: 300, Depth: 1, MoveTime: 500 * time.Millisecond},
+	{Elo: 400, Depth: 2, MoveTime: 500 * time.Millisecond},
+	{Elo: 500, Depth: 3, MoveTime: 500 * time.Millisecond},
+	{Elo: 700, Depth: 4, MoveTime: 750 * time.Millisecond},
+	{Elo: 900, Depth: 6, MoveTime: 750 * time.Millisecond},
+	{Elo: 1100, Depth: 8, MoveTime: 1000 * time.Millisecond},
+	{Elo: 1400, MoveTime: 1250 * time.Millisecond},
+	{Elo: 1700, MoveTime: 1500 * time.Millisecond},
+	{Elo: 2000, MoveTime: 2000 * time.Millisecond},
+	{Elo: 2400, MoveTime: 2250 * time.Millisecond},
+}
+
+// GetLevel returns the settings of a difficulty level, or false if there is no such level
+func GetLevel(level int) (Level, bool) {
+	if level < MinLevel || level > MaxLevel {
+		return Level{}, false
+	}
+	return levels[level-1], true
+}
+
+// Opponent chooses the computer's moves
+type Opponent interface {
+	// Move returns the computer's move (UCI) in the position reached by playing moves
+	// from the starting position, at a valid difficulty level
+	Move(ctx context.Context, moves []string, level int) (string, error)
+}
+
+// Default is the process-wide opponent set by Init; nil when games against the
+// computer are disabled
+var Default Opponent
+
+// Init makes the engine pool the computer's opponent. A nil pool disables games
+// against the computer.
+func Init(pool *engine.Pool) {
+	if pool == nil {
+		logger.Info("Games against the computer disabled (no engine configured)")
+		return
+	}
+	Default = NewEngineOpponent(pool)
+}
+
+// EngineOpponent plays with a UCI engine limited to each level's strength
+type EngineOpponent struct {
+	pool *engine.Pool
+}
+
+// NewEngineOpponent creates an opponent searching with pool
+func NewEngineOpponent(pool *engine.Pool) *EngineOpponent {
+	return &EngineOpponent{pool: pool}
+}
+
+// Move searches the position for the level's think time, or until its depth limit
+func (o *EngineOpponent) Move(ctx context.Context, moves []string, level int) (string, error) {
+	l, ok := GetLevel(level)
+	if !ok {
+		return "", fmt.Errorf("computer: unknown level %d", level)
+	}
+	a, err := o.pool.Analyze(ctx, "", moves, engine.Limits{Depth: l.Depth, MoveTime: l.MoveTime, Elo: l.Elo})
+	if err != nil {
+		return "", err
+	}
+	if a.BestMove == "" {
+		return "", ErrNoMove
+	}
+	return a.BestMove, nil
+}
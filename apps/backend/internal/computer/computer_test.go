@@ -0,0 +1,82 @@
+package computer
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestGetLevel(t *testing.T) {
+	if _, ok := GetLevel(MinLevel - 1); ok {
+		t.Error("expected level below the minimum to be rejected")
+	}
+	if _, ok := GetLevel(MaxLevel + 1); ok {
+		t.Error("expected level above the maximum to be rejected")
+	}
+	weakest, _ := GetLevel(MinLevel)
+	strongest, _ := GetLevel(MaxLevel)
+	if weakest.Elo >= strongest.Elo || weakest.MoveTime > strongest.MoveTime {
+		t.Errorf("expected higher levels to be stronger, got %+v and %+v", weakest, strongest)
+	}
+
+	// Levels below Stockfish's UCI_Elo minimum differ only in depth, which must grow
+	for level := MinLevel; level < MaxLevel; level++ {
+		l, _ := GetLevel(level)
+		next, _ := GetLevel(level + 1)
+		if l.Elo < 1320 && (l.Depth <= 0 || next.Depth != 0 && next.Depth <= l.Depth) {
+			t.Errorf("level %d (%+v) needs a depth limit below level %d's (%+v)", level, l, level+1, next)
+		}
+	}
+}
+
+func TestEngineOpponent_UnknownLevel(t *testing.T) {
+	if _, err := NewEngineOpponent(nil).Move(context.Background(), nil, 0); err == nil {
+		t.Error("expected an unknown level to be rejected")
+	}
+}
+
+func TestRandomOpponent(t *testing.T) {
+	moves := strings.Fields("e2e4 e7e5 g1f3")
+	move, err := RandomOpponent{}.Move(context.Background(), moves, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := replay(append(moves, move)); err != nil {
+		t.Errorf("expected a legal move, got %s: %v", move, err)
+	}
+
+	// Fool's mate: white has no moves
+	_, err = RandomOpponent{}.Move(context.Background(), strings.Fields("f2f3 e7e5 g2g4 d8h4"), 1)
+	if !errors.Is(err, ErrNoMove) {
+		t.Errorf("expected ErrNoMove, got %v", err)
+	}
+}
+
+func TestMaterialOpponent(t *testing.T) {
+	tests := []struct {
+		name  string
+		moves string
+		want  string
+	}{
+		{"mates in one", "e2e4 e7e5 f1c4 b8c6 d1h5 g8f6", "h5f7"},
+		{"takes a hanging queen", "e2e4 d7d5 d1g4", "c8g4"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MaterialOpponent{}.Move(context.Background(), strings.Fields(tt.moves), 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReplay_RejectsIllegalMove(t *testing.T) {
+	if _, err := replay(strings.Fields("e2e4 e2e4")); err == nil {
+		t.Error("expected an illegal move to be rejected")
+	}
+}
@@ -3,14 +3,16 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 )
 
 type Config struct {
 	Port                string
-	Environment         string   // "development" or "production"
-	LogLevel            string   // "DEBUG", "INFO", "WARN", "ERROR"
-	LogJSON             bool     // true for JSON output (production)
+	Environment         string // "development" or "production"
+	LogLevel            string // "DEBUG", "INFO", "WARN", "ERROR"
+	LogJSON             bool   // true for JSON output (production)
 	GoogleClientID      string
 	GoogleClientSecret  string
 	GitHubClientID      string
@@ -18,7 +20,15 @@ type Config struct {
 	DiscordClientID     string
 	DiscordClientSecret string
 	FrontendURL         string
-	TrustedProxies      []string // CIDRs or IPs of trusted reverse proxies
+	TrustedProxies      []string      // CIDRs or IPs of trusted reverse proxies
+	SyzygyPath          string        // Directory of Syzygy tablebase files; empty disables probing
+	AchievementsFile    string        // JSON achievement definitions replacing the built-in set; empty keeps them
+	EnginePath          string        // UCI engine binary (e.g. Stockfish); empty disables server-side analysis
+	EnginePoolSize      int           // Maximum engine processes searching at once
+	EngineMaxDepth      int           // Deepest search a request may ask for
+	EngineMaxMoveTime   time.Duration // Longest search a request may ask for
+	AnalysisWorkers     int           // Games analyzed at once after they finish
+	AnalysisDepth       int           // Search depth per position in post-game analysis
 }
 
 // IsProd returns true if running in production environment
@@ -73,6 +83,14 @@ func Load() (*Config, []string) {
 		DiscordClientSecret: os.Getenv("DISCORD_CLIENT_SECRET"),
 		FrontendURL:         os.Getenv("FRONTEND_URL"),
 		TrustedProxies:      trustedProxies,
+		SyzygyPath:          os.Getenv("SYZYGY_PATH"),
+		AchievementsFile:    os.Getenv("ACHIEVEMENTS_FILE"),
+		EnginePath:          os.Getenv("ENGINE_PATH"),
+		EnginePoolSize:      envInt("ENGINE_POOL_SIZE", 2),
+		EngineMaxDepth:      envInt("ENGINE_MAX_DEPTH", 22),
+		EngineMaxMoveTime:   time.Duration(envInt("ENGINE_MAX_MOVETIME_MS", 5000)) * time.Millisecond,
+		AnalysisWorkers:     envInt("ANALYSIS_WORKERS", 1),
+		AnalysisDepth:       envInt("ANALYSIS_DEPTH", 14),
 	}
 
 	if cfg.GoogleClientID == "" || cfg.GoogleClientSecret == "" {
@@ -94,3 +112,13 @@ func Load() (*Config, []string) {
 
 	return cfg, warnings
 }
+
+// envInt reads a positive integer from the environment, falling back to defaultVal
+func envInt(key string, defaultVal int) int {
+	if val := os.Getenv(key); val != "" {
+		if i, err := strconv.Atoi(val); err == nil && i > 0 {
+			return i
+		}
+	}
+	return defaultVal
+}
@@ -0,0 +1,138 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/database"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/engine"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/httpx"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/logger"
+)
+
+const (
+	// Time a request may wait for a free engine and search, within the server's
+	// write timeout
+	analysisTimeout  = 12 * time.Second
+	maxAnalysisMoves = 600
+)
+
+// Stored games are identified by UUIDs
+var gameIDRegex = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+type analysisRequest struct {
+	FEN        string   `json:"fen"`   // start position; empty for the standard starting position
+	Moves      []string `json:"moves"` // UCI moves played from the start position
+	Depth      int      `json:"depth"`
+	MoveTimeMs int      `json:"movetime_ms"`
+	MultiPV    int      `json:"multipv"`
+}
+
+// AnalysisHandler evaluates a position with the server's UCI engine
+// POST /api/analysis
+// Depth and time are capped by the server's engine limits. Each line's score is
+// from White's point of view, in centipawns (cp) or moves to mate (mate).
+func AnalysisHandler(w http.ResponseWriter, r *http.Request) {
+	if engine.Default == nil {
+		httpx.WriteJSONError(w, http.StatusServiceUnavailable, "Engine analysis is not available")
+		return
+	}
+
+	var req analysisRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpx.WriteJSONError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if len(req.Moves) > maxAnalysisMoves {
+		httpx.WriteJSONError(w, http.StatusBadRequest, "Too many moves")
+		return
+	}
+	if req.Depth < 0 || req.MoveTimeMs < 0 || req.MultiPV < 0 || req.MultiPV > engine.MaxMultiPV {
+		httpx.WriteJSONError(w, http.StatusBadRequest, "Invalid search limits")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), analysisTimeout)
+	defer cancel()
+
+	analysis, err := engine.Default.Analyze(ctx, req.FEN, req.Moves, engine.Limits{
+		Depth:    req.Depth,
+		MoveTime: time.Duration(req.MoveTimeMs) * time.Millisecond,
+		MultiPV:  req.MultiPV,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, engine.ErrInvalidPosition):
+			httpx.WriteJSONError(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
+			httpx.WriteJSONError(w, http.StatusServiceUnavailable, "Engine is busy, please try again later")
+		default:
+			logger.Error("Engine analysis failed", logger.F("fen", req.FEN, "error", err.Error()))
+			httpx.WriteJSONError(w, http.StatusInternalServerError, "Engine analysis failed")
+		}
+		return
+	}
+
+	httpx.WriteJSON(w, http.StatusOK, analysis)
+}
+
+// GameHandler returns a finished game with its players, result, moves and opening
+// GET /api/games/{id}
+func GameHandler(w http.ResponseWriter, r *http.Request) {
+	gameID := chi.URLParam(r, "id")
+	if !gameIDRegex.MatchString(gameID) {
+		httpx.WriteJSONError(w, http.StatusNotFound, "Game not found")
+		return
+	}
+
+	game, err := database.GetGameDetail(gameID)
+	if err != nil {
+		httpx.WriteJSONError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	if game == nil {
+		httpx.WriteJSONError(w, http.StatusNotFound, "Game not found")
+		return
+	}
+
+	httpx.WriteJSON(w, http.StatusOK, game)
+}
+
+// GameAnalysisHandler returns the computer analysis of a stored game
+// GET /api/games/{id}/analysis
+// status is "pending" or "running" while the game waits for the analysis queue, then
+// "done" with per-player accuracy, ACPL and error counts plus per-move results, or
+// "failed" with an error.
+func GameAnalysisHandler(w http.ResponseWriter, r *http.Request) {
+	gameID := chi.URLParam(r, "id")
+	if !gameIDRegex.MatchString(gameID) {
+		httpx.WriteJSONError(w, http.StatusNotFound, "Game not found")
+		return
+	}
+
+	result, err := database.GetGameAnalysis(gameID)
+	if err != nil {
+		httpx.WriteJSONError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	if result == nil {
+		game, err := database.GetGameByID(gameID)
+		if err != nil {
+			httpx.WriteJSONError(w, http.StatusInternalServerError, "Internal server error")
+			return
+		}
+		if game == nil {
+			httpx.WriteJSONError(w, http.StatusNotFound, "Game not found")
+			return
+		}
+		httpx.WriteJSONError(w, http.StatusNotFound, "This game has not been analyzed")
+		return
+	}
+
+	httpx.WriteJSON(w, http.StatusOK, result)
+}
@@ -0,0 +1,204 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/apitokens"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/database"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/httpx"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/logger"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/middleware"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/models"
+)
+
+const (
+	maxAPITokens          = 20
+	maxAPITokenNameLength = 64
+	maxAPITokenDays       = 365
+	defaultGamesPageSize  = 50
+	maxGamesPageSize      = 100
+)
+
+// MyAPITokensHandler lists the signed-in user's personal API tokens
+// GET /api/me/tokens
+func MyAPITokensHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok || userID == "" {
+		httpx.WriteJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	tokens, err := database.GetAPITokens(userID)
+	if err != nil {
+		httpx.WriteJSONError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	httpx.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"tokens":           tokens,
+		"available_scopes": models.APITokenScopes,
+	})
+}
+
+// CreateAPITokenHandler creates a personal API token for the signed-in user. The
+// token is only ever returned in this response.
+// POST /api/me/tokens
+// Body: {"name": "...", "scopes": ["games:read"], "expires_in_days": 90}; tokens
+// without expires_in_days do not expire.
+func CreateAPITokenHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok || userID == "" {
+		httpx.WriteJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req struct {
+		Name          string   `json:"name"`
+		Scopes        []string `json:"scopes"`
+		ExpiresInDays *int     `json:"expires_in_days"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpx.WriteJSONError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" || utf8.RuneCountInString(name) > maxAPITokenNameLength {
+		httpx.WriteJSONError(w, http.StatusBadRequest, "name must be between 1 and 64 characters")
+		return
+	}
+	scopes, ok := normalizeScopes(req.Scopes)
+	if !ok {
+		httpx.WriteJSONError(w, http.StatusBadRequest, "scopes must be one or more of "+strings.Join(models.APITokenScopes, ", "))
+		return
+	}
+	var expiresAt *time.Time
+	if req.ExpiresInDays != nil {
+		if *req.ExpiresInDays < 1 || *req.ExpiresInDays > maxAPITokenDays {
+			httpx.WriteJSONError(w, http.StatusBadRequest, "expires_in_days must be between 1 and 365")
+			return
+		}
+		t := time.Now().AddDate(0, 0, *req.ExpiresInDays)
+		expiresAt = &t
+	}
+
+	existing, err := database.GetAPITokens(userID)
+	if err != nil {
+		httpx.WriteJSONError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	if len(existing) >= maxAPITokens {
+		httpx.WriteJSONError(w, http.StatusConflict, "Too many API tokens; revoke one first")
+		return
+	}
+
+	secret, hash, prefix, err := apitokens.Generate()
+	if err != nil {
+		logger.Error("API token generation failed", logger.F("error", err.Error()))
+		httpx.WriteJSONError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	token, err := database.CreateAPIToken(userID, name, hash, prefix, scopes, expiresAt)
+	if err != nil {
+		httpx.WriteJSONError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	logger.Info("API token created", logger.F("userId", userID, "tokenId", token.TokenID, "scopes", strings.Join(scopes, ",")))
+	httpx.WriteJSON(w, http.StatusCreated, map[string]interface{}{
+		"token":   secret,
+		"details": token,
+	})
+}
+
+// RevokeAPITokenHandler revokes one of the signed-in user's personal API tokens
+// DELETE /api/me/tokens/{tokenID}
+func RevokeAPITokenHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok || userID == "" {
+		httpx.WriteJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	tokenID := chi.URLParam(r, "tokenID")
+	found, err := database.RevokeAPIToken(userID, tokenID)
+	if err != nil {
+		httpx.WriteJSONError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	if !found {
+		httpx.WriteJSONError(w, http.StatusNotFound, "API token not found")
+		return
+	}
+
+	logger.Info("API token revoked", logger.F("userId", userID, "tokenId", tokenID))
+	httpx.WriteJSON(w, http.StatusOK, map[string]string{
+		"message": "API token revoked",
+	})
+}
+
+// MyGamesHandler returns a page of the signed-in user's finished games with PGN,
+// newest first. Pass the created_at of the last game as before for the next page.
+// GET /api/me/games?before=<RFC 3339 time>&limit=50
+func MyGamesHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok || userID == "" {
+		httpx.WriteJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	before := time.Now()
+	if beforeStr := r.URL.Query().Get("before"); beforeStr != "" {
+		t, err := time.Parse(time.RFC3339Nano, beforeStr)
+		if err != nil {
+			httpx.WriteJSONError(w, http.StatusBadRequest, "before must be an RFC 3339 time")
+			return
+		}
+		before = t
+	}
+
+	limit := defaultGamesPageSize
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		l, err := strconv.Atoi(limitStr)
+		if err != nil || l < 1 || l > maxGamesPageSize {
+			httpx.WriteJSONError(w, http.StatusBadRequest, "limit must be between 1 and 100")
+			return
+		}
+		limit = l
+	}
+
+	games, err := database.GetGamesByUserID(userID, before, limit)
+	if err != nil {
+		httpx.WriteJSONError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	httpx.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"games": games,
+	})
+}
+
+// normalizeScopes validates requested scopes and removes duplicates
+func normalizeScopes(requested []string) ([]string, bool) {
+	if len(requested) == 0 {
+		return nil, false
+	}
+	seen := make(map[string]bool, len(requested))
+	var scopes []string
+	for _, s := range requested {
+		if !models.IsAPITokenScope(s) {
+			return nil, false
+		}
+		if !seen[s] {
+			seen[s] = true
+			scopes = append(scopes, s)
+		}
+	}
+	return scopes, true
+}
@@ -12,6 +12,20 @@ import (
 // LogoutHandler handles user logout by clearing the session
 func LogoutHandler(cfg *config.Config) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		// The mobile app signs out by ending the session of its access token
+		if token, ok := httpx.BearerToken(r); ok {
+			if userID, sessionID, found := sessions.GetAccessTokenSession(token); found {
+				if _, err := sessions.RevokeSession(userID, sessionID); err != nil {
+					logger.Error("Failed to delete session", logger.F("error", err.Error()))
+				}
+			}
+			logger.Info("User logged out")
+			httpx.WriteJSON(w, http.StatusOK, map[string]string{
+				"message": "Logged out successfully",
+			})
+			return
+		}
+
 		// Get session token from cookie
 		cookie, err := r.Cookie("session_token")
 		if err != nil {
@@ -0,0 +1,216 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/achievements"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/chess"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/database"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/httpx"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/logger"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/middleware"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/models"
+)
+
+// GetDailyPuzzleHandler returns today's (UTC) daily puzzle
+// GET /api/puzzle/daily
+// Every user gets the same puzzle on a given date. For a logged-in user the
+// response also carries their result on it and their daily streak.
+func GetDailyPuzzleHandler(w http.ResponseWriter, r *http.Request) {
+	date := models.DailyPuzzleDate(time.Now())
+
+	puzzle, err := ensureDailyPuzzle(date)
+	if err != nil {
+		httpx.WriteJSONError(w, http.StatusInternalServerError, "Failed to get daily puzzle")
+		return
+	}
+	if puzzle == nil {
+		httpx.WriteJSONError(w, http.StatusNotFound, "No puzzles available")
+		return
+	}
+
+	resp := map[string]interface{}{
+		"date":          date.Format(models.DailyPuzzleDateLayout),
+		"puzzle":        toPuzzleResponse(puzzle),
+		"authenticated": false,
+	}
+
+	if userID, ok := middleware.UserIDFromContext(r.Context()); ok && userID != "" {
+		result, err := database.GetDailyPuzzleResult(userID, date)
+		if err != nil {
+			httpx.WriteJSONError(w, http.StatusInternalServerError, "Internal server error")
+			return
+		}
+		resp["authenticated"] = true
+		resp["result"] = result
+	}
+
+	httpx.WriteJSON(w, http.StatusOK, resp)
+}
+
+// SubmitDailyPuzzleHandler verifies moves played on the daily puzzle
+// POST /api/puzzle/daily/submit
+// Body: {"date": "2006-01-02", "moves": ["e2e4", ...]}. The date defaults to today;
+// yesterday is also accepted so a puzzle started before midnight UTC can be finished.
+// The first finished attempt is recorded and drives the daily streak; the daily
+// puzzle does not change the puzzle rating.
+func SubmitDailyPuzzleHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok || userID == "" {
+		httpx.WriteJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req struct {
+		Date  string   `json:"date"`
+		Moves []string `json:"moves"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpx.WriteJSONError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	today := models.DailyPuzzleDate(time.Now())
+	date := today
+	if req.Date != "" {
+		d, err := time.Parse(models.DailyPuzzleDateLayout, req.Date)
+		if err != nil || !(d.Equal(today) || d.Equal(today.AddDate(0, 0, -1))) {
+			httpx.WriteJSONError(w, http.StatusBadRequest, "date must be today or yesterday (UTC)")
+			return
+		}
+		date = d
+	}
+
+	puzzle, err := ensureDailyPuzzle(date)
+	if err != nil {
+		httpx.WriteJSONError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	if puzzle == nil {
+		httpx.WriteJSONError(w, http.StatusNotFound, "No puzzles available")
+		return
+	}
+
+	solution := chess.SplitMoves(puzzle.Moves)
+	check, err := chess.CheckPuzzleLine(puzzle.FEN, solution, req.Moves)
+	if err != nil {
+		if errors.Is(err, chess.ErrIllegalPuzzleMove) {
+			httpx.WriteJSONError(w, http.StatusBadRequest, "Illegal move")
+			return
+		}
+		httpx.WriteJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if check.Status == chess.PuzzleIncomplete {
+		httpx.WriteJSON(w, http.StatusOK, map[string]interface{}{
+			"status": check.Status,
+			"reply":  check.Reply,
+		})
+		return
+	}
+
+	solved := check.Status == chess.PuzzleSolved
+	recorded, result, err := database.RecordDailyPuzzleResult(userID, date, solved)
+	if err != nil {
+		httpx.WriteJSONError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	resp := map[string]interface{}{
+		"status":   check.Status,
+		"solved":   solved,
+		"recorded": recorded,
+		"result":   result,
+	}
+	if !solved {
+		resp["solution"] = solution
+	}
+	if recorded {
+		logger.Info("Daily puzzle attempt recorded", logger.F(
+			"userID", userID,
+			"date", date.Format(models.DailyPuzzleDateLayout),
+			"solved", solved,
+			"streak", result.Streak,
+		))
+		if solved {
+			resp["new_achievements"] = achievements.CheckDailyPuzzleAchievements(userID, result.Streak)
+		}
+	}
+
+	httpx.WriteJSON(w, http.StatusOK, resp)
+}
+
+// SetDailyPuzzleHandler overrides the daily puzzle for a date
+// PUT /admin/puzzle/daily (internal network only)
+// Body: {"date": "2006-01-02", "puzzle_id": "..."}; the date defaults to today (UTC).
+func SetDailyPuzzleHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Date     string `json:"date"`
+		PuzzleID string `json:"puzzle_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpx.WriteJSONError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.PuzzleID == "" {
+		httpx.WriteJSONError(w, http.StatusBadRequest, "puzzle_id is required")
+		return
+	}
+
+	date := models.DailyPuzzleDate(time.Now())
+	if req.Date != "" {
+		d, err := time.Parse(models.DailyPuzzleDateLayout, req.Date)
+		if err != nil {
+			httpx.WriteJSONError(w, http.StatusBadRequest, "date must be formatted YYYY-MM-DD")
+			return
+		}
+		date = d
+	}
+
+	puzzle, err := database.GetPuzzleByID(req.PuzzleID)
+	if err != nil {
+		httpx.WriteJSONError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	if puzzle == nil {
+		httpx.WriteJSONError(w, http.StatusNotFound, "Puzzle not found")
+		return
+	}
+
+	if err := database.AssignDailyPuzzle(date, puzzle.PuzzleID, true); err != nil {
+		httpx.WriteJSONError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	logger.Info("Daily puzzle overridden", logger.F(
+		"date", date.Format(models.DailyPuzzleDateLayout),
+		"puzzleID", puzzle.PuzzleID,
+	))
+	httpx.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"date":      date.Format(models.DailyPuzzleDateLayout),
+		"puzzle_id": puzzle.PuzzleID,
+	})
+}
+
+// ensureDailyPuzzle returns the puzzle assigned to date, assigning the deterministic
+// pick first if the date has none yet. It returns nil when there are no puzzles.
+func ensureDailyPuzzle(date time.Time) (*models.Puzzle, error) {
+	puzzle, err := database.GetDailyPuzzle(date)
+	if err != nil || puzzle != nil {
+		return puzzle, err
+	}
+
+	puzzleID, err := database.PickDailyPuzzleID(date)
+	if err != nil || puzzleID == "" {
+		return nil, err
+	}
+	if err := database.AssignDailyPuzzle(date, puzzleID, false); err != nil {
+		return nil, err
+	}
+	// Read back the assignment: a concurrent request or an override may have won
+	return database.GetDailyPuzzle(date)
+}
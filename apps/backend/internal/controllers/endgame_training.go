@@ -0,0 +1,405 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/achievements"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/chess"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/database"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/elo"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/engine"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/httpx"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/logger"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/middleware"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/models"
+)
+
+const (
+	maxEndgameAttemptMoves = 500
+	maxEndgameAttemptTime  = 2 * time.Hour
+
+	// endgameWinMoveLimit is the number of moves the side to move has to mate or
+	// convert a won position; endgameHoldMoves is how long a drawn or lost position
+	// has to be held
+	endgameWinMoveLimit = 50
+	endgameHoldMoves    = 30
+
+	// The engine defends positions the tablebase does not cover. trainingReplyTimeout
+	// includes waiting for a free engine.
+	trainingReplyDepth   = 18
+	trainingReplyTime    = 500 * time.Millisecond
+	trainingReplyTimeout = 5 * time.Second
+)
+
+// trainingReplies remembers the engine replies handed out during endgame attempts
+var trainingReplies = newReplyLog(maxEndgameAttemptTime)
+
+// SubmitEndgameHandler judges the moves the current user played in an endgame position
+// POST /api/training/endgame/submit
+// Body: {"position_id": "...", "moves": ["e2e4", ...], "time_ms": 42000}
+// moves are all moves played from the position, both sides, starting with the user.
+// The server replays them and decides the outcome: mate delivered, win converted or
+// draw held within the move limit. With tablebases configured, a move that gives away
+// the expected result fails at once. Until then the status is "continue", with the
+// opponent's reply: the tablebase's best when it covers the position, otherwise the
+// engine's. A decided attempt updates the review schedule. It updates the training
+// rating (once per position per day) and training achievements only when every
+// reply in the line was a tablebase-best defence or the reply the server handed out
+// ("verified"), so a line with a weak opponent earns nothing. Without tablebases or
+// an engine no attempt is verified.
+func SubmitEndgameHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok || userID == "" {
+		httpx.WriteJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req struct {
+		PositionID string   `json:"position_id"`
+		Moves      []string `json:"moves"`
+		TimeMs     int      `json:"time_ms"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpx.WriteJSONError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.PositionID == "" || len(req.Moves) == 0 {
+		httpx.WriteJSONError(w, http.StatusBadRequest, "position_id and moves are required")
+		return
+	}
+	if req.TimeMs < 0 || time.Duration(req.TimeMs)*time.Millisecond > maxEndgameAttemptTime {
+		httpx.WriteJSONError(w, http.StatusBadRequest, "time_ms is out of range")
+		return
+	}
+
+	pos, err := database.GetEndgamePositionByID(req.PositionID)
+	if err != nil {
+		httpx.WriteJSONError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	if pos == nil {
+		httpx.WriteJSONError(w, http.StatusNotFound, "Position not found")
+		return
+	}
+
+	goal, limit := endgameGoal(pos)
+	served := func(line []string) bool {
+		return trainingReplies.has(userID, pos.PositionID, line)
+	}
+	outcome, err := chess.JudgeTrainingLine(pos.FEN, req.Moves, goal, limit, trainingOracle(), served)
+	if err != nil {
+		if errors.Is(err, chess.ErrIllegalPuzzleMove) {
+			httpx.WriteJSONError(w, http.StatusBadRequest, "Illegal move")
+			return
+		}
+		httpx.WriteJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if outcome.Status == chess.TrainingIncomplete {
+		resp := map[string]interface{}{
+			"status":          outcome.Status,
+			"goal":            goal,
+			"move_limit":      limit,
+			"moves_remaining": limit - outcome.TraineeMoves,
+		}
+		reply := outcome.Reply
+		if reply == "" && len(req.Moves)%2 == 1 {
+			if reply = engineReply(r.Context(), pos.FEN, req.Moves); reply != "" {
+				trainingReplies.add(userID, pos.PositionID, append(req.Moves, reply))
+			}
+		}
+		if reply != "" {
+			resp["reply"] = reply
+		}
+		httpx.WriteJSON(w, http.StatusOK, resp)
+		return
+	}
+
+	success := outcome.Status == chess.TrainingSuccess
+	attempt := models.EndgameAttempt{
+		UserID:     userID,
+		PositionID: pos.PositionID,
+		Success:    success,
+		TimeMs:     req.TimeMs,
+		Moves:      req.Moves,
+	}
+
+	recent, err := database.HasRecentEndgameAttempt(userID, pos.PositionID, time.Now().Add(-models.EndgameRepeatWindow))
+	if err != nil {
+		httpx.WriteJSONError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	// Without a checked defence the result stands on the client's replies, so it is
+	// kept like a client-reported attempt: no outcome, rating or achievements
+	if outcome.DefenceChecked {
+		attempt.Outcome = string(outcome.Reason)
+	}
+	if !recent && outcome.DefenceChecked {
+		rating, rated, err := database.GetTrainingRating(userID)
+		if err != nil {
+			httpx.WriteJSONError(w, http.StatusInternalServerError, "Internal server error")
+			return
+		}
+		// Rated like a puzzle: the position's rating is its difficulty
+		rc := elo.CalculatePuzzle(rating, pos.Rating, success, rated)
+		attempt.Rated = true
+		attempt.RatingBefore = rc.PlayerOld
+		attempt.RatingAfter = rc.PlayerNew
+	}
+
+	review, err := database.RecordEndgameAttempt(attempt, models.ReviewQuality(success, time.Duration(req.TimeMs)*time.Millisecond))
+	if err != nil {
+		httpx.WriteJSONError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	logger.Info("Endgame training judged", logger.F(
+		"userID", userID,
+		"positionID", pos.PositionID,
+		"goal", goal,
+		"status", outcome.Status,
+		"reason", outcome.Reason,
+		"rated", attempt.Rated,
+		"defenceChecked", outcome.DefenceChecked,
+	))
+
+	resp := map[string]interface{}{
+		"status":        outcome.Status,
+		"reason":        outcome.Reason,
+		"goal":          goal,
+		"trainee_moves": outcome.TraineeMoves,
+		"rated":         attempt.Rated,
+		"verified":      outcome.DefenceChecked,
+		"review":        review,
+	}
+	if attempt.Rated {
+		resp["old_rating"] = attempt.RatingBefore
+		resp["new_rating"] = attempt.RatingAfter
+		resp["rating_delta"] = attempt.RatingAfter - attempt.RatingBefore
+	}
+	resp["new_achievements"] = []achievements.AchievementUnlock{}
+	if outcome.DefenceChecked {
+		// RatingAfter is zero for an unrated attempt, so no rating milestone is checked
+		resp["new_achievements"] = achievements.CheckTrainingAchievements(userID, achievements.TrainingContext{
+			Success:   success,
+			Goal:      string(goal),
+			NewRating: attempt.RatingAfter,
+		})
+	}
+
+	httpx.WriteJSON(w, http.StatusOK, resp)
+}
+
+// SubmitEndgameAttemptHandler records the current user's attempt on an endgame position
+// POST /api/training/endgame/attempt
+// Body: {"position_id": "...", "success": true, "time_ms": 42000, "moves": ["e2e4", ...]}
+// moves are all moves played from the position (both sides) and must be legal. The
+// attempt reschedules the position for spaced-repetition review; the response
+// carries the new review state.
+func SubmitEndgameAttemptHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok || userID == "" {
+		httpx.WriteJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req struct {
+		PositionID string   `json:"position_id"`
+		Success    bool     `json:"success"`
+		TimeMs     int      `json:"time_ms"`
+		Moves      []string `json:"moves"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpx.WriteJSONError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.PositionID == "" {
+		httpx.WriteJSONError(w, http.StatusBadRequest, "position_id is required")
+		return
+	}
+	if req.TimeMs < 0 || time.Duration(req.TimeMs)*time.Millisecond > maxEndgameAttemptTime {
+		httpx.WriteJSONError(w, http.StatusBadRequest, "time_ms is out of range")
+		return
+	}
+	if len(req.Moves) > maxEndgameAttemptMoves {
+		httpx.WriteJSONError(w, http.StatusBadRequest, "Too many moves")
+		return
+	}
+
+	pos, err := database.GetEndgamePositionByID(req.PositionID)
+	if err != nil {
+		httpx.WriteJSONError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	if pos == nil {
+		httpx.WriteJSONError(w, http.StatusNotFound, "Position not found")
+		return
+	}
+
+	if len(req.Moves) > 0 {
+		if _, err := chess.ValidateLine(pos.FEN, req.Moves); err != nil {
+			if errors.Is(err, chess.ErrIllegalPuzzleMove) {
+				httpx.WriteJSONError(w, http.StatusBadRequest, "Illegal move")
+				return
+			}
+			httpx.WriteJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	elapsed := time.Duration(req.TimeMs) * time.Millisecond
+	quality := models.ReviewQuality(req.Success, elapsed)
+	review, err := database.RecordEndgameAttempt(models.EndgameAttempt{
+		UserID:     userID,
+		PositionID: pos.PositionID,
+		Success:    req.Success,
+		TimeMs:     req.TimeMs,
+		Moves:      req.Moves,
+	}, quality)
+	if err != nil {
+		httpx.WriteJSONError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	logger.Info("Endgame attempt recorded", logger.F(
+		"userID", userID,
+		"positionID", pos.PositionID,
+		"success", req.Success,
+		"quality", quality,
+		"intervalDays", review.IntervalDays,
+	))
+
+	httpx.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"review":   review,
+		"mastered": review.Mastered(),
+	})
+}
+
+// GetEndgameProgressHandler returns the current user's endgame training progress
+// GET /api/training/endgame/progress
+// Mastery is reported per theme and per difficulty (1-10); a position is mastered
+// after models.MasteryRepetitions successful reviews in a row.
+func GetEndgameProgressHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok || userID == "" {
+		httpx.WriteJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	progress, err := database.GetEndgameProgress(userID)
+	if err != nil {
+		httpx.WriteJSONError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	themeTotals, ratingTotals, err := database.GetEndgamePositionTotals()
+	if err != nil {
+		httpx.WriteJSONError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	now := time.Now()
+	byTheme, byDifficulty := models.BuildEndgameProgress(progress, themeTotals, ratingTotals, now)
+
+	due, mastered := 0, 0
+	for _, p := range progress {
+		if !p.Review.DueAt.After(now) {
+			due++
+		}
+		if p.Review.Mastered() {
+			mastered++
+		}
+	}
+
+	httpx.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"attempted":     len(progress),
+		"mastered":      mastered,
+		"due":           due,
+		"by_theme":      byTheme,
+		"by_difficulty": byDifficulty,
+	})
+}
+
+// endgameGoal returns what the side to move has to achieve in pos and within how
+// many of its moves. Basic mates must be mated; other won positions may also be
+// converted to a basic mate. Drawn and lost positions are to be held.
+func endgameGoal(pos *models.EndgamePosition) (chess.TrainingGoal, int) {
+	if expectedResult(pos) != models.ExpectedWin {
+		return chess.GoalDraw, endgameHoldMoves
+	}
+	for _, t := range pos.Themes {
+		if t == chess.ThemeBasicMate {
+			return chess.GoalMate, endgameWinMoveLimit
+		}
+	}
+	return chess.GoalWin, endgameWinMoveLimit
+}
+
+// engineReply returns the engine's best move after moves from fen, or "" when no
+// engine is configured or the search fails
+func engineReply(ctx context.Context, fen string, moves []string) string {
+	if engine.Default == nil {
+		return ""
+	}
+	ctx, cancel := context.WithTimeout(ctx, trainingReplyTimeout)
+	defer cancel()
+
+	analysis, err := engine.Default.Analyze(ctx, fen, moves, engine.Limits{
+		Depth:    trainingReplyDepth,
+		MoveTime: trainingReplyTime,
+	})
+	if err != nil {
+		logger.Warn("Engine training reply failed", logger.F("fen", fen, "error", err.Error()))
+		return ""
+	}
+	return analysis.BestMove
+}
+
+// replyLog records the lines, ending in a reply the server chose, played in endgame
+// attempts. Entries expire after ttl, the longest an attempt may take.
+type replyLog struct {
+	mu        sync.Mutex
+	ttl       time.Duration
+	entries   map[[sha256.Size]byte]time.Time // expiry by line
+	nextPrune time.Time
+}
+
+func newReplyLog(ttl time.Duration) *replyLog {
+	return &replyLog{ttl: ttl, entries: make(map[[sha256.Size]byte]time.Time)}
+}
+
+func replyKey(userID, positionID string, line []string) [sha256.Size]byte {
+	return sha256.Sum256([]byte(userID + "\x00" + positionID + "\x00" + strings.Join(line, " ")))
+}
+
+func (l *replyLog) add(userID, positionID string, line []string) {
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if now.After(l.nextPrune) {
+		for key, expiry := range l.entries {
+			if now.After(expiry) {
+				delete(l.entries, key)
+			}
+		}
+		l.nextPrune = now.Add(time.Minute)
+	}
+	l.entries[replyKey(userID, positionID, line)] = now.Add(l.ttl)
+}
+
+// has reports whether the last move of line is the reply the server chose after the
+// moves before it, in an attempt by userID on positionID
+func (l *replyLog) has(userID, positionID string, line []string) bool {
+	l.mu.Lock()
+	expiry, ok := l.entries[replyKey(userID, positionID, line)]
+	l.mu.Unlock()
+	return ok && time.Now().Before(expiry)
+}
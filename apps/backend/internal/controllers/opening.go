@@ -0,0 +1,31 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/chess"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/httpx"
+)
+
+// OpeningHandler names the opening a position belongs to
+// GET /api/chess/opening?fen=...
+// Positions are matched regardless of the moves that reached them, so
+// transpositions are named too. Positions outside the opening book are not found.
+func OpeningHandler(w http.ResponseWriter, r *http.Request) {
+	fen := r.URL.Query().Get("fen")
+	opening, ok, err := chess.LookupOpening(fen)
+	if err != nil {
+		httpx.WriteJSONError(w, http.StatusBadRequest, "Invalid FEN")
+		return
+	}
+	if !ok {
+		httpx.WriteJSONError(w, http.StatusNotFound, "No opening matches this position")
+		return
+	}
+
+	httpx.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"fen":  fen,
+		"eco":  opening.ECO,
+		"name": opening.Name,
+	})
+}
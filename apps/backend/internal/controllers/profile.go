@@ -2,7 +2,9 @@ package controllers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/tmcarmichael/nxtchess/apps/backend/internal/achievements"
@@ -46,6 +48,10 @@ func UserProfileHandler(w http.ResponseWriter, r *http.Request) {
 		Username:          user.Username,
 		Rating:            user.Rating,
 		PuzzleRating:      user.PuzzleRating,
+		TrainingRating:    user.TrainingRating,
+		BotRating:         user.BotRating,
+		ComputerRating:    user.ComputerRating,
+		IsBot:             user.IsBot,
 		ProfileIcon:       user.ProfileIcon,
 		CreatedAt:         user.CreatedAt,
 		GamesPlayed:       gamesPlayed,
@@ -173,7 +179,27 @@ func CheckUsernameHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-func SetUsernameHandler(w http.ResponseWriter, r *http.Request) {
+// usernameChangeCooldown is how long a user waits between username changes. Choosing
+// the first username is not limited.
+const usernameChangeCooldown = 30 * 24 * time.Hour
+
+// MyProfileHandler returns the signed-in user's private profile
+// GET /api/me (also /api/profile/myprofile)
+func MyProfileHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok || userID == "" {
+		httpx.WriteJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	writePrivateProfile(w, userID)
+}
+
+// UpdateMyProfileHandler changes the signed-in user's username and/or profile icon and
+// returns the updated private profile. A starting rating may only accompany the first
+// username; later username changes are limited to one per usernameChangeCooldown.
+// PATCH /api/me (also /api/profile/myprofile)
+func UpdateMyProfileHandler(w http.ResponseWriter, r *http.Request) {
 	userID, ok := middleware.UserIDFromContext(r.Context())
 	if !ok || userID == "" {
 		httpx.WriteJSONError(w, http.StatusUnauthorized, "Unauthorized")
@@ -181,59 +207,120 @@ func SetUsernameHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req struct {
-		Username       string `json:"username"`
-		StartingRating *int   `json:"starting_rating"`
+		Username       *string `json:"username"`
+		StartingRating *int    `json:"starting_rating"`
+		ProfileIcon    *string `json:"profile_icon"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		httpx.WriteJSONError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
-
-	// Validate username
-	if err := validation.ValidateUsername(req.Username); err != nil {
-		httpx.WriteJSONError(w, http.StatusBadRequest, err.Message)
+	if req.Username == nil && req.ProfileIcon == nil {
+		httpx.WriteJSONError(w, http.StatusBadRequest, "Nothing to update")
 		return
 	}
+	if req.StartingRating != nil && req.Username == nil {
+		httpx.WriteJSONError(w, http.StatusBadRequest, "Starting rating can only be set with a username")
+		return
+	}
+	// Validate the icon up front so a rejected icon does not leave a half-applied update
+	if req.ProfileIcon != nil {
+		if err := validation.ValidateProfileIcon(*req.ProfileIcon); err != nil {
+			httpx.WriteJSONError(w, http.StatusBadRequest, err.Message)
+			return
+		}
+	}
 
-	// Validate starting rating if provided
-	if req.StartingRating != nil {
-		r := *req.StartingRating
-		if r != 500 && r != 1000 && r != 1500 {
-			httpx.WriteJSONError(w, http.StatusBadRequest, "Starting rating must be 500, 1000, or 1500")
+	if req.Username != nil {
+		if _, ok := updateUsername(w, userID, *req.Username, req.StartingRating); !ok {
+			return
+		}
+	}
+	if req.ProfileIcon != nil {
+		if !updateProfileIcon(w, userID, *req.ProfileIcon) {
 			return
 		}
 	}
 
-	// Sanitize username
-	username := validation.SanitizeUsername(req.Username)
+	writePrivateProfile(w, userID)
+}
 
-	// Check if already taken
-	taken, err := database.UsernameExists(username)
+// MyIdentitiesHandler lists the sign-in providers linked to the signed-in user.
+// Accounts are linked through GET /auth/{provider}/link.
+// GET /api/me/identities
+func MyIdentitiesHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok || userID == "" {
+		httpx.WriteJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	identities, err := database.GetIdentities(userID)
 	if err != nil {
-		logger.Error("Failed to check username availability", logger.F("username", username, "error", err.Error()))
-		httpx.WriteJSONError(w, http.StatusInternalServerError, "Database error")
+		httpx.WriteJSONError(w, http.StatusInternalServerError, "Internal server error")
 		return
 	}
-	if taken {
-		httpx.WriteJSONError(w, http.StatusConflict, "Username already taken")
+
+	httpx.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"identities": identities,
+	})
+}
+
+// UnlinkIdentityHandler removes a linked sign-in provider from the signed-in user. The
+// last remaining provider cannot be unlinked.
+// DELETE /api/me/identities/{provider}
+func UnlinkIdentityHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok || userID == "" {
+		httpx.WriteJSONError(w, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
-	// Set the username (with optional starting rating)
-	if req.StartingRating != nil {
-		if err := database.UpsertUsernameWithRating(userID, username, *req.StartingRating); err != nil {
-			logger.Error("Failed to set username with rating", logger.F("userId", userID, "username", username, "rating", *req.StartingRating, "error", err.Error()))
-			httpx.WriteJSONError(w, http.StatusInternalServerError, "Failed to set username")
-			return
-		}
-		logger.Info("Username set with starting rating", logger.F("userId", userID, "username", username, "rating", *req.StartingRating))
-	} else {
-		if err := database.UpsertUsername(userID, username); err != nil {
-			logger.Error("Failed to set username", logger.F("userId", userID, "username", username, "error", err.Error()))
-			httpx.WriteJSONError(w, http.StatusInternalServerError, "Failed to set username")
-			return
-		}
-		logger.Info("Username set", logger.F("userId", userID, "username", username))
+	provider := chi.URLParam(r, "provider")
+	found, err := database.UnlinkIdentity(userID, provider)
+	if errors.Is(err, database.ErrLastIdentity) {
+		httpx.WriteJSONError(w, http.StatusConflict, "Cannot unlink the only sign-in method")
+		return
+	}
+	if err != nil {
+		httpx.WriteJSONError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	if !found {
+		httpx.WriteJSONError(w, http.StatusNotFound, "Provider not linked")
+		return
+	}
+
+	logger.Info("OAuth account unlinked", logger.F("userId", userID, "provider", provider))
+	httpx.WriteJSON(w, http.StatusOK, map[string]string{
+		"message":  "Provider unlinked",
+		"provider": provider,
+	})
+}
+
+// SetUsernameHandler sets the signed-in user's username under the same policy as
+// UpdateMyProfileHandler.
+// Deprecated: use PATCH /api/me. Kept for clients that still call it.
+// POST /set-username
+func SetUsernameHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok || userID == "" {
+		httpx.WriteJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req struct {
+		Username       string `json:"username"`
+		StartingRating *int   `json:"starting_rating"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpx.WriteJSONError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	username, ok := updateUsername(w, userID, req.Username, req.StartingRating)
+	if !ok {
+		return
 	}
 
 	httpx.WriteJSON(w, http.StatusOK, map[string]string{
@@ -242,6 +329,9 @@ func SetUsernameHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// SetProfileIconHandler sets the signed-in user's profile icon.
+// Deprecated: use PATCH /api/me. Kept for clients that still call it.
+// POST /set-profile-icon
 func SetProfileIconHandler(w http.ResponseWriter, r *http.Request) {
 	userID, ok := middleware.UserIDFromContext(r.Context())
 	if !ok || userID == "" {
@@ -257,26 +347,153 @@ func SetProfileIconHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Validate icon
 	if err := validation.ValidateProfileIcon(req.Icon); err != nil {
 		httpx.WriteJSONError(w, http.StatusBadRequest, err.Message)
 		return
 	}
-
-	// Set the profile icon
-	if err := database.SetProfileIcon(userID, req.Icon); err != nil {
-		logger.Error("Failed to set profile icon", logger.F("userId", userID, "icon", req.Icon, "error", err.Error()))
-		httpx.WriteJSONError(w, http.StatusInternalServerError, "Failed to set profile icon")
+	if !updateProfileIcon(w, userID, req.Icon) {
 		return
 	}
 
-	logger.Info("Profile icon set", logger.F("userId", userID, "icon", req.Icon))
 	httpx.WriteJSON(w, http.StatusOK, map[string]string{
 		"message": "Profile icon set successfully",
 		"icon":    req.Icon,
 	})
 }
 
+func writePrivateProfile(w http.ResponseWriter, userID string) {
+	profile, err := database.GetPrivateProfile(userID)
+	if err != nil {
+		logger.Error("Failed to get private profile", logger.F("userId", userID, "error", err.Error()))
+		httpx.WriteJSONError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	if profile == nil {
+		httpx.WriteJSONError(w, http.StatusNotFound, "Profile not found")
+		return
+	}
+
+	profile.GamesPlayed, profile.Wins, profile.Losses, profile.Draws, err = database.GetGameStatsByUserID(userID)
+	if err != nil {
+		logger.Error("Failed to get game stats", logger.F("userId", userID, "error", err.Error()))
+	}
+	profile.LinkedProviders, err = database.GetIdentities(userID)
+	if err != nil {
+		logger.Error("Failed to get linked providers", logger.F("userId", userID, "error", err.Error()))
+	}
+	if profile.UsernameChangedAt != nil {
+		if next := profile.UsernameChangedAt.Add(usernameChangeCooldown); next.After(time.Now()) {
+			profile.UsernameChangeAvailableAt = &next
+		}
+	}
+
+	httpx.WriteJSON(w, http.StatusOK, profile)
+}
+
+// updateUsername validates and stores a username under the username change policy,
+// writing the error response when it is refused. It returns the stored username.
+func updateUsername(w http.ResponseWriter, userID, requested string, startingRating *int) (string, bool) {
+	if err := validation.ValidateUsername(requested); err != nil {
+		httpx.WriteJSONError(w, http.StatusBadRequest, err.Message)
+		return "", false
+	}
+	if startingRating != nil {
+		r := *startingRating
+		if r != 500 && r != 1000 && r != 1500 {
+			httpx.WriteJSONError(w, http.StatusBadRequest, "Starting rating must be 500, 1000, or 1500")
+			return "", false
+		}
+	}
+
+	username := validation.SanitizeUsername(requested)
+
+	current, err := database.GetPrivateProfile(userID)
+	if err != nil {
+		httpx.WriteJSONError(w, http.StatusInternalServerError, "Database error")
+		return "", false
+	}
+	renaming := current != nil && current.UsernameSet
+
+	if renaming {
+		if startingRating != nil {
+			httpx.WriteJSONError(w, http.StatusBadRequest, "Starting rating can only be chosen with the first username")
+			return "", false
+		}
+		if username == current.Username {
+			return username, true
+		}
+		if current.UsernameChangedAt != nil {
+			if next := current.UsernameChangedAt.Add(usernameChangeCooldown); time.Now().Before(next) {
+				httpx.WriteJSONError(w, http.StatusForbidden,
+					"Username can be changed again after "+next.UTC().Format("2006-01-02"))
+				return "", false
+			}
+		}
+	}
+
+	taken, err := database.UsernameExists(username)
+	if err != nil {
+		logger.Error("Failed to check username availability", logger.F("username", username, "error", err.Error()))
+		httpx.WriteJSONError(w, http.StatusInternalServerError, "Database error")
+		return "", false
+	}
+	if taken {
+		httpx.WriteJSONError(w, http.StatusConflict, "Username already taken")
+		return "", false
+	}
+
+	switch {
+	case renaming:
+		err = database.ChangeUsername(userID, username, usernameChangeCooldown)
+	case startingRating != nil:
+		err = database.UpsertUsernameWithRating(userID, username, *startingRating)
+	default:
+		err = database.UpsertUsername(userID, username)
+	}
+	if errors.Is(err, database.ErrUsernameTaken) {
+		httpx.WriteJSONError(w, http.StatusConflict, "Username already taken")
+		return "", false
+	}
+	if errors.Is(err, database.ErrUsernameCooldown) {
+		// Another request renamed the user since the check above
+		httpx.WriteJSONError(w, http.StatusForbidden, "Username was changed too recently")
+		return "", false
+	}
+	if err != nil {
+		logger.Error("Failed to set username", logger.F("userId", userID, "username", username, "error", err.Error()))
+		httpx.WriteJSONError(w, http.StatusInternalServerError, "Failed to set username")
+		return "", false
+	}
+
+	switch {
+	case renaming:
+		logger.Info("Username changed", logger.F("userId", userID, "from", current.Username, "to", username))
+	case startingRating != nil:
+		logger.Info("Username set with starting rating", logger.F("userId", userID, "username", username, "rating", *startingRating))
+	default:
+		logger.Info("Username set", logger.F("userId", userID, "username", username))
+	}
+	return username, true
+}
+
+// updateProfileIcon stores an already validated profile icon, writing the error
+// response on failure
+func updateProfileIcon(w http.ResponseWriter, userID, icon string) bool {
+	if err := database.SetProfileIcon(userID, icon); err != nil {
+		logger.Error("Failed to set profile icon", logger.F("userId", userID, "icon", icon, "error", err.Error()))
+		httpx.WriteJSONError(w, http.StatusInternalServerError, "Failed to set profile icon")
+		return false
+	}
+	logger.Info("Profile icon set", logger.F("userId", userID, "icon", icon))
+	return true
+}
+
+// UserAchievementsHandler returns a user's unlocked achievements and their progress
+// towards the locked ones
+// GET /api/profile/{username}/achievements
+// progress maps achievement IDs to {current, target}. Progress on private counters
+// (streaks, puzzles solved, endgame successes, sprint scores) is only included when
+// the user views their own profile.
 func UserAchievementsHandler(w http.ResponseWriter, r *http.Request) {
 	username := chi.URLParam(r, "username")
 	if username == "" {
@@ -284,6 +501,17 @@ func UserAchievementsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	user, err := database.GetUserProfileByUsername(username)
+	if err != nil {
+		logger.Error("Failed to get user profile", logger.F("username", username, "error", err.Error()))
+		httpx.WriteJSONError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	if user == nil {
+		httpx.WriteJSONError(w, http.StatusNotFound, "User not found")
+		return
+	}
+
 	userAchievements, totalPoints, err := database.GetUserAchievementsByUsername(username)
 	if err != nil {
 		logger.Error("Failed to get user achievements", logger.F("username", username, "error", err.Error()))
@@ -307,7 +535,9 @@ func UserAchievementsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var response []achievementResponse
+	unlocked := make(map[string]bool, len(userAchievements))
 	for _, ua := range userAchievements {
+		unlocked[ua.AchievementID] = true
 		def, ok := achievements.GetByID(ua.AchievementID)
 		if !ok {
 			continue
@@ -330,8 +560,19 @@ func UserAchievementsHandler(w http.ResponseWriter, r *http.Request) {
 
 	allAchievements := achievements.GetAll()
 
+	progress := map[string]achievements.Progress{}
+	counters, err := database.GetAchievementCounters(user.UserID)
+	if err != nil {
+		logger.Error("Failed to get achievement counters", logger.F("username", username, "error", err.Error()))
+	} else if counters != nil {
+		viewerID, _ := middleware.UserIDFromContext(r.Context())
+		isOwner := viewerID != "" && viewerID == user.UserID
+		progress = achievements.ComputeProgress(*counters, unlocked, isOwner, time.Now())
+	}
+
 	httpx.WriteJSON(w, http.StatusOK, map[string]interface{}{
 		"achievements":    response,
+		"progress":        progress,
 		"total_points":    totalPoints,
 		"total_unlocked":  len(response),
 		"total_available": len(allAchievements),
@@ -2,23 +2,48 @@ package controllers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/tmcarmichael/nxtchess/apps/backend/internal/achievements"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/chess"
 	"github.com/tmcarmichael/nxtchess/apps/backend/internal/database"
 	"github.com/tmcarmichael/nxtchess/apps/backend/internal/elo"
 	"github.com/tmcarmichael/nxtchess/apps/backend/internal/httpx"
 	"github.com/tmcarmichael/nxtchess/apps/backend/internal/logger"
 	"github.com/tmcarmichael/nxtchess/apps/backend/internal/middleware"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/models"
 )
 
+// puzzleSubmitCooldown is the minimum time between two rated puzzle results
+const puzzleSubmitCooldown = 3 * time.Second
+
 var puzzleRatingByCategory = map[string]int{
 	"mate-in-1": 800,
 	"mate-in-2": 1200,
 	"mate-in-3": 1600,
 }
 
+// Adaptive puzzle selection tuning
+const (
+	// puzzleStatsAttempts is how many recent attempts feed the theme statistics
+	puzzleStatsAttempts = 300
+	// weakThemeMinAttempts is the number of attempts before a theme can be judged weak
+	weakThemeMinAttempts = 5
+	// weakThemeMaxRate is the solve rate below which a theme counts as weak
+	weakThemeMaxRate = 0.6
+	// weakThemeLimit caps how many weak themes are targeted at once
+	weakThemeLimit = 3
+)
+
+// SubmitPuzzleResultHandler is the legacy endpoint for client-judged results of
+// built-in puzzles
+// POST /api/puzzle/result
+// Deprecated: the result is taken on the client's word, so it is no longer rated or
+// counted. The player's puzzle rating is returned unchanged; verified, rated attempts
+// go through POST /api/puzzle/submit.
 func SubmitPuzzleResultHandler(w http.ResponseWriter, r *http.Request) {
 	userID, ok := middleware.UserIDFromContext(r.Context())
 	if !ok || userID == "" {
@@ -35,25 +60,206 @@ func SubmitPuzzleResultHandler(w http.ResponseWriter, r *http.Request) {
 		httpx.WriteJSONError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
-
-	puzzleRating, validCategory := puzzleRatingByCategory[req.Category]
-	if !validCategory {
+	if _, validCategory := puzzleRatingByCategory[req.Category]; !validCategory {
 		httpx.WriteJSONError(w, http.StatusBadRequest, "Category must be mate-in-1, mate-in-2, or mate-in-3")
 		return
 	}
+	if req.PuzzleID == "" {
+		httpx.WriteJSONError(w, http.StatusBadRequest, "puzzle_id is required")
+		return
+	}
+
+	rating, _, err := database.GetPuzzleRatingInfo(userID)
+	if err != nil {
+		logger.Error("Failed to get puzzle rating info", logger.F("userID", userID, "error", err.Error()))
+		httpx.WriteJSONError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	w.Header().Set("Deprecation", "true")
+	w.Header().Set("Link", `</api/puzzle/submit>; rel="successor-version"`)
+	httpx.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"rated":            false,
+		"new_rating":       rating,
+		"rating_delta":     0,
+		"old_rating":       rating,
+		"new_achievements": []achievements.AchievementUnlock{},
+	})
+}
+
+// GetRandomPuzzleHandler returns a random puzzle without its solution
+// GET /api/puzzle/random
+// Query params:
+//   - difficulty: 1-10 (maps to rating ranges)
+//   - theme: puzzle theme (e.g., "fork", "mateIn2")
+//   - exclude: puzzle ID to skip
+func GetRandomPuzzleHandler(w http.ResponseWriter, r *http.Request) {
+	params := models.PuzzleQueryParams{}
+
+	if diffStr := r.URL.Query().Get("difficulty"); diffStr != "" {
+		diff, err := strconv.Atoi(diffStr)
+		if err != nil || diff < 1 || diff > 10 {
+			httpx.WriteJSONError(w, http.StatusBadRequest, "difficulty must be between 1 and 10")
+			return
+		}
+		params.MinRating, params.MaxRating = models.DifficultyToRatingRange(diff)
+	}
+	params.Theme = r.URL.Query().Get("theme")
+	params.ExcludePuzzleID = r.URL.Query().Get("exclude")
 
+	puzzle, err := database.GetRandomPuzzle(params)
+	if err != nil {
+		logger.Error("Failed to get random puzzle", logger.F("theme", params.Theme, "error", err.Error()))
+		httpx.WriteJSONError(w, http.StatusInternalServerError, "Failed to get puzzle")
+		return
+	}
+	if puzzle == nil {
+		httpx.WriteJSONError(w, http.StatusNotFound, "No puzzles found matching criteria")
+		return
+	}
+
+	httpx.WriteJSON(w, http.StatusOK, toPuzzleResponse(puzzle))
+}
+
+// GetNextPuzzleHandler picks a puzzle adapted to the current user
+// GET /api/puzzle/next
+// Puzzles are chosen near the user's puzzle rating, preferring themes the user
+// often fails, and never one the user attempted within models.PuzzleRepeatWindow.
+// Query params:
+//   - theme: only pick puzzles with this theme (e.g., "mateIn2"); weak themes are
+//     then not targeted
+func GetNextPuzzleHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok || userID == "" {
+		httpx.WriteJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	rating, _, err := database.GetPuzzleRatingInfo(userID)
+	if err != nil {
+		logger.Error("Failed to get puzzle rating info", logger.F("userID", userID, "error", err.Error()))
+		httpx.WriteJSONError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	theme := r.URL.Query().Get("theme")
+	var weakThemes []string
+	if theme == "" {
+		stats, err := database.GetPuzzleThemeStats(userID, puzzleStatsAttempts)
+		if err != nil {
+			// Theme preference is best effort; fall back to rating-only selection
+			stats = nil
+		}
+		weakThemes = models.WeakPuzzleThemes(stats, weakThemeMinAttempts, weakThemeMaxRate, weakThemeLimit)
+	}
+
+	since := time.Now().Add(-models.PuzzleRepeatWindow)
+	for _, params := range models.PuzzleSelectionPlan(rating, weakThemes) {
+		params.Theme = theme
+		params.ExcludeAttemptedBy = userID
+		params.AttemptedSince = since
+
+		puzzle, err := database.GetRandomPuzzle(params)
+		if err != nil {
+			logger.Error("Failed to select puzzle", logger.F("userID", userID, "error", err.Error()))
+			httpx.WriteJSONError(w, http.StatusInternalServerError, "Failed to get puzzle")
+			return
+		}
+		if puzzle != nil {
+			httpx.WriteJSON(w, http.StatusOK, toPuzzleResponse(puzzle))
+			return
+		}
+	}
+
+	httpx.WriteJSONError(w, http.StatusNotFound, "No new puzzles available")
+}
+
+// SubmitPuzzleHandler verifies the moves played for a puzzle against the stored solution
+// POST /api/puzzle/submit
+// Body: {"puzzle_id": "...", "moves": ["e2e4", ...]} with only the solver's moves, in UCI.
+// While the line is correct but unfinished, the response carries the opponent's reply and
+// nothing is recorded. A wrong move or a completed solution finalizes the rated attempt,
+// unless the user already attempted the puzzle within models.PuzzleRepeatWindow.
+func SubmitPuzzleHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok || userID == "" {
+		httpx.WriteJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req struct {
+		PuzzleID string   `json:"puzzle_id"`
+		Moves    []string `json:"moves"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpx.WriteJSONError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
 	if req.PuzzleID == "" {
 		httpx.WriteJSONError(w, http.StatusBadRequest, "puzzle_id is required")
 		return
 	}
 
+	puzzle, err := database.GetPuzzleByID(req.PuzzleID)
+	if err != nil {
+		logger.Error("Failed to get puzzle", logger.F("puzzleID", req.PuzzleID, "error", err.Error()))
+		httpx.WriteJSONError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	if puzzle == nil {
+		httpx.WriteJSONError(w, http.StatusNotFound, "Puzzle not found")
+		return
+	}
+
+	solution := chess.SplitMoves(puzzle.Moves)
+	check, err := chess.CheckPuzzleLine(puzzle.FEN, solution, req.Moves)
+	if err != nil {
+		if errors.Is(err, chess.ErrIllegalPuzzleMove) {
+			httpx.WriteJSONError(w, http.StatusBadRequest, "Illegal move")
+			return
+		}
+		httpx.WriteJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if check.Status == chess.PuzzleIncomplete {
+		httpx.WriteJSON(w, http.StatusOK, map[string]interface{}{
+			"status": check.Status,
+			"reply":  check.Reply,
+		})
+		return
+	}
+
+	solved := check.Status == chess.PuzzleSolved
+	resp := map[string]interface{}{
+		"status": check.Status,
+		"solved": solved,
+	}
+	if !solved {
+		resp["solution"] = solution
+	}
+
+	// Repeats within the window are verified but not rated, so a known solution
+	// cannot be replayed for rating points
+	repeated, err := database.HasRecentPuzzleAttempt(userID, puzzle.PuzzleID, time.Now().Add(-models.PuzzleRepeatWindow))
+	if err != nil {
+		logger.Error("Failed to check recent puzzle attempt", logger.F("userID", userID, "puzzleID", puzzle.PuzzleID, "error", err.Error()))
+		httpx.WriteJSONError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	if repeated {
+		resp["rated"] = false
+		httpx.WriteJSON(w, http.StatusOK, resp)
+		return
+	}
+
 	lastSubmission, err := database.GetLastPuzzleSubmissionTime(userID)
 	if err != nil {
 		logger.Error("Failed to check last puzzle submission", logger.F("userID", userID, "error", err.Error()))
 		httpx.WriteJSONError(w, http.StatusInternalServerError, "Internal server error")
 		return
 	}
-	if !lastSubmission.IsZero() && time.Since(lastSubmission) < 3*time.Second {
+	if !lastSubmission.IsZero() && time.Since(lastSubmission) < puzzleSubmitCooldown {
 		httpx.WriteJSONError(w, http.StatusTooManyRequests, "Please wait before submitting another result")
 		return
 	}
@@ -65,34 +271,58 @@ func SubmitPuzzleResultHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	rc := elo.CalculatePuzzle(playerRating, puzzleRating, req.Solved, gamesPlayed)
+	rc := elo.CalculatePuzzleAttempt(playerRating, puzzle.Rating, solved, gamesPlayed, puzzle.NbPlays)
 
-	if err := database.FinalizePuzzleResult(userID, rc.PlayerOld, rc.PlayerNew); err != nil {
-		logger.Error("Failed to finalize puzzle result", logger.F("userID", userID, "error", err.Error()))
+	attempt := models.PuzzleAttempt{
+		UserID:       userID,
+		PuzzleID:     puzzle.PuzzleID,
+		Solved:       solved,
+		PuzzleRating: puzzle.Rating,
+		RatingBefore: rc.PlayerOld,
+		RatingAfter:  rc.PlayerNew,
+		Themes:       puzzle.Themes,
+	}
+	if err := database.FinalizePuzzleAttempt(attempt, rc.PuzzleDelta); err != nil {
+		logger.Error("Failed to finalize puzzle attempt", logger.F("userID", userID, "puzzleID", puzzle.PuzzleID, "error", err.Error()))
 		httpx.WriteJSONError(w, http.StatusInternalServerError, "Internal server error")
 		return
 	}
 
-	logger.Info("Puzzle result submitted", logger.F(
+	logger.Info("Puzzle attempt verified", logger.F(
 		"userID", userID,
-		"puzzleID", req.PuzzleID,
-		"category", req.Category,
-		"solved", req.Solved,
+		"puzzleID", puzzle.PuzzleID,
+		"solved", solved,
 		"oldRating", rc.PlayerOld,
 		"newRating", rc.PlayerNew,
-		"delta", rc.PlayerDelta,
+		"puzzleOldRating", rc.PuzzleOld,
+		"puzzleNewRating", rc.PuzzleNew,
 	))
 
-	puzzleCtx := achievements.PuzzleContext{
-		Solved:    req.Solved,
+	newAchievements := achievements.CheckPuzzleAchievements(userID, achievements.PuzzleContext{
+		Solved:    solved,
 		NewRating: rc.PlayerNew,
-	}
-	newAchievements := achievements.CheckPuzzleAchievements(userID, puzzleCtx)
-
-	httpx.WriteJSON(w, http.StatusOK, map[string]interface{}{
-		"new_rating":       rc.PlayerNew,
-		"rating_delta":     rc.PlayerDelta,
-		"old_rating":       rc.PlayerOld,
-		"new_achievements": newAchievements,
 	})
+
+	resp["rated"] = true
+	resp["new_rating"] = rc.PlayerNew
+	resp["rating_delta"] = rc.PlayerDelta
+	resp["old_rating"] = rc.PlayerOld
+	resp["puzzle_rating"] = rc.PuzzleNew
+	resp["new_achievements"] = newAchievements
+	httpx.WriteJSON(w, http.StatusOK, resp)
+}
+
+// toPuzzleResponse builds the client-facing view of a puzzle, without its solution
+func toPuzzleResponse(p *models.Puzzle) models.PuzzleResponse {
+	themes := p.Themes
+	if themes == nil {
+		themes = []string{}
+	}
+	return models.PuzzleResponse{
+		PuzzleID:       p.PuzzleID,
+		FEN:            p.FEN,
+		Rating:         p.Rating,
+		Themes:         themes,
+		SolutionLength: (len(chess.SplitMoves(p.Moves)) + 1) / 2,
+	}
 }
@@ -0,0 +1,298 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/achievements"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/chess"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/database"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/httpx"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/logger"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/middleware"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/models"
+)
+
+// sprintSearchRadii are the rating distances tried in turn around a sprint's
+// target rating before falling back to any unseen puzzle
+var sprintSearchRadii = []int{50, 150, 400}
+
+const (
+	recentSprintsLimit     = 10
+	defaultLeaderboardSize = 50
+	maxLeaderboardSize     = 100
+)
+
+// StartPuzzleSprintHandler starts a timed puzzle sprint for the current user
+// POST /api/puzzle/sprint
+// Any sprint the user still has open is closed. The response carries the first puzzle.
+func StartPuzzleSprintHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok || userID == "" {
+		httpx.WriteJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	puzzle, err := pickSprintPuzzle(0, nil)
+	if err != nil {
+		logger.Error("Failed to pick sprint puzzle", logger.F("userID", userID, "error", err.Error()))
+		httpx.WriteJSONError(w, http.StatusInternalServerError, "Failed to get puzzle")
+		return
+	}
+	if puzzle == nil {
+		httpx.WriteJSONError(w, http.StatusNotFound, "No puzzles available")
+		return
+	}
+
+	sprint, err := database.CreatePuzzleSprint(userID, puzzle.PuzzleID, models.SprintDuration)
+	if err != nil {
+		logger.Error("Failed to create sprint", logger.F("userID", userID, "error", err.Error()))
+		httpx.WriteJSONError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	logger.Info("Puzzle sprint started", logger.F("userID", userID, "sprintID", sprint.SprintID))
+
+	httpx.WriteJSON(w, http.StatusCreated, map[string]interface{}{
+		"sprint":       sprint,
+		"max_mistakes": models.SprintMaxMistakes,
+		"puzzle":       toPuzzleResponse(puzzle),
+	})
+}
+
+// SubmitPuzzleSprintHandler verifies the moves played on the sprint's current puzzle
+// POST /api/puzzle/sprint/{sprintID}/submit
+// Body: {"moves": ["e2e4", ...]} with only the solver's moves, as for /api/puzzle/submit.
+// A finished puzzle scores a point or a mistake and the next, harder puzzle is returned.
+// The sprint ends on the third mistake or with the first answer after the timer.
+func SubmitPuzzleSprintHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok || userID == "" {
+		httpx.WriteJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	sprintID, err := strconv.ParseInt(chi.URLParam(r, "sprintID"), 10, 64)
+	if err != nil {
+		httpx.WriteJSONError(w, http.StatusBadRequest, "Invalid sprint ID")
+		return
+	}
+
+	var req struct {
+		Moves []string `json:"moves"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpx.WriteJSONError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	sprint, err := database.GetPuzzleSprint(sprintID, userID)
+	if err != nil {
+		httpx.WriteJSONError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	if sprint == nil {
+		httpx.WriteJSONError(w, http.StatusNotFound, "Sprint not found")
+		return
+	}
+
+	now := time.Now()
+	if sprint.Over(now) {
+		status := "finished"
+		if sprint.FinishedAt == nil {
+			status = "timeout"
+			if err := database.FinishPuzzleSprint(sprint); err != nil {
+				httpx.WriteJSONError(w, http.StatusInternalServerError, "Internal server error")
+				return
+			}
+		}
+		httpx.WriteJSON(w, http.StatusOK, map[string]interface{}{
+			"status":   status,
+			"finished": true,
+			"sprint":   sprint,
+		})
+		return
+	}
+
+	puzzle, err := database.GetPuzzleByID(sprint.CurrentPuzzleID())
+	if err != nil {
+		httpx.WriteJSONError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	if puzzle == nil {
+		httpx.WriteJSONError(w, http.StatusNotFound, "Puzzle not found")
+		return
+	}
+
+	solution := chess.SplitMoves(puzzle.Moves)
+	check, err := chess.CheckPuzzleLine(puzzle.FEN, solution, req.Moves)
+	if err != nil {
+		if errors.Is(err, chess.ErrIllegalPuzzleMove) {
+			httpx.WriteJSONError(w, http.StatusBadRequest, "Illegal move")
+			return
+		}
+		httpx.WriteJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if check.Status == chess.PuzzleIncomplete {
+		httpx.WriteJSON(w, http.StatusOK, map[string]interface{}{
+			"status": check.Status,
+			"reply":  check.Reply,
+		})
+		return
+	}
+
+	solved := check.Status == chess.PuzzleSolved
+	finish := !solved && sprint.Mistakes+1 >= models.SprintMaxMistakes
+
+	var next *models.Puzzle
+	if !finish {
+		next, err = pickSprintPuzzle(len(sprint.PuzzleIDs), sprint.PuzzleIDs)
+		if err != nil {
+			logger.Error("Failed to pick sprint puzzle", logger.F("sprintID", sprint.SprintID, "error", err.Error()))
+			httpx.WriteJSONError(w, http.StatusInternalServerError, "Failed to get puzzle")
+			return
+		}
+		// Running out of puzzles ends the sprint with the score reached so far
+		finish = next == nil
+	}
+
+	nextID := ""
+	if next != nil {
+		nextID = next.PuzzleID
+	}
+	applied, err := database.AdvancePuzzleSprint(sprint, solved, nextID, finish)
+	if err != nil {
+		httpx.WriteJSONError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	if !applied {
+		httpx.WriteJSONError(w, http.StatusConflict, "Puzzle already answered")
+		return
+	}
+
+	resp := map[string]interface{}{
+		"status":   check.Status,
+		"solved":   solved,
+		"finished": finish,
+		"sprint":   sprint,
+	}
+	if !solved {
+		resp["solution"] = solution
+	}
+	if next != nil {
+		resp["puzzle"] = toPuzzleResponse(next)
+	}
+	if solved {
+		resp["new_achievements"] = achievements.CheckSprintAchievements(userID, sprint.Score)
+	}
+	if finish {
+		logger.Info("Puzzle sprint finished", logger.F(
+			"userID", userID,
+			"sprintID", sprint.SprintID,
+			"score", sprint.Score,
+			"mistakes", sprint.Mistakes,
+		))
+	}
+
+	httpx.WriteJSON(w, http.StatusOK, resp)
+}
+
+// GetPuzzleSprintHistoryHandler returns the current user's sprint record
+// GET /api/puzzle/sprint/history
+// personal_bests lists every sprint that raised the user's best score, oldest first.
+func GetPuzzleSprintHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok || userID == "" {
+		httpx.WriteJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	personalBests, err := database.GetSprintPersonalBests(userID)
+	if err != nil {
+		httpx.WriteJSONError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	recent, err := database.GetRecentPuzzleSprints(userID, recentSprintsLimit)
+	if err != nil {
+		httpx.WriteJSONError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	best := 0
+	if len(personalBests) > 0 {
+		best = personalBests[len(personalBests)-1].Score
+	}
+
+	httpx.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"personal_best":  best,
+		"personal_bests": personalBests,
+		"recent":         recent,
+	})
+}
+
+// GetPuzzleSprintLeaderboardHandler returns the best sprint score per user
+// GET /api/puzzle/sprint/leaderboard
+// Query params:
+//   - period: "day", "week" or "all" (default "all")
+//   - limit: number of entries, 1-100 (default 50)
+func GetPuzzleSprintLeaderboardHandler(w http.ResponseWriter, r *http.Request) {
+	var since time.Time
+	switch r.URL.Query().Get("period") {
+	case "", "all":
+	case "day":
+		since = time.Now().Add(-24 * time.Hour)
+	case "week":
+		since = time.Now().Add(-7 * 24 * time.Hour)
+	default:
+		httpx.WriteJSONError(w, http.StatusBadRequest, "period must be day, week or all")
+		return
+	}
+
+	limit := defaultLeaderboardSize
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		l, err := strconv.Atoi(limitStr)
+		if err != nil || l < 1 || l > maxLeaderboardSize {
+			httpx.WriteJSONError(w, http.StatusBadRequest, "limit must be between 1 and 100")
+			return
+		}
+		limit = l
+	}
+
+	entries, err := database.GetSprintLeaderboard(since, limit)
+	if err != nil {
+		httpx.WriteJSONError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	httpx.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"entries": entries,
+	})
+}
+
+// pickSprintPuzzle chooses the puzzle at position index of a sprint, near the rating
+// for that position and never one already handed out in the sprint
+func pickSprintPuzzle(index int, seen []string) (*models.Puzzle, error) {
+	target := models.SprintPuzzleRating(index)
+	for _, radius := range sprintSearchRadii {
+		minRating := target - radius
+		if minRating < 1 {
+			minRating = 1
+		}
+		puzzle, err := database.GetRandomPuzzle(models.PuzzleQueryParams{
+			MinRating:        minRating,
+			MaxRating:        target + radius,
+			ExcludePuzzleIDs: seen,
+		})
+		if err != nil || puzzle != nil {
+			return puzzle, err
+		}
+	}
+	return database.GetRandomPuzzle(models.PuzzleQueryParams{ExcludePuzzleIDs: seen})
+}
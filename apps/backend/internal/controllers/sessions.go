@@ -0,0 +1,120 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/database"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/httpx"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/logger"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/middleware"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/sessions"
+)
+
+// sessionView is a session as listed to its user
+type sessionView struct {
+	sessions.Session
+	Current bool `json:"current"`
+}
+
+// MySessionsHandler lists the signed-in user's sessions, most recently used first.
+// The session making the request is marked current.
+// GET /api/me/sessions
+func MySessionsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok || userID == "" {
+		httpx.WriteJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	currentID, _ := middleware.SessionIDFromContext(r.Context())
+
+	list, err := sessions.ListUserSessions(userID)
+	if err != nil {
+		httpx.WriteJSONError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	views := make([]sessionView, len(list))
+	for i, s := range list {
+		views[i] = sessionView{Session: s, Current: s.ID == currentID}
+	}
+	httpx.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"sessions": views,
+	})
+}
+
+// RevokeSessionHandler signs out one of the signed-in user's sessions and closes its
+// live connections.
+// DELETE /api/me/sessions/{id}
+func RevokeSessionHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok || userID == "" {
+		httpx.WriteJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	sessionID := chi.URLParam(r, "id")
+	found, err := sessions.RevokeSession(userID, sessionID)
+	if err != nil {
+		httpx.WriteJSONError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	if !found {
+		httpx.WriteJSONError(w, http.StatusNotFound, "Session not found")
+		return
+	}
+
+	httpx.WriteJSON(w, http.StatusOK, map[string]string{
+		"message": "Session revoked",
+	})
+}
+
+// RevokeOtherSessionsHandler signs out every session of the signed-in user except
+// the one making the request.
+// DELETE /api/me/sessions
+func RevokeOtherSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok || userID == "" {
+		httpx.WriteJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	currentID, _ := middleware.SessionIDFromContext(r.Context())
+
+	revoked, err := sessions.RevokeUserSessions(userID, currentID)
+	if err != nil {
+		httpx.WriteJSONError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	httpx.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"message": "Other sessions revoked",
+		"revoked": revoked,
+	})
+}
+
+// RevokeUserSessionsHandler signs a user out everywhere, e.g. after a ban. Their
+// personal API tokens are revoked too and open connections, bot streams included,
+// are closed.
+// DELETE /admin/users/{userID}/sessions (internal network only)
+func RevokeUserSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	userID := chi.URLParam(r, "userID")
+
+	revokedTokens, err := database.RevokeUserAPITokens(userID)
+	if err != nil {
+		httpx.WriteJSONError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	revoked, err := sessions.RevokeUserSessions(userID, "")
+	if err != nil {
+		httpx.WriteJSONError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	sessions.DisconnectUser(userID)
+
+	logger.Info("User signed out everywhere", logger.F("userId", userID, "revoked", revoked, "revokedTokens", revokedTokens))
+	httpx.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"user_id":        userID,
+		"revoked":        revoked,
+		"revoked_tokens": revokedTokens,
+	})
+}
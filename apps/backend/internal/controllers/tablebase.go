@@ -0,0 +1,89 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/chess"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/httpx"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/logger"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/models"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/tablebase"
+)
+
+// ProbeTablebaseHandler returns the Syzygy tablebase result of a position
+// GET /api/training/tablebase?fen=...
+// wdl is the result for the side to move ("win", "cursed_win", "draw",
+// "blessed_loss" or "loss"), result the same under the fifty-move rule, and dtz the
+// signed distance in plies to the next capture or pawn move (null without DTZ tables).
+func ProbeTablebaseHandler(w http.ResponseWriter, r *http.Request) {
+	if tablebase.Default == nil {
+		httpx.WriteJSONError(w, http.StatusServiceUnavailable, "Tablebase is not available")
+		return
+	}
+
+	fen := r.URL.Query().Get("fen")
+	if _, err := chess.NewGameFromFEN(fen); err != nil {
+		httpx.WriteJSONError(w, http.StatusBadRequest, "Invalid FEN")
+		return
+	}
+
+	wdl, err := tablebase.Default.ProbeWDL(fen)
+	if err != nil {
+		switch {
+		case errors.Is(err, tablebase.ErrNotAvailable):
+			httpx.WriteJSONError(w, http.StatusNotFound, "No tablebase covers this position")
+		case errors.Is(err, tablebase.ErrTooManyPieces), errors.Is(err, tablebase.ErrCastling):
+			httpx.WriteJSONError(w, http.StatusBadRequest, err.Error())
+		default:
+			logger.Error("Tablebase probe failed", logger.F("fen", fen, "error", err.Error()))
+			httpx.WriteJSONError(w, http.StatusInternalServerError, "Failed to probe tablebase")
+		}
+		return
+	}
+
+	var dtz *int
+	if d, err := tablebase.Default.ProbeDTZ(fen); err == nil {
+		dtz = &d
+	} else if !errors.Is(err, tablebase.ErrNotAvailable) {
+		logger.Warn("Tablebase DTZ probe failed", logger.F("fen", fen, "error", err.Error()))
+	}
+
+	httpx.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"fen":    fen,
+		"wdl":    wdl.String(),
+		"result": wdl.Result(),
+		"dtz":    dtz,
+	})
+}
+
+// expectedResult returns the result the side to move should reach in pos. When the
+// tablebase covers the position its result wins, and a stored result that
+// contradicts it is logged.
+func expectedResult(pos *models.EndgamePosition) string {
+	stated := pos.ExpectedOutcome()
+	if tablebase.Default == nil {
+		return stated
+	}
+
+	wdl, err := tablebase.Default.ProbeWDL(pos.FEN)
+	if err != nil {
+		return stated
+	}
+	if wdl.Result() != stated {
+		logger.Warn("Endgame position result contradicts tablebase", logger.F(
+			"positionID", pos.PositionID,
+			"stated", stated,
+			"tablebase", wdl.Result(),
+		))
+	}
+	return wdl.Result()
+}
+
+// trainingOracle returns the tablebase for judging training lines, or nil
+func trainingOracle() chess.Oracle {
+	if tablebase.Default == nil {
+		return nil
+	}
+	return tablebase.Default
+}
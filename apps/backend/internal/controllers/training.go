@@ -4,9 +4,11 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/chess"
 	"github.com/tmcarmichael/nxtchess/apps/backend/internal/database"
 	"github.com/tmcarmichael/nxtchess/apps/backend/internal/httpx"
 	"github.com/tmcarmichael/nxtchess/apps/backend/internal/logger"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/middleware"
 	"github.com/tmcarmichael/nxtchess/apps/backend/internal/models"
 )
 
@@ -16,6 +18,8 @@ import (
 //   - difficulty: 1-10 (maps to rating ranges)
 //   - theme: specific endgame theme (e.g., "rookEndgame", "pawnEndgame")
 //   - side: 'w' or 'b' (filter by side to move)
+//
+// With a session, a position whose spaced-repetition review is due is preferred.
 func GetRandomEndgamePosition(w http.ResponseWriter, r *http.Request) {
 	// Parse query parameters
 	params := models.EndgameQueryParams{}
@@ -34,19 +38,7 @@ func GetRandomEndgamePosition(w http.ResponseWriter, r *http.Request) {
 	theme := r.URL.Query().Get("theme")
 	if theme != "" {
 		// Validate theme is a recognized endgame theme
-		validThemes := map[string]bool{
-			"pawnEndgame":      true,
-			"rookEndgame":      true,
-			"bishopEndgame":    true,
-			"knightEndgame":    true,
-			"queenEndgame":     true,
-			"queenRookEndgame": true,
-			"basicMate":        true,
-			"opposition":       true,
-			"lucena":           true,
-			"philidor":         true,
-		}
-		if !validThemes[theme] {
+		if !chess.IsEndgameTheme(theme) {
 			httpx.WriteJSONError(w, http.StatusBadRequest, "invalid endgame theme")
 			return
 		}
@@ -68,7 +60,7 @@ func GetRandomEndgamePosition(w http.ResponseWriter, r *http.Request) {
 	// Default to true unless explicitly set to false, OR if theme is basicMate
 	// (basicMate positions are specifically K+piece vs lone K)
 	requireMaterial := r.URL.Query().Get("requireOpponentMaterial")
-	if theme == "basicMate" {
+	if theme == chess.ThemeBasicMate {
 		params.RequireOpponentMaterial = false
 	} else {
 		params.RequireOpponentMaterial = requireMaterial != "false"
@@ -76,12 +68,31 @@ func GetRandomEndgamePosition(w http.ResponseWriter, r *http.Request) {
 
 	// For knight and bishop endgames, require at least one pawn for the side to move
 	// A lone knight or bishop cannot force checkmate (K+N vs K and K+B vs K are draws)
-	if theme == "knightEndgame" || theme == "bishopEndgame" {
+	if theme == chess.ThemeKnightEndgame || theme == chess.ThemeBishopEndgame {
 		params.RequirePawnForSideToMove = true
 	}
 
+	// For a logged-in user, positions due for review come first; positions practised
+	// recently and not yet due are skipped while anything else is left
+	var pos *models.EndgamePosition
+	var err error
+	review := false
+	if userID, ok := middleware.UserIDFromContext(r.Context()); ok && userID != "" {
+		due := params
+		due.DueForUserID = userID
+		pos, err = database.GetRandomEndgamePosition(due)
+		review = pos != nil
+		if err == nil && pos == nil {
+			scheduled := params
+			scheduled.ExcludeScheduledFor = userID
+			pos, err = database.GetRandomEndgamePosition(scheduled)
+		}
+	}
+
 	// Get random position from database
-	pos, err := database.GetRandomEndgamePosition(params)
+	if err == nil && pos == nil {
+		pos, err = database.GetRandomEndgamePosition(params)
+	}
 	if err != nil {
 		logger.Error("Failed to get random endgame position",
 			logger.F("difficulty", r.URL.Query().Get("difficulty"),
@@ -100,21 +111,24 @@ func GetRandomEndgamePosition(w http.ResponseWriter, r *http.Request) {
 
 	// Build response matching frontend expectations
 	resp := models.EndgamePositionResponse{
-		PositionID:    pos.PositionID,
-		FEN:           pos.FEN,
-		InitialEval:   pos.InitialEval,
-		Theme:         primaryTheme,
-		Difficulty:    ratingToDifficulty(pos.Rating),
-		SolutionMoves: pos.Moves,
+		PositionID:     pos.PositionID,
+		FEN:            pos.FEN,
+		InitialEval:    pos.InitialEval,
+		Theme:          primaryTheme,
+		Difficulty:     models.RatingToDifficulty(pos.Rating),
+		SolutionMoves:  pos.Moves,
+		ExpectedResult: expectedResult(pos),
+		Review:         review,
 	}
 
 	httpx.WriteJSON(w, http.StatusOK, resp)
 }
 
-// GetEndgameThemes returns available endgame themes
+// GetEndgameThemes returns the endgame themes in use with their position counts,
+// most common first
 // GET /api/training/endgame/themes
 func GetEndgameThemes(w http.ResponseWriter, r *http.Request) {
-	themes, err := database.GetAvailableEndgameThemes()
+	themes, err := database.GetEndgameThemeCounts()
 	if err != nil {
 		logger.Error("Failed to get endgame themes", logger.F("error", err.Error()))
 		httpx.WriteJSONError(w, http.StatusInternalServerError, "Failed to get themes")
@@ -159,20 +173,95 @@ func GetEndgameStats(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// GetRandomMiddlegamePosition returns a random middlegame training position
+// GET /api/training/middlegame/random
+// Query params:
+//   - difficulty: 1-10 (maps to rating ranges)
+//   - theme: "attack", "defence", "pawnStructure" or "pieceActivity"
+//   - side: 'w' or 'b' (filter by side to move)
+//   - exclude: position ID to skip (avoids repeating the same position on restart)
+func GetRandomMiddlegamePosition(w http.ResponseWriter, r *http.Request) {
+	params := models.MiddlegameQueryParams{}
+
+	if diffStr := r.URL.Query().Get("difficulty"); diffStr != "" {
+		diff, err := strconv.Atoi(diffStr)
+		if err != nil || diff < 1 || diff > 10 {
+			httpx.WriteJSONError(w, http.StatusBadRequest, "difficulty must be between 1 and 10")
+			return
+		}
+		params.MinRating, params.MaxRating = models.DifficultyToRatingRange(diff)
+	}
+
+	theme := r.URL.Query().Get("theme")
+	if theme != "" && !models.IsMiddlegameTheme(theme) {
+		httpx.WriteJSONError(w, http.StatusBadRequest, "invalid middlegame theme")
+		return
+	}
+	params.Theme = theme
+
+	side := r.URL.Query().Get("side")
+	if side != "" && side != "w" && side != "b" {
+		httpx.WriteJSONError(w, http.StatusBadRequest, "side must be 'w' or 'b'")
+		return
+	}
+	params.Side = side
+
+	params.ExcludePositionID = r.URL.Query().Get("exclude")
+
+	pos, err := database.GetRandomMiddlegamePosition(params)
+	if err != nil {
+		logger.Error("Failed to get random middlegame position",
+			logger.F("difficulty", r.URL.Query().Get("difficulty"),
+				"theme", theme, "side", side, "error", err.Error()))
+		httpx.WriteJSONError(w, http.StatusInternalServerError, "Failed to get training position")
+		return
+	}
+
+	if pos == nil {
+		httpx.WriteJSONError(w, http.StatusNotFound, "No positions found matching criteria")
+		return
+	}
+
+	// Same response shape as the endgame endpoint so the frontend can share the flow
+	httpx.WriteJSON(w, http.StatusOK, models.EndgamePositionResponse{
+		PositionID:    pos.PositionID,
+		FEN:           pos.FEN,
+		InitialEval:   pos.InitialEval,
+		Theme:         getPrimaryMiddlegameTheme(pos.Themes),
+		Difficulty:    models.RatingToDifficulty(pos.Rating),
+		SolutionMoves: pos.Moves,
+	})
+}
+
+// GetMiddlegameThemes returns available middlegame themes
+// GET /api/training/middlegame/themes
+func GetMiddlegameThemes(w http.ResponseWriter, r *http.Request) {
+	themes, err := database.GetAvailableMiddlegameThemes()
+	if err != nil {
+		logger.Error("Failed to get middlegame themes", logger.F("error", err.Error()))
+		httpx.WriteJSONError(w, http.StatusInternalServerError, "Failed to get themes")
+		return
+	}
+
+	httpx.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"themes": themes,
+	})
+}
+
 // Helper: Get the most specific endgame theme from a list of themes
 func getPrimaryEndgameTheme(themes []string) string {
 	// Priority order: specific endgame types first
 	priority := []string{
-		"pawnEndgame",
-		"rookEndgame",
-		"bishopEndgame",
-		"knightEndgame",
-		"queenEndgame",
-		"queenRookEndgame",
-		"basicMate",
-		"opposition",
-		"lucena",
-		"philidor",
+		chess.ThemePawnEndgame,
+		chess.ThemeRookEndgame,
+		chess.ThemeBishopEndgame,
+		chess.ThemeKnightEndgame,
+		chess.ThemeQueenEndgame,
+		chess.ThemeQueenRookEndgame,
+		chess.ThemeBasicMate,
+		chess.ThemeOpposition,
+		chess.ThemeLucena,
+		chess.ThemePhilidor,
 	}
 
 	themeSet := make(map[string]bool)
@@ -192,28 +281,15 @@ func getPrimaryEndgameTheme(themes []string) string {
 	return ""
 }
 
-// Helper: Convert rating back to difficulty scale (1-10)
-func ratingToDifficulty(rating int) int {
-	switch {
-	case rating < 500:
-		return 1
-	case rating < 800:
-		return 2
-	case rating < 1100:
-		return 3
-	case rating < 1400:
-		return 4
-	case rating < 1700:
-		return 5
-	case rating < 2000:
-		return 6
-	case rating < 2300:
-		return 7
-	case rating < 2600:
-		return 8
-	case rating < 2800:
-		return 9
-	default:
-		return 10
+// Helper: Get the first recognised middlegame theme from a list of themes
+func getPrimaryMiddlegameTheme(themes []string) string {
+	for _, t := range themes {
+		if models.IsMiddlegameTheme(t) {
+			return t
+		}
 	}
+	if len(themes) > 0 {
+		return themes[0]
+	}
+	return ""
 }
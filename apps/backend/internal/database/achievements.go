@@ -6,6 +6,7 @@ import (
 
 	"github.com/tmcarmichael/nxtchess/apps/backend/internal/logger"
 	"github.com/tmcarmichael/nxtchess/apps/backend/internal/metrics"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/models"
 )
 
 type UserAchievement struct {
@@ -175,34 +176,31 @@ func UpdateWinStreak(userID string, won bool) (int, error) {
 	return newStreak, nil
 }
 
-func UpdatePuzzleStreak(userID string, solved bool) (int, error) {
-	defer metrics.ObserveQuery("UpdatePuzzleStreak", time.Now())
+// RefreshPuzzleStreak recomputes the user's current puzzle streak from puzzle_attempts
+// (solved attempts since the last failure), stores it on the profile and returns it
+func RefreshPuzzleStreak(userID string) (int, error) {
+	defer metrics.ObserveQuery("RefreshPuzzleStreak", time.Now())
 	ctx, cancel := QueryContext()
 	defer cancel()
 
-	var newStreak int
-	if solved {
-		err := DB.QueryRowContext(ctx,
-			`UPDATE profiles SET puzzle_streak = puzzle_streak + 1 WHERE user_id = $1 RETURNING puzzle_streak`,
-			userID,
-		).Scan(&newStreak)
-		if err != nil {
-			logger.Error("Error incrementing puzzle streak", logger.F("userID", userID, "error", err.Error()))
-			return 0, err
-		}
-	} else {
-		_, err := DB.ExecContext(ctx,
-			`UPDATE profiles SET puzzle_streak = 0 WHERE user_id = $1`, userID,
+	var streak int
+	err := DB.QueryRowContext(ctx, `
+		UPDATE profiles SET puzzle_streak = (
+			SELECT COUNT(*) FROM puzzle_attempts
+			WHERE user_id = $1 AND solved AND id > COALESCE(
+				(SELECT MAX(id) FROM puzzle_attempts WHERE user_id = $1 AND NOT solved), 0)
 		)
-		if err != nil {
-			logger.Error("Error resetting puzzle streak", logger.F("userID", userID, "error", err.Error()))
-			return 0, err
-		}
-		newStreak = 0
+		WHERE user_id = $1
+		RETURNING puzzle_streak
+	`, userID).Scan(&streak)
+	if err != nil {
+		logger.Error("Error refreshing puzzle streak", logger.F("userID", userID, "error", err.Error()))
+		return 0, err
 	}
-	return newStreak, nil
+	return streak, nil
 }
 
+// GetGamesPlayedCount counts the user's games against other accounts
 func GetGamesPlayedCount(userID string) (int, error) {
 	defer metrics.ObserveQuery("GetGamesPlayedCount", time.Now())
 	ctx, cancel := QueryContext()
@@ -210,7 +208,7 @@ func GetGamesPlayedCount(userID string) (int, error) {
 
 	var count int
 	err := DB.QueryRowContext(ctx,
-		`SELECT COUNT(*) FROM games WHERE "playerW_id" = $1 OR "playerB_id" = $1`, userID,
+		`SELECT COUNT(*) FROM games WHERE (playerW_id = $1 OR playerB_id = $1) AND `+playerGames, userID,
 	).Scan(&count)
 	if err != nil {
 		logger.Error("Error counting games played", logger.F("userID", userID, "error", err.Error()))
@@ -219,6 +217,104 @@ func GetGamesPlayedCount(userID string) (int, error) {
 	return count, nil
 }
 
+// ListPlayerIDs returns up to limit user IDs of human players that played at least
+// one game against another account, in ascending order after afterUserID. Bots earn
+// no achievements, so they are left out.
+func ListPlayerIDs(afterUserID string, limit int) ([]string, error) {
+	defer metrics.ObserveQuery("ListPlayerIDs", time.Now())
+	ctx, cancel := QueryContext()
+	defer cancel()
+
+	rows, err := DB.QueryContext(ctx, `
+		SELECT p.user_id FROM profiles p
+		WHERE p.user_id > $1
+		  AND NOT p.is_bot
+		  AND EXISTS (SELECT 1 FROM games g WHERE (g.playerW_id = p.user_id OR g.playerB_id = p.user_id)
+		              AND `+playerGames+`)
+		ORDER BY p.user_id
+		LIMIT $2
+	`, afterUserID, limit)
+	if err != nil {
+		logger.Error("Error listing players", logger.F("afterUserID", afterUserID, "error", err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// GetFinishedGamesByUserID returns the user's decided games against other accounts,
+// oldest first
+func GetFinishedGamesByUserID(userID string) ([]models.Game, error) {
+	defer metrics.ObserveQuery("GetFinishedGamesByUserID", time.Now())
+	ctx, cancel := QueryContextWithTimeout(30 * time.Second)
+	defer cancel()
+
+	rows, err := DB.QueryContext(ctx, `
+		SELECT game_id, pgn, COALESCE(playerW_id, ''), COALESCE(playerB_id, ''),
+		       COALESCE(playerW_start_rating, 0), COALESCE(playerB_start_rating, 0), result, created_at
+		FROM games
+		WHERE (playerW_id = $1 OR playerB_id = $1)
+		  AND result IS NOT NULL AND result <> '*'
+		  AND `+playerGames+`
+		ORDER BY created_at, game_id
+	`, userID)
+	if err != nil {
+		logger.Error("Error loading finished games", logger.F("userID", userID, "error", err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var games []models.Game
+	for rows.Next() {
+		var g models.Game
+		if err := rows.Scan(&g.GameID, &g.PGN, &g.PlayerWID, &g.PlayerBID,
+			&g.PlayerWStartRating, &g.PlayerBStartRating, &g.Result, &g.CreatedAt); err != nil {
+			return nil, err
+		}
+		games = append(games, g)
+	}
+	return games, rows.Err()
+}
+
+// GetRatingHistoryByUserID returns every rating the user held in pool, oldest first.
+// A game's rating change is stored in the same transaction as the game, so it shares
+// the game's created_at.
+func GetRatingHistoryByUserID(userID, pool string) ([]models.RatingPoint, error) {
+	defer metrics.ObserveQuery("GetRatingHistoryByUserID", time.Now())
+	ctx, cancel := QueryContextWithTimeout(30 * time.Second)
+	defer cancel()
+
+	rows, err := DB.QueryContext(ctx, `
+		SELECT rating, created_at FROM rating_history
+		WHERE user_id = $1 AND pool = $2
+		ORDER BY created_at, id
+	`, userID, pool)
+	if err != nil {
+		logger.Error("Error loading rating history", logger.F("userID", userID, "pool", pool, "error", err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var history []models.RatingPoint
+	for rows.Next() {
+		var p models.RatingPoint
+		if err := rows.Scan(&p.Rating, &p.CreatedAt); err != nil {
+			return nil, err
+		}
+		history = append(history, p)
+	}
+	return history, rows.Err()
+}
+
 func GetPuzzlesSolvedCount(userID string) (int, error) {
 	defer metrics.ObserveQuery("GetPuzzlesSolvedCount", time.Now())
 	ctx, cancel := QueryContext()
@@ -226,7 +322,7 @@ func GetPuzzlesSolvedCount(userID string) (int, error) {
 
 	var count int
 	err := DB.QueryRowContext(ctx,
-		`SELECT COUNT(*) FROM puzzle_rating_history WHERE user_id = $1`, userID,
+		`SELECT COUNT(*) FROM puzzle_attempts WHERE user_id = $1 AND solved`, userID,
 	).Scan(&count)
 	if err != nil {
 		logger.Error("Error counting puzzles solved", logger.F("userID", userID, "error", err.Error()))
@@ -250,3 +346,55 @@ func GetAchievementPoints(userID string) (int, error) {
 	}
 	return points, nil
 }
+
+// AchievementCounters are the per-user counters achievement progress is measured on
+type AchievementCounters struct {
+	Rating           int
+	PuzzleRating     int
+	TrainingRating   int
+	ComputerRating   int
+	WinStreak        int
+	PuzzleStreak     int
+	DailyStreak      int
+	MembershipDays   int
+	GamesPlayed      int
+	PuzzlesSolved    int
+	EndgameSuccesses int
+	BestSprintScore  int
+}
+
+// GetAchievementCounters loads every achievement counter of a user in one query, or
+// nil if the user has no profile. The daily streak only counts while the last solved
+// daily puzzle is from today (UTC) or the day before.
+func GetAchievementCounters(userID string) (*AchievementCounters, error) {
+	defer metrics.ObserveQuery("GetAchievementCounters", time.Now())
+	ctx, cancel := QueryContext()
+	defer cancel()
+
+	var c AchievementCounters
+	err := DB.QueryRowContext(ctx, `
+		SELECT
+			p.rating, p.puzzle_rating, p.training_rating, p.computer_rating, p.win_streak, p.puzzle_streak,
+			CASE WHEN p.last_daily_puzzle_solved >= $2::date - 1 THEN p.daily_puzzle_streak ELSE 0 END,
+			EXTRACT(DAY FROM now() - p.created_at)::int,
+			(SELECT COUNT(*) FROM games WHERE (playerW_id = $1 OR playerB_id = $1) AND `+playerGames+`),
+			(SELECT COUNT(*) FROM puzzle_attempts WHERE user_id = $1 AND solved),
+			(SELECT COUNT(DISTINCT position_id) FROM endgame_attempts
+			 WHERE user_id = $1 AND success AND outcome IS NOT NULL),
+			(SELECT COALESCE(MAX(score), 0) FROM puzzle_sprints WHERE user_id = $1)
+		FROM profiles p
+		WHERE p.user_id = $1
+	`, userID, dateParam(time.Now())).Scan(
+		&c.Rating, &c.PuzzleRating, &c.TrainingRating, &c.ComputerRating, &c.WinStreak, &c.PuzzleStreak,
+		&c.DailyStreak, &c.MembershipDays, &c.GamesPlayed, &c.PuzzlesSolved,
+		&c.EndgameSuccesses, &c.BestSprintScore,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		logger.Error("Error getting achievement counters", logger.F("userID", userID, "error", err.Error()))
+		return nil, err
+	}
+	return &c, nil
+}
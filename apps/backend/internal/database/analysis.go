@@ -0,0 +1,187 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/logger"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/metrics"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/models"
+)
+
+// CreateGameAnalysis queues a game for analysis. A game already queued or analyzed
+// is left as it is.
+func CreateGameAnalysis(gameID string) error {
+	defer metrics.ObserveQuery("CreateGameAnalysis", time.Now())
+	ctx, cancel := QueryContext()
+	defer cancel()
+
+	_, err := DB.ExecContext(ctx, `
+		INSERT INTO game_analysis (game_id) VALUES ($1)
+		ON CONFLICT (game_id) DO NOTHING
+	`, gameID)
+	if err != nil {
+		logger.Error("Error creating game analysis", logger.F("gameID", gameID, "error", err.Error()))
+		return err
+	}
+	return nil
+}
+
+// StartGameAnalysis marks a queued analysis as running
+func StartGameAnalysis(gameID string) error {
+	defer metrics.ObserveQuery("StartGameAnalysis", time.Now())
+	ctx, cancel := QueryContext()
+	defer cancel()
+
+	_, err := DB.ExecContext(ctx, `UPDATE game_analysis SET status = 'running' WHERE game_id = $1`, gameID)
+	if err != nil {
+		logger.Error("Error starting game analysis", logger.F("gameID", gameID, "error", err.Error()))
+		return err
+	}
+	return nil
+}
+
+// CompleteGameAnalysis stores the results of an analysis
+func CompleteGameAnalysis(a *models.GameAnalysis) error {
+	defer metrics.ObserveQuery("CompleteGameAnalysis", time.Now())
+	ctx, cancel := QueryContext()
+	defer cancel()
+
+	moves, err := json.Marshal(a.Moves)
+	if err != nil {
+		return err
+	}
+
+	_, err = DB.ExecContext(ctx, `
+		UPDATE game_analysis SET
+			status = 'done', depth = $2,
+			white_accuracy = $3, white_acpl = $4, white_inaccuracies = $5, white_mistakes = $6, white_blunders = $7,
+			black_accuracy = $8, black_acpl = $9, black_inaccuracies = $10, black_mistakes = $11, black_blunders = $12,
+			moves = $13, error = NULL, completed_at = now()
+		WHERE game_id = $1
+	`, a.GameID, a.Depth,
+		a.White.Accuracy, a.White.ACPL, a.White.Inaccuracies, a.White.Mistakes, a.White.Blunders,
+		a.Black.Accuracy, a.Black.ACPL, a.Black.Inaccuracies, a.Black.Mistakes, a.Black.Blunders,
+		moves,
+	)
+	if err != nil {
+		logger.Error("Error completing game analysis", logger.F("gameID", a.GameID, "error", err.Error()))
+		return err
+	}
+	return nil
+}
+
+// FailGameAnalysis records why an analysis could not be completed
+func FailGameAnalysis(gameID, reason string) error {
+	defer metrics.ObserveQuery("FailGameAnalysis", time.Now())
+	ctx, cancel := QueryContext()
+	defer cancel()
+
+	_, err := DB.ExecContext(ctx, `
+		UPDATE game_analysis SET status = 'failed', error = $2, completed_at = now()
+		WHERE game_id = $1
+	`, gameID, reason)
+	if err != nil {
+		logger.Error("Error failing game analysis", logger.F("gameID", gameID, "error", err.Error()))
+		return err
+	}
+	return nil
+}
+
+// GetGameAnalysis returns a game's analysis, or nil if it was never queued
+func GetGameAnalysis(gameID string) (*models.GameAnalysis, error) {
+	defer metrics.ObserveQuery("GetGameAnalysis", time.Now())
+	ctx, cancel := QueryContext()
+	defer cancel()
+
+	var (
+		a                                                          models.GameAnalysis
+		depth                                                      sql.NullInt32
+		whiteAccuracy, blackAccuracy                               sql.NullFloat64
+		whiteACPL, whiteInaccuracies, whiteMistakes, whiteBlunders sql.NullInt32
+		blackACPL, blackInaccuracies, blackMistakes, blackBlunders sql.NullInt32
+		moves                                                      []byte
+		errMsg                                                     sql.NullString
+		completedAt                                                sql.NullTime
+	)
+	err := DB.QueryRowContext(ctx, `
+		SELECT game_id, status, depth,
+			white_accuracy, white_acpl, white_inaccuracies, white_mistakes, white_blunders,
+			black_accuracy, black_acpl, black_inaccuracies, black_mistakes, black_blunders,
+			moves, error, created_at, completed_at
+		FROM game_analysis
+		WHERE game_id = $1
+	`, gameID).Scan(
+		&a.GameID, &a.Status, &depth,
+		&whiteAccuracy, &whiteACPL, &whiteInaccuracies, &whiteMistakes, &whiteBlunders,
+		&blackAccuracy, &blackACPL, &blackInaccuracies, &blackMistakes, &blackBlunders,
+		&moves, &errMsg, &a.CreatedAt, &completedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		logger.Error("Error getting game analysis", logger.F("gameID", gameID, "error", err.Error()))
+		return nil, err
+	}
+
+	a.Error = errMsg.String
+	if completedAt.Valid {
+		a.CompletedAt = &completedAt.Time
+	}
+	if a.Status != models.AnalysisDone {
+		return &a, nil
+	}
+
+	a.Depth = int(depth.Int32)
+	a.White = &models.PlayerAnalysis{
+		Accuracy:     whiteAccuracy.Float64,
+		ACPL:         int(whiteACPL.Int32),
+		Inaccuracies: int(whiteInaccuracies.Int32),
+		Mistakes:     int(whiteMistakes.Int32),
+		Blunders:     int(whiteBlunders.Int32),
+	}
+	a.Black = &models.PlayerAnalysis{
+		Accuracy:     blackAccuracy.Float64,
+		ACPL:         int(blackACPL.Int32),
+		Inaccuracies: int(blackInaccuracies.Int32),
+		Mistakes:     int(blackMistakes.Int32),
+		Blunders:     int(blackBlunders.Int32),
+	}
+	if len(moves) > 0 {
+		if err := json.Unmarshal(moves, &a.Moves); err != nil {
+			logger.Error("Error decoding game analysis moves", logger.F("gameID", gameID, "error", err.Error()))
+			return nil, err
+		}
+	}
+	return &a, nil
+}
+
+// GetUnfinishedGameAnalyses returns the games whose analysis is queued or was
+// interrupted, oldest first
+func GetUnfinishedGameAnalyses() ([]string, error) {
+	defer metrics.ObserveQuery("GetUnfinishedGameAnalyses", time.Now())
+	ctx, cancel := QueryContext()
+	defer cancel()
+
+	rows, err := DB.QueryContext(ctx, `
+		SELECT game_id FROM game_analysis
+		WHERE status IN ('pending', 'running')
+		ORDER BY created_at
+	`)
+	if err != nil {
+		logger.Error("Error getting unfinished game analyses", logger.F("error", err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
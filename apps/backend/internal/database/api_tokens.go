@@ -0,0 +1,144 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/logger"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/metrics"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/models"
+)
+
+// apiTokenTouchInterval limits how often use of a token is written to last_used_at
+const apiTokenTouchInterval = time.Minute
+
+// CreateAPIToken stores a new token for a user by its hash
+func CreateAPIToken(userID, name, hash, prefix string, scopes []string, expiresAt *time.Time) (*models.APIToken, error) {
+	defer metrics.ObserveQuery("CreateAPIToken", time.Now())
+	ctx, cancel := QueryContext()
+	defer cancel()
+
+	t := models.APIToken{Name: name, Prefix: prefix, Scopes: scopes, ExpiresAt: expiresAt}
+	err := DB.QueryRowContext(ctx, `
+		INSERT INTO api_tokens (user_id, name, token_hash, token_prefix, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING token_id, created_at
+	`, userID, name, hash, prefix, pq.Array(scopes), expiresAt).Scan(&t.TokenID, &t.CreatedAt)
+	if err != nil {
+		logger.Error("Error creating API token", logger.F("userID", userID, "error", err.Error()))
+		return nil, err
+	}
+	return &t, nil
+}
+
+// GetAPITokens returns the user's tokens that are not revoked, newest first.
+// Expired tokens are included so users can see why a tool stopped working.
+func GetAPITokens(userID string) ([]models.APIToken, error) {
+	defer metrics.ObserveQuery("GetAPITokens", time.Now())
+	ctx, cancel := QueryContext()
+	defer cancel()
+
+	rows, err := DB.QueryContext(ctx, `
+		SELECT token_id, name, token_prefix, scopes, created_at, expires_at, last_used_at
+		FROM api_tokens
+		WHERE user_id = $1 AND revoked_at IS NULL
+		ORDER BY created_at DESC
+	`, userID)
+	if err != nil {
+		logger.Error("Error getting API tokens", logger.F("userID", userID, "error", err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []models.APIToken{}
+	for rows.Next() {
+		var t models.APIToken
+		var scopes pq.StringArray
+		var expiresAt, lastUsedAt sql.NullTime
+		if err := rows.Scan(&t.TokenID, &t.Name, &t.Prefix, &scopes, &t.CreatedAt, &expiresAt, &lastUsedAt); err != nil {
+			return nil, err
+		}
+		t.Scopes = []string(scopes)
+		if expiresAt.Valid {
+			t.ExpiresAt = &expiresAt.Time
+		}
+		if lastUsedAt.Valid {
+			t.LastUsedAt = &lastUsedAt.Time
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens, rows.Err()
+}
+
+// RevokeAPIToken revokes one of the user's tokens. It reports false if the user has
+// no live token with that ID.
+func RevokeAPIToken(userID, tokenID string) (bool, error) {
+	defer metrics.ObserveQuery("RevokeAPIToken", time.Now())
+	ctx, cancel := QueryContext()
+	defer cancel()
+
+	res, err := DB.ExecContext(ctx, `
+		UPDATE api_tokens SET revoked_at = now()
+		WHERE token_id = $1 AND user_id = $2 AND revoked_at IS NULL
+	`, tokenID, userID)
+	if err != nil {
+		logger.Error("Error revoking API token", logger.F("userID", userID, "error", err.Error()))
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// RevokeUserAPITokens revokes every live token of the user, e.g. when they are
+// banned, and returns how many were revoked
+func RevokeUserAPITokens(userID string) (int, error) {
+	defer metrics.ObserveQuery("RevokeUserAPITokens", time.Now())
+	ctx, cancel := QueryContext()
+	defer cancel()
+
+	res, err := DB.ExecContext(ctx, `
+		UPDATE api_tokens SET revoked_at = now()
+		WHERE user_id = $1 AND revoked_at IS NULL
+	`, userID)
+	if err != nil {
+		logger.Error("Error revoking API tokens", logger.F("userID", userID, "error", err.Error()))
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// UseAPIToken returns the owner and scopes of the live token with hash and records
+// that it was used. userID is empty if there is no such token or it expired or was
+// revoked.
+func UseAPIToken(hash string) (userID string, scopes []string, err error) {
+	defer metrics.ObserveQuery("UseAPIToken", time.Now())
+	ctx, cancel := QueryContext()
+	defer cancel()
+
+	var tokenID string
+	var granted pq.StringArray
+	var lastUsedAt sql.NullTime
+	err = DB.QueryRowContext(ctx, `
+		SELECT token_id, user_id, scopes, last_used_at
+		FROM api_tokens
+		WHERE token_hash = $1
+			AND revoked_at IS NULL
+			AND (expires_at IS NULL OR expires_at > now())
+	`, hash).Scan(&tokenID, &userID, &granted, &lastUsedAt)
+	if err == sql.ErrNoRows {
+		return "", nil, nil
+	}
+	if err != nil {
+		logger.Error("Error looking up API token", logger.F("error", err.Error()))
+		return "", nil, err
+	}
+
+	if !lastUsedAt.Valid || time.Since(lastUsedAt.Time) > apiTokenTouchInterval {
+		if _, err := DB.ExecContext(ctx, `UPDATE api_tokens SET last_used_at = now() WHERE token_id = $1`, tokenID); err != nil {
+			logger.Warn("Error recording API token use", logger.F("tokenID", tokenID, "error", err.Error()))
+		}
+	}
+	return userID, []string(granted), nil
+}
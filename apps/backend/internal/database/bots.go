@@ -0,0 +1,55 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/logger"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/metrics"
+)
+
+// ErrBotHasGames is returned when upgrading an account that already played games.
+// Bots start with a clean history so their games never mix with a human's.
+var ErrBotHasGames = errors.New("accounts that played games cannot become bots")
+
+// IsBotAccount reports whether the user is a bot
+func IsBotAccount(userID string) (bool, error) {
+	defer metrics.ObserveQuery("IsBotAccount", time.Now())
+	ctx, cancel := QueryContext()
+	defer cancel()
+
+	var isBot bool
+	err := DB.QueryRowContext(ctx, `SELECT is_bot FROM profiles WHERE user_id = $1`, userID).Scan(&isBot)
+	if err == sql.ErrNoRows {
+		return false, nil
+	} else if err != nil {
+		logger.Error("Error checking bot account", logger.F("userID", userID, "error", err.Error()))
+		return false, err
+	}
+	return isBot, nil
+}
+
+// UpgradeToBot turns the user into a bot account. Upgrading is permanent and only
+// allowed before the account has played any game.
+func UpgradeToBot(userID string) error {
+	defer metrics.ObserveQuery("UpgradeToBot", time.Now())
+	ctx, cancel := QueryContext()
+	defer cancel()
+
+	res, err := DB.ExecContext(ctx, `
+		UPDATE profiles SET is_bot = true
+		WHERE user_id = $1
+			AND NOT EXISTS (SELECT 1 FROM games WHERE playerW_id = $1 OR playerB_id = $1)
+	`, userID)
+	if err != nil {
+		logger.Error("Error upgrading bot account", logger.F("userID", userID, "error", err.Error()))
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrBotHasGames
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package database
+
+import (
+	"time"
+
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/logger"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/metrics"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/models"
+)
+
+// ComputerGame is a finished game between a player and the computer
+type ComputerGame struct {
+	UserID         string
+	Color          string // the player's color, "white" or "black"
+	Level          int    // the computer's difficulty level
+	ComputerRating int    // the computer's rating at that level
+	Rated          bool
+	PGN            string
+	Result         string // "1-0", "0-1" or "1/2-1/2"
+	ECO            string // the opening's ECO code; empty when unclassified
+	Opening        string // and its name
+	OldRating      int    // the player's computer rating before the game
+	NewRating      int    // and after it; only stored for rated games
+}
+
+// FinalizeComputerGame stores a game against the computer, returning the stored
+// game's ID. The computer's side has no player. Rated games also update the player's
+// computer rating.
+func FinalizeComputerGame(g ComputerGame) (string, error) {
+	defer metrics.ObserveQuery("FinalizeComputerGame", time.Now())
+	ctx, cancel := QueryContext()
+	defer cancel()
+
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		logger.Error("Error starting computer game transaction", logger.F("error", err.Error()))
+		return "", err
+	}
+	defer tx.Rollback()
+
+	var whiteID, blackID *string
+	whiteRating, blackRating := g.OldRating, g.ComputerRating
+	if g.Color == "white" {
+		whiteID = &g.UserID
+	} else {
+		blackID = &g.UserID
+		whiteRating, blackRating = blackRating, whiteRating
+	}
+
+	var gameID string
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO games (pgn, playerW_id, playerB_id, stockfish_difficulty, playerW_start_rating, playerB_start_rating,
+		                   result, rating_pool, rated, eco, opening)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NULLIF($10, ''), NULLIF($11, ''))
+		RETURNING game_id
+	`, g.PGN, whiteID, blackID, g.Level, whiteRating, blackRating, g.Result, models.RatingPoolComputer, g.Rated,
+		g.ECO, g.Opening).Scan(&gameID)
+	if err != nil {
+		logger.Error("Error inserting computer game", logger.F("userID", g.UserID, "error", err.Error()))
+		return "", err
+	}
+
+	if g.Rated {
+		_, err = tx.ExecContext(ctx, `UPDATE profiles SET computer_rating = $1 WHERE user_id = $2`, g.NewRating, g.UserID)
+		if err != nil {
+			logger.Error("Error updating computer rating", logger.F("userID", g.UserID, "error", err.Error()))
+			return "", err
+		}
+
+		_, err = tx.ExecContext(ctx, `INSERT INTO rating_history (user_id, rating, pool) VALUES ($1, $2, $3)`,
+			g.UserID, g.NewRating, models.RatingPoolComputer)
+		if err != nil {
+			logger.Error("Error inserting computer rating history", logger.F("userID", g.UserID, "error", err.Error()))
+			return "", err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		logger.Error("Error committing computer game transaction", logger.F("error", err.Error()))
+		return "", err
+	}
+
+	return gameID, nil
+}
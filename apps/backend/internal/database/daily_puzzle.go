@@ -0,0 +1,201 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/logger"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/metrics"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/models"
+)
+
+// dailyPuzzlePool restricts the daily puzzle to well-tested puzzles of moderate
+// difficulty. When no puzzle qualifies (e.g., a small development database) the
+// whole puzzles table is used instead.
+const dailyPuzzlePool = `rating BETWEEN 1000 AND 2200 AND popularity >= 80 AND nb_plays >= 500`
+
+// GetDailyPuzzle returns the puzzle assigned to date, or nil if none is assigned yet
+func GetDailyPuzzle(date time.Time) (*models.Puzzle, error) {
+	defer metrics.ObserveQuery("GetDailyPuzzle", time.Now())
+	ctx, cancel := QueryContext()
+	defer cancel()
+
+	p, err := scanPuzzle(DB.QueryRowContext(ctx, `
+		SELECT `+puzzleColumns+`
+		FROM puzzles
+		WHERE puzzle_id = (SELECT puzzle_id FROM daily_puzzles WHERE puzzle_date = $1)
+	`, dateParam(date)))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		logger.Error("Error getting daily puzzle", logger.F("date", date.Format(models.DailyPuzzleDateLayout), "error", err.Error()))
+		return nil, err
+	}
+	return p, nil
+}
+
+// PickDailyPuzzleID returns the deterministic daily puzzle for date: the puzzle at
+// models.DailyPuzzleIndex in the pool ordered by puzzle ID. It returns "" when
+// there are no puzzles at all.
+func PickDailyPuzzleID(date time.Time) (string, error) {
+	defer metrics.ObserveQuery("PickDailyPuzzleID", time.Now())
+	ctx, cancel := QueryContext()
+	defer cancel()
+
+	pool := dailyPuzzlePool
+	var size int
+	if err := DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM puzzles WHERE `+pool).Scan(&size); err != nil {
+		logger.Error("Error counting daily puzzle pool", logger.F("error", err.Error()))
+		return "", err
+	}
+	if size == 0 {
+		pool = "TRUE"
+		if err := DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM puzzles`).Scan(&size); err != nil {
+			logger.Error("Error counting puzzles", logger.F("error", err.Error()))
+			return "", err
+		}
+		if size == 0 {
+			return "", nil
+		}
+	}
+
+	var puzzleID string
+	err := DB.QueryRowContext(ctx, `
+		SELECT puzzle_id FROM puzzles
+		WHERE `+pool+`
+		ORDER BY puzzle_id
+		OFFSET $1 LIMIT 1
+	`, models.DailyPuzzleIndex(date, size)).Scan(&puzzleID)
+	if err != nil {
+		logger.Error("Error picking daily puzzle", logger.F("error", err.Error()))
+		return "", err
+	}
+	return puzzleID, nil
+}
+
+// AssignDailyPuzzle fixes the puzzle for date. An automatic pick never replaces an
+// existing assignment, so concurrent first requests agree; an override always does.
+func AssignDailyPuzzle(date time.Time, puzzleID string, override bool) error {
+	defer metrics.ObserveQuery("AssignDailyPuzzle", time.Now())
+	ctx, cancel := QueryContext()
+	defer cancel()
+
+	query := `
+		INSERT INTO daily_puzzles (puzzle_date, puzzle_id, overridden)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (puzzle_date) DO NOTHING
+	`
+	if override {
+		query = `
+			INSERT INTO daily_puzzles (puzzle_date, puzzle_id, overridden)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (puzzle_date) DO UPDATE SET
+				puzzle_id = EXCLUDED.puzzle_id,
+				overridden = EXCLUDED.overridden,
+				created_at = now()
+		`
+	}
+
+	if _, err := DB.ExecContext(ctx, query, dateParam(date), puzzleID, override); err != nil {
+		logger.Error("Error assigning daily puzzle", logger.F(
+			"date", date.Format(models.DailyPuzzleDateLayout), "puzzleID", puzzleID, "error", err.Error()))
+		return err
+	}
+	return nil
+}
+
+// GetDailyPuzzleResult returns the user's result on the daily puzzle of date along
+// with their current daily streak. A streak only counts while the last solved daily
+// puzzle is from date or the day before.
+func GetDailyPuzzleResult(userID string, date time.Time) (models.DailyPuzzleResult, error) {
+	defer metrics.ObserveQuery("GetDailyPuzzleResult", time.Now())
+	ctx, cancel := QueryContext()
+	defer cancel()
+
+	var result models.DailyPuzzleResult
+	var solved sql.NullBool
+	err := DB.QueryRowContext(ctx, `
+		SELECT
+			CASE WHEN p.last_daily_puzzle_solved >= $2::date - 1 THEN p.daily_puzzle_streak ELSE 0 END,
+			r.solved
+		FROM profiles p
+		LEFT JOIN daily_puzzle_results r ON r.user_id = p.user_id AND r.puzzle_date = $2
+		WHERE p.user_id = $1
+	`, userID, dateParam(date)).Scan(&result.Streak, &solved)
+	if err == sql.ErrNoRows {
+		return result, nil
+	}
+	if err != nil {
+		logger.Error("Error getting daily puzzle result", logger.F("userID", userID, "error", err.Error()))
+		return result, err
+	}
+	result.Attempted = solved.Valid
+	result.Solved = solved.Bool
+	return result, nil
+}
+
+// RecordDailyPuzzleResult stores the user's first finished attempt on the daily
+// puzzle of date and updates their daily streak. Only the first attempt counts; it
+// returns recorded=false (and the unchanged result) for later attempts. Puzzles
+// older than the last one solved leave the streak as it is.
+func RecordDailyPuzzleResult(userID string, date time.Time, solved bool) (bool, models.DailyPuzzleResult, error) {
+	defer metrics.ObserveQuery("RecordDailyPuzzleResult", time.Now())
+	ctx, cancel := QueryContext()
+	defer cancel()
+
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		logger.Error("Error starting daily puzzle transaction", logger.F("error", err.Error()))
+		return false, models.DailyPuzzleResult{}, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO daily_puzzle_results (user_id, puzzle_date, solved)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, puzzle_date) DO NOTHING
+	`, userID, dateParam(date), solved)
+	if err != nil {
+		logger.Error("Error inserting daily puzzle result", logger.F("userID", userID, "error", err.Error()))
+		return false, models.DailyPuzzleResult{}, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		tx.Rollback()
+		result, err := GetDailyPuzzleResult(userID, date)
+		return false, result, err
+	}
+
+	var streak int
+	err = tx.QueryRowContext(ctx, `
+		UPDATE profiles SET
+			daily_puzzle_streak = CASE
+				WHEN last_daily_puzzle_solved >= $2::date THEN daily_puzzle_streak
+				WHEN NOT $3 THEN 0
+				WHEN last_daily_puzzle_solved = $2::date - 1 THEN daily_puzzle_streak + 1
+				ELSE 1
+			END,
+			last_daily_puzzle_solved = CASE
+				WHEN $3 THEN GREATEST(last_daily_puzzle_solved, $2::date)
+				ELSE last_daily_puzzle_solved
+			END
+		WHERE user_id = $1
+		RETURNING daily_puzzle_streak
+	`, userID, dateParam(date), solved).Scan(&streak)
+	if err != nil {
+		logger.Error("Error updating daily puzzle streak", logger.F("userID", userID, "error", err.Error()))
+		return false, models.DailyPuzzleResult{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		logger.Error("Error committing daily puzzle transaction", logger.F("error", err.Error()))
+		return false, models.DailyPuzzleResult{}, err
+	}
+
+	return true, models.DailyPuzzleResult{Attempted: true, Solved: solved, Streak: streak}, nil
+}
+
+// dateParam formats a date for a DATE column, independent of the session time zone
+func dateParam(date time.Time) string {
+	return models.DailyPuzzleDate(date).Format(models.DailyPuzzleDateLayout)
+}
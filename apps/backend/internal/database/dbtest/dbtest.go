@@ -0,0 +1,94 @@
+// Package dbtest provides a database/sql connection for tests that records the
+// statements run on it instead of running them. Queries return no rows and
+// statements affect none.
+package dbtest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"strings"
+	"sync"
+)
+
+// Statement is one statement run on a recorded connection
+type Statement struct {
+	SQL  string
+	Args []driver.Value
+}
+
+// Recorder collects the statements run on the connection returned by Open
+type Recorder struct {
+	mu         sync.Mutex
+	statements []Statement
+}
+
+// Open returns a connection that records to a new Recorder
+func Open() (*sql.DB, *Recorder) {
+	r := &Recorder{}
+	return sql.OpenDB(connector{r}), r
+}
+
+// Statements returns the statements run so far, oldest first
+func (r *Recorder) Statements() []Statement {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Statement(nil), r.statements...)
+}
+
+// Find returns the first statement whose SQL contains substr
+func (r *Recorder) Find(substr string) (Statement, bool) {
+	for _, s := range r.Statements() {
+		if strings.Contains(s.SQL, substr) {
+			return s, true
+		}
+	}
+	return Statement{}, false
+}
+
+func (r *Recorder) record(query string, args []driver.Value) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.statements = append(r.statements, Statement{SQL: query, Args: args})
+}
+
+type connector struct{ r *Recorder }
+
+func (c connector) Connect(context.Context) (driver.Conn, error) { return conn(c), nil }
+func (c connector) Driver() driver.Driver                        { return nil }
+
+type conn struct{ r *Recorder }
+
+func (c conn) Prepare(query string) (driver.Stmt, error) { return stmt{c.r, query}, nil }
+func (conn) Close() error                                { return nil }
+func (conn) Begin() (driver.Tx, error)                   { return tx{}, nil }
+
+type stmt struct {
+	r     *Recorder
+	query string
+}
+
+func (stmt) Close() error  { return nil }
+func (stmt) NumInput() int { return -1 }
+
+func (s stmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.r.record(s.query, args)
+	return driver.RowsAffected(0), nil
+}
+
+func (s stmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.r.record(s.query, args)
+	return rows{}, nil
+}
+
+type tx struct{}
+
+func (tx) Commit() error   { return nil }
+func (tx) Rollback() error { return nil }
+
+type rows struct{}
+
+func (rows) Columns() []string         { return nil }
+func (rows) Close() error              { return nil }
+func (rows) Next([]driver.Value) error { return io.EOF }
@@ -12,37 +12,27 @@ import (
 	"github.com/tmcarmichael/nxtchess/apps/backend/internal/models"
 )
 
-// GetRandomEndgamePosition retrieves a random endgame position matching the given criteria
-func GetRandomEndgamePosition(params models.EndgameQueryParams) (*models.EndgamePosition, error) {
-	defer metrics.ObserveQuery("GetRandomEndgamePosition", time.Now())
-	ctx, cancel := QueryContext()
-	defer cancel()
-
-	// Build dynamic query with filters
-	query := `
-		SELECT position_id, fen, COALESCE(moves, ''), rating, themes,
-		       initial_eval, COALESCE(description, ''), COALESCE(source, ''), created_at
-		FROM endgame_positions
-		WHERE 1=1
-	`
+// endgameFilters builds the WHERE clause shared by the endgame position queries
+func endgameFilters(params models.EndgameQueryParams) (string, []interface{}) {
+	where := " WHERE 1=1"
 	args := []interface{}{}
 	argIndex := 1
 
 	// Rating range filter
 	if params.MinRating > 0 {
-		query += fmt.Sprintf(" AND rating >= $%d", argIndex)
+		where += fmt.Sprintf(" AND rating >= $%d", argIndex)
 		args = append(args, params.MinRating)
 		argIndex++
 	}
 	if params.MaxRating > 0 {
-		query += fmt.Sprintf(" AND rating <= $%d", argIndex)
+		where += fmt.Sprintf(" AND rating <= $%d", argIndex)
 		args = append(args, params.MaxRating)
 		argIndex++
 	}
 
 	// Theme filter (array contains)
 	if params.Theme != "" {
-		query += fmt.Sprintf(" AND $%d = ANY(themes)", argIndex)
+		where += fmt.Sprintf(" AND $%d = ANY(themes)", argIndex)
 		args = append(args, params.Theme)
 		argIndex++
 	}
@@ -51,23 +41,38 @@ func GetRandomEndgamePosition(params models.EndgameQueryParams) (*models.Endgame
 	if params.Side == "w" || params.Side == "b" {
 		// FEN format: position turn castling en-passant halfmove fullmove
 		// Check if the second field is 'w' or 'b'
-		query += fmt.Sprintf(" AND split_part(fen, ' ', 2) = $%d", argIndex)
+		where += fmt.Sprintf(" AND split_part(fen, ' ', 2) = $%d", argIndex)
 		args = append(args, params.Side)
 		argIndex++
 	}
 
 	// Exclude specific position (to avoid repeats on restart)
 	if params.ExcludePositionID != "" {
-		query += fmt.Sprintf(" AND position_id != $%d", argIndex)
+		where += fmt.Sprintf(" AND position_id != $%d", argIndex)
 		args = append(args, params.ExcludePositionID)
 		argIndex++
 	}
 
+	// Spaced repetition: only positions due for review, or skip those not due yet
+	if params.DueForUserID != "" {
+		where += fmt.Sprintf(` AND position_id IN (
+			SELECT position_id FROM endgame_reviews WHERE user_id = $%d AND due_at <= now()
+		)`, argIndex)
+		args = append(args, params.DueForUserID)
+		argIndex++
+	}
+	if params.ExcludeScheduledFor != "" {
+		where += fmt.Sprintf(` AND position_id NOT IN (
+			SELECT position_id FROM endgame_reviews WHERE user_id = $%d AND due_at > now()
+		)`, argIndex)
+		args = append(args, params.ExcludeScheduledFor)
+	}
+
 	// Filter out positions where opponent has only king (no pieces/pawns)
 	// If side='w', opponent is black - check for lowercase pieces (qrbnp)
 	// If side='b', opponent is white - check for uppercase pieces (QRBNP)
 	if params.RequireOpponentMaterial {
-		query += ` AND (
+		where += ` AND (
 			(split_part(fen, ' ', 2) = 'w' AND split_part(fen, ' ', 1) ~ '[qrbnp]')
 			OR
 			(split_part(fen, ' ', 2) = 'b' AND split_part(fen, ' ', 1) ~ '[QRBNP]')
@@ -78,34 +83,26 @@ func GetRandomEndgamePosition(params models.EndgameQueryParams) (*models.Endgame
 	// If side='w', check for uppercase P (white pawn)
 	// If side='b', check for lowercase p (black pawn)
 	if params.RequirePawnForSideToMove {
-		query += ` AND (
+		where += ` AND (
 			(split_part(fen, ' ', 2) = 'w' AND split_part(fen, ' ', 1) ~ '[P]')
 			OR
 			(split_part(fen, ' ', 2) = 'b' AND split_part(fen, ' ', 1) ~ '[p]')
 		)`
 	}
 
-	// Order by random and limit to 1
-	query += " ORDER BY RANDOM() LIMIT 1"
-
-	row := DB.QueryRowContext(ctx, query, args...)
+	return where, args
+}
 
-	pos := &models.EndgamePosition{}
-	var initialEval sql.NullInt32
-	var themes pq.StringArray
+// GetRandomEndgamePosition retrieves a random endgame position matching the given criteria
+func GetRandomEndgamePosition(params models.EndgameQueryParams) (*models.EndgamePosition, error) {
+	defer metrics.ObserveQuery("GetRandomEndgamePosition", time.Now())
+	ctx, cancel := QueryContext()
+	defer cancel()
 
-	err := row.Scan(
-		&pos.PositionID,
-		&pos.FEN,
-		&pos.Moves,
-		&pos.Rating,
-		&themes,
-		&initialEval,
-		&pos.Description,
-		&pos.Source,
-		&pos.CreatedAt,
-	)
+	where, args := endgameFilters(params)
+	query := `SELECT ` + endgameColumns + ` FROM endgame_positions` + where + ` ORDER BY RANDOM() LIMIT 1`
 
+	pos, err := scanEndgamePosition(DB.QueryRowContext(ctx, query, args...))
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -116,12 +113,6 @@ func GetRandomEndgamePosition(params models.EndgameQueryParams) (*models.Endgame
 		return nil, err
 	}
 
-	pos.Themes = []string(themes)
-	if initialEval.Valid {
-		eval := int(initialEval.Int32)
-		pos.InitialEval = &eval
-	}
-
 	return pos, nil
 }
 
@@ -131,47 +122,10 @@ func GetEndgamePositionCount(params models.EndgameQueryParams) (int, error) {
 	ctx, cancel := QueryContext()
 	defer cancel()
 
-	query := `SELECT COUNT(*) FROM endgame_positions WHERE 1=1`
-	args := []interface{}{}
-	argIndex := 1
-
-	if params.MinRating > 0 {
-		query += fmt.Sprintf(" AND rating >= $%d", argIndex)
-		args = append(args, params.MinRating)
-		argIndex++
-	}
-	if params.MaxRating > 0 {
-		query += fmt.Sprintf(" AND rating <= $%d", argIndex)
-		args = append(args, params.MaxRating)
-		argIndex++
-	}
-	if params.Theme != "" {
-		query += fmt.Sprintf(" AND $%d = ANY(themes)", argIndex)
-		args = append(args, params.Theme)
-		argIndex++
-	}
-	if params.Side == "w" || params.Side == "b" {
-		query += fmt.Sprintf(" AND split_part(fen, ' ', 2) = $%d", argIndex)
-		args = append(args, params.Side)
-		argIndex++
-	}
-	if params.RequireOpponentMaterial {
-		query += ` AND (
-			(split_part(fen, ' ', 2) = 'w' AND split_part(fen, ' ', 1) ~ '[qrbnp]')
-			OR
-			(split_part(fen, ' ', 2) = 'b' AND split_part(fen, ' ', 1) ~ '[QRBNP]')
-		)`
-	}
-	if params.RequirePawnForSideToMove {
-		query += ` AND (
-			(split_part(fen, ' ', 2) = 'w' AND split_part(fen, ' ', 1) ~ '[P]')
-			OR
-			(split_part(fen, ' ', 2) = 'b' AND split_part(fen, ' ', 1) ~ '[p]')
-		)`
-	}
+	where, args := endgameFilters(params)
 
 	var count int
-	err := DB.QueryRowContext(ctx, query, args...).Scan(&count)
+	err := DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM endgame_positions`+where, args...).Scan(&count)
 	if err != nil {
 		logger.Error("Error counting endgame positions", logger.F("error", err.Error()))
 		return 0, err
@@ -193,13 +147,13 @@ func BulkInsertEndgamePositions(positions []models.EndgamePosition) error {
 
 	// Build bulk insert query
 	valueStrings := make([]string, 0, len(positions))
-	valueArgs := make([]interface{}, 0, len(positions)*8)
+	valueArgs := make([]interface{}, 0, len(positions)*9)
 
 	for i, pos := range positions {
-		base := i * 8
+		base := i * 9
 		valueStrings = append(valueStrings, fmt.Sprintf(
-			"($%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d)",
-			base+1, base+2, base+3, base+4, base+5, base+6, base+7, base+8,
+			"($%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, NULLIF($%d, ''))",
+			base+1, base+2, base+3, base+4, base+5, base+6, base+7, base+8, base+9,
 		))
 		valueArgs = append(valueArgs,
 			pos.PositionID,
@@ -210,12 +164,13 @@ func BulkInsertEndgamePositions(positions []models.EndgamePosition) error {
 			pos.InitialEval,
 			pos.Description,
 			pos.Source,
+			pos.ExpectedResult,
 		)
 	}
 
 	query := fmt.Sprintf(`
 		INSERT INTO endgame_positions
-			(position_id, fen, moves, rating, themes, initial_eval, description, source)
+			(position_id, fen, moves, rating, themes, initial_eval, description, source, expected_result)
 		VALUES %s
 		ON CONFLICT (position_id) DO UPDATE SET
 			fen = EXCLUDED.fen,
@@ -224,7 +179,8 @@ func BulkInsertEndgamePositions(positions []models.EndgamePosition) error {
 			themes = EXCLUDED.themes,
 			initial_eval = EXCLUDED.initial_eval,
 			description = EXCLUDED.description,
-			source = EXCLUDED.source
+			source = EXCLUDED.source,
+			expected_result = EXCLUDED.expected_result
 	`, strings.Join(valueStrings, ","))
 
 	_, err := DB.ExecContext(ctx, query, valueArgs...)
@@ -237,33 +193,133 @@ func BulkInsertEndgamePositions(positions []models.EndgamePosition) error {
 	return nil
 }
 
-// GetAvailableEndgameThemes returns the list of distinct themes in the database
-func GetAvailableEndgameThemes() ([]string, error) {
-	defer metrics.ObserveQuery("GetAvailableEndgameThemes", time.Now())
+// GetEndgameThemeCounts returns the number of endgame positions tagged with each theme
+func GetEndgameThemeCounts() ([]models.ThemeCount, error) {
+	defer metrics.ObserveQuery("GetEndgameThemeCounts", time.Now())
 	ctx, cancel := QueryContext()
 	defer cancel()
 
-	query := `
-		SELECT DISTINCT unnest(themes) as theme
-		FROM endgame_positions
-		ORDER BY theme
-	`
+	rows, err := DB.QueryContext(ctx, `
+		SELECT theme, COUNT(*)
+		FROM endgame_positions, unnest(themes) AS theme
+		GROUP BY theme
+		ORDER BY COUNT(*) DESC, theme
+	`)
+	if err != nil {
+		logger.Error("Error counting endgame positions by theme", logger.F("error", err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := []models.ThemeCount{}
+	for rows.Next() {
+		var c models.ThemeCount
+		if err := rows.Scan(&c.Theme, &c.Count); err != nil {
+			return nil, err
+		}
+		counts = append(counts, c)
+	}
+
+	return counts, rows.Err()
+}
+
+// UpdateEndgameClassification stores re-derived themes and rating for a position
+func UpdateEndgameClassification(positionID string, themes []string, rating int) error {
+	defer metrics.ObserveQuery("UpdateEndgameClassification", time.Now())
+	ctx, cancel := QueryContext()
+	defer cancel()
+
+	_, err := DB.ExecContext(ctx,
+		`UPDATE endgame_positions SET themes = $2, rating = $3 WHERE position_id = $1`,
+		positionID, pq.Array(themes), rating)
+	if err != nil {
+		logger.Error("Error updating endgame classification", logger.F("positionID", positionID, "error", err.Error()))
+		return err
+	}
+	return nil
+}
+
+// ListEndgamePositions returns up to limit positions ordered by ID, starting after
+// afterID (empty for the first page). Used by import/export scripts.
+func ListEndgamePositions(afterID string, limit int) ([]models.EndgamePosition, error) {
+	defer metrics.ObserveQuery("ListEndgamePositions", time.Now())
+	ctx, cancel := QueryContext()
+	defer cancel()
 
-	rows, err := DB.QueryContext(ctx, query)
+	rows, err := DB.QueryContext(ctx,
+		`SELECT `+endgameColumns+` FROM endgame_positions WHERE position_id > $1 ORDER BY position_id LIMIT $2`,
+		afterID, limit)
 	if err != nil {
-		logger.Error("Error getting available endgame themes", logger.F("error", err.Error()))
+		logger.Error("Error listing endgame positions", logger.F("afterID", afterID, "error", err.Error()))
 		return nil, err
 	}
 	defer rows.Close()
 
-	themes := []string{}
+	positions := []models.EndgamePosition{}
 	for rows.Next() {
-		var theme string
-		if err := rows.Scan(&theme); err != nil {
+		pos, err := scanEndgamePosition(rows)
+		if err != nil {
 			return nil, err
 		}
-		themes = append(themes, theme)
+		positions = append(positions, *pos)
 	}
 
-	return themes, rows.Err()
+	return positions, rows.Err()
+}
+
+// GetEndgamePositionByID retrieves one endgame position, or nil if it does not exist
+func GetEndgamePositionByID(positionID string) (*models.EndgamePosition, error) {
+	defer metrics.ObserveQuery("GetEndgamePositionByID", time.Now())
+	ctx, cancel := QueryContext()
+	defer cancel()
+
+	pos, err := scanEndgamePosition(DB.QueryRowContext(ctx,
+		`SELECT `+endgameColumns+` FROM endgame_positions WHERE position_id = $1`, positionID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		logger.Error("Error getting endgame position", logger.F("positionID", positionID, "error", err.Error()))
+		return nil, err
+	}
+
+	return pos, nil
+}
+
+// endgameColumns is the column list read by scanEndgamePosition
+const endgameColumns = `position_id, fen, COALESCE(moves, ''), rating, themes, initial_eval,
+	COALESCE(description, ''), COALESCE(source, ''), COALESCE(expected_result, ''), created_at`
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanEndgamePosition(row rowScanner) (*models.EndgamePosition, error) {
+	pos := &models.EndgamePosition{}
+	var initialEval sql.NullInt32
+	var themes pq.StringArray
+
+	err := row.Scan(
+		&pos.PositionID,
+		&pos.FEN,
+		&pos.Moves,
+		&pos.Rating,
+		&themes,
+		&initialEval,
+		&pos.Description,
+		&pos.Source,
+		&pos.ExpectedResult,
+		&pos.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	pos.Themes = []string(themes)
+	if initialEval.Valid {
+		eval := int(initialEval.Int32)
+		pos.InitialEval = &eval
+	}
+	return pos, nil
 }
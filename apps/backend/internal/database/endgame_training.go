@@ -0,0 +1,241 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/logger"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/metrics"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/models"
+)
+
+// RecordEndgameAttempt stores an attempt and reschedules the user's review of the
+// position with the attempt graded quality (SM-2, 0-5). A rated attempt also sets the
+// user's training rating. It returns the new review state.
+func RecordEndgameAttempt(attempt models.EndgameAttempt, quality int) (models.EndgameReview, error) {
+	defer metrics.ObserveQuery("RecordEndgameAttempt", time.Now())
+	ctx, cancel := QueryContext()
+	defer cancel()
+
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		logger.Error("Error starting endgame attempt transaction", logger.F("error", err.Error()))
+		return models.EndgameReview{}, err
+	}
+	defer tx.Rollback()
+
+	var ratingBefore, ratingAfter sql.NullInt32
+	if attempt.Rated {
+		ratingBefore = sql.NullInt32{Int32: int32(attempt.RatingBefore), Valid: true}
+		ratingAfter = sql.NullInt32{Int32: int32(attempt.RatingAfter), Valid: true}
+	}
+	moves := attempt.Moves
+	if moves == nil {
+		moves = []string{}
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO endgame_attempts
+			(user_id, position_id, success, time_ms, moves, outcome, rating_before, rating_after)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7, $8)
+	`, attempt.UserID, attempt.PositionID, attempt.Success, attempt.TimeMs, pq.Array(moves),
+		attempt.Outcome, ratingBefore, ratingAfter)
+	if err != nil {
+		logger.Error("Error inserting endgame attempt", logger.F(
+			"userID", attempt.UserID, "positionID", attempt.PositionID, "error", err.Error()))
+		return models.EndgameReview{}, err
+	}
+
+	if attempt.Rated {
+		_, err = tx.ExecContext(ctx, `UPDATE profiles SET training_rating = $1 WHERE user_id = $2`,
+			attempt.RatingAfter, attempt.UserID)
+		if err != nil {
+			logger.Error("Error updating training rating", logger.F("userID", attempt.UserID, "error", err.Error()))
+			return models.EndgameReview{}, err
+		}
+	}
+
+	review := models.NewEndgameReview(attempt.PositionID)
+	err = tx.QueryRowContext(ctx, `
+		SELECT ease_factor, interval_days, repetitions, lapses, due_at, last_reviewed_at
+		FROM endgame_reviews
+		WHERE user_id = $1 AND position_id = $2
+		FOR UPDATE
+	`, attempt.UserID, attempt.PositionID).Scan(
+		&review.EaseFactor,
+		&review.IntervalDays,
+		&review.Repetitions,
+		&review.Lapses,
+		&review.DueAt,
+		&review.LastReviewedAt,
+	)
+	if err != nil && err != sql.ErrNoRows {
+		logger.Error("Error getting endgame review", logger.F(
+			"userID", attempt.UserID, "positionID", attempt.PositionID, "error", err.Error()))
+		return models.EndgameReview{}, err
+	}
+
+	review = review.Schedule(quality, time.Now())
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO endgame_reviews
+			(user_id, position_id, ease_factor, interval_days, repetitions, lapses, due_at, last_reviewed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (user_id, position_id) DO UPDATE SET
+			ease_factor = EXCLUDED.ease_factor,
+			interval_days = EXCLUDED.interval_days,
+			repetitions = EXCLUDED.repetitions,
+			lapses = EXCLUDED.lapses,
+			due_at = EXCLUDED.due_at,
+			last_reviewed_at = EXCLUDED.last_reviewed_at
+	`, attempt.UserID, attempt.PositionID, review.EaseFactor, review.IntervalDays,
+		review.Repetitions, review.Lapses, review.DueAt, review.LastReviewedAt)
+	if err != nil {
+		logger.Error("Error updating endgame review", logger.F(
+			"userID", attempt.UserID, "positionID", attempt.PositionID, "error", err.Error()))
+		return models.EndgameReview{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		logger.Error("Error committing endgame attempt transaction", logger.F("error", err.Error()))
+		return models.EndgameReview{}, err
+	}
+
+	return review, nil
+}
+
+// GetEndgameProgress returns the user's record on every endgame position they attempted
+func GetEndgameProgress(userID string) ([]models.EndgamePositionProgress, error) {
+	defer metrics.ObserveQuery("GetEndgameProgress", time.Now())
+	ctx, cancel := QueryContext()
+	defer cancel()
+
+	rows, err := DB.QueryContext(ctx, `
+		SELECT p.position_id, p.rating, p.themes,
+		       COUNT(a.id), COUNT(a.id) FILTER (WHERE a.success),
+		       r.ease_factor, r.interval_days, r.repetitions, r.lapses, r.due_at, r.last_reviewed_at
+		FROM endgame_reviews r
+		JOIN endgame_positions p ON p.position_id = r.position_id
+		LEFT JOIN endgame_attempts a ON a.user_id = r.user_id AND a.position_id = r.position_id
+		WHERE r.user_id = $1
+		GROUP BY p.position_id, r.user_id, r.position_id
+	`, userID)
+	if err != nil {
+		logger.Error("Error getting endgame progress", logger.F("userID", userID, "error", err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	progress := []models.EndgamePositionProgress{}
+	for rows.Next() {
+		var p models.EndgamePositionProgress
+		var themes pq.StringArray
+		if err := rows.Scan(
+			&p.PositionID, &p.Rating, &themes,
+			&p.Attempts, &p.Successes,
+			&p.Review.EaseFactor, &p.Review.IntervalDays, &p.Review.Repetitions,
+			&p.Review.Lapses, &p.Review.DueAt, &p.Review.LastReviewedAt,
+		); err != nil {
+			logger.Error("Error scanning endgame progress", logger.F("userID", userID, "error", err.Error()))
+			return nil, err
+		}
+		p.Themes = []string(themes)
+		p.Review.PositionID = p.PositionID
+		progress = append(progress, p)
+	}
+
+	return progress, rows.Err()
+}
+
+// GetEndgamePositionTotals returns the number of endgame positions per theme and per rating
+func GetEndgamePositionTotals() (map[string]int, map[int]int, error) {
+	defer metrics.ObserveQuery("GetEndgamePositionTotals", time.Now())
+	ctx, cancel := QueryContext()
+	defer cancel()
+
+	counts, err := GetEndgameThemeCounts()
+	if err != nil {
+		return nil, nil, err
+	}
+	themeTotals := make(map[string]int, len(counts))
+	for _, c := range counts {
+		themeTotals[c.Theme] = c.Count
+	}
+
+	ratingTotals := make(map[int]int)
+	rows, err := DB.QueryContext(ctx, `SELECT rating, COUNT(*) FROM endgame_positions GROUP BY rating`)
+	if err != nil {
+		logger.Error("Error counting endgame positions by rating", logger.F("error", err.Error()))
+		return nil, nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var rating, n int
+		if err := rows.Scan(&rating, &n); err != nil {
+			return nil, nil, err
+		}
+		ratingTotals[rating] = n
+	}
+
+	return themeTotals, ratingTotals, rows.Err()
+}
+
+// GetTrainingRating returns the user's training rating and number of rated training attempts
+func GetTrainingRating(userID string) (int, int, error) {
+	defer metrics.ObserveQuery("GetTrainingRating", time.Now())
+	ctx, cancel := QueryContext()
+	defer cancel()
+
+	var rating, rated int
+	err := DB.QueryRowContext(ctx, `
+		SELECT p.training_rating,
+		       (SELECT COUNT(*) FROM endgame_attempts a WHERE a.user_id = p.user_id AND a.rating_after IS NOT NULL)
+		FROM profiles p
+		WHERE p.user_id = $1
+	`, userID).Scan(&rating, &rated)
+	if err != nil {
+		logger.Error("Error getting training rating", logger.F("userID", userID, "error", err.Error()))
+		return 0, 0, err
+	}
+	return rating, rated, nil
+}
+
+// HasRecentEndgameAttempt reports whether the user attempted the position after since
+func HasRecentEndgameAttempt(userID, positionID string, since time.Time) (bool, error) {
+	defer metrics.ObserveQuery("HasRecentEndgameAttempt", time.Now())
+	ctx, cancel := QueryContext()
+	defer cancel()
+
+	var exists bool
+	err := DB.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM endgame_attempts
+			WHERE user_id = $1 AND position_id = $2 AND created_at > $3
+		)
+	`, userID, positionID, since).Scan(&exists)
+	if err != nil {
+		logger.Error("Error checking recent endgame attempt",
+			logger.F("userID", userID, "positionID", positionID, "error", err.Error()))
+		return false, err
+	}
+	return exists, nil
+}
+
+// GetEndgameSuccessCount returns the number of distinct endgame positions the user
+// succeeded in with a server-judged attempt
+func GetEndgameSuccessCount(userID string) (int, error) {
+	defer metrics.ObserveQuery("GetEndgameSuccessCount", time.Now())
+	ctx, cancel := QueryContext()
+	defer cancel()
+
+	var count int
+	err := DB.QueryRowContext(ctx, `
+		SELECT COUNT(DISTINCT position_id) FROM endgame_attempts
+		WHERE user_id = $1 AND success AND outcome IS NOT NULL
+	`, userID).Scan(&count)
+	if err != nil {
+		logger.Error("Error counting endgame successes", logger.F("userID", userID, "error", err.Error()))
+		return 0, err
+	}
+	return count, nil
+}
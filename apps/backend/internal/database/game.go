@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"time"
 
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/logger"
 	"github.com/tmcarmichael/nxtchess/apps/backend/internal/metrics"
 	"github.com/tmcarmichael/nxtchess/apps/backend/internal/models"
 )
@@ -31,7 +32,7 @@ func CreateGame(g *models.Game) error {
 func GetGameByID(id string) (*models.Game, error) {
     defer metrics.ObserveQuery("GetGameByID", time.Now())
     row := DB.QueryRow(`
-        SELECT game_id, pgn, playerW_id, playerB_id, stockfish_difficulty,
+        SELECT game_id, pgn, COALESCE(playerW_id, ''), COALESCE(playerB_id, ''), stockfish_difficulty,
                playerW_start_rating, playerB_start_rating, created_at
         FROM games
         WHERE game_id = $1
@@ -63,3 +64,71 @@ func GetGameByID(id string) (*models.Game, error) {
 
     return &game, nil
 }
+
+// GetGameDetail returns a finished game with its players' usernames, or nil if
+// there is no such game
+func GetGameDetail(id string) (*models.GameDetail, error) {
+    defer metrics.ObserveQuery("GetGameDetail", time.Now())
+    ctx, cancel := QueryContext()
+    defer cancel()
+
+    var (
+        game    models.GameDetail
+        level   sql.NullInt32
+        eco     sql.NullString
+        opening sql.NullString
+    )
+    err := DB.QueryRowContext(ctx, `
+        SELECT
+            g.game_id,
+            CASE
+                WHEN g.playerW_id IS NULL AND g.stockfish_difficulty IS NOT NULL THEN 'Computer'
+                ELSE COALESCE(pw.username, 'Unknown')
+            END,
+            CASE
+                WHEN g.playerB_id IS NULL AND g.stockfish_difficulty IS NOT NULL THEN 'Computer'
+                ELSE COALESCE(pb.username, 'Unknown')
+            END,
+            COALESCE(g.playerW_start_rating, 0),
+            COALESCE(g.playerB_start_rating, 0),
+            g.result,
+            COALESCE(g.pgn, ''),
+            g.rating_pool,
+            g.rated,
+            g.stockfish_difficulty,
+            g.eco,
+            g.opening,
+            g.created_at
+        FROM games g
+        LEFT JOIN profiles pw ON g.playerW_id = pw.user_id
+        LEFT JOIN profiles pb ON g.playerB_id = pb.user_id
+        WHERE g.game_id = $1
+            AND g.result IS NOT NULL
+            AND g.result != '*'
+    `, id).Scan(
+        &game.GameID,
+        &game.White,
+        &game.Black,
+        &game.WhiteRating,
+        &game.BlackRating,
+        &game.Result,
+        &game.PGN,
+        &game.RatingPool,
+        &game.Rated,
+        &level,
+        &eco,
+        &opening,
+        &game.CreatedAt,
+    )
+    if err == sql.ErrNoRows {
+        return nil, nil
+    } else if err != nil {
+        logger.Error("Error getting game detail", logger.F("gameID", id, "error", err.Error()))
+        return nil, err
+    }
+
+    game.ComputerLevel = computerLevel(level)
+    game.ECO = eco.String
+    game.Opening = opening.String
+    return &game, nil
+}
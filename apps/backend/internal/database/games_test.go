@@ -0,0 +1,48 @@
+package database
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/database/dbtest"
+)
+
+// recordQueries runs fn against a recording connection in place of DB
+func recordQueries(t *testing.T, fn func()) []dbtest.Statement {
+	t.Helper()
+	db, rec := dbtest.Open()
+	prev := DB
+	DB = db
+	defer func() {
+		db.Close()
+		DB = prev
+	}()
+	fn()
+	return rec.Statements()
+}
+
+func TestGameCountsExcludeComputerGames(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func()
+	}{
+		{"GetGameStatsByUserID", func() { GetGameStatsByUserID("u1") }},
+		{"GetGamesPlayedCount", func() { GetGamesPlayedCount("u1") }},
+		{"GetAchievementCounters", func() { GetAchievementCounters("u1") }},
+		{"GetFinishedGamesByUserID", func() { GetFinishedGamesByUserID("u1") }},
+		{"ListPlayerIDs", func() { ListPlayerIDs("", 10) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statements := recordQueries(t, tt.run)
+			if len(statements) == 0 {
+				t.Fatal("no query was run")
+			}
+			for _, s := range statements {
+				if strings.Contains(s.SQL, "FROM games") && !strings.Contains(s.SQL, playerGames) {
+					t.Errorf("query counts every game pool:\n%s", s.SQL)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,228 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/logger"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/metrics"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/models"
+)
+
+// LegacyProvider marks identities carried over from before providers were recorded.
+// Their subject is the old user ID; the first provider to sign in with it claims it.
+const LegacyProvider = "legacy"
+
+var (
+	// ErrIdentityLinked is returned when the identity belongs to another user
+	ErrIdentityLinked = errors.New("identity is linked to another account")
+	// ErrProviderLinked is returned when the user already linked an account of the provider
+	ErrProviderLinked = errors.New("an account of this provider is already linked")
+	// ErrLastIdentity is returned when unlinking would leave the user unable to sign in
+	ErrLastIdentity = errors.New("cannot unlink the only sign-in method")
+)
+
+// ResolveIdentityWithContext returns the internal user ID for a provider subject,
+// claiming a legacy identity or creating a new profile when there is none. created
+// reports whether a profile was created.
+func ResolveIdentityWithContext(ctx context.Context, provider, subject string) (userID string, created bool, err error) {
+	defer metrics.ObserveQuery("ResolveIdentityWithContext", time.Now())
+
+	userID, created, err = resolveIdentity(ctx, provider, subject)
+	if isUniqueViolation(err) {
+		// A concurrent sign-in created the identity first
+		userID, created, err = resolveIdentity(ctx, provider, subject)
+	}
+	if err != nil {
+		logger.Error("Error resolving identity", logger.F("provider", provider, "error", err.Error()))
+	}
+	return userID, created, err
+}
+
+func resolveIdentity(ctx context.Context, provider, subject string) (string, bool, error) {
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		return "", false, err
+	}
+	defer tx.Rollback()
+
+	userID, err := claimIdentity(ctx, tx, provider, subject)
+	if err != nil {
+		return "", false, err
+	}
+	if userID != "" {
+		return userID, false, tx.Commit()
+	}
+
+	err = tx.QueryRowContext(ctx,
+		`INSERT INTO profiles (user_id) VALUES (gen_random_uuid()::text) RETURNING user_id`,
+	).Scan(&userID)
+	if err != nil {
+		return "", false, err
+	}
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO identities (provider, subject, user_id) VALUES ($1, $2, $3)`,
+		provider, subject, userID,
+	)
+	if err != nil {
+		return "", false, err
+	}
+	return userID, true, tx.Commit()
+}
+
+// claimIdentity returns the owner of (provider, subject), first converting a legacy
+// identity with that subject, or "" if there is none
+func claimIdentity(ctx context.Context, tx *sql.Tx, provider, subject string) (string, error) {
+	var userID string
+	err := tx.QueryRowContext(ctx,
+		`SELECT user_id FROM identities WHERE provider = $1 AND subject = $2`,
+		provider, subject,
+	).Scan(&userID)
+	if err != sql.ErrNoRows {
+		return userID, err
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		UPDATE identities SET provider = $1
+		WHERE provider = $2 AND subject = $3
+		RETURNING user_id
+	`, provider, LegacyProvider, subject).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return userID, err
+}
+
+// LinkIdentity adds a provider account to a user. Linking an identity the user
+// already has is a no-op.
+func LinkIdentity(userID, provider, subject string) error {
+	defer metrics.ObserveQuery("LinkIdentity", time.Now())
+	ctx, cancel := QueryContext()
+	defer cancel()
+
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	owner, err := claimIdentity(ctx, tx, provider, subject)
+	if err != nil {
+		logger.Error("Error looking up identity", logger.F("userID", userID, "provider", provider, "error", err.Error()))
+		return err
+	}
+	if owner == userID {
+		return tx.Commit()
+	}
+	if owner != "" {
+		return ErrIdentityLinked
+	}
+
+	var exists bool
+	err = tx.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM identities WHERE user_id = $1 AND provider = $2)`,
+		userID, provider,
+	).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return ErrProviderLinked
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO identities (provider, subject, user_id) VALUES ($1, $2, $3)`,
+		provider, subject, userID,
+	)
+	if isUniqueViolation(err) {
+		return ErrIdentityLinked
+	}
+	if err != nil {
+		logger.Error("Error linking identity", logger.F("userID", userID, "provider", provider, "error", err.Error()))
+		return err
+	}
+	return tx.Commit()
+}
+
+// UnlinkIdentity removes the user's account of a provider. It reports false if none
+// was linked, and ErrLastIdentity if it is the user's only identity.
+func UnlinkIdentity(userID, provider string) (bool, error) {
+	defer metrics.ObserveQuery("UnlinkIdentity", time.Now())
+	ctx, cancel := QueryContext()
+	defer cancel()
+
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	// Lock the user's identities so concurrent unlinks cannot remove the last two
+	rows, err := tx.QueryContext(ctx,
+		`SELECT provider FROM identities WHERE user_id = $1 FOR UPDATE`, userID,
+	)
+	if err != nil {
+		logger.Error("Error loading identities", logger.F("userID", userID, "error", err.Error()))
+		return false, err
+	}
+	count, found := 0, false
+	for rows.Next() {
+		var p string
+		if err := rows.Scan(&p); err != nil {
+			rows.Close()
+			return false, err
+		}
+		count++
+		found = found || p == provider
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return false, err
+	}
+
+	if !found {
+		return false, nil
+	}
+	if count == 1 {
+		return true, ErrLastIdentity
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`DELETE FROM identities WHERE user_id = $1 AND provider = $2`, userID, provider,
+	)
+	if err != nil {
+		logger.Error("Error unlinking identity", logger.F("userID", userID, "provider", provider, "error", err.Error()))
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// GetIdentities returns the providers linked to a user, oldest first
+func GetIdentities(userID string) ([]models.LinkedIdentity, error) {
+	defer metrics.ObserveQuery("GetIdentities", time.Now())
+	ctx, cancel := QueryContext()
+	defer cancel()
+
+	rows, err := DB.QueryContext(ctx, `
+		SELECT provider, created_at FROM identities
+		WHERE user_id = $1
+		ORDER BY created_at, provider
+	`, userID)
+	if err != nil {
+		logger.Error("Error getting identities", logger.F("userID", userID, "error", err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	identities := []models.LinkedIdentity{}
+	for rows.Next() {
+		var i models.LinkedIdentity
+		if err := rows.Scan(&i.Provider, &i.LinkedAt); err != nil {
+			return nil, err
+		}
+		identities = append(identities, i)
+	}
+	return identities, rows.Err()
+}
@@ -0,0 +1,179 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/logger"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/metrics"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/models"
+)
+
+// middlegameFilters builds the WHERE clause shared by the middlegame position queries
+func middlegameFilters(params models.MiddlegameQueryParams) (string, []interface{}) {
+	where := " WHERE 1=1"
+	args := []interface{}{}
+	argIndex := 1
+
+	if params.MinRating > 0 {
+		where += fmt.Sprintf(" AND rating >= $%d", argIndex)
+		args = append(args, params.MinRating)
+		argIndex++
+	}
+	if params.MaxRating > 0 {
+		where += fmt.Sprintf(" AND rating <= $%d", argIndex)
+		args = append(args, params.MaxRating)
+		argIndex++
+	}
+	if params.Theme != "" {
+		where += fmt.Sprintf(" AND $%d = ANY(themes)", argIndex)
+		args = append(args, params.Theme)
+		argIndex++
+	}
+	// Side to move is the second FEN field
+	if params.Side == "w" || params.Side == "b" {
+		where += fmt.Sprintf(" AND split_part(fen, ' ', 2) = $%d", argIndex)
+		args = append(args, params.Side)
+		argIndex++
+	}
+	if params.ExcludePositionID != "" {
+		where += fmt.Sprintf(" AND position_id != $%d", argIndex)
+		args = append(args, params.ExcludePositionID)
+	}
+
+	return where, args
+}
+
+// GetRandomMiddlegamePosition retrieves a random middlegame position matching the given criteria
+func GetRandomMiddlegamePosition(params models.MiddlegameQueryParams) (*models.MiddlegamePosition, error) {
+	defer metrics.ObserveQuery("GetRandomMiddlegamePosition", time.Now())
+	ctx, cancel := QueryContext()
+	defer cancel()
+
+	where, args := middlegameFilters(params)
+	query := `
+		SELECT position_id, fen, COALESCE(moves, ''), rating, themes,
+		       initial_eval, COALESCE(description, ''), COALESCE(source, ''), created_at
+		FROM middlegame_positions` + where + `
+		ORDER BY RANDOM() LIMIT 1`
+
+	pos := &models.MiddlegamePosition{}
+	var initialEval sql.NullInt32
+	var themes pq.StringArray
+
+	err := DB.QueryRowContext(ctx, query, args...).Scan(
+		&pos.PositionID,
+		&pos.FEN,
+		&pos.Moves,
+		&pos.Rating,
+		&themes,
+		&initialEval,
+		&pos.Description,
+		&pos.Source,
+		&pos.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		logger.Error("Error getting random middlegame position",
+			logger.F("minRating", params.MinRating, "maxRating", params.MaxRating,
+				"theme", params.Theme, "error", err.Error()))
+		return nil, err
+	}
+
+	pos.Themes = []string(themes)
+	if initialEval.Valid {
+		eval := int(initialEval.Int32)
+		pos.InitialEval = &eval
+	}
+
+	return pos, nil
+}
+
+// BulkInsertMiddlegamePositions upserts multiple middlegame positions in one statement
+// Used by the importmiddlegames command
+func BulkInsertMiddlegamePositions(positions []models.MiddlegamePosition) error {
+	defer metrics.ObserveQuery("BulkInsertMiddlegamePositions", time.Now())
+	if len(positions) == 0 {
+		return nil
+	}
+
+	ctx, cancel := QueryContextWithTimeout(30 * time.Second)
+	defer cancel()
+
+	valueStrings := make([]string, 0, len(positions))
+	valueArgs := make([]interface{}, 0, len(positions)*8)
+
+	for i, pos := range positions {
+		base := i * 8
+		valueStrings = append(valueStrings, fmt.Sprintf(
+			"($%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d)",
+			base+1, base+2, base+3, base+4, base+5, base+6, base+7, base+8,
+		))
+		valueArgs = append(valueArgs,
+			pos.PositionID,
+			pos.FEN,
+			pos.Moves,
+			pos.Rating,
+			pq.Array(pos.Themes),
+			pos.InitialEval,
+			pos.Description,
+			pos.Source,
+		)
+	}
+
+	query := fmt.Sprintf(`
+		INSERT INTO middlegame_positions
+			(position_id, fen, moves, rating, themes, initial_eval, description, source)
+		VALUES %s
+		ON CONFLICT (position_id) DO UPDATE SET
+			fen = EXCLUDED.fen,
+			moves = EXCLUDED.moves,
+			rating = EXCLUDED.rating,
+			themes = EXCLUDED.themes,
+			initial_eval = EXCLUDED.initial_eval,
+			description = EXCLUDED.description,
+			source = EXCLUDED.source
+	`, strings.Join(valueStrings, ","))
+
+	if _, err := DB.ExecContext(ctx, query, valueArgs...); err != nil {
+		logger.Error("Error bulk inserting middlegame positions",
+			logger.F("count", len(positions), "error", err.Error()))
+		return err
+	}
+
+	return nil
+}
+
+// GetAvailableMiddlegameThemes returns the list of distinct themes in the database
+func GetAvailableMiddlegameThemes() ([]string, error) {
+	defer metrics.ObserveQuery("GetAvailableMiddlegameThemes", time.Now())
+	ctx, cancel := QueryContext()
+	defer cancel()
+
+	rows, err := DB.QueryContext(ctx, `
+		SELECT DISTINCT unnest(themes) as theme
+		FROM middlegame_positions
+		ORDER BY theme
+	`)
+	if err != nil {
+		logger.Error("Error getting available middlegame themes", logger.F("error", err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	themes := []string{}
+	for rows.Next() {
+		var theme string
+		if err := rows.Scan(&theme); err != nil {
+			return nil, err
+		}
+		themes = append(themes, theme)
+	}
+
+	return themes, rows.Err()
+}
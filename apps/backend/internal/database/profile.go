@@ -3,13 +3,28 @@ package database
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"time"
 
+	"github.com/lib/pq"
 	"github.com/tmcarmichael/nxtchess/apps/backend/internal/logger"
 	"github.com/tmcarmichael/nxtchess/apps/backend/internal/metrics"
 	"github.com/tmcarmichael/nxtchess/apps/backend/internal/models"
 )
 
+var (
+	// ErrUsernameTaken is returned when another profile already holds the username
+	ErrUsernameTaken = errors.New("username already taken")
+	// ErrUsernameCooldown is returned when the username was changed too recently
+	ErrUsernameCooldown = errors.New("username changed too recently")
+)
+
+// isUniqueViolation reports whether err is a Postgres unique constraint violation
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
 // HasUsername checks if a user has set a username
 func HasUsername(userID string) (bool, error) {
 	defer metrics.ObserveQuery("HasUsername", time.Now())
@@ -79,13 +94,14 @@ func GetUserProfileByUsername(username string) (*models.Profile, error) {
 	defer cancel()
 
 	row := DB.QueryRowContext(ctx, `
-        SELECT user_id, username, rating, puzzle_rating, COALESCE(profile_icon, 'white-pawn'), created_at
+        SELECT user_id, username, rating, puzzle_rating, training_rating, bot_rating, computer_rating, is_bot,
+               COALESCE(profile_icon, 'white-pawn'), created_at
         FROM profiles
         WHERE username = $1
     `, username)
 
 	u := &models.Profile{}
-	err := row.Scan(&u.UserID, &u.Username, &u.Rating, &u.PuzzleRating, &u.ProfileIcon, &u.CreatedAt)
+	err := row.Scan(&u.UserID, &u.Username, &u.Rating, &u.PuzzleRating, &u.TrainingRating, &u.BotRating, &u.ComputerRating, &u.IsBot, &u.ProfileIcon, &u.CreatedAt)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	} else if err != nil {
@@ -123,6 +139,9 @@ func UpsertUsername(userID, newUsername string) error {
         ON CONFLICT (user_id) DO UPDATE
         SET username = EXCLUDED.username
     `, userID, newUsername)
+	if isUniqueViolation(err) {
+		return ErrUsernameTaken
+	}
 	if err != nil {
 		logger.Error("Error upserting username", logger.F("userID", userID, "username", newUsername, "error", err.Error()))
 	}
@@ -149,6 +168,9 @@ func UpsertUsernameWithRating(userID, newUsername string, rating int) error {
 		ON CONFLICT (user_id) DO UPDATE
 		SET username = EXCLUDED.username, rating = EXCLUDED.rating
 	`, userID, newUsername, rating)
+	if isUniqueViolation(err) {
+		return ErrUsernameTaken
+	}
 	if err != nil {
 		logger.Error("Error upserting username with rating", logger.F("userID", userID, "username", newUsername, "rating", rating, "error", err.Error()))
 		return err
@@ -171,14 +193,77 @@ func UpsertUsernameWithRating(userID, newUsername string, rating int) error {
 	return nil
 }
 
-// CreateProfileWithContext creates a new user profile using the provided context
-func CreateProfileWithContext(ctx context.Context, userID string) error {
-	defer metrics.ObserveQuery("CreateProfileWithContext", time.Now())
-	_, err := DB.ExecContext(ctx, `INSERT INTO profiles (user_id) VALUES ($1) ON CONFLICT DO NOTHING`, userID)
+// ChangeUsername renames a user who already has a username and records when, for the
+// username change policy. It returns ErrUsernameCooldown, changing nothing, when the
+// last change was less than cooldown ago; the check is part of the update so that
+// concurrent requests cannot both rename.
+func ChangeUsername(userID, newUsername string, cooldown time.Duration) error {
+	defer metrics.ObserveQuery("ChangeUsername", time.Now())
+	ctx, cancel := QueryContext()
+	defer cancel()
+
+	res, err := DB.ExecContext(ctx, `
+		UPDATE profiles
+		SET username = $2, username_changed_at = now()
+		WHERE user_id = $1
+		  AND (username_changed_at IS NULL OR username_changed_at <= now() - $3 * interval '1 second')
+	`, userID, newUsername, cooldown.Seconds())
+	if isUniqueViolation(err) {
+		return ErrUsernameTaken
+	}
 	if err != nil {
-		logger.Error("Error creating profile", logger.F("userID", userID, "error", err.Error()))
+		logger.Error("Error changing username", logger.F("userID", userID, "username", newUsername, "error", err.Error()))
+		return err
 	}
-	return err
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		logger.Error("Error changing username", logger.F("userID", userID, "username", newUsername, "error", err.Error()))
+		return err
+	}
+	if n == 0 {
+		return ErrUsernameCooldown
+	}
+	return nil
+}
+
+// GetPrivateProfile retrieves the signed-in user's own profile, or nil if there is
+// none. Game statistics are left for the caller. The daily puzzle streak only counts
+// while the last solved daily puzzle is from today (UTC) or the day before.
+func GetPrivateProfile(userID string) (*models.PrivateProfile, error) {
+	defer metrics.ObserveQuery("GetPrivateProfile", time.Now())
+	ctx, cancel := QueryContext()
+	defer cancel()
+
+	var p models.PrivateProfile
+	var username sql.NullString
+	var changedAt sql.NullTime
+	err := DB.QueryRowContext(ctx, `
+		SELECT user_id, username, rating, puzzle_rating, training_rating,
+		       COALESCE(profile_icon, 'white-pawn'), created_at, COALESCE(achievement_points, 0),
+		       win_streak, puzzle_streak,
+		       CASE WHEN last_daily_puzzle_solved >= $2::date - 1 THEN daily_puzzle_streak ELSE 0 END,
+		       username_changed_at
+		FROM profiles
+		WHERE user_id = $1
+	`, userID, dateParam(time.Now())).Scan(
+		&p.UserID, &username, &p.Rating, &p.PuzzleRating, &p.TrainingRating,
+		&p.ProfileIcon, &p.CreatedAt, &p.AchievementPoints,
+		&p.WinStreak, &p.PuzzleStreak, &p.DailyPuzzleStreak, &changedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		logger.Error("Error getting private profile", logger.F("userID", userID, "error", err.Error()))
+		return nil, err
+	}
+
+	p.Username = username.String
+	p.UsernameSet = username.Valid && username.String != ""
+	if changedAt.Valid {
+		p.UsernameChangedAt = &changedAt.Time
+	}
+	return &p, nil
 }
 
 func GetRatingByID(userID string) (int, error) {
@@ -264,6 +349,8 @@ func GetProfileCreatedAt(userID string) (time.Time, error) {
 	return createdAt, nil
 }
 
+// GetGameStatsByUserID counts the user's finished games against other accounts and
+// their results
 func GetGameStatsByUserID(userID string) (gamesPlayed, wins, losses, draws int, err error) {
 	defer metrics.ObserveQuery("GetGameStatsByUserID", time.Now())
 	ctx, cancel := QueryContext()
@@ -285,6 +372,7 @@ func GetGameStatsByUserID(userID string) (gamesPlayed, wins, losses, draws int,
 		WHERE (playerW_id = $1 OR playerB_id = $1)
 			AND result IS NOT NULL
 			AND result != '*'
+			AND `+playerGames+`
 	`, userID).Scan(&gamesPlayed, &wins, &losses, &draws)
 	if err != nil {
 		logger.Error("Error getting game stats", logger.F("userID", userID, "error", err.Error()))
@@ -299,9 +387,12 @@ func GetRatingHistoryByUsername(username string) ([]models.RatingPoint, []models
 	defer cancel()
 
 	gameRows, err := DB.QueryContext(ctx, `
-		SELECT rating, created_at FROM rating_history
-		WHERE user_id = (SELECT user_id FROM profiles WHERE username = $1)
-		ORDER BY created_at ASC
+		SELECT h.rating, h.created_at FROM rating_history h
+		JOIN profiles p ON p.user_id = h.user_id
+		WHERE p.username = $1
+			-- Bots chart their bot pool rating; humans their standard rating
+			AND h.pool = CASE WHEN p.is_bot THEN 'bot' ELSE 'standard' END
+		ORDER BY h.created_at ASC
 		LIMIT 100
 	`, username)
 	if err != nil {
@@ -370,6 +461,7 @@ func GetRecentGamesByUsername(username string) ([]models.RecentGame, error) {
 				ELSE 'black'
 			END,
 			CASE
+				WHEN g.stockfish_difficulty IS NOT NULL THEN 'Computer'
 				WHEN g.playerW_id = t.user_id THEN COALESCE(opp_b.username, 'Unknown')
 				ELSE COALESCE(opp_w.username, 'Unknown')
 			END,
@@ -378,7 +470,10 @@ func GetRecentGamesByUsername(username string) ([]models.RecentGame, error) {
 					OR (g.playerB_id = t.user_id AND g.result = '0-1') THEN 'win'
 				WHEN g.result = '1/2-1/2' THEN 'draw'
 				ELSE 'loss'
-			END
+			END,
+			g.stockfish_difficulty,
+			COALESCE(g.eco, ''),
+			COALESCE(g.opening, '')
 		FROM games g
 		CROSS JOIN target t
 		LEFT JOIN profiles opp_b ON g.playerB_id = opp_b.user_id
@@ -398,10 +493,12 @@ func GetRecentGamesByUsername(username string) ([]models.RecentGame, error) {
 	var games []models.RecentGame
 	for rows.Next() {
 		var g models.RecentGame
-		if err := rows.Scan(&g.GameID, &g.CreatedAt, &g.PlayerColor, &g.Opponent, &g.Result); err != nil {
+		var level sql.NullInt32
+		if err := rows.Scan(&g.GameID, &g.CreatedAt, &g.PlayerColor, &g.Opponent, &g.Result, &level, &g.ECO, &g.Opening); err != nil {
 			logger.Error("Error scanning recent game", logger.F("error", err.Error()))
 			return nil, err
 		}
+		g.ComputerLevel = computerLevel(level)
 		games = append(games, g)
 	}
 	if err := rows.Err(); err != nil {
@@ -410,4 +507,70 @@ func GetRecentGamesByUsername(username string) ([]models.RecentGame, error) {
 	}
 
 	return games, nil
-}
\ No newline at end of file
+}
+// GetGamesByUserID returns a page of the user's finished games with their PGN, newest
+// first. Pass the created_at of the last game of a page as before to get the next one.
+func GetGamesByUserID(userID string, before time.Time, limit int) ([]models.RecentGame, error) {
+	defer metrics.ObserveQuery("GetGamesByUserID", time.Now())
+	ctx, cancel := QueryContext()
+	defer cancel()
+
+	rows, err := DB.QueryContext(ctx, `
+		SELECT
+			g.game_id,
+			g.created_at,
+			CASE WHEN g.playerW_id = $1 THEN 'white' ELSE 'black' END,
+			CASE
+				WHEN g.stockfish_difficulty IS NOT NULL THEN 'Computer'
+				WHEN g.playerW_id = $1 THEN COALESCE(opp_b.username, 'Unknown')
+				ELSE COALESCE(opp_w.username, 'Unknown')
+			END,
+			CASE
+				WHEN (g.playerW_id = $1 AND g.result = '1-0')
+					OR (g.playerB_id = $1 AND g.result = '0-1') THEN 'win'
+				WHEN g.result = '1/2-1/2' THEN 'draw'
+				ELSE 'loss'
+			END,
+			COALESCE(g.pgn, ''),
+			g.stockfish_difficulty,
+			COALESCE(g.eco, ''),
+			COALESCE(g.opening, '')
+		FROM games g
+		LEFT JOIN profiles opp_b ON g.playerB_id = opp_b.user_id
+		LEFT JOIN profiles opp_w ON g.playerW_id = opp_w.user_id
+		WHERE (g.playerW_id = $1 OR g.playerB_id = $1)
+			AND g.result IS NOT NULL
+			AND g.result != '*'
+			AND g.created_at < $2
+		ORDER BY g.created_at DESC
+		LIMIT $3
+	`, userID, before, limit)
+	if err != nil {
+		logger.Error("Error getting games", logger.F("userID", userID, "error", err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	games := []models.RecentGame{}
+	for rows.Next() {
+		var g models.RecentGame
+		var level sql.NullInt32
+		if err := rows.Scan(&g.GameID, &g.CreatedAt, &g.PlayerColor, &g.Opponent, &g.Result, &g.PGN, &level, &g.ECO, &g.Opening); err != nil {
+			logger.Error("Error scanning game", logger.F("error", err.Error()))
+			return nil, err
+		}
+		g.ComputerLevel = computerLevel(level)
+		games = append(games, g)
+	}
+	return games, rows.Err()
+}
+
+// computerLevel is the difficulty of a game against the computer, or nil for games
+// between players
+func computerLevel(level sql.NullInt32) *int {
+	if !level.Valid {
+		return nil
+	}
+	l := int(level.Int32)
+	return &l
+}
@@ -1,11 +1,16 @@
 package database
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
+	"strings"
 	"time"
 
+	"github.com/lib/pq"
 	"github.com/tmcarmichael/nxtchess/apps/backend/internal/logger"
 	"github.com/tmcarmichael/nxtchess/apps/backend/internal/metrics"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/models"
 )
 
 func GetLastPuzzleSubmissionTime(userID string) (time.Time, error) {
@@ -51,32 +56,288 @@ func GetPuzzleRatingInfo(userID string) (puzzleRating int, gamesPlayed int, err
 	return puzzleRating, gamesPlayed, nil
 }
 
-func FinalizePuzzleResult(userID string, oldRating, newRating int) error {
-	defer metrics.ObserveQuery("FinalizePuzzleResult", time.Now())
+// GetRandomPuzzle retrieves a random puzzle matching the given criteria
+func GetRandomPuzzle(params models.PuzzleQueryParams) (*models.Puzzle, error) {
+	defer metrics.ObserveQuery("GetRandomPuzzle", time.Now())
+	ctx, cancel := QueryContext()
+	defer cancel()
+
+	query := `
+		SELECT ` + puzzleColumns + `
+		FROM puzzles
+		WHERE 1=1
+	`
+	args := []interface{}{}
+	argIndex := 1
+
+	if params.MinRating > 0 {
+		query += fmt.Sprintf(" AND rating >= $%d", argIndex)
+		args = append(args, params.MinRating)
+		argIndex++
+	}
+	if params.MaxRating > 0 {
+		query += fmt.Sprintf(" AND rating <= $%d", argIndex)
+		args = append(args, params.MaxRating)
+		argIndex++
+	}
+	if params.Theme != "" {
+		query += fmt.Sprintf(" AND $%d = ANY(themes)", argIndex)
+		args = append(args, params.Theme)
+		argIndex++
+	}
+	if params.ExcludePuzzleID != "" {
+		query += fmt.Sprintf(" AND puzzle_id != $%d", argIndex)
+		args = append(args, params.ExcludePuzzleID)
+		argIndex++
+	}
+	if len(params.ExcludePuzzleIDs) > 0 {
+		query += fmt.Sprintf(" AND puzzle_id <> ALL($%d)", argIndex)
+		args = append(args, pq.Array(params.ExcludePuzzleIDs))
+		argIndex++
+	}
+	if len(params.AnyThemes) > 0 {
+		query += fmt.Sprintf(" AND themes && $%d", argIndex)
+		args = append(args, pq.Array(params.AnyThemes))
+		argIndex++
+	}
+	if params.ExcludeAttemptedBy != "" {
+		query += fmt.Sprintf(` AND NOT EXISTS (
+			SELECT 1 FROM puzzle_attempts a
+			WHERE a.user_id = $%d AND a.puzzle_id = puzzles.puzzle_id AND a.created_at > $%d
+		)`, argIndex, argIndex+1)
+		args = append(args, params.ExcludeAttemptedBy, params.AttemptedSince)
+		argIndex += 2
+	}
+
+	query += " ORDER BY RANDOM() LIMIT 1"
+
+	p, err := scanPuzzle(DB.QueryRowContext(ctx, query, args...))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		logger.Error("Error getting random puzzle",
+			logger.F("minRating", params.MinRating, "maxRating", params.MaxRating,
+				"theme", params.Theme, "error", err.Error()))
+		return nil, err
+	}
+	return p, nil
+}
+
+// GetPuzzleByID retrieves a puzzle by its ID, returning nil if it does not exist
+func GetPuzzleByID(puzzleID string) (*models.Puzzle, error) {
+	defer metrics.ObserveQuery("GetPuzzleByID", time.Now())
+	ctx, cancel := QueryContext()
+	defer cancel()
+
+	p, err := scanPuzzle(DB.QueryRowContext(ctx, `
+		SELECT `+puzzleColumns+`
+		FROM puzzles
+		WHERE puzzle_id = $1
+	`, puzzleID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		logger.Error("Error getting puzzle", logger.F("puzzleID", puzzleID, "error", err.Error()))
+		return nil, err
+	}
+	return p, nil
+}
+
+// puzzleColumns is the column list read by scanPuzzle
+const puzzleColumns = `puzzle_id, fen, moves, rating, rating_deviation, themes,
+		popularity, nb_plays, game_url, opening_tags, created_at`
+
+func scanPuzzle(row *sql.Row) (*models.Puzzle, error) {
+	p := &models.Puzzle{}
+	var themes, openingTags pq.StringArray
+	err := row.Scan(&p.PuzzleID, &p.FEN, &p.Moves, &p.Rating, &p.RatingDeviation, &themes,
+		&p.Popularity, &p.NbPlays, &p.GameURL, &openingTags, &p.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	p.Themes = []string(themes)
+	p.OpeningTags = []string(openingTags)
+	return p, nil
+}
+
+// FinalizePuzzleAttempt records a rated attempt: it updates the player's puzzle rating,
+// stores the attempt and adjusts the puzzle's own rating in a single transaction. The
+// puzzle rating is adjusted by delta rather than overwritten so concurrent attempts on
+// the same puzzle do not lose updates.
+func FinalizePuzzleAttempt(attempt models.PuzzleAttempt, puzzleDelta int) error {
+	defer metrics.ObserveQuery("FinalizePuzzleAttempt", time.Now())
 	ctx, cancel := QueryContext()
 	defer cancel()
 
 	tx, err := DB.BeginTx(ctx, nil)
 	if err != nil {
-		logger.Error("Error starting puzzle finalize transaction", logger.F("error", err.Error()))
+		logger.Error("Error starting puzzle attempt transaction", logger.F("error", err.Error()))
 		return err
 	}
 	defer tx.Rollback()
 
-	_, err = tx.ExecContext(ctx, `UPDATE profiles SET puzzle_rating = $1 WHERE user_id = $2`, newRating, userID)
+	_, err = tx.ExecContext(ctx, `UPDATE profiles SET puzzle_rating = $1 WHERE user_id = $2`, attempt.RatingAfter, attempt.UserID)
 	if err != nil {
 		logger.Error("Error updating puzzle rating", logger.F("error", err.Error()))
 		return err
 	}
 
-	_, err = tx.ExecContext(ctx, `INSERT INTO puzzle_rating_history (user_id, rating) VALUES ($1, $2)`, userID, newRating)
+	_, err = tx.ExecContext(ctx, `INSERT INTO puzzle_rating_history (user_id, rating) VALUES ($1, $2)`, attempt.UserID, attempt.RatingAfter)
 	if err != nil {
 		logger.Error("Error inserting puzzle rating history", logger.F("error", err.Error()))
 		return err
 	}
 
+	if err = insertPuzzleAttempt(ctx, tx, attempt); err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE puzzles
+		SET rating = LEAST(GREATEST(rating + $1, 0), 4000), nb_plays = nb_plays + 1
+		WHERE puzzle_id = $2
+	`, puzzleDelta, attempt.PuzzleID)
+	if err != nil {
+		logger.Error("Error updating puzzle difficulty", logger.F("puzzleID", attempt.PuzzleID, "error", err.Error()))
+		return err
+	}
+
 	if err = tx.Commit(); err != nil {
-		logger.Error("Error committing puzzle finalize transaction", logger.F("error", err.Error()))
+		logger.Error("Error committing puzzle attempt transaction", logger.F("error", err.Error()))
+		return err
+	}
+
+	return nil
+}
+
+func insertPuzzleAttempt(ctx context.Context, tx *sql.Tx, a models.PuzzleAttempt) error {
+	themes := a.Themes
+	if themes == nil {
+		themes = []string{}
+	}
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO puzzle_attempts
+			(user_id, puzzle_id, solved, puzzle_rating, rating_before, rating_after, themes)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, a.UserID, a.PuzzleID, a.Solved, a.PuzzleRating, a.RatingBefore, a.RatingAfter, pq.Array(themes))
+	if err != nil {
+		logger.Error("Error inserting puzzle attempt",
+			logger.F("userID", a.UserID, "puzzleID", a.PuzzleID, "error", err.Error()))
+		return err
+	}
+	return nil
+}
+
+// HasRecentPuzzleAttempt reports whether the user attempted the puzzle after since
+func HasRecentPuzzleAttempt(userID, puzzleID string, since time.Time) (bool, error) {
+	defer metrics.ObserveQuery("HasRecentPuzzleAttempt", time.Now())
+	ctx, cancel := QueryContext()
+	defer cancel()
+
+	var exists bool
+	err := DB.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM puzzle_attempts
+			WHERE user_id = $1 AND puzzle_id = $2 AND created_at > $3
+		)
+	`, userID, puzzleID, since).Scan(&exists)
+	if err != nil {
+		logger.Error("Error checking recent puzzle attempt",
+			logger.F("userID", userID, "puzzleID", puzzleID, "error", err.Error()))
+		return false, err
+	}
+	return exists, nil
+}
+
+// GetPuzzleThemeStats aggregates the user's most recent attempts by puzzle theme
+func GetPuzzleThemeStats(userID string, recentAttempts int) ([]models.PuzzleThemeStat, error) {
+	defer metrics.ObserveQuery("GetPuzzleThemeStats", time.Now())
+	ctx, cancel := QueryContext()
+	defer cancel()
+
+	rows, err := DB.QueryContext(ctx, `
+		SELECT theme, COUNT(*), COUNT(*) FILTER (WHERE solved)
+		FROM (
+			SELECT themes, solved FROM puzzle_attempts
+			WHERE user_id = $1
+			ORDER BY created_at DESC
+			LIMIT $2
+		) recent, unnest(recent.themes) AS theme
+		GROUP BY theme
+	`, userID, recentAttempts)
+	if err != nil {
+		logger.Error("Error getting puzzle theme stats", logger.F("userID", userID, "error", err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []models.PuzzleThemeStat
+	for rows.Next() {
+		var s models.PuzzleThemeStat
+		if err := rows.Scan(&s.Theme, &s.Attempts, &s.Solved); err != nil {
+			logger.Error("Error scanning puzzle theme stat", logger.F("error", err.Error()))
+			return nil, err
+		}
+		stats = append(stats, s)
+	}
+	return stats, rows.Err()
+}
+
+// BulkUpsertPuzzles inserts or updates multiple puzzles in one statement.
+// Used by the puzzle importer. The rating and play count of an existing puzzle are
+// kept, since they are maintained by rated attempts once the puzzle is live.
+func BulkUpsertPuzzles(puzzles []models.Puzzle) error {
+	defer metrics.ObserveQuery("BulkUpsertPuzzles", time.Now())
+	if len(puzzles) == 0 {
+		return nil
+	}
+
+	ctx, cancel := QueryContextWithTimeout(30 * time.Second)
+	defer cancel()
+
+	valueStrings := make([]string, 0, len(puzzles))
+	valueArgs := make([]interface{}, 0, len(puzzles)*10)
+
+	for i, p := range puzzles {
+		base := i * 10
+		valueStrings = append(valueStrings, fmt.Sprintf(
+			"($%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d)",
+			base+1, base+2, base+3, base+4, base+5, base+6, base+7, base+8, base+9, base+10,
+		))
+		valueArgs = append(valueArgs,
+			p.PuzzleID,
+			p.FEN,
+			p.Moves,
+			p.Rating,
+			p.RatingDeviation,
+			pq.Array(p.Themes),
+			p.Popularity,
+			p.NbPlays,
+			p.GameURL,
+			pq.Array(p.OpeningTags),
+		)
+	}
+
+	query := fmt.Sprintf(`
+		INSERT INTO puzzles
+			(puzzle_id, fen, moves, rating, rating_deviation, themes, popularity, nb_plays, game_url, opening_tags)
+		VALUES %s
+		ON CONFLICT (puzzle_id) DO UPDATE SET
+			fen = EXCLUDED.fen,
+			moves = EXCLUDED.moves,
+			rating_deviation = EXCLUDED.rating_deviation,
+			themes = EXCLUDED.themes,
+			popularity = EXCLUDED.popularity,
+			game_url = EXCLUDED.game_url,
+			opening_tags = EXCLUDED.opening_tags
+	`, strings.Join(valueStrings, ","))
+
+	_, err := DB.ExecContext(ctx, query, valueArgs...)
+	if err != nil {
+		logger.Error("Error bulk upserting puzzles",
+			logger.F("count", len(puzzles), "error", err.Error()))
 		return err
 	}
 
@@ -0,0 +1,245 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/logger"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/metrics"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/models"
+)
+
+// sprintCompleted matches sprints that are over: finished explicitly or past their timer
+const sprintCompleted = `(finished_at IS NOT NULL OR ends_at <= now())`
+
+// CreatePuzzleSprint starts a sprint with its first puzzle. Any sprint the user
+// still has open is closed first, so a user runs at most one sprint at a time.
+func CreatePuzzleSprint(userID, firstPuzzleID string, duration time.Duration) (*models.PuzzleSprint, error) {
+	defer metrics.ObserveQuery("CreatePuzzleSprint", time.Now())
+	ctx, cancel := QueryContext()
+	defer cancel()
+
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		logger.Error("Error starting sprint transaction", logger.F("error", err.Error()))
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE puzzle_sprints SET finished_at = LEAST(now(), ends_at)
+		WHERE user_id = $1 AND finished_at IS NULL
+	`, userID)
+	if err != nil {
+		logger.Error("Error closing open sprints", logger.F("userID", userID, "error", err.Error()))
+		return nil, err
+	}
+
+	s := &models.PuzzleSprint{UserID: userID, PuzzleIDs: []string{firstPuzzleID}}
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO puzzle_sprints (user_id, puzzle_ids, ends_at)
+		VALUES ($1, $2, now() + make_interval(secs => $3))
+		RETURNING id, started_at, ends_at
+	`, userID, pq.Array(s.PuzzleIDs), duration.Seconds()).Scan(&s.SprintID, &s.StartedAt, &s.EndsAt)
+	if err != nil {
+		logger.Error("Error creating sprint", logger.F("userID", userID, "error", err.Error()))
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		logger.Error("Error committing sprint transaction", logger.F("error", err.Error()))
+		return nil, err
+	}
+	return s, nil
+}
+
+// GetPuzzleSprint returns a sprint owned by the user, or nil if there is none
+func GetPuzzleSprint(sprintID int64, userID string) (*models.PuzzleSprint, error) {
+	defer metrics.ObserveQuery("GetPuzzleSprint", time.Now())
+	ctx, cancel := QueryContext()
+	defer cancel()
+
+	s := &models.PuzzleSprint{}
+	var puzzleIDs pq.StringArray
+	var finishedAt sql.NullTime
+	err := DB.QueryRowContext(ctx, `
+		SELECT id, user_id, score, mistakes, puzzle_ids, started_at, ends_at, finished_at
+		FROM puzzle_sprints
+		WHERE id = $1 AND user_id = $2
+	`, sprintID, userID).Scan(&s.SprintID, &s.UserID, &s.Score, &s.Mistakes, &puzzleIDs,
+		&s.StartedAt, &s.EndsAt, &finishedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		logger.Error("Error getting sprint", logger.F("sprintID", sprintID, "error", err.Error()))
+		return nil, err
+	}
+	s.PuzzleIDs = []string(puzzleIDs)
+	if finishedAt.Valid {
+		s.FinishedAt = &finishedAt.Time
+	}
+	return s, nil
+}
+
+// AdvancePuzzleSprint records the answer to the current puzzle and, unless finish is
+// set, hands out nextPuzzleID. The update only applies while the sprint is still on
+// the puzzle count the caller saw, so concurrent answers to one puzzle are counted
+// once; it returns false when another answer won.
+func AdvancePuzzleSprint(s *models.PuzzleSprint, solved bool, nextPuzzleID string, finish bool) (bool, error) {
+	defer metrics.ObserveQuery("AdvancePuzzleSprint", time.Now())
+	ctx, cancel := QueryContext()
+	defer cancel()
+
+	scoreDelta, mistakeDelta := 0, 1
+	if solved {
+		scoreDelta, mistakeDelta = 1, 0
+	}
+
+	var finishedAt sql.NullTime
+	err := DB.QueryRowContext(ctx, `
+		UPDATE puzzle_sprints SET
+			score = score + $1,
+			mistakes = mistakes + $2,
+			puzzle_ids = CASE WHEN $3 = '' THEN puzzle_ids ELSE array_append(puzzle_ids, $3) END,
+			finished_at = CASE WHEN $4 THEN now() ELSE NULL END
+		WHERE id = $5 AND cardinality(puzzle_ids) = $6 AND finished_at IS NULL
+		RETURNING score, mistakes, finished_at
+	`, scoreDelta, mistakeDelta, nextPuzzleID, finish, s.SprintID, len(s.PuzzleIDs)).Scan(&s.Score, &s.Mistakes, &finishedAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		logger.Error("Error advancing sprint", logger.F("sprintID", s.SprintID, "error", err.Error()))
+		return false, err
+	}
+	if nextPuzzleID != "" {
+		s.PuzzleIDs = append(s.PuzzleIDs, nextPuzzleID)
+	}
+	if finishedAt.Valid {
+		s.FinishedAt = &finishedAt.Time
+	}
+	return true, nil
+}
+
+// FinishPuzzleSprint closes a sprint whose timer ran out, keeping its score
+func FinishPuzzleSprint(s *models.PuzzleSprint) error {
+	defer metrics.ObserveQuery("FinishPuzzleSprint", time.Now())
+	ctx, cancel := QueryContext()
+	defer cancel()
+
+	var finishedAt time.Time
+	err := DB.QueryRowContext(ctx, `
+		UPDATE puzzle_sprints SET finished_at = COALESCE(finished_at, LEAST(now(), ends_at))
+		WHERE id = $1
+		RETURNING finished_at
+	`, s.SprintID).Scan(&finishedAt)
+	if err != nil {
+		logger.Error("Error finishing sprint", logger.F("sprintID", s.SprintID, "error", err.Error()))
+		return err
+	}
+	s.FinishedAt = &finishedAt
+	return nil
+}
+
+// GetSprintPersonalBests returns the user's completed sprints that set a new
+// personal best, oldest first; the last entry is the current personal best
+func GetSprintPersonalBests(userID string) ([]models.PuzzleSprint, error) {
+	defer metrics.ObserveQuery("GetSprintPersonalBests", time.Now())
+	ctx, cancel := QueryContext()
+	defer cancel()
+
+	rows, err := DB.QueryContext(ctx, `
+		SELECT id, score, mistakes, started_at, ends_at
+		FROM (
+			SELECT id, score, mistakes, started_at, ends_at,
+				MAX(score) OVER (ORDER BY started_at, id ROWS BETWEEN UNBOUNDED PRECEDING AND 1 PRECEDING) AS prev_best
+			FROM puzzle_sprints
+			WHERE user_id = $1 AND `+sprintCompleted+`
+		) s
+		WHERE score > COALESCE(prev_best, 0)
+		ORDER BY started_at, id
+	`, userID)
+	if err != nil {
+		logger.Error("Error getting sprint personal bests", logger.F("userID", userID, "error", err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanSprintSummaries(rows)
+}
+
+// GetRecentPuzzleSprints returns the user's most recent completed sprints, newest first
+func GetRecentPuzzleSprints(userID string, limit int) ([]models.PuzzleSprint, error) {
+	defer metrics.ObserveQuery("GetRecentPuzzleSprints", time.Now())
+	ctx, cancel := QueryContext()
+	defer cancel()
+
+	rows, err := DB.QueryContext(ctx, `
+		SELECT id, score, mistakes, started_at, ends_at
+		FROM puzzle_sprints
+		WHERE user_id = $1 AND `+sprintCompleted+`
+		ORDER BY started_at DESC
+		LIMIT $2
+	`, userID, limit)
+	if err != nil {
+		logger.Error("Error getting recent sprints", logger.F("userID", userID, "error", err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanSprintSummaries(rows)
+}
+
+func scanSprintSummaries(rows *sql.Rows) ([]models.PuzzleSprint, error) {
+	sprints := []models.PuzzleSprint{}
+	for rows.Next() {
+		var s models.PuzzleSprint
+		if err := rows.Scan(&s.SprintID, &s.Score, &s.Mistakes, &s.StartedAt, &s.EndsAt); err != nil {
+			logger.Error("Error scanning sprint", logger.F("error", err.Error()))
+			return nil, err
+		}
+		sprints = append(sprints, s)
+	}
+	return sprints, rows.Err()
+}
+
+// GetSprintLeaderboard returns each user's best completed sprint started at or after
+// since, highest score first; ties go to whoever reached the score first
+func GetSprintLeaderboard(since time.Time, limit int) ([]models.SprintLeaderboardEntry, error) {
+	defer metrics.ObserveQuery("GetSprintLeaderboard", time.Now())
+	ctx, cancel := QueryContext()
+	defer cancel()
+
+	rows, err := DB.QueryContext(ctx, `
+		SELECT username, score, started_at
+		FROM (
+			SELECT DISTINCT ON (s.user_id) p.username, s.score, s.started_at
+			FROM puzzle_sprints s
+			JOIN profiles p ON p.user_id = s.user_id
+			WHERE s.started_at >= $1 AND s.score > 0 AND p.username IS NOT NULL
+				AND (s.finished_at IS NOT NULL OR s.ends_at <= now())
+			ORDER BY s.user_id, s.score DESC, s.started_at ASC
+		) best
+		ORDER BY score DESC, started_at ASC
+		LIMIT $2
+	`, since, limit)
+	if err != nil {
+		logger.Error("Error getting sprint leaderboard", logger.F("error", err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []models.SprintLeaderboardEntry{}
+	for rows.Next() {
+		var e models.SprintLeaderboardEntry
+		if err := rows.Scan(&e.Username, &e.Score, &e.AchievedAt); err != nil {
+			logger.Error("Error scanning sprint leaderboard entry", logger.F("error", err.Error()))
+			return nil, err
+		}
+		e.Rank = len(entries) + 1
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
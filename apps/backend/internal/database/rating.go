@@ -5,15 +5,35 @@ import (
 
 	"github.com/tmcarmichael/nxtchess/apps/backend/internal/logger"
 	"github.com/tmcarmichael/nxtchess/apps/backend/internal/metrics"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/models"
 )
 
-func GetPlayerRatingInfo(userID string) (rating int, gamesPlayed int, err error) {
+// playerGames restricts a games query to rated games between two accounts, in the
+// standard and bot pools. Games against the built-in computer have their own rating
+// and do not count towards a player's games, results or game achievements.
+const playerGames = `rating_pool IN ('standard', 'bot') AND rated`
+
+// ratingColumn is the profiles column holding the rating of a pool
+func ratingColumn(pool string) string {
+	switch pool {
+	case models.RatingPoolBot:
+		return "bot_rating"
+	case models.RatingPoolComputer:
+		return "computer_rating"
+	default:
+		return "rating"
+	}
+}
+
+// GetPlayerRatingInfo returns a player's rating in pool and how many rated games they
+// played in it
+func GetPlayerRatingInfo(userID, pool string) (rating int, gamesPlayed int, err error) {
 	defer metrics.ObserveQuery("GetPlayerRatingInfo", time.Now())
 	ctx, cancel := QueryContext()
 	defer cancel()
 
 	err = DB.QueryRowContext(ctx,
-		`SELECT rating FROM profiles WHERE user_id = $1`, userID,
+		`SELECT `+ratingColumn(pool)+` FROM profiles WHERE user_id = $1`, userID,
 	).Scan(&rating)
 	if err != nil {
 		logger.Error("Error fetching player rating", logger.F("userID", userID, "error", err.Error()))
@@ -21,7 +41,7 @@ func GetPlayerRatingInfo(userID string) (rating int, gamesPlayed int, err error)
 	}
 
 	err = DB.QueryRowContext(ctx,
-		`SELECT COUNT(*) FROM games WHERE playerW_id = $1 OR playerB_id = $1`, userID,
+		`SELECT COUNT(*) FROM games WHERE (playerW_id = $1 OR playerB_id = $1) AND rating_pool = $2 AND rated`, userID, pool,
 	).Scan(&gamesPlayed)
 	if err != nil {
 		logger.Error("Error counting player games", logger.F("userID", userID, "error", err.Error()))
@@ -31,7 +51,9 @@ func GetPlayerRatingInfo(userID string) (rating int, gamesPlayed int, err error)
 	return rating, gamesPlayed, nil
 }
 
-func FinalizeGameResult(whiteUID, blackUID, pgn, resultPGN string, whiteOld, blackOld, whiteNew, blackNew int) error {
+// FinalizeGameResult stores a rated game and both players' new ratings in pool,
+// returning the stored game's ID. eco and opening are empty for unclassified games.
+func FinalizeGameResult(whiteUID, blackUID, pool, pgn, resultPGN, eco, opening string, whiteOld, blackOld, whiteNew, blackNew int) (string, error) {
 	defer metrics.ObserveQuery("FinalizeGameResult", time.Now())
 	ctx, cancel := QueryContext()
 	defer cancel()
@@ -39,47 +61,51 @@ func FinalizeGameResult(whiteUID, blackUID, pgn, resultPGN string, whiteOld, bla
 	tx, err := DB.BeginTx(ctx, nil)
 	if err != nil {
 		logger.Error("Error starting finalize transaction", logger.F("error", err.Error()))
-		return err
+		return "", err
 	}
 	defer tx.Rollback()
 
-	_, err = tx.ExecContext(ctx, `
-		INSERT INTO games (pgn, playerW_id, playerB_id, playerW_start_rating, playerB_start_rating, result)
-		VALUES ($1, $2, $3, $4, $5, $6)
-	`, pgn, whiteUID, blackUID, whiteOld, blackOld, resultPGN)
+	var gameID string
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO games (pgn, playerW_id, playerB_id, playerW_start_rating, playerB_start_rating, result, rating_pool,
+		                   eco, opening)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), NULLIF($9, ''))
+		RETURNING game_id
+	`, pgn, whiteUID, blackUID, whiteOld, blackOld, resultPGN, pool, eco, opening).Scan(&gameID)
 	if err != nil {
 		logger.Error("Error inserting game", logger.F("error", err.Error()))
-		return err
+		return "", err
 	}
 
-	_, err = tx.ExecContext(ctx, `UPDATE profiles SET rating = $1 WHERE user_id = $2`, whiteNew, whiteUID)
+	column := ratingColumn(pool)
+	_, err = tx.ExecContext(ctx, `UPDATE profiles SET `+column+` = $1 WHERE user_id = $2`, whiteNew, whiteUID)
 	if err != nil {
 		logger.Error("Error updating white rating", logger.F("error", err.Error()))
-		return err
+		return "", err
 	}
 
-	_, err = tx.ExecContext(ctx, `UPDATE profiles SET rating = $1 WHERE user_id = $2`, blackNew, blackUID)
+	_, err = tx.ExecContext(ctx, `UPDATE profiles SET `+column+` = $1 WHERE user_id = $2`, blackNew, blackUID)
 	if err != nil {
 		logger.Error("Error updating black rating", logger.F("error", err.Error()))
-		return err
+		return "", err
 	}
 
-	_, err = tx.ExecContext(ctx, `INSERT INTO rating_history (user_id, rating) VALUES ($1, $2)`, whiteUID, whiteNew)
+	_, err = tx.ExecContext(ctx, `INSERT INTO rating_history (user_id, rating, pool) VALUES ($1, $2, $3)`, whiteUID, whiteNew, pool)
 	if err != nil {
 		logger.Error("Error inserting white rating history", logger.F("error", err.Error()))
-		return err
+		return "", err
 	}
 
-	_, err = tx.ExecContext(ctx, `INSERT INTO rating_history (user_id, rating) VALUES ($1, $2)`, blackUID, blackNew)
+	_, err = tx.ExecContext(ctx, `INSERT INTO rating_history (user_id, rating, pool) VALUES ($1, $2, $3)`, blackUID, blackNew, pool)
 	if err != nil {
 		logger.Error("Error inserting black rating history", logger.F("error", err.Error()))
-		return err
+		return "", err
 	}
 
 	if err = tx.Commit(); err != nil {
 		logger.Error("Error committing finalize transaction", logger.F("error", err.Error()))
-		return err
+		return "", err
 	}
 
-	return nil
+	return gameID, nil
 }
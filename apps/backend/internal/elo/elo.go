@@ -54,6 +54,20 @@ func Calculate(whiteRating, blackRating int, result float64, whiteGames, blackGa
 
 type PuzzleRatingChange struct {
 	PlayerOld, PlayerNew, PlayerDelta int
+	PuzzleOld, PuzzleNew, PuzzleDelta int
+}
+
+// puzzleKFactor is the K-factor for a puzzle's own rating. New puzzles move quickly
+// towards their true difficulty, established ones barely move per attempt.
+func puzzleKFactor(puzzlePlays int) float64 {
+	switch {
+	case puzzlePlays < 20:
+		return 24
+	case puzzlePlays < 200:
+		return 12
+	default:
+		return 6
+	}
 }
 
 func CalculatePuzzle(playerRating, puzzleRating int, solved bool, puzzleGamesPlayed int) PuzzleRatingChange {
@@ -74,6 +88,25 @@ func CalculatePuzzle(playerRating, puzzleRating int, solved bool, puzzleGamesPla
 	}
 }
 
+// CalculatePuzzleAttempt rates an attempt as a game between player and puzzle:
+// the player gains when solving, the puzzle gains when the player fails.
+func CalculatePuzzleAttempt(playerRating, puzzleRating int, solved bool, puzzleGamesPlayed, puzzlePlays int) PuzzleRatingChange {
+	rc := CalculatePuzzle(playerRating, puzzleRating, solved, puzzleGamesPlayed)
+
+	var puzzleResult float64
+	if !solved {
+		puzzleResult = 1.0
+	}
+	expected := expectedScore(puzzleRating, playerRating)
+	delta := int(math.Round(puzzleKFactor(puzzlePlays) * (puzzleResult - expected)))
+	newPuzzleRating := clamp(puzzleRating+delta, 0, 4000)
+
+	rc.PuzzleOld = puzzleRating
+	rc.PuzzleNew = newPuzzleRating
+	rc.PuzzleDelta = newPuzzleRating - puzzleRating
+	return rc
+}
+
 func ResultFromWinner(winner string) float64 {
 	switch winner {
 	case "white":
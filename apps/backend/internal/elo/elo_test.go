@@ -126,6 +126,26 @@ func TestCalculatePuzzleNewPlayerKFactor(t *testing.T) {
 	}
 }
 
+func TestCalculatePuzzleAttemptSolvedLowersPuzzle(t *testing.T) {
+	rc := CalculatePuzzleAttempt(1500, 1500, true, 20, 500)
+	if rc.PlayerDelta != 16 {
+		t.Errorf("expected player delta 16, got %d", rc.PlayerDelta)
+	}
+	if rc.PuzzleDelta != -3 {
+		t.Errorf("expected puzzle delta -3 with K=6, got %d", rc.PuzzleDelta)
+	}
+}
+
+func TestCalculatePuzzleAttemptFailedRaisesPuzzle(t *testing.T) {
+	rc := CalculatePuzzleAttempt(1500, 1500, false, 20, 5)
+	if rc.PuzzleDelta != 12 {
+		t.Errorf("expected puzzle delta 12 with K=24, got %d", rc.PuzzleDelta)
+	}
+	if rc.PuzzleOld != 1500 || rc.PuzzleNew != 1512 {
+		t.Errorf("expected puzzle 1500 -> 1512, got %d -> %d", rc.PuzzleOld, rc.PuzzleNew)
+	}
+}
+
 func TestResultFromWinner(t *testing.T) {
 	if ResultFromWinner("white") != 1.0 {
 		t.Error("white should be 1.0")
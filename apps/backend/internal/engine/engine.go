@@ -0,0 +1,264 @@
+// Package engine runs local UCI chess engines such as Stockfish. A Pool starts
+// engine processes on demand, reuses them between searches and bounds how many
+// search at once. Positions are validated before they reach an engine, and scores
+// are reported from White's point of view.
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/chess"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/logger"
+)
+
+const (
+	// DefaultDepth is searched when a request sets neither depth nor time
+	DefaultDepth = 18
+	// MaxMultiPV is the most principal variations a search may report
+	MaxMultiPV = 5
+)
+
+var (
+	// ErrInvalidPosition is returned for an invalid FEN or an illegal move
+	ErrInvalidPosition = errors.New("engine: invalid position")
+	// ErrEngineFailed is returned when an engine process crashes or stops responding
+	ErrEngineFailed = errors.New("engine: process failed")
+	// ErrClosed is returned by a pool after Close
+	ErrClosed = errors.New("engine: pool closed")
+)
+
+// Default is the process-wide pool set by Init; nil when no engine is configured
+var Default *Pool
+
+// Init creates Default from opts. An empty path disables the engine.
+func Init(opts Options) {
+	if opts.Path == "" {
+		logger.Info("UCI engine disabled (ENGINE_PATH not set)")
+		return
+	}
+	Default = NewPool(opts)
+	logger.Info("UCI engine configured", logger.F(
+		"path", opts.Path,
+		"poolSize", Default.opts.Size,
+		"maxDepth", Default.opts.MaxDepth,
+		"maxMoveTimeMs", Default.opts.MaxMoveTime.Milliseconds(),
+	))
+}
+
+// Options configures a Pool
+type Options struct {
+	Path        string        // engine binary
+	Args        []string      // engine command-line arguments
+	Size        int           // maximum processes searching at once
+	MaxDepth    int           // deepest search allowed
+	MaxMoveTime time.Duration // longest search allowed; depth-only searches are stopped here too
+}
+
+// Limits bound a single search. With neither depth nor time set, DefaultDepth is used.
+// Elo is clamped to the range of the engine's UCI_Elo option; below its minimum the
+// engine plays at its lowest Skill Level, and callers wanting weaker play also limit
+// the depth.
+type Limits struct {
+	Depth    int           // search depth in plies; 0 for no depth limit
+	MoveTime time.Duration // search time; 0 for no time limit
+	MultiPV  int           // principal variations to report, 1 to MaxMultiPV
+	Elo      int           // playing strength to limit the engine to; 0 for full strength
+}
+
+// Score is an evaluation from White's point of view: either centipawns or moves to
+// mate, negative when Black is better or mating
+type Score struct {
+	CP   *int `json:"cp,omitempty"`
+	Mate *int `json:"mate,omitempty"`
+}
+
+// Line is one principal variation of a search
+type Line struct {
+	MultiPV int `json:"multipv"`
+	Depth   int `json:"depth"`
+	Score
+	Moves []string `json:"moves"` // UCI moves, starting with the move from the position
+}
+
+// Analysis is the result of a search
+type Analysis struct {
+	FEN      string `json:"fen"`       // the position searched
+	BestMove string `json:"best_move"` // UCI move; empty when the game is over
+	Depth    int    `json:"depth"`
+	Lines    []Line `json:"lines"`
+}
+
+// Pool manages engine processes. It is safe for concurrent use.
+type Pool struct {
+	opts  Options
+	slots chan struct{} // one per search in progress
+	idle  chan *process // started processes waiting for a search
+	done  chan struct{} // closed by Close
+}
+
+// NewPool creates a pool. Processes are started when first needed.
+func NewPool(opts Options) *Pool {
+	if opts.Size < 1 {
+		opts.Size = 1
+	}
+	if opts.MaxDepth < 1 {
+		opts.MaxDepth = DefaultDepth
+	}
+	if opts.MaxMoveTime <= 0 {
+		opts.MaxMoveTime = 5 * time.Second
+	}
+	return &Pool{
+		opts:  opts,
+		slots: make(chan struct{}, opts.Size),
+		idle:  make(chan *process, opts.Size),
+		done:  make(chan struct{}),
+	}
+}
+
+// Analyze searches the position reached by playing moves (UCI) from fen, or from the
+// starting position when fen is empty. It waits for a free engine until ctx is done.
+func (p *Pool) Analyze(ctx context.Context, fen string, moves []string, limits Limits) (*Analysis, error) {
+	pos, err := newPosition(fen, moves)
+	if err != nil {
+		return nil, err
+	}
+	limits = p.clamp(limits)
+
+	select {
+	case p.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-p.done:
+		return nil, ErrClosed
+	}
+	defer func() { <-p.slots }()
+
+	proc, err := p.acquire()
+	if err != nil {
+		return nil, err
+	}
+	analysis, err := proc.search(ctx, pos, limits, p.opts.MaxMoveTime)
+	p.release(proc, err)
+	if err != nil {
+		return nil, err
+	}
+	analysis.FEN = pos.fen
+	return analysis, nil
+}
+
+// Close stops the idle processes. Searches in progress finish, then their processes
+// are stopped too.
+func (p *Pool) Close() {
+	select {
+	case <-p.done:
+		return
+	default:
+		close(p.done)
+	}
+	for {
+		select {
+		case proc := <-p.idle:
+			proc.quit()
+		default:
+			return
+		}
+	}
+}
+
+func (p *Pool) clamp(l Limits) Limits {
+	if l.Depth <= 0 && l.MoveTime <= 0 {
+		l.Depth = DefaultDepth
+	}
+	if l.Depth > p.opts.MaxDepth {
+		l.Depth = p.opts.MaxDepth
+	}
+	if l.MoveTime > p.opts.MaxMoveTime {
+		l.MoveTime = p.opts.MaxMoveTime
+	}
+	if l.MultiPV < 1 {
+		l.MultiPV = 1
+	} else if l.MultiPV > MaxMultiPV {
+		l.MultiPV = MaxMultiPV
+	}
+	if l.Elo < 0 {
+		l.Elo = 0
+	}
+	return l
+}
+
+func (p *Pool) acquire() (*process, error) {
+	select {
+	case proc := <-p.idle:
+		return proc, nil
+	default:
+	}
+	proc, err := startProcess(p.opts.Path, p.opts.Args)
+	if err != nil {
+		logger.Error("Failed to start UCI engine", logger.F("path", p.opts.Path, "error", err.Error()))
+		return nil, err
+	}
+	return proc, nil
+}
+
+// release returns a healthy process to the pool and stops a failed one
+func (p *Pool) release(proc *process, err error) {
+	if errors.Is(err, ErrEngineFailed) {
+		logger.Warn("Replacing failed UCI engine", logger.F("error", err.Error()))
+		proc.kill()
+		return
+	}
+	select {
+	case <-p.done:
+		proc.quit()
+		return
+	default:
+	}
+	select {
+	case p.idle <- proc:
+	default:
+		proc.quit()
+	}
+}
+
+// position is a validated position in the form sent to engines
+type position struct {
+	command     string // UCI position command
+	fen         string // the position after the moves
+	whiteToMove bool
+}
+
+// newPosition validates fen and moves. Only the normalized FEN and moves are passed
+// on, so request input never reaches an engine's stdin.
+func newPosition(fen string, moves []string) (*position, error) {
+	var g *chess.Game
+	if fen == "" {
+		g = chess.NewGame()
+	} else {
+		var err error
+		if g, err = chess.NewGameFromFEN(fen); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPosition, err)
+		}
+	}
+	command := "position fen " + g.FEN()
+
+	played := make([]string, 0, len(moves))
+	for i, move := range moves {
+		from, to, promotion, err := chess.ParseUCI(move)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPosition, err)
+		}
+		if result := g.TryMove(from, to, promotion); !result.Valid {
+			return nil, fmt.Errorf("%w: move %d (%s) is illegal", ErrInvalidPosition, i+1, move)
+		}
+		played = append(played, from+to+promotion)
+	}
+	if len(played) > 0 {
+		command += " moves " + strings.Join(played, " ")
+	}
+
+	return &position{command: command, fen: g.FEN(), whiteToMove: g.IsWhiteTurn()}, nil
+}
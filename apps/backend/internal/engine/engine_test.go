@@ -0,0 +1,377 @@
+package engine
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Positions the fake engine treats specially
+const (
+	crashFEN = "8/8/8/8/8/8/8/K6k w - - 0 1"  // exits during the search
+	hangFEN  = "8/8/8/8/8/8/8/K5k1 w - - 0 1" // searches until stopped
+)
+
+func TestMain(m *testing.M) {
+	if os.Getenv("FAKE_UCI_ENGINE") == "1" {
+		fakeUCI()
+		return
+	}
+	os.Exit(m.Run())
+}
+
+// fakeUCI is a minimal UCI engine run as a child process of the test binary. Each
+// principal variation k scores 50-10k centipawns for the side to move. It plays
+// d2d4 instead of e2e4 while its strength is limited to an Elo, and g1f3 at Skill
+// Level 0. Like Stockfish, it ignores values outside an option's range.
+func fakeUCI() {
+	out := bufio.NewWriter(os.Stdout)
+	reply := func(lines ...string) {
+		for _, l := range lines {
+			fmt.Fprintln(out, l)
+		}
+		out.Flush()
+	}
+
+	multiPV := 1
+	limited := false
+	elo, skill := 0, 20
+	placement := ""
+	in := bufio.NewScanner(os.Stdin)
+	for in.Scan() {
+		fields := strings.Fields(in.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "uci":
+			reply(
+				"id name FakeUCI",
+				"option name UCI_LimitStrength type check default false",
+				"option name UCI_Elo type spin default 1320 min 1320 max 3190",
+				"option name Skill Level type spin default 20 min 0 max 20",
+				"uciok",
+			)
+		case "isready":
+			reply("readyok")
+		case "setoption":
+			if len(fields) == 5 && fields[2] == "MultiPV" {
+				fmt.Sscan(fields[4], &multiPV)
+			}
+			if len(fields) == 5 && fields[2] == "UCI_LimitStrength" {
+				limited = fields[4] == "true"
+			}
+			var n int
+			if len(fields) == 5 && fields[2] == "UCI_Elo" {
+				if fmt.Sscan(fields[4], &n); n >= 1320 && n <= 3190 {
+					elo = n
+				}
+			}
+			if len(fields) == 6 && fields[2] == "Skill" && fields[3] == "Level" {
+				if fmt.Sscan(fields[5], &n); n >= 0 && n <= 20 {
+					skill = n
+				}
+			}
+		case "position":
+			if len(fields) > 2 {
+				placement = fields[2]
+			}
+		case "go":
+			switch placement {
+			case strings.Fields(crashFEN)[0]:
+				os.Exit(3)
+			case strings.Fields(hangFEN)[0]:
+				continue
+			}
+			for k := 1; k <= multiPV; k++ {
+				reply(
+					fmt.Sprintf("info depth 1 multipv %d score cp 999 upperbound pv a2a3", k),
+					fmt.Sprintf("info depth 12 seldepth 15 multipv %d score cp %d nodes 1000 pv e2e4 e7e5", k, 50-10*k),
+				)
+			}
+			if limited && elo > 0 {
+				reply("bestmove d2d4")
+			} else if !limited && skill == 0 {
+				reply("bestmove g1f3")
+			} else {
+				reply("bestmove e2e4 ponder e7e5")
+			}
+		case "stop":
+			reply("bestmove a1a2")
+		case "quit":
+			return
+		}
+	}
+}
+
+func newTestPool(t *testing.T, size int) *Pool {
+	t.Helper()
+	t.Setenv("FAKE_UCI_ENGINE", "1")
+	p := NewPool(Options{Path: os.Args[0], Size: size, MaxDepth: 20, MaxMoveTime: time.Second})
+	t.Cleanup(p.Close)
+	return p
+}
+
+func TestAnalyze_MultiPV(t *testing.T) {
+	p := newTestPool(t, 1)
+
+	a, err := p.Analyze(context.Background(), "", nil, Limits{Depth: 12, MultiPV: 3})
+	if err != nil {
+		t.Fatalf("Analyze: %v", err)
+	}
+	if a.BestMove != "e2e4" {
+		t.Errorf("expected best move e2e4, got %q", a.BestMove)
+	}
+	if a.Depth != 12 {
+		t.Errorf("expected depth 12, got %d", a.Depth)
+	}
+	if len(a.Lines) != 3 {
+		t.Fatalf("expected 3 lines, got %d", len(a.Lines))
+	}
+	for i, l := range a.Lines {
+		if l.MultiPV != i+1 {
+			t.Errorf("line %d: expected multipv %d, got %d", i, i+1, l.MultiPV)
+		}
+		if l.CP == nil || *l.CP != 50-10*(i+1) {
+			t.Errorf("line %d: expected cp %d, got %v", i, 50-10*(i+1), l.CP)
+		}
+		if strings.Join(l.Moves, " ") != "e2e4 e7e5" {
+			t.Errorf("line %d: unexpected pv %v", i, l.Moves)
+		}
+	}
+}
+
+func TestAnalyze_ScoresFromWhitePointOfView(t *testing.T) {
+	p := newTestPool(t, 1)
+
+	a, err := p.Analyze(context.Background(), "", []string{"e2e4"}, Limits{})
+	if err != nil {
+		t.Fatalf("Analyze: %v", err)
+	}
+	if !strings.Contains(a.FEN, " b ") {
+		t.Errorf("expected black to move after e2e4, got %q", a.FEN)
+	}
+	if len(a.Lines) != 1 || a.Lines[0].CP == nil || *a.Lines[0].CP != -40 {
+		t.Errorf("expected black's +40 reported as -40, got %+v", a.Lines)
+	}
+}
+
+func TestAnalyze_InvalidPosition(t *testing.T) {
+	p := newTestPool(t, 1)
+
+	tests := []struct {
+		name  string
+		fen   string
+		moves []string
+	}{
+		{"bad fen", "not a fen", nil},
+		{"illegal move", "", []string{"e2e5"}},
+		{"malformed move", "", []string{"e2e4\nquit"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := p.Analyze(context.Background(), tt.fen, tt.moves, Limits{})
+			if !errors.Is(err, ErrInvalidPosition) {
+				t.Errorf("expected ErrInvalidPosition, got %v", err)
+			}
+		})
+	}
+}
+
+func TestAnalyze_ReusesProcess(t *testing.T) {
+	p := newTestPool(t, 2)
+
+	for i := 0; i < 3; i++ {
+		if _, err := p.Analyze(context.Background(), "", nil, Limits{}); err != nil {
+			t.Fatalf("Analyze %d: %v", i, err)
+		}
+	}
+	if n := len(p.idle); n != 1 {
+		t.Errorf("expected sequential searches to share one process, got %d idle", n)
+	}
+}
+
+func TestAnalyze_ReplacesCrashedProcess(t *testing.T) {
+	p := newTestPool(t, 1)
+
+	if _, err := p.Analyze(context.Background(), crashFEN, nil, Limits{}); !errors.Is(err, ErrEngineFailed) {
+		t.Fatalf("expected ErrEngineFailed, got %v", err)
+	}
+	if n := len(p.idle); n != 0 {
+		t.Errorf("expected crashed process to be dropped, got %d idle", n)
+	}
+	if _, err := p.Analyze(context.Background(), "", nil, Limits{}); err != nil {
+		t.Errorf("expected a new process after a crash, got %v", err)
+	}
+}
+
+func TestAnalyze_CancelStopsSearch(t *testing.T) {
+	p := newTestPool(t, 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := p.Analyze(ctx, hangFEN, nil, Limits{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if n := len(p.idle); n != 1 {
+		t.Errorf("expected stopped process to be reused, got %d idle", n)
+	}
+}
+
+func TestAnalyze_BoundsConcurrency(t *testing.T) {
+	p := newTestPool(t, 1)
+
+	busy, cancelBusy := context.WithCancel(context.Background())
+	started := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		close(started)
+		p.Analyze(busy, hangFEN, nil, Limits{})
+		close(finished)
+	}()
+	<-started
+	time.Sleep(20 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := p.Analyze(ctx, "", nil, Limits{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected second search to wait for the only engine, got %v", err)
+	}
+
+	cancelBusy()
+	<-finished
+}
+
+func TestAnalyze_LimitsStrength(t *testing.T) {
+	p := newTestPool(t, 1)
+
+	// One process serves every search, so each must reset what the last one set
+	tests := []struct {
+		name string
+		elo  int
+		want string
+	}{
+		{"above the engine's range", 4000, "d2d4"},
+		{"full strength", 0, "e2e4"},
+		{"below the engine's range", 300, "g1f3"},
+		{"within the range", 1500, "d2d4"},
+		{"below the range again", 1000, "g1f3"},
+		{"full strength again", 0, "e2e4"},
+	}
+	for _, tt := range tests {
+		a, err := p.Analyze(context.Background(), "", nil, Limits{Depth: 5, Elo: tt.elo})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if a.BestMove != tt.want {
+			t.Errorf("%s: expected %s, got %s", tt.name, tt.want, a.BestMove)
+		}
+	}
+}
+
+func TestStrengthFor(t *testing.T) {
+	stockfish := &process{spins: map[string]spinRange{
+		"UCI_Elo":     {min: 1320, max: 3190},
+		"Skill Level": {min: 0, max: 20},
+	}}
+	eloOnly := &process{spins: map[string]spinRange{"UCI_Elo": {min: 1320, max: 3190}}}
+
+	tests := []struct {
+		name string
+		p    *process
+		elo  int
+		want strength
+	}{
+		{"full strength", stockfish, 0, strength{skill: 20}},
+		{"within the range", stockfish, 1500, strength{limit: true, elo: 1500, skill: 20}},
+		{"above the range", stockfish, 4000, strength{limit: true, elo: 3190, skill: 20}},
+		{"below the range", stockfish, 300, strength{skill: 0}},
+		{"below the range without Skill Level", eloOnly, 300, strength{limit: true, elo: 1320, skill: -1}},
+		{"no strength options", &process{}, 1500, strength{skill: -1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.p.strengthFor(tt.elo); got != tt.want {
+				t.Errorf("strengthFor(%d) = %+v, want %+v", tt.elo, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSpinOption(t *testing.T) {
+	tests := []struct {
+		line string
+		name string
+		r    spinRange
+		ok   bool
+	}{
+		{"name UCI_Elo type spin default 1320 min 1320 max 3190", "UCI_Elo", spinRange{1320, 3190}, true},
+		{"name Skill Level type spin default 20 min 0 max 20", "Skill Level", spinRange{0, 20}, true},
+		{"name UCI_LimitStrength type check default false", "", spinRange{}, false},
+		{"name Hash type spin default 16 min 1", "", spinRange{}, false},
+		{"name Threads type spin default 1 min x max 1024", "", spinRange{}, false},
+	}
+	for _, tt := range tests {
+		name, r, ok := parseSpinOption(strings.Fields(tt.line))
+		if name != tt.name || r != tt.r || ok != tt.ok {
+			t.Errorf("parseSpinOption(%q) = %q, %+v, %v; want %q, %+v, %v", tt.line, name, r, ok, tt.name, tt.r, tt.ok)
+		}
+	}
+}
+
+func TestClamp(t *testing.T) {
+	p := NewPool(Options{Path: "unused", MaxDepth: 20, MaxMoveTime: time.Second})
+
+	got := p.clamp(Limits{})
+	if got.Depth != DefaultDepth || got.MultiPV != 1 {
+		t.Errorf("expected default depth and one line, got %+v", got)
+	}
+	got = p.clamp(Limits{Depth: 99, MoveTime: time.Minute, MultiPV: 50})
+	if got.Depth != 20 || got.MoveTime != time.Second || got.MultiPV != MaxMultiPV {
+		t.Errorf("expected limits capped, got %+v", got)
+	}
+}
+
+func TestParseInfo(t *testing.T) {
+	tests := []struct {
+		line        string
+		whiteToMove bool
+		ok          bool
+		cp, mate    *int
+	}{
+		{"depth 10 multipv 2 score cp 35 nodes 5 pv e2e4", true, true, intPtr(35), nil},
+		{"depth 10 score cp 35 pv e2e4", false, true, intPtr(-35), nil},
+		{"depth 10 score mate 3 pv d1h5", true, true, nil, intPtr(3)},
+		{"depth 10 score mate 2 pv d8h4", false, true, nil, intPtr(-2)},
+		{"depth 10 score cp 35 lowerbound pv e2e4", true, false, nil, nil},
+		{"depth 10 currmove e2e4 currmovenumber 1", true, false, nil, nil},
+		{"string NNUE evaluation enabled", true, false, nil, nil},
+	}
+	for _, tt := range tests {
+		l, ok := parseInfo(strings.Fields(tt.line), tt.whiteToMove)
+		if ok != tt.ok {
+			t.Errorf("parseInfo(%q) ok = %v, want %v", tt.line, ok, tt.ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if !equalIntPtr(l.CP, tt.cp) || !equalIntPtr(l.Mate, tt.mate) {
+			t.Errorf("parseInfo(%q) score = %+v", tt.line, l.Score)
+		}
+	}
+}
+
+func intPtr(n int) *int { return &n }
+
+func equalIntPtr(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -0,0 +1,422 @@
+package engine
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// Time allowed for the engine to answer uci and isready
+	handshakeTimeout = 10 * time.Second
+
+	// Time allowed past the search limit before the search is stopped, and after
+	// stop before the engine is considered hung
+	searchGrace = 2 * time.Second
+)
+
+// process is one running UCI engine. It serves one search at a time.
+type process struct {
+	cmd      *exec.Cmd
+	stdin    io.WriteCloser
+	lines    chan string          // engine output; closed when the process exits
+	spins    map[string]spinRange // spin options the engine declared, by name
+	multiPV  int                  // MultiPV option currently set
+	strength strength             // strength options currently set
+}
+
+// spinRange is the range of values a spin option accepts
+type spinRange struct {
+	min, max int
+}
+
+// strength is how the engine's playing strength is limited. Engines ignore values
+// outside an option's range, so only values within range are ever set.
+type strength struct {
+	limit bool // UCI_LimitStrength
+	elo   int  // UCI_Elo while limit is set
+	skill int  // Skill Level; -1 when the engine has no such option
+}
+
+// startProcess starts the engine and completes the UCI handshake
+func startProcess(path string, args []string) (*process, error) {
+	cmd := exec.Command(path, args...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	p := &process{
+		cmd:     cmd,
+		stdin:   stdin,
+		lines:   make(chan string, 64),
+		spins:   make(map[string]spinRange),
+		multiPV: 1,
+	}
+	go p.readLoop(stdout)
+
+	if err := p.send("uci"); err != nil {
+		p.kill()
+		return nil, err
+	}
+	if err := p.readOptions(handshakeTimeout); err != nil {
+		p.kill()
+		return nil, err
+	}
+	// The engine starts at full strength
+	p.strength = p.strengthFor(0)
+	return p, nil
+}
+
+// readOptions records the spin options the engine declares until uciok
+func (p *process) readOptions(timeout time.Duration) error {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case line, ok := <-p.lines:
+			if !ok {
+				return fmt.Errorf("%w: exited waiting for uciok", ErrEngineFailed)
+			}
+			fields := strings.Fields(line)
+			if len(fields) == 0 {
+				continue
+			}
+			switch fields[0] {
+			case "uciok":
+				return nil
+			case "option":
+				if name, r, ok := parseSpinOption(fields[1:]); ok {
+					p.spins[name] = r
+				}
+			}
+		case <-timer.C:
+			return fmt.Errorf("%w: no uciok within %s", ErrEngineFailed, timeout)
+		}
+	}
+}
+
+// parseSpinOption reads the name and range of a spin option, e.g.
+// "name Skill Level type spin default 20 min 0 max 20"
+func parseSpinOption(fields []string) (string, spinRange, bool) {
+	var name []string
+	var r spinRange
+	var spin, hasMin, hasMax bool
+	for i := 0; i < len(fields); i++ {
+		switch fields[i] {
+		case "name":
+			for i+1 < len(fields) && fields[i+1] != "type" {
+				name = append(name, fields[i+1])
+				i++
+			}
+		case "type":
+			spin = i+1 < len(fields) && fields[i+1] == "spin"
+			i++
+		case "min", "max":
+			if i+1 >= len(fields) {
+				return "", spinRange{}, false
+			}
+			n, err := strconv.Atoi(fields[i+1])
+			if err != nil {
+				return "", spinRange{}, false
+			}
+			if fields[i] == "min" {
+				r.min, hasMin = n, true
+			} else {
+				r.max, hasMax = n, true
+			}
+			i++
+		}
+	}
+	if !spin || !hasMin || !hasMax || len(name) == 0 {
+		return "", spinRange{}, false
+	}
+	return strings.Join(name, " "), r, true
+}
+
+func (p *process) readLoop(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		p.lines <- scanner.Text()
+	}
+	close(p.lines)
+}
+
+func (p *process) send(command string) error {
+	if _, err := io.WriteString(p.stdin, command+"\n"); err != nil {
+		return fmt.Errorf("%w: %v", ErrEngineFailed, err)
+	}
+	return nil
+}
+
+// waitFor reads output until a line starting with token
+func (p *process) waitFor(token string, timeout time.Duration) error {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case line, ok := <-p.lines:
+			if !ok {
+				return fmt.Errorf("%w: exited waiting for %s", ErrEngineFailed, token)
+			}
+			if fields := strings.Fields(line); len(fields) > 0 && fields[0] == token {
+				return nil
+			}
+		case <-timer.C:
+			return fmt.Errorf("%w: no %s within %s", ErrEngineFailed, token, timeout)
+		}
+	}
+}
+
+// search runs one search. Searches without a time limit are stopped at maxTime.
+// When ctx ends the search is stopped early and ctx's error returned; the process
+// stays usable as long as it answers stop.
+func (p *process) search(ctx context.Context, pos *position, limits Limits, maxTime time.Duration) (*Analysis, error) {
+	if limits.MultiPV != p.multiPV {
+		if err := p.send("setoption name MultiPV value " + strconv.Itoa(limits.MultiPV)); err != nil {
+			return nil, err
+		}
+		p.multiPV = limits.MultiPV
+	}
+	if err := p.setStrength(p.strengthFor(limits.Elo)); err != nil {
+		return nil, err
+	}
+	if err := p.send("ucinewgame"); err != nil {
+		return nil, err
+	}
+	if err := p.send("isready"); err != nil {
+		return nil, err
+	}
+	if err := p.waitFor("readyok", handshakeTimeout); err != nil {
+		return nil, err
+	}
+
+	goCommand := "go"
+	if limits.Depth > 0 {
+		goCommand += " depth " + strconv.Itoa(limits.Depth)
+	}
+	if limits.MoveTime > 0 {
+		goCommand += " movetime " + strconv.FormatInt(limits.MoveTime.Milliseconds(), 10)
+	}
+	if err := p.send(pos.command); err != nil {
+		return nil, err
+	}
+	if err := p.send(goCommand); err != nil {
+		return nil, err
+	}
+
+	searchTime := maxTime
+	if limits.MoveTime > 0 {
+		searchTime = limits.MoveTime
+	}
+	deadline := time.NewTimer(searchTime + searchGrace)
+	defer deadline.Stop()
+
+	done := ctx.Done()
+	stopped := false
+	stop := func() error {
+		stopped = true
+		deadline.Reset(searchGrace)
+		return p.send("stop")
+	}
+
+	best := make(map[int]Line)
+	for {
+		select {
+		case line, ok := <-p.lines:
+			if !ok {
+				return nil, fmt.Errorf("%w: exited during search", ErrEngineFailed)
+			}
+			fields := strings.Fields(line)
+			if len(fields) == 0 {
+				continue
+			}
+			switch fields[0] {
+			case "info":
+				if l, ok := parseInfo(fields[1:], pos.whiteToMove); ok && l.MultiPV <= limits.MultiPV {
+					best[l.MultiPV] = l
+				}
+			case "bestmove":
+				if err := ctx.Err(); err != nil {
+					return nil, err
+				}
+				return newAnalysis(fields, best), nil
+			}
+
+		case <-done:
+			done = nil
+			if !stopped {
+				if err := stop(); err != nil {
+					return nil, err
+				}
+			}
+
+		case <-deadline.C:
+			if stopped {
+				return nil, fmt.Errorf("%w: no bestmove after stop", ErrEngineFailed)
+			}
+			if err := stop(); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// strengthFor returns the options that come closest to playing at elo, or at full
+// strength when elo is 0. Elo is clamped to the engine's UCI_Elo range; below its
+// minimum the engine plays at its lowest Skill Level instead, when it has one.
+func (p *process) strengthFor(elo int) strength {
+	s := strength{skill: -1}
+	skill, hasSkill := p.spins["Skill Level"]
+	if hasSkill {
+		s.skill = skill.max
+	}
+	if elo <= 0 {
+		return s
+	}
+	if r, ok := p.spins["UCI_Elo"]; ok && (elo >= r.min || !hasSkill) {
+		s.limit, s.elo = true, min(max(elo, r.min), r.max)
+		return s
+	}
+	if hasSkill {
+		s.skill = skill.min
+	}
+	return s
+}
+
+// setStrength sets the options of s that differ from those currently set.
+// UCI_LimitStrength must be set before UCI_Elo takes effect.
+func (p *process) setStrength(s strength) error {
+	if s.limit != p.strength.limit {
+		if err := p.send("setoption name UCI_LimitStrength value " + strconv.FormatBool(s.limit)); err != nil {
+			return err
+		}
+	}
+	if s.limit && s.elo != p.strength.elo {
+		if err := p.send("setoption name UCI_Elo value " + strconv.Itoa(s.elo)); err != nil {
+			return err
+		}
+	}
+	if s.skill >= 0 && s.skill != p.strength.skill {
+		if err := p.send("setoption name Skill Level value " + strconv.Itoa(s.skill)); err != nil {
+			return err
+		}
+	}
+	p.strength = s
+	return nil
+}
+
+func newAnalysis(bestmove []string, best map[int]Line) *Analysis {
+	a := &Analysis{Lines: make([]Line, 0, len(best))}
+	if len(bestmove) > 1 && bestmove[1] != "(none)" {
+		a.BestMove = bestmove[1]
+	}
+	for _, l := range best {
+		a.Lines = append(a.Lines, l)
+	}
+	sort.Slice(a.Lines, func(i, j int) bool { return a.Lines[i].MultiPV < a.Lines[j].MultiPV })
+	if len(a.Lines) > 0 {
+		a.Depth = a.Lines[0].Depth
+	}
+	return a
+}
+
+// parseInfo reads an info line that carries a score and a principal variation.
+// Bound scores from an unfinished iteration are skipped.
+func parseInfo(fields []string, whiteToMove bool) (Line, bool) {
+	line := Line{MultiPV: 1}
+	sign := 1
+	if !whiteToMove {
+		sign = -1
+	}
+
+	for i := 0; i < len(fields); i++ {
+		switch fields[i] {
+		case "depth", "multipv":
+			if i+1 >= len(fields) {
+				return Line{}, false
+			}
+			n, err := strconv.Atoi(fields[i+1])
+			if err != nil {
+				return Line{}, false
+			}
+			if fields[i] == "depth" {
+				line.Depth = n
+			} else {
+				line.MultiPV = n
+			}
+			i++
+		case "score":
+			if i+2 >= len(fields) {
+				return Line{}, false
+			}
+			n, err := strconv.Atoi(fields[i+2])
+			if err != nil {
+				return Line{}, false
+			}
+			n *= sign
+			switch fields[i+1] {
+			case "cp":
+				line.CP = &n
+			case "mate":
+				line.Mate = &n
+			default:
+				return Line{}, false
+			}
+			i += 2
+		case "lowerbound", "upperbound":
+			return Line{}, false
+		case "pv":
+			line.Moves = append([]string(nil), fields[i+1:]...)
+			i = len(fields)
+		}
+	}
+
+	if (line.CP == nil && line.Mate == nil) || len(line.Moves) == 0 {
+		return Line{}, false
+	}
+	return line, true
+}
+
+// quit asks the engine to exit, killing it if it does not
+func (p *process) quit() {
+	go p.drain()
+	p.send("quit")
+	p.stdin.Close()
+	exited := make(chan struct{})
+	go func() {
+		p.cmd.Wait()
+		close(exited)
+	}()
+	select {
+	case <-exited:
+	case <-time.After(time.Second):
+		p.cmd.Process.Kill()
+	}
+}
+
+func (p *process) kill() {
+	go p.drain()
+	p.stdin.Close()
+	p.cmd.Process.Kill()
+	go p.cmd.Wait()
+}
+
+// drain discards output nobody waits for, so the reader can finish
+func (p *process) drain() {
+	for range p.lines {
+	}
+}
@@ -64,6 +64,16 @@ func NewSecureCookie(cfg *config.Config, name, value string, maxAge int) *http.C
 	}
 }
 
+// BearerToken returns the token of an "Authorization: Bearer <token>" header
+func BearerToken(r *http.Request) (string, bool) {
+	scheme, token, found := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
 // GetClientIP extracts the client IP from an HTTP request.
 // Only trusts X-Forwarded-For and X-Real-IP headers when the request
 // originates from a configured trusted proxy. Falls back to RemoteAddr.
@@ -132,6 +132,31 @@ func TestNewSecureCookie_Prod(t *testing.T) {
 	}
 }
 
+func TestBearerToken(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+		ok     bool
+	}{
+		{"Bearer abc123", "abc123", true},
+		{"bearer abc123", "abc123", true},
+		{"Bearer ", "", false},
+		{"Basic dXNlcjpwYXNz", "", false},
+		{"abc123", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tt.header != "" {
+			r.Header.Set("Authorization", tt.header)
+		}
+		got, ok := BearerToken(r)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("BearerToken(%q) = (%q, %v), want (%q, %v)", tt.header, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
 func TestGetClientIP_NoProxy(t *testing.T) {
 	cfg := &config.Config{
 		TrustedProxies: nil,
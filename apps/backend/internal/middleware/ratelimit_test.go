@@ -121,3 +121,60 @@ func TestNewAPIRateLimiter(t *testing.T) {
 		t.Fatal("expected non-nil API rate limiter")
 	}
 }
+
+func TestRateLimiter_Refill(t *testing.T) {
+	rl := NewRateLimiter(1, 20*time.Millisecond, 1, nil)
+	if !rl.Allow("client-a") {
+		t.Fatal("expected first request to be allowed")
+	}
+
+	// Calls more frequent than the interval must not postpone the refill
+	allowed := false
+	for deadline := time.Now().Add(200 * time.Millisecond); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		if rl.Allow("client-a") {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		t.Fatal("expected a token to be refilled while the client kept calling")
+	}
+}
+
+func TestRateLimiter_TokenMiddleware(t *testing.T) {
+	rl := NewRateLimiter(10, time.Minute, 1, nil)
+	handler := rl.TokenMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	serve := func(token string) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/bot/game/g1/move/e2e4", nil)
+		req.RemoteAddr = "10.0.0.3:12345"
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	// Two tokens behind one address each get their own budget
+	for _, token := range []string{"nxt_token-a", "nxt_token-b"} {
+		if code := serve(token); code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d", token, code)
+		}
+	}
+	if code := serve("nxt_token-a"); code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 once a token's budget is spent, got %d", code)
+	}
+	if code := serve(""); code != http.StatusOK {
+		t.Fatalf("expected a request without a token to be limited by IP, got %d", code)
+	}
+}
+
+func TestNewBotRateLimiter(t *testing.T) {
+	rl := NewBotRateLimiter(nil)
+	if rl == nil {
+		t.Fatal("expected non-nil bot rate limiter")
+	}
+}
@@ -102,6 +102,12 @@ func (w *statusResponseWriter) Write(b []byte) (int, error) {
 	return w.ResponseWriter.Write(b)
 }
 
+// Unwrap lets http.ResponseController reach the underlying writer, e.g. to flush
+// streamed responses
+func (w *statusResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
 // LogFieldsWithRequestID returns logger fields including the request ID from context.
 // Use this to add request ID to error logs within handlers.
 func LogFieldsWithRequestID(ctx context.Context, keyvals ...interface{}) map[string]interface{} {
@@ -4,33 +4,88 @@ import (
 	"context"
 	"net/http"
 
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/apitokens"
 	"github.com/tmcarmichael/nxtchess/apps/backend/internal/httpx"
 	"github.com/tmcarmichael/nxtchess/apps/backend/internal/sessions"
 )
 
 type contextKey string
 
-const userIDKey contextKey = "userID"
+const (
+	userIDKey    contextKey = "userID"
+	sessionIDKey contextKey = "sessionID"
+	scopesKey    contextKey = "scopes"
+)
+
+var (
+	sessionLookup  = sessions.GetSessionUserID
+	bearerLookup   = sessions.GetAccessTokenSession
+	apiTokenLookup = apitokens.Lookup
+)
+
+// credential is how a request identified its session
+type credential int
+
+const (
+	noCredential credential = iota
+	cookieCredential
+	bearerCredential
+	apiTokenCredential
+)
+
+// auth is who a request is authenticated as. Scopes is nil unless the request used a
+// personal API token.
+type auth struct {
+	userID    string
+	sessionID string
+	scopes    []string
+}
+
+// authenticate resolves a request from its Authorization: Bearer token (a personal
+// API token or a mobile access token), or else its session cookie
+func authenticate(r *http.Request) (a auth, cred credential, ok bool) {
+	if token, found := httpx.BearerToken(r); found {
+		if apitokens.IsToken(token) {
+			if a.userID, a.scopes, ok = apiTokenLookup(token); ok {
+				if a.scopes == nil {
+					a.scopes = []string{}
+				}
+				return a, apiTokenCredential, true
+			}
+		}
+		a.userID, a.sessionID, ok = bearerLookup(token)
+		return a, bearerCredential, ok
+	}
 
-var sessionLookup = sessions.GetSessionUserID
+	cookie, err := r.Cookie("session_token")
+	if err != nil {
+		return a, noCredential, false
+	}
+	if a.userID, ok = sessionLookup(cookie.Value); !ok {
+		return a, cookieCredential, false
+	}
+	a.sessionID = sessions.SessionID(cookie.Value)
+	return a, cookieCredential, true
+}
 
+// Session rejects requests without a valid session cookie or bearer token and puts
+// the user and session IDs (or API token scopes) in the request context
 func Session(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		cookie, err := r.Cookie("session_token")
-		if err != nil {
-			httpx.WriteJSONError(w, http.StatusUnauthorized, "Missing session cookie")
+		a, cred, ok := authenticate(r)
+		if cred == noCredential {
+			httpx.WriteJSONError(w, http.StatusUnauthorized, "Missing session cookie or bearer token")
 			return
 		}
-
-		userID, found := sessionLookup(cookie.Value)
-		if !found {
-			clearStaleCookie(w)
+		if !ok {
+			if cred == cookieCredential {
+				clearStaleCookie(w)
+			}
 			httpx.WriteJSONError(w, http.StatusUnauthorized, "Invalid or expired session token")
 			return
 		}
 
-		ctx := context.WithValue(r.Context(), userIDKey, userID)
-		next.ServeHTTP(w, r.WithContext(ctx))
+		next.ServeHTTP(w, r.WithContext(withAuth(r.Context(), a)))
 	})
 }
 
@@ -38,19 +93,24 @@ func Session(next http.Handler) http.Handler {
 // but does not reject the request if no session is present.
 func OptionalSession(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		cookie, err := r.Cookie("session_token")
-		if err == nil {
-			if userID, found := sessionLookup(cookie.Value); found {
-				ctx := context.WithValue(r.Context(), userIDKey, userID)
-				r = r.WithContext(ctx)
-			} else {
-				clearStaleCookie(w)
-			}
+		a, cred, ok := authenticate(r)
+		if ok {
+			r = r.WithContext(withAuth(r.Context(), a))
+		} else if cred == cookieCredential {
+			clearStaleCookie(w)
 		}
 		next.ServeHTTP(w, r)
 	})
 }
 
+func withAuth(ctx context.Context, a auth) context.Context {
+	ctx = context.WithValue(ctx, userIDKey, a.userID)
+	if a.scopes != nil {
+		return context.WithValue(ctx, scopesKey, a.scopes)
+	}
+	return context.WithValue(ctx, sessionIDKey, a.sessionID)
+}
+
 func clearStaleCookie(w http.ResponseWriter) {
 	http.SetCookie(w, &http.Cookie{
 		Name:     "session_token",
@@ -65,3 +125,58 @@ func UserIDFromContext(ctx context.Context) (string, bool) {
 	id, ok := ctx.Value(userIDKey).(string)
 	return id, ok
 }
+
+// SessionIDFromContext returns the ID of the session that authenticated the request
+func SessionIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(sessionIDKey).(string)
+	return id, ok
+}
+
+// ScopesFromContext returns the scopes of the personal API token that authenticated
+// the request. ok is false for requests made with a session, which are not limited
+// by scopes.
+func ScopesFromContext(ctx context.Context) (scopes []string, ok bool) {
+	scopes, ok = ctx.Value(scopesKey).([]string)
+	return scopes, ok
+}
+
+// HasScope reports whether the request may act within scope: it was made with a
+// session, or with an API token granted scope
+func HasScope(ctx context.Context, scope string) bool {
+	scopes, ok := ScopesFromContext(ctx)
+	if !ok {
+		return true
+	}
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// RequireScope rejects requests made with an API token that was not granted scope.
+// It must run after Session.
+func RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !HasScope(r.Context(), scope) {
+				httpx.WriteJSONError(w, http.StatusForbidden, "API token is missing the "+scope+" scope")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// DenyAPITokens rejects requests made with an API token, keeping account management
+// to signed-in sessions. It must run after Session.
+func DenyAPITokens(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := ScopesFromContext(r.Context()); ok {
+			httpx.WriteJSONError(w, http.StatusForbidden, "API tokens cannot be used for this endpoint")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -5,6 +5,8 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/sessions"
 )
 
 func TestSession_ValidCookie(t *testing.T) {
@@ -17,10 +19,11 @@ func TestSession_ValidCookie(t *testing.T) {
 	}
 	defer func() { sessionLookup = original }()
 
-	var capturedUserID string
+	var capturedUserID, capturedSessionID string
 	var found bool
 	inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		capturedUserID, found = UserIDFromContext(r.Context())
+		capturedSessionID, _ = SessionIDFromContext(r.Context())
 		w.WriteHeader(http.StatusOK)
 	})
 	handler := Session(inner)
@@ -39,6 +42,9 @@ func TestSession_ValidCookie(t *testing.T) {
 	if capturedUserID != "user-123" {
 		t.Errorf("expected userID 'user-123', got %q", capturedUserID)
 	}
+	if want := sessions.SessionID("valid-token"); capturedSessionID != want {
+		t.Errorf("expected sessionID %q, got %q", want, capturedSessionID)
+	}
 }
 
 func TestSession_MissingCookie(t *testing.T) {
@@ -133,3 +139,186 @@ func TestOptionalSession_NoCookie(t *testing.T) {
 		t.Fatalf("expected 200 even without cookie, got %d", rr.Code)
 	}
 }
+
+func TestSession_ValidBearer(t *testing.T) {
+	original := bearerLookup
+	bearerLookup = func(token string) (string, string, bool) {
+		if token == "access-token" {
+			return "user-789", "session-789", true
+		}
+		return "", "", false
+	}
+	defer func() { bearerLookup = original }()
+
+	var capturedUserID, capturedSessionID string
+	inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		capturedUserID, _ = UserIDFromContext(r.Context())
+		capturedSessionID, _ = SessionIDFromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := Session(inner)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer access-token")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	if capturedUserID != "user-789" || capturedSessionID != "session-789" {
+		t.Errorf("expected user-789/session-789, got %q/%q", capturedUserID, capturedSessionID)
+	}
+}
+
+func TestSession_InvalidBearer(t *testing.T) {
+	original := bearerLookup
+	bearerLookup = func(token string) (string, string, bool) {
+		return "", "", false
+	}
+	defer func() { bearerLookup = original }()
+
+	inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := Session(inner)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer expired-token")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", rr.Code)
+	}
+	if cookies := rr.Result().Cookies(); len(cookies) != 0 {
+		t.Errorf("expected no cookie to be cleared for a bearer token, got %v", cookies)
+	}
+}
+
+func TestOptionalSession_ValidBearer(t *testing.T) {
+	original := bearerLookup
+	bearerLookup = func(token string) (string, string, bool) {
+		return "user-789", "session-789", token == "access-token"
+	}
+	defer func() { bearerLookup = original }()
+
+	var capturedUserID string
+	inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		capturedUserID, _ = UserIDFromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := OptionalSession(inner)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer access-token")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if capturedUserID != "user-789" {
+		t.Errorf("expected userID 'user-789', got %q", capturedUserID)
+	}
+}
+
+func TestSession_APIToken(t *testing.T) {
+	original := apiTokenLookup
+	apiTokenLookup = func(token string) (string, []string, bool) {
+		if token == "nxt_valid" {
+			return "user-bot", []string{"games:read"}, true
+		}
+		return "", nil, false
+	}
+	defer func() { apiTokenLookup = original }()
+
+	var capturedUserID string
+	var capturedScopes []string
+	var scoped bool
+	inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		capturedUserID, _ = UserIDFromContext(r.Context())
+		capturedScopes, scoped = ScopesFromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := Session(inner)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer nxt_valid")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	if capturedUserID != "user-bot" {
+		t.Errorf("expected userID 'user-bot', got %q", capturedUserID)
+	}
+	if !scoped || len(capturedScopes) != 1 || capturedScopes[0] != "games:read" {
+		t.Errorf("expected scopes [games:read], got %v (ok=%v)", capturedScopes, scoped)
+	}
+}
+
+func TestRequireScope(t *testing.T) {
+	original := apiTokenLookup
+	apiTokenLookup = func(token string) (string, []string, bool) {
+		return "user-bot", []string{"games:read"}, true
+	}
+	defer func() { apiTokenLookup = original }()
+	originalSession := sessionLookup
+	sessionLookup = func(token string) (string, bool) {
+		return "user-123", true
+	}
+	defer func() { sessionLookup = originalSession }()
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name   string
+		scope  string
+		header string
+		cookie string
+		want   int
+	}{
+		{"token with scope", "games:read", "Bearer nxt_token", "", http.StatusOK},
+		{"token without scope", "puzzles:submit", "Bearer nxt_token", "", http.StatusForbidden},
+		{"session is not limited", "puzzles:submit", "", "valid-token", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := Session(RequireScope(tt.scope)(ok))
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: "session_token", Value: tt.cookie})
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+			if rr.Code != tt.want {
+				t.Errorf("expected %d, got %d", tt.want, rr.Code)
+			}
+		})
+	}
+}
+
+func TestDenyAPITokens(t *testing.T) {
+	original := apiTokenLookup
+	apiTokenLookup = func(token string) (string, []string, bool) {
+		return "user-bot", []string{"games:read", "bot:play", "puzzles:submit"}, true
+	}
+	defer func() { apiTokenLookup = original }()
+
+	handler := Session(DenyAPITokens(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer nxt_token")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,24 @@
+-- Migration: Add puzzles table for server-side puzzle verification
+
+-- Puzzles table
+-- fen is the position presented to the solver; moves is the solution line
+-- in UCI format starting with the solver's move and alternating sides
+CREATE TABLE IF NOT EXISTS puzzles (
+    puzzle_id   TEXT PRIMARY KEY,
+    fen         TEXT NOT NULL,
+    moves       TEXT NOT NULL,
+    rating      INT NOT NULL DEFAULT 1500 CHECK (rating >= 0 AND rating <= 4000),
+    themes      TEXT[] NOT NULL DEFAULT '{}',
+    popularity  INT NOT NULL DEFAULT 0,
+    nb_plays    INT NOT NULL DEFAULT 0,
+    created_at  TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS puzzles_rating_idx ON puzzles(rating);
+CREATE INDEX IF NOT EXISTS puzzles_themes_idx ON puzzles USING GIN(themes);
+
+COMMENT ON TABLE puzzles IS 'Tactical puzzles verified server-side';
+COMMENT ON COLUMN puzzles.moves IS 'Solution line in UCI, solver move first';
+COMMENT ON COLUMN puzzles.rating IS 'Puzzle difficulty rating, updated after each rated attempt';
+
+GRANT SELECT, INSERT, UPDATE ON puzzles TO anon;
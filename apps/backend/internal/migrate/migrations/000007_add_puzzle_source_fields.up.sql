@@ -0,0 +1,8 @@
+-- Migration: Add source metadata columns used by the Lichess puzzle importer
+
+ALTER TABLE puzzles ADD COLUMN rating_deviation INT NOT NULL DEFAULT 0;
+ALTER TABLE puzzles ADD COLUMN game_url TEXT NOT NULL DEFAULT '';
+ALTER TABLE puzzles ADD COLUMN opening_tags TEXT[] NOT NULL DEFAULT '{}';
+
+COMMENT ON COLUMN puzzles.rating_deviation IS 'Rating deviation reported by the puzzle source';
+COMMENT ON COLUMN puzzles.game_url IS 'Game the puzzle was extracted from';
@@ -0,0 +1,32 @@
+-- Migration: Record per-user puzzle attempts
+
+CREATE TABLE IF NOT EXISTS puzzle_attempts (
+    id             SERIAL PRIMARY KEY,
+    user_id        TEXT NOT NULL REFERENCES profiles(user_id) ON DELETE CASCADE,
+    puzzle_id      TEXT NOT NULL,
+    solved         BOOLEAN NOT NULL,
+    puzzle_rating  INT NOT NULL DEFAULT 0,
+    rating_before  INT NOT NULL,
+    rating_after   INT NOT NULL,
+    themes         TEXT[] NOT NULL DEFAULT '{}',
+    created_at     TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS puzzle_attempts_user_created_idx ON puzzle_attempts(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS puzzle_attempts_user_puzzle_idx ON puzzle_attempts(user_id, puzzle_id);
+
+COMMENT ON COLUMN puzzle_attempts.puzzle_id IS 'Puzzle ID; not a foreign key so client-side puzzles can be recorded too';
+
+-- Backfill from rating history so solved counts and streaks carry over.
+-- History rows do not record the outcome; a rating gain means the puzzle was solved.
+INSERT INTO puzzle_attempts (user_id, puzzle_id, solved, rating_before, rating_after, created_at)
+SELECT user_id, '', rating > rating_before, rating_before, rating, created_at
+FROM (
+    SELECT user_id, rating, created_at,
+           LAG(rating, 1, 1200) OVER (PARTITION BY user_id ORDER BY created_at, id) AS rating_before
+    FROM puzzle_rating_history
+) h
+ORDER BY created_at;
+
+GRANT SELECT, INSERT, UPDATE ON puzzle_attempts TO anon;
+GRANT USAGE, SELECT ON SEQUENCE puzzle_attempts_id_seq TO anon;
@@ -0,0 +1,2 @@
+-- Down migration: Remove middlegame_positions table
+DROP TABLE IF EXISTS middlegame_positions;
@@ -0,0 +1,45 @@
+-- Migration: Add middlegame_positions table for training mode
+
+-- Middlegame positions table
+-- Mirrors endgame_positions: curated middlegame positions for training
+CREATE TABLE IF NOT EXISTS middlegame_positions (
+    -- Unique position identifier
+    position_id     TEXT PRIMARY KEY,
+
+    -- FEN string for the position
+    fen             TEXT NOT NULL,
+
+    -- Suggested continuation in UCI format (e.g., "e2e4 e7e5 g1f3")
+    -- Optional - most middlegame positions are played out against the engine
+    moves           TEXT,
+
+    -- Position difficulty rating (1-3000 scale for compatibility)
+    rating          INT NOT NULL DEFAULT 1500 CHECK (rating >= 0 AND rating <= 4000),
+
+    -- Themes as array (e.g., {'attack'}, {'pawnStructure'})
+    themes          TEXT[] NOT NULL DEFAULT '{}',
+
+    -- Initial evaluation in centipawns (from white's perspective)
+    initial_eval    INT,
+
+    -- Human-readable description or title
+    description     TEXT,
+
+    -- Source attribution (e.g., "curated", an opening or game reference)
+    source          TEXT,
+
+    -- Import/creation metadata
+    created_at      TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+-- Rating index for difficulty-based filtering
+CREATE INDEX IF NOT EXISTS middlegame_positions_rating_idx ON middlegame_positions(rating);
+
+-- Theme filtering using GIN index for array contains queries
+CREATE INDEX IF NOT EXISTS middlegame_positions_themes_idx ON middlegame_positions USING GIN(themes);
+
+COMMENT ON TABLE middlegame_positions IS 'Middlegame training positions for practice mode';
+COMMENT ON COLUMN middlegame_positions.themes IS 'Array of theme tags (attack, defence, pawnStructure, pieceActivity)';
+COMMENT ON COLUMN middlegame_positions.rating IS 'Difficulty rating - higher means harder (1-3000 scale)';
+
+GRANT SELECT, INSERT, UPDATE ON middlegame_positions TO anon;
@@ -0,0 +1,36 @@
+-- Migration: Record per-user endgame training attempts and review schedule
+
+CREATE TABLE IF NOT EXISTS endgame_attempts (
+    id             SERIAL PRIMARY KEY,
+    user_id        TEXT NOT NULL REFERENCES profiles(user_id) ON DELETE CASCADE,
+    position_id    TEXT NOT NULL REFERENCES endgame_positions(position_id) ON DELETE CASCADE,
+    success        BOOLEAN NOT NULL,
+    time_ms        INT NOT NULL DEFAULT 0 CHECK (time_ms >= 0),
+    moves          TEXT[] NOT NULL DEFAULT '{}',
+    created_at     TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS endgame_attempts_user_created_idx ON endgame_attempts(user_id, created_at DESC);
+
+COMMENT ON COLUMN endgame_attempts.moves IS 'Moves played from the position in UCI, both sides';
+
+-- Spaced-repetition state (SM-2) per user and position
+CREATE TABLE IF NOT EXISTS endgame_reviews (
+    user_id          TEXT NOT NULL REFERENCES profiles(user_id) ON DELETE CASCADE,
+    position_id      TEXT NOT NULL REFERENCES endgame_positions(position_id) ON DELETE CASCADE,
+    ease_factor      REAL NOT NULL DEFAULT 2.5,
+    interval_days    INT NOT NULL DEFAULT 0,
+    repetitions      INT NOT NULL DEFAULT 0,
+    lapses           INT NOT NULL DEFAULT 0,
+    due_at           TIMESTAMP WITH TIME ZONE NOT NULL,
+    last_reviewed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, position_id)
+);
+
+CREATE INDEX IF NOT EXISTS endgame_reviews_user_due_idx ON endgame_reviews(user_id, due_at);
+
+COMMENT ON COLUMN endgame_reviews.repetitions IS 'Consecutive successful reviews; reset to 0 on failure';
+
+GRANT SELECT, INSERT, UPDATE ON endgame_attempts TO anon;
+GRANT USAGE, SELECT ON SEQUENCE endgame_attempts_id_seq TO anon;
+GRANT SELECT, INSERT, UPDATE ON endgame_reviews TO anon;
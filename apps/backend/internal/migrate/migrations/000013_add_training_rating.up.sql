@@ -0,0 +1,18 @@
+-- Migration: Server-judged endgame training results and the training rating
+
+-- Result the side to move should reach with best play: 'win', 'draw' or 'loss'.
+-- NULL means it is derived from initial_eval and the themes.
+ALTER TABLE endgame_positions ADD COLUMN expected_result TEXT
+    CHECK (expected_result IN ('win', 'draw', 'loss'));
+
+ALTER TABLE profiles ADD COLUMN training_rating INT NOT NULL DEFAULT 1200
+    CHECK (training_rating >= 0 AND training_rating <= 4000);
+
+-- Judged outcome and rating change of each attempt; NULL ratings mark unrated attempts
+ALTER TABLE endgame_attempts ADD COLUMN outcome TEXT;
+ALTER TABLE endgame_attempts ADD COLUMN rating_before INT;
+ALTER TABLE endgame_attempts ADD COLUMN rating_after INT;
+
+CREATE INDEX IF NOT EXISTS endgame_attempts_user_position_idx ON endgame_attempts(user_id, position_id, created_at DESC);
+
+COMMENT ON COLUMN endgame_attempts.outcome IS 'Reason the attempt was judged, e.g. checkmate, converted, held, move_limit';
@@ -0,0 +1,41 @@
+-- Migration: Map OAuth identities to internal user IDs
+--
+-- profiles.user_id used to be the raw ID returned by the OAuth provider, so the same
+-- person signing in with two providers had two accounts. Sign-in now resolves
+-- (provider, subject) through identities to an internal user ID.
+
+CREATE TABLE IF NOT EXISTS identities (
+    provider   TEXT NOT NULL,
+    subject    TEXT NOT NULL,
+    user_id    TEXT NOT NULL REFERENCES profiles(user_id) ON UPDATE CASCADE ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    PRIMARY KEY (provider, subject)
+);
+
+CREATE INDEX IF NOT EXISTS identities_user_id_idx ON identities(user_id);
+
+-- Let user ID changes cascade to every table that references profiles (games,
+-- rating_history, user_achievements, puzzle and training tables), keeping the
+-- existing ON DELETE behaviour of each foreign key
+DO $$
+DECLARE
+    fk RECORD;
+BEGIN
+    FOR fk IN
+        SELECT conrelid::regclass AS tbl, conname, pg_get_constraintdef(oid) AS def
+        FROM pg_constraint
+        WHERE contype = 'f' AND confrelid = 'profiles'::regclass AND confupdtype <> 'c'
+    LOOP
+        EXECUTE format('ALTER TABLE %s DROP CONSTRAINT %I', fk.tbl, fk.conname);
+        EXECUTE format('ALTER TABLE %s ADD CONSTRAINT %I %s ON UPDATE CASCADE', fk.tbl, fk.conname, fk.def);
+    END LOOP;
+END $$;
+
+-- The provider of existing accounts was never recorded. Keep each old user ID as a
+-- 'legacy' identity; the first provider to sign in with that subject claims it.
+INSERT INTO identities (provider, subject, user_id)
+SELECT 'legacy', user_id, user_id FROM profiles
+ON CONFLICT DO NOTHING;
+
+-- Move every profile to an internal ID; references follow through ON UPDATE CASCADE
+UPDATE profiles SET user_id = gen_random_uuid()::text;
@@ -0,0 +1,10 @@
+-- Migration: Bot accounts and a separate rated pool for games involving bots
+
+-- Accounts upgraded to bots play through the bot API and are flagged to opponents
+ALTER TABLE profiles ADD COLUMN is_bot BOOLEAN NOT NULL DEFAULT false;
+-- Rating in the bot pool: every rated game involving a bot, for bots and humans alike
+ALTER TABLE profiles ADD COLUMN bot_rating INT NOT NULL DEFAULT 1500 CHECK (bot_rating >= 0 AND bot_rating <= 4000);
+
+-- Which rated pool a game counted towards: 'standard' or 'bot'
+ALTER TABLE games ADD COLUMN rating_pool TEXT NOT NULL DEFAULT 'standard';
+ALTER TABLE rating_history ADD COLUMN pool TEXT NOT NULL DEFAULT 'standard';
@@ -0,0 +1,8 @@
+-- Migration: Server-run games against the computer and a separate computer rating
+
+-- Rating in rated games against the computer
+ALTER TABLE profiles ADD COLUMN computer_rating INT NOT NULL DEFAULT 1500 CHECK (computer_rating >= 0 AND computer_rating <= 4000);
+
+-- Games against the computer are stored whether or not they were rated; only rated
+-- games count towards a pool's games played
+ALTER TABLE games ADD COLUMN rated BOOLEAN NOT NULL DEFAULT true;
@@ -0,0 +1,6 @@
+-- Migration: Opening classification of stored games
+
+-- ECO code (A00-E99) and name of the opening, from the last book position the game
+-- reached; NULL for games stored before this migration or that never reached one
+ALTER TABLE games ADD COLUMN eco VARCHAR(3) CHECK (eco ~ '^[A-E][0-9]{2}$');
+ALTER TABLE games ADD COLUMN opening TEXT;
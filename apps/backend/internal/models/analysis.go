@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// Game analysis statuses
+const (
+	AnalysisPending = "pending"
+	AnalysisRunning = "running"
+	AnalysisDone    = "done"
+	AnalysisFailed  = "failed"
+)
+
+// Move classifications, by how much a move lowered the mover's winning chances
+const (
+	MoveInaccuracy = "inaccuracy"
+	MoveMistake    = "mistake"
+	MoveBlunder    = "blunder"
+)
+
+// GameAnalysis is the computer analysis of a stored game. Results are only set once
+// the status is done.
+type GameAnalysis struct {
+	GameID      string          `json:"game_id"`
+	Status      string          `json:"status"`
+	Depth       int             `json:"depth,omitempty"`
+	White       *PlayerAnalysis `json:"white,omitempty"`
+	Black       *PlayerAnalysis `json:"black,omitempty"`
+	Moves       []MoveAnalysis  `json:"moves,omitempty"`
+	Error       string          `json:"error,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+	CompletedAt *time.Time      `json:"completed_at,omitempty"`
+}
+
+// PlayerAnalysis summarizes one side's play
+type PlayerAnalysis struct {
+	Accuracy     float64 `json:"accuracy"` // 0-100
+	ACPL         int     `json:"acpl"`     // average centipawn loss
+	Inaccuracies int     `json:"inaccuracies"`
+	Mistakes     int     `json:"mistakes"`
+	Blunders     int     `json:"blunders"`
+}
+
+// MoveAnalysis is the evaluation of one ply
+type MoveAnalysis struct {
+	Ply            int     `json:"ply"`  // 1 for White's first move
+	Move           string  `json:"move"` // UCI
+	Eval           int     `json:"eval"` // centipawns after the move from White's point of view, mates as ±1000
+	Mate           *int    `json:"mate,omitempty"`
+	BestMove       string  `json:"best_move,omitempty"` // engine's choice in the position before the move
+	CPLoss         int     `json:"cp_loss"`
+	Accuracy       float64 `json:"accuracy"`
+	Classification string  `json:"classification,omitempty"`
+}
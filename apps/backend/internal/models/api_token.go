@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// Scopes a personal API token can be granted
+const (
+	// ScopeGamesRead allows reading the owner's games
+	ScopeGamesRead = "games:read"
+	// ScopeBotPlay allows playing games as a bot account
+	ScopeBotPlay = "bot:play"
+	// ScopePuzzlesSubmit allows fetching and submitting puzzles
+	ScopePuzzlesSubmit = "puzzles:submit"
+)
+
+// APITokenScopes lists every scope in the order they are shown to users
+var APITokenScopes = []string{ScopeGamesRead, ScopeBotPlay, ScopePuzzlesSubmit}
+
+// IsAPITokenScope reports whether scope is a known API token scope
+func IsAPITokenScope(scope string) bool {
+	for _, s := range APITokenScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// APIToken is a personal access token as shown to its owner. Prefix is the start of
+// the token so users can tell their tokens apart; the token itself is never stored.
+type APIToken struct {
+	TokenID    string     `json:"token_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"hash/fnv"
+	"time"
+)
+
+// DailyPuzzleDateLayout is the format of daily puzzle dates in requests and responses
+const DailyPuzzleDateLayout = "2006-01-02"
+
+// DailyPuzzleDate truncates t to its UTC calendar date
+func DailyPuzzleDate(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// DailyPuzzleIndex picks the position of the daily puzzle for date in a pool of
+// poolSize puzzles ordered by ID. The same date and pool always give the same index,
+// and consecutive dates are spread over the pool rather than walking through it.
+func DailyPuzzleIndex(date time.Time, poolSize int) int {
+	if poolSize <= 0 {
+		return 0
+	}
+	h := fnv.New64a()
+	h.Write([]byte(DailyPuzzleDate(date).Format(DailyPuzzleDateLayout)))
+	return int(h.Sum64() % uint64(poolSize))
+}
+
+// DailyPuzzleResult is a user's outcome on the daily puzzle of a date
+type DailyPuzzleResult struct {
+	Attempted bool `json:"attempted"`
+	Solved    bool `json:"solved"`
+	// Streak is the number of consecutive days up to today (or yesterday) the user solved the daily puzzle
+	Streak int `json:"streak"`
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDailyPuzzleIndexDeterministic(t *testing.T) {
+	date := time.Date(2025, 3, 14, 0, 0, 0, 0, time.UTC)
+	first := DailyPuzzleIndex(date, 1000)
+	for i := 0; i < 10; i++ {
+		if got := DailyPuzzleIndex(date, 1000); got != first {
+			t.Fatalf("DailyPuzzleIndex() = %d, want %d on repeated calls", got, first)
+		}
+	}
+
+	// Any time on the same UTC date selects the same puzzle
+	later := time.Date(2025, 3, 14, 23, 59, 0, 0, time.UTC)
+	if got := DailyPuzzleIndex(later, 1000); got != first {
+		t.Errorf("DailyPuzzleIndex(late same day) = %d, want %d", got, first)
+	}
+	tokyo := time.FixedZone("JST", 9*3600)
+	if got := DailyPuzzleIndex(time.Date(2025, 3, 15, 8, 0, 0, 0, tokyo), 1000); got != first {
+		t.Errorf("DailyPuzzleIndex(same UTC date in another zone) = %d, want %d", got, first)
+	}
+}
+
+func TestDailyPuzzleIndexRange(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	seen := make(map[int]bool)
+	for day := 0; day < 60; day++ {
+		idx := DailyPuzzleIndex(start.AddDate(0, 0, day), 500)
+		if idx < 0 || idx >= 500 {
+			t.Fatalf("DailyPuzzleIndex() = %d, out of range [0, 500)", idx)
+		}
+		seen[idx] = true
+	}
+	if len(seen) < 50 {
+		t.Errorf("60 days produced only %d distinct puzzles, want a spread", len(seen))
+	}
+
+	if got := DailyPuzzleIndex(start, 0); got != 0 {
+		t.Errorf("DailyPuzzleIndex(empty pool) = %d, want 0", got)
+	}
+}
@@ -1,18 +1,72 @@
 package models
 
-import "time"
+import (
+	"strings"
+	"time"
+)
 
 // EndgamePosition represents a training position for endgame practice
 type EndgamePosition struct {
-	PositionID  string    `json:"position_id"`
-	FEN         string    `json:"fen"`
-	Moves       string    `json:"moves,omitempty"`
-	Rating      int       `json:"rating"`
-	Themes      []string  `json:"themes"`
-	InitialEval *int      `json:"initial_eval,omitempty"`
-	Description string    `json:"description,omitempty"`
-	Source      string    `json:"source,omitempty"`
-	CreatedAt   time.Time `json:"created_at"`
+	PositionID     string    `json:"position_id"`
+	FEN            string    `json:"fen"`
+	Moves          string    `json:"moves,omitempty"`
+	Rating         int       `json:"rating"`
+	Themes         []string  `json:"themes"`
+	InitialEval    *int      `json:"initial_eval,omitempty"`
+	Description    string    `json:"description,omitempty"`
+	Source         string    `json:"source,omitempty"`
+	ExpectedResult string    `json:"expected_result,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// ExpectedResult values: the result the side to move should reach with best play
+const (
+	ExpectedWin  = "win"
+	ExpectedDraw = "draw"
+	ExpectedLoss = "loss"
+)
+
+// decisiveEval is the advantage, in centipawns for the side to move, from which a
+// position without a recorded result is treated as decisive
+const decisiveEval = 100
+
+// ExpectedOutcome returns the result the side to move should reach: the recorded
+// ExpectedResult, otherwise a win for basic mates and positions without an
+// evaluation, otherwise the result implied by InitialEval.
+func (p EndgamePosition) ExpectedOutcome() string {
+	if p.ExpectedResult != "" {
+		return p.ExpectedResult
+	}
+	for _, t := range p.Themes {
+		if t == "basicMate" {
+			return ExpectedWin
+		}
+	}
+	if p.InitialEval == nil {
+		return ExpectedWin
+	}
+
+	eval := *p.InitialEval
+	if len(p.FEN) > 0 && sideToMove(p.FEN) == "b" {
+		eval = -eval
+	}
+	switch {
+	case eval >= decisiveEval:
+		return ExpectedWin
+	case eval <= -decisiveEval:
+		return ExpectedLoss
+	default:
+		return ExpectedDraw
+	}
+}
+
+// sideToMove returns the second FEN field ("w" or "b")
+func sideToMove(fen string) string {
+	fields := strings.Fields(fen)
+	if len(fields) < 2 {
+		return ""
+	}
+	return fields[1]
 }
 
 // EndgamePositionResponse is the API response for training endpoints
@@ -24,6 +78,14 @@ type EndgamePositionResponse struct {
 	Difficulty     int    `json:"difficulty"`
 	SolutionMoves  string `json:"solution_moves,omitempty"`
 	ExpectedResult string `json:"expected_result,omitempty"`
+	// Review is set when the position was picked because the user's review of it is due
+	Review bool `json:"review,omitempty"`
+}
+
+// ThemeCount is the number of positions tagged with a theme
+type ThemeCount struct {
+	Theme string `json:"theme"`
+	Count int    `json:"count"`
 }
 
 // EndgameQueryParams holds filter parameters for querying positions
@@ -43,6 +105,11 @@ type EndgameQueryParams struct {
 	// RequirePawnForSideToMove when true, ensures the side to move has at least one pawn
 	// Used for knight/bishop endgames where a lone piece cannot force checkmate
 	RequirePawnForSideToMove bool
+	// DueForUserID when set, only returns positions this user has a review due for
+	DueForUserID string
+	// ExcludeScheduledFor when set, skips positions this user practised whose
+	// review is not due yet
+	ExcludeScheduledFor string
 }
 
 // DifficultyToRatingRange maps difficulty levels (1-10) to rating ranges
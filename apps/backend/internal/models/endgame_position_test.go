@@ -84,3 +84,29 @@ func TestDifficultyRatingRangeValidity(t *testing.T) {
 		}
 	}
 }
+
+func TestEndgamePositionExpectedOutcome(t *testing.T) {
+	eval := func(v int) *int { return &v }
+
+	tests := []struct {
+		name string
+		pos  EndgamePosition
+		want string
+	}{
+		{"recorded result wins", EndgamePosition{ExpectedResult: ExpectedDraw, InitialEval: eval(900)}, ExpectedDraw},
+		{"basic mate", EndgamePosition{Themes: []string{"basicMate"}, InitialEval: eval(0)}, ExpectedWin},
+		{"no evaluation", EndgamePosition{FEN: "8/8/8/8/8/8/8/8 w - - 0 1"}, ExpectedWin},
+		{"white to move, white better", EndgamePosition{FEN: "8/8/8/8/8/8/8/8 w - - 0 1", InitialEval: eval(300)}, ExpectedWin},
+		{"black to move, black better", EndgamePosition{FEN: "8/8/8/8/8/8/8/8 b - - 0 1", InitialEval: eval(-300)}, ExpectedWin},
+		{"black to move, white better", EndgamePosition{FEN: "8/8/8/8/8/8/8/8 b - - 0 1", InitialEval: eval(300)}, ExpectedLoss},
+		{"balanced", EndgamePosition{FEN: "8/8/8/8/8/8/8/8 w - - 0 1", InitialEval: eval(40)}, ExpectedDraw},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.pos.ExpectedOutcome(); got != tt.want {
+				t.Errorf("ExpectedOutcome() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,252 @@
+package models
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// SM-2 constants for endgame spaced repetition
+const (
+	DefaultEaseFactor = 2.5
+	MinEaseFactor     = 1.3
+	// MasteryRepetitions is the number of consecutive successful reviews after
+	// which a position counts as mastered
+	MasteryRepetitions = 3
+)
+
+// EndgameRepeatWindow is how long after an attempt on a position a further attempt
+// on it leaves the training rating unchanged
+const EndgameRepeatWindow = 24 * time.Hour
+
+// EndgameAttempt is one training attempt of a user on an endgame position
+type EndgameAttempt struct {
+	UserID     string
+	PositionID string
+	Success    bool
+	TimeMs     int
+	Moves      []string
+	// Outcome is the reason the server judged the attempt by; empty when reported by the client
+	Outcome string
+	// Rated is set when the attempt changed the training rating from RatingBefore to RatingAfter
+	Rated        bool
+	RatingBefore int
+	RatingAfter  int
+	CreatedAt    time.Time
+}
+
+// EndgameReview is a user's SM-2 review state for one endgame position
+type EndgameReview struct {
+	PositionID     string    `json:"position_id"`
+	EaseFactor     float64   `json:"ease_factor"`
+	IntervalDays   int       `json:"interval_days"`
+	Repetitions    int       `json:"repetitions"`
+	Lapses         int       `json:"lapses"`
+	DueAt          time.Time `json:"due_at"`
+	LastReviewedAt time.Time `json:"last_reviewed_at"`
+}
+
+// NewEndgameReview returns the review state of a position never practised before
+func NewEndgameReview(positionID string) EndgameReview {
+	return EndgameReview{PositionID: positionID, EaseFactor: DefaultEaseFactor}
+}
+
+// ReviewQuality grades an attempt on the SM-2 0-5 scale. A failure is graded 1, so
+// the position comes back the next day; a success is graded by how long it took.
+func ReviewQuality(success bool, elapsed time.Duration) int {
+	switch {
+	case !success:
+		return 1
+	case elapsed <= time.Minute:
+		return 5
+	case elapsed <= 3*time.Minute:
+		return 4
+	default:
+		return 3
+	}
+}
+
+// Schedule returns the review state after an attempt graded quality (0-5) at now.
+// A quality below 3 is a lapse: repetitions restart and the position is due again
+// in a day. Otherwise the interval grows 1, 6, then by the ease factor (SM-2).
+func (r EndgameReview) Schedule(quality int, now time.Time) EndgameReview {
+	if quality < 0 {
+		quality = 0
+	} else if quality > 5 {
+		quality = 5
+	}
+	if r.EaseFactor == 0 {
+		r.EaseFactor = DefaultEaseFactor
+	}
+
+	if quality < 3 {
+		r.Repetitions = 0
+		r.IntervalDays = 1
+		r.Lapses++
+	} else {
+		switch r.Repetitions {
+		case 0:
+			r.IntervalDays = 1
+		case 1:
+			r.IntervalDays = 6
+		default:
+			r.IntervalDays = int(math.Round(float64(r.IntervalDays) * r.EaseFactor))
+		}
+		r.Repetitions++
+	}
+
+	q := float64(5 - quality)
+	r.EaseFactor += 0.1 - q*(0.08+q*0.02)
+	if r.EaseFactor < MinEaseFactor {
+		r.EaseFactor = MinEaseFactor
+	}
+
+	r.LastReviewedAt = now
+	r.DueAt = now.AddDate(0, 0, r.IntervalDays)
+	return r
+}
+
+// Mastered reports whether the position has been solved MasteryRepetitions times in a row
+func (r EndgameReview) Mastered() bool {
+	return r.Repetitions >= MasteryRepetitions
+}
+
+// EndgamePositionProgress is a user's record on one endgame position they attempted
+type EndgamePositionProgress struct {
+	PositionID string
+	Rating     int
+	Themes     []string
+	Attempts   int
+	Successes  int
+	Review     EndgameReview
+}
+
+// MasteryStat summarizes a user's progress on a group of endgame positions
+type MasteryStat struct {
+	// Total is the number of positions in the group
+	Total int `json:"total"`
+	// Attempted is the number of positions the user has practised
+	Attempted int `json:"attempted"`
+	// Solved is the number of positions the user has solved at least once
+	Solved int `json:"solved"`
+	// Mastered is the number of positions with MasteryRepetitions successful reviews in a row
+	Mastered int `json:"mastered"`
+	// Due is the number of practised positions scheduled for review
+	Due int `json:"due"`
+	// Mastery is Mastered as a fraction of Total
+	Mastery float64 `json:"mastery"`
+}
+
+// ThemeMastery is the progress on positions tagged with a theme
+type ThemeMastery struct {
+	Theme string `json:"theme"`
+	MasteryStat
+}
+
+// DifficultyMastery is the progress on positions of a difficulty level (1-10)
+type DifficultyMastery struct {
+	Difficulty int `json:"difficulty"`
+	MasteryStat
+}
+
+// BuildEndgameProgress groups a user's per-position progress by theme and by
+// difficulty. themeTotals and ratingTotals hold the number of positions per theme
+// and per rating; groups with no positions are left out.
+func BuildEndgameProgress(progress []EndgamePositionProgress, themeTotals map[string]int, ratingTotals map[int]int, now time.Time) ([]ThemeMastery, []DifficultyMastery) {
+	byTheme := make(map[string]*MasteryStat)
+	for theme, n := range themeTotals {
+		byTheme[theme] = &MasteryStat{Total: n}
+	}
+	byDifficulty := make(map[int]*MasteryStat)
+	for rating, n := range ratingTotals {
+		d := RatingToDifficulty(rating)
+		if byDifficulty[d] == nil {
+			byDifficulty[d] = &MasteryStat{}
+		}
+		byDifficulty[d].Total += n
+	}
+
+	for _, p := range progress {
+		groups := make([]*MasteryStat, 0, len(p.Themes)+1)
+		for _, theme := range p.Themes {
+			if st := byTheme[theme]; st != nil {
+				groups = append(groups, st)
+			}
+		}
+		if st := byDifficulty[RatingToDifficulty(p.Rating)]; st != nil {
+			groups = append(groups, st)
+		}
+		for _, st := range groups {
+			st.add(p, now)
+		}
+	}
+
+	themes := make([]ThemeMastery, 0, len(byTheme))
+	for theme, st := range byTheme {
+		if st.Total > 0 {
+			st.finish()
+			themes = append(themes, ThemeMastery{Theme: theme, MasteryStat: *st})
+		}
+	}
+	sort.Slice(themes, func(i, j int) bool { return themes[i].Theme < themes[j].Theme })
+
+	difficulties := make([]DifficultyMastery, 0, len(byDifficulty))
+	for d, st := range byDifficulty {
+		if st.Total > 0 {
+			st.finish()
+			difficulties = append(difficulties, DifficultyMastery{Difficulty: d, MasteryStat: *st})
+		}
+	}
+	sort.Slice(difficulties, func(i, j int) bool { return difficulties[i].Difficulty < difficulties[j].Difficulty })
+
+	return themes, difficulties
+}
+
+func (s *MasteryStat) add(p EndgamePositionProgress, now time.Time) {
+	if p.Attempts == 0 {
+		return
+	}
+	s.Attempted++
+	if p.Successes > 0 {
+		s.Solved++
+	}
+	if p.Review.Mastered() {
+		s.Mastered++
+	}
+	if !p.Review.DueAt.After(now) {
+		s.Due++
+	}
+}
+
+func (s *MasteryStat) finish() {
+	if s.Total > 0 {
+		s.Mastery = float64(s.Mastered) / float64(s.Total)
+	}
+}
+
+// RatingToDifficulty converts a position rating to the difficulty scale (1-10),
+// the inverse of DifficultyToRatingRange
+func RatingToDifficulty(rating int) int {
+	switch {
+	case rating < 500:
+		return 1
+	case rating < 800:
+		return 2
+	case rating < 1100:
+		return 3
+	case rating < 1400:
+		return 4
+	case rating < 1700:
+		return 5
+	case rating < 2000:
+		return 6
+	case rating < 2300:
+		return 7
+	case rating < 2600:
+		return 8
+	case rating < 2800:
+		return 9
+	default:
+		return 10
+	}
+}
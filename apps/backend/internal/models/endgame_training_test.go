@@ -0,0 +1,132 @@
+package models
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+var reviewNow = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func TestEndgameReviewSchedule_Successes(t *testing.T) {
+	r := NewEndgameReview("p1")
+	wantIntervals := []int{1, 6, 15, 38}
+	for i, want := range wantIntervals {
+		r = r.Schedule(4, reviewNow)
+		if r.IntervalDays != want {
+			t.Fatalf("review %d: IntervalDays = %d, want %d", i+1, r.IntervalDays, want)
+		}
+	}
+	if r.Repetitions != 4 {
+		t.Errorf("Repetitions = %d, want 4", r.Repetitions)
+	}
+	if r.EaseFactor != DefaultEaseFactor {
+		t.Errorf("EaseFactor = %v, want %v (quality 4 keeps the ease)", r.EaseFactor, DefaultEaseFactor)
+	}
+	if want := reviewNow.AddDate(0, 0, 38); !r.DueAt.Equal(want) {
+		t.Errorf("DueAt = %v, want %v", r.DueAt, want)
+	}
+	if !r.Mastered() {
+		t.Error("expected position to be mastered after 4 successful reviews")
+	}
+}
+
+func TestEndgameReviewSchedule_Lapse(t *testing.T) {
+	r := NewEndgameReview("p1")
+	r = r.Schedule(5, reviewNow)
+	r = r.Schedule(5, reviewNow)
+	r = r.Schedule(1, reviewNow)
+
+	if r.Repetitions != 0 {
+		t.Errorf("Repetitions = %d, want 0", r.Repetitions)
+	}
+	if r.IntervalDays != 1 {
+		t.Errorf("IntervalDays = %d, want 1", r.IntervalDays)
+	}
+	if r.Lapses != 1 {
+		t.Errorf("Lapses = %d, want 1", r.Lapses)
+	}
+	// 2.5 + 0.1 + 0.1 - 0.54
+	if math.Abs(r.EaseFactor-2.16) > 1e-9 {
+		t.Errorf("EaseFactor = %v, want 2.16", r.EaseFactor)
+	}
+}
+
+func TestEndgameReviewSchedule_MinEase(t *testing.T) {
+	r := NewEndgameReview("p1")
+	for i := 0; i < 10; i++ {
+		r = r.Schedule(0, reviewNow)
+	}
+	if r.EaseFactor != MinEaseFactor {
+		t.Errorf("EaseFactor = %v, want %v", r.EaseFactor, MinEaseFactor)
+	}
+}
+
+func TestReviewQuality(t *testing.T) {
+	tests := []struct {
+		success bool
+		elapsed time.Duration
+		want    int
+	}{
+		{false, 10 * time.Second, 1},
+		{true, 30 * time.Second, 5},
+		{true, 2 * time.Minute, 4},
+		{true, 10 * time.Minute, 3},
+	}
+
+	for _, tt := range tests {
+		if got := ReviewQuality(tt.success, tt.elapsed); got != tt.want {
+			t.Errorf("ReviewQuality(%v, %v) = %d, want %d", tt.success, tt.elapsed, got, tt.want)
+		}
+	}
+}
+
+func TestRatingToDifficulty(t *testing.T) {
+	for diff := 1; diff <= 10; diff++ {
+		min, max := DifficultyToRatingRange(diff)
+		if got := RatingToDifficulty((min + max) / 2); got < diff-1 || got > diff+1 {
+			t.Errorf("RatingToDifficulty(%d) = %d, want about %d", (min+max)/2, got, diff)
+		}
+	}
+	if got := RatingToDifficulty(1450); got != 5 {
+		t.Errorf("RatingToDifficulty(1450) = %d, want 5", got)
+	}
+}
+
+func TestBuildEndgameProgress(t *testing.T) {
+	mastered := EndgameReview{Repetitions: MasteryRepetitions, DueAt: reviewNow.AddDate(0, 0, 10)}
+	due := EndgameReview{Repetitions: 0, DueAt: reviewNow.Add(-time.Hour)}
+
+	progress := []EndgamePositionProgress{
+		{PositionID: "a", Rating: 900, Themes: []string{"pawnEndgame", "opposition"}, Attempts: 3, Successes: 3, Review: mastered},
+		{PositionID: "b", Rating: 950, Themes: []string{"pawnEndgame"}, Attempts: 2, Successes: 0, Review: due},
+	}
+	themeTotals := map[string]int{"pawnEndgame": 4, "opposition": 2, "rookEndgame": 5}
+	ratingTotals := map[int]int{900: 3, 950: 1, 1500: 5}
+
+	byTheme, byDifficulty := BuildEndgameProgress(progress, themeTotals, ratingTotals, reviewNow)
+
+	if len(byTheme) != 3 {
+		t.Fatalf("len(byTheme) = %d, want 3", len(byTheme))
+	}
+	pawn := byTheme[1]
+	if pawn.Theme != "pawnEndgame" {
+		t.Fatalf("byTheme[1].Theme = %q, want %q", pawn.Theme, "pawnEndgame")
+	}
+	if pawn.Total != 4 || pawn.Attempted != 2 || pawn.Solved != 1 || pawn.Mastered != 1 || pawn.Due != 1 {
+		t.Errorf("pawnEndgame = %+v, want total 4, attempted 2, solved 1, mastered 1, due 1", pawn.MasteryStat)
+	}
+	if pawn.Mastery != 0.25 {
+		t.Errorf("pawnEndgame mastery = %v, want 0.25", pawn.Mastery)
+	}
+	if rook := byTheme[2]; rook.Attempted != 0 || rook.Total != 5 {
+		t.Errorf("rookEndgame = %+v, want total 5 and nothing attempted", rook.MasteryStat)
+	}
+
+	if len(byDifficulty) != 2 {
+		t.Fatalf("len(byDifficulty) = %d, want 2", len(byDifficulty))
+	}
+	if d := byDifficulty[0]; d.Difficulty != 3 || d.Total != 4 || d.Attempted != 2 {
+		t.Errorf("byDifficulty[0] = %+v, want difficulty 3 with total 4, attempted 2", d)
+	}
+}
@@ -2,6 +2,15 @@ package models
 
 import "time"
 
+// Rated pools. Rated games involving a bot count towards a separate rating so bots
+// cannot inflate or deflate the ratings humans earn against each other, and so do
+// games against the computer.
+const (
+    RatingPoolStandard = "standard"
+    RatingPoolBot      = "bot"
+    RatingPoolComputer = "computer"
+)
+
 type Game struct {
     GameID              string    `json:"game_id"`
     PGN                 string    `json:"pgn"`
@@ -12,4 +21,22 @@ type Game struct {
     PlayerBStartRating  int       `json:"player_b_start_rating"`
     Result              string    `json:"result"` // "1-0", "0-1", "1/2-1/2", "*" (ongoing)
     CreatedAt           time.Time `json:"created_at"`
+}
+
+// GameDetail is a finished game as shown to anyone: players by username, the
+// computer's side as "Computer"
+type GameDetail struct {
+    GameID        string    `json:"game_id"`
+    White         string    `json:"white"`
+    Black         string    `json:"black"`
+    WhiteRating   int       `json:"white_rating"` // Ratings when the game started
+    BlackRating   int       `json:"black_rating"`
+    Result        string    `json:"result"`
+    PGN           string    `json:"pgn"` // Space-separated UCI moves
+    RatingPool    string    `json:"rating_pool"`
+    Rated         bool      `json:"rated"`
+    ComputerLevel *int      `json:"computer_level,omitempty"`
+    ECO           string    `json:"eco,omitempty"` // Opening code and name, when the game reached a book position
+    Opening       string    `json:"opening,omitempty"`
+    CreatedAt     time.Time `json:"created_at"`
 }
\ No newline at end of file
@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// MiddlegameThemes are the training themes a middlegame position can be tagged with
+var MiddlegameThemes = []string{"attack", "defence", "pawnStructure", "pieceActivity"}
+
+// MiddlegamePosition represents a training position for middlegame practice
+type MiddlegamePosition struct {
+	PositionID  string    `json:"position_id"`
+	FEN         string    `json:"fen"`
+	Moves       string    `json:"moves,omitempty"`
+	Rating      int       `json:"rating"`
+	Themes      []string  `json:"themes"`
+	InitialEval *int      `json:"initial_eval,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Source      string    `json:"source,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// MiddlegameQueryParams holds filter parameters for querying middlegame positions
+type MiddlegameQueryParams struct {
+	// MinRating filters positions with rating >= this value
+	MinRating int
+	// MaxRating filters positions with rating <= this value
+	MaxRating int
+	// Theme filters by specific theme (e.g., "attack")
+	Theme string
+	// Side filters by side to move ('w' or 'b')
+	Side string
+	// ExcludePositionID excludes a specific position (to avoid repeats on restart)
+	ExcludePositionID string
+}
+
+// IsMiddlegameTheme reports whether theme is one of MiddlegameThemes
+func IsMiddlegameTheme(theme string) bool {
+	for _, t := range MiddlegameThemes {
+		if t == theme {
+			return true
+		}
+	}
+	return false
+}
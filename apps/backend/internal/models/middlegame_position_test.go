@@ -0,0 +1,24 @@
+package models
+
+import "testing"
+
+func TestIsMiddlegameTheme(t *testing.T) {
+	tests := []struct {
+		theme string
+		want  bool
+	}{
+		{"attack", true},
+		{"defence", true},
+		{"pawnStructure", true},
+		{"pieceActivity", true},
+		{"defense", false},
+		{"rookEndgame", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsMiddlegameTheme(tt.theme); got != tt.want {
+			t.Errorf("IsMiddlegameTheme(%q) = %v, want %v", tt.theme, got, tt.want)
+		}
+	}
+}
@@ -7,6 +7,10 @@ type Profile struct {
     Username     string    `json:"username"`
     Rating       int       `json:"rating"`
     PuzzleRating int       `json:"puzzle_rating"`
+    TrainingRating int     `json:"training_rating"`
+    BotRating    int       `json:"bot_rating"`
+    ComputerRating int     `json:"computer_rating"`
+    IsBot        bool      `json:"is_bot"`
     ProfileIcon  string    `json:"profile_icon"`
     CreatedAt    time.Time `json:"created_at"`
 }
@@ -15,6 +19,10 @@ type PublicProfile struct {
     Username          string    `json:"username"`
     Rating            int       `json:"rating"`
     PuzzleRating      int       `json:"puzzle_rating"`
+    TrainingRating    int       `json:"training_rating"`
+    BotRating         int       `json:"bot_rating"` // Rating in games against bots, or the bot's own rating
+    ComputerRating    int       `json:"computer_rating"` // Rating in rated games against the computer
+    IsBot             bool      `json:"is_bot"`
     ProfileIcon       string    `json:"profile_icon"`
     CreatedAt         time.Time `json:"created_at"`
     GamesPlayed       int       `json:"games_played"`
@@ -24,15 +32,49 @@ type PublicProfile struct {
     AchievementPoints int       `json:"achievement_points"`
 }
 
+// PrivateProfile is everything the signed-in user may see about themselves, including
+// fields that never appear on the public profile
+type PrivateProfile struct {
+    UserID                    string     `json:"user_id"`
+    Username                  string     `json:"username"`
+    UsernameSet               bool       `json:"username_set"`
+    Rating                    int        `json:"rating"`
+    PuzzleRating              int        `json:"puzzle_rating"`
+    TrainingRating            int        `json:"training_rating"`
+    ProfileIcon               string     `json:"profile_icon"`
+    CreatedAt                 time.Time  `json:"created_at"`
+    GamesPlayed               int        `json:"games_played"`
+    Wins                      int        `json:"wins"`
+    Losses                    int        `json:"losses"`
+    Draws                     int        `json:"draws"`
+    AchievementPoints         int        `json:"achievement_points"`
+    WinStreak                 int        `json:"win_streak"`
+    PuzzleStreak              int        `json:"puzzle_streak"`
+    DailyPuzzleStreak         int        `json:"daily_puzzle_streak"`
+    UsernameChangedAt         *time.Time `json:"username_changed_at"`
+    UsernameChangeAvailableAt *time.Time `json:"username_change_available_at"`
+    LinkedProviders           []LinkedIdentity `json:"linked_providers"`
+}
+
+// LinkedIdentity is a sign-in provider account linked to a user
+type LinkedIdentity struct {
+    Provider string    `json:"provider"`
+    LinkedAt time.Time `json:"linked_at"`
+}
+
 type RatingPoint struct {
     Rating    int       `json:"rating"`
     CreatedAt time.Time `json:"created_at"`
 }
 
 type RecentGame struct {
-    GameID      string    `json:"game_id"`
-    Opponent    string    `json:"opponent"`
-    Result      string    `json:"result"`
-    PlayerColor string    `json:"player_color"`
-    CreatedAt   time.Time `json:"created_at"`
+    GameID        string    `json:"game_id"`
+    Opponent      string    `json:"opponent"`
+    Result        string    `json:"result"`
+    PlayerColor   string    `json:"player_color"`
+    CreatedAt     time.Time `json:"created_at"`
+    PGN           string    `json:"pgn,omitempty"`            // Only set in the owner's game history
+    ComputerLevel *int      `json:"computer_level,omitempty"` // Set for games against the computer
+    ECO           string    `json:"eco,omitempty"`            // Opening code and name, when the game reached a book position
+    Opening       string    `json:"opening,omitempty"`
 }
\ No newline at end of file
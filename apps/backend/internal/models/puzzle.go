@@ -0,0 +1,140 @@
+package models
+
+import (
+	"sort"
+	"time"
+)
+
+// PuzzleRepeatWindow is how long a puzzle the user attempted is kept out of
+// selection; attempting it again within the window is not rated
+const PuzzleRepeatWindow = 30 * 24 * time.Hour
+
+// puzzleSelectionRadii are the rating distances tried in turn when picking a
+// puzzle for a user, from the closest match outwards
+var puzzleSelectionRadii = []int{100, 250, 500}
+
+// Puzzle represents a tactical puzzle with a server-side solution
+type Puzzle struct {
+	PuzzleID string `json:"puzzle_id"`
+	// FEN is the position presented to the solver (solver to move)
+	FEN string `json:"fen"`
+	// Moves is the solution line in UCI format, solver move first, alternating sides
+	Moves           string    `json:"moves"`
+	Rating          int       `json:"rating"`
+	RatingDeviation int       `json:"rating_deviation"`
+	Themes          []string  `json:"themes"`
+	Popularity      int       `json:"popularity"`
+	NbPlays         int       `json:"nb_plays"`
+	GameURL         string    `json:"game_url"`
+	OpeningTags     []string  `json:"opening_tags"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// PuzzleResponse is the API response for a puzzle handed out to a client.
+// The solution is never included; the client submits its moves for verification.
+type PuzzleResponse struct {
+	PuzzleID string   `json:"puzzle_id"`
+	FEN      string   `json:"fen"`
+	Rating   int      `json:"rating"`
+	Themes   []string `json:"themes"`
+	// SolutionLength is the number of moves the solver has to find
+	SolutionLength int `json:"solution_length"`
+}
+
+// PuzzleQueryParams holds filter parameters for querying puzzles
+type PuzzleQueryParams struct {
+	// MinRating filters puzzles with rating >= this value
+	MinRating int
+	// MaxRating filters puzzles with rating <= this value
+	MaxRating int
+	// Theme filters by specific theme (e.g., "fork", "mateIn2")
+	Theme string
+	// ExcludePuzzleID excludes a specific puzzle (to avoid immediate repeats)
+	ExcludePuzzleID string
+	// ExcludePuzzleIDs excludes a set of puzzles (e.g., those already seen in a sprint)
+	ExcludePuzzleIDs []string
+	// AnyThemes filters puzzles carrying at least one of these themes
+	AnyThemes []string
+	// ExcludeAttemptedBy excludes puzzles this user attempted after AttemptedSince
+	ExcludeAttemptedBy string
+	AttemptedSince     time.Time
+}
+
+// PuzzleAttempt is one rated attempt of a user on a puzzle
+type PuzzleAttempt struct {
+	UserID       string
+	PuzzleID     string
+	Solved       bool
+	PuzzleRating int
+	RatingBefore int
+	RatingAfter  int
+	Themes       []string
+	CreatedAt    time.Time
+}
+
+// PuzzleThemeStat summarizes a user's recent attempts on puzzles with a theme
+type PuzzleThemeStat struct {
+	Theme    string
+	Attempts int
+	Solved   int
+}
+
+// SuccessRate returns the fraction of attempts that were solved
+func (s PuzzleThemeStat) SuccessRate() float64 {
+	if s.Attempts == 0 {
+		return 0
+	}
+	return float64(s.Solved) / float64(s.Attempts)
+}
+
+// WeakPuzzleThemes returns up to limit themes the user solves least often, weakest
+// first. Themes with fewer than minAttempts attempts are ignored as too noisy, and
+// themes solved at maxRate or better are not considered weak.
+func WeakPuzzleThemes(stats []PuzzleThemeStat, minAttempts int, maxRate float64, limit int) []string {
+	candidates := make([]PuzzleThemeStat, 0, len(stats))
+	for _, s := range stats {
+		if s.Attempts >= minAttempts && s.SuccessRate() < maxRate {
+			candidates = append(candidates, s)
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		ri, rj := candidates[i].SuccessRate(), candidates[j].SuccessRate()
+		if ri != rj {
+			return ri < rj
+		}
+		if candidates[i].Attempts != candidates[j].Attempts {
+			return candidates[i].Attempts > candidates[j].Attempts
+		}
+		return candidates[i].Theme < candidates[j].Theme
+	})
+
+	themes := make([]string, 0, limit)
+	for _, c := range candidates {
+		if len(themes) == limit {
+			break
+		}
+		themes = append(themes, c.Theme)
+	}
+	return themes
+}
+
+// PuzzleSelectionPlan returns the queries to try, in order, when picking a puzzle
+// for a user rated rating. The rating window widens step by step; when weakThemes is
+// non-empty each window is tried with those themes first. The final query drops
+// the rating filter so a user is only left without a puzzle once every puzzle in
+// the pool has been attempted within the repeat window.
+func PuzzleSelectionPlan(rating int, weakThemes []string) []PuzzleQueryParams {
+	plan := make([]PuzzleQueryParams, 0, 2*len(puzzleSelectionRadii)+1)
+	for _, radius := range puzzleSelectionRadii {
+		minRating, maxRating := rating-radius, rating+radius
+		if minRating < 0 {
+			minRating = 0
+		}
+		if len(weakThemes) > 0 {
+			plan = append(plan, PuzzleQueryParams{MinRating: minRating, MaxRating: maxRating, AnyThemes: weakThemes})
+		}
+		plan = append(plan, PuzzleQueryParams{MinRating: minRating, MaxRating: maxRating})
+	}
+	return append(plan, PuzzleQueryParams{})
+}
@@ -0,0 +1,66 @@
+package models
+
+import "time"
+
+// Puzzle sprint rules
+const (
+	// SprintDuration is the time a sprint runs for
+	SprintDuration = 3 * time.Minute
+	// SprintMaxMistakes is the number of failed puzzles that ends a sprint
+	SprintMaxMistakes = 3
+	// SprintGrace allows for network latency on answers sent just before the timer runs out
+	SprintGrace = 2 * time.Second
+
+	sprintStartRating = 600
+	sprintRatingStep  = 60
+	sprintMaxRating   = 3000
+)
+
+// PuzzleSprint is a timed run of puzzles of rising difficulty
+type PuzzleSprint struct {
+	SprintID int64  `json:"sprint_id"`
+	UserID   string `json:"-"`
+	Score    int    `json:"score"`
+	Mistakes int    `json:"mistakes"`
+	// PuzzleIDs lists the puzzles handed out so far; the last one is the current puzzle
+	PuzzleIDs  []string   `json:"-"`
+	StartedAt  time.Time  `json:"started_at"`
+	EndsAt     time.Time  `json:"ends_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// CurrentPuzzleID returns the puzzle the user is working on
+func (s *PuzzleSprint) CurrentPuzzleID() string {
+	if len(s.PuzzleIDs) == 0 {
+		return ""
+	}
+	return s.PuzzleIDs[len(s.PuzzleIDs)-1]
+}
+
+// Expired reports whether the sprint timer (plus grace) has run out at now
+func (s *PuzzleSprint) Expired(now time.Time) bool {
+	return now.After(s.EndsAt.Add(SprintGrace))
+}
+
+// Over reports whether the sprint no longer accepts answers at now
+func (s *PuzzleSprint) Over(now time.Time) bool {
+	return s.FinishedAt != nil || s.Mistakes >= SprintMaxMistakes || s.Expired(now)
+}
+
+// SprintPuzzleRating returns the target puzzle rating for the puzzle at index
+// (0-based) in a sprint; difficulty rises steadily with every puzzle handed out
+func SprintPuzzleRating(index int) int {
+	rating := sprintStartRating + index*sprintRatingStep
+	if rating > sprintMaxRating {
+		return sprintMaxRating
+	}
+	return rating
+}
+
+// SprintLeaderboardEntry is a user's best sprint score within a period
+type SprintLeaderboardEntry struct {
+	Rank       int       `json:"rank"`
+	Username   string    `json:"username"`
+	Score      int       `json:"score"`
+	AchievedAt time.Time `json:"achieved_at"`
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSprintPuzzleRating(t *testing.T) {
+	tests := []struct {
+		index int
+		want  int
+	}{
+		{0, sprintStartRating},
+		{1, sprintStartRating + sprintRatingStep},
+		{10, sprintStartRating + 10*sprintRatingStep},
+		{1000, sprintMaxRating},
+	}
+
+	for _, tt := range tests {
+		if got := SprintPuzzleRating(tt.index); got != tt.want {
+			t.Errorf("SprintPuzzleRating(%d) = %d, want %d", tt.index, got, tt.want)
+		}
+	}
+
+	for i := 1; i < 50; i++ {
+		if SprintPuzzleRating(i) < SprintPuzzleRating(i-1) {
+			t.Errorf("SprintPuzzleRating(%d) < SprintPuzzleRating(%d), difficulty must not fall", i, i-1)
+		}
+	}
+}
+
+func TestPuzzleSprintOver(t *testing.T) {
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	finished := start.Add(time.Minute)
+
+	tests := []struct {
+		name   string
+		sprint PuzzleSprint
+		now    time.Time
+		want   bool
+	}{
+		{"running", PuzzleSprint{EndsAt: start.Add(SprintDuration)}, start.Add(time.Minute), false},
+		{"within grace", PuzzleSprint{EndsAt: start.Add(SprintDuration)}, start.Add(SprintDuration + SprintGrace/2), false},
+		{"timed out", PuzzleSprint{EndsAt: start.Add(SprintDuration)}, start.Add(SprintDuration + SprintGrace + time.Second), true},
+		{"max mistakes", PuzzleSprint{EndsAt: start.Add(SprintDuration), Mistakes: SprintMaxMistakes}, start, true},
+		{"finished", PuzzleSprint{EndsAt: start.Add(SprintDuration), FinishedAt: &finished}, start, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.sprint.Over(tt.now); got != tt.want {
+				t.Errorf("Over() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPuzzleSprintCurrentPuzzleID(t *testing.T) {
+	s := PuzzleSprint{}
+	if got := s.CurrentPuzzleID(); got != "" {
+		t.Errorf("CurrentPuzzleID() = %q, want empty", got)
+	}
+	s.PuzzleIDs = []string{"a", "b"}
+	if got := s.CurrentPuzzleID(); got != "b" {
+		t.Errorf("CurrentPuzzleID() = %q, want %q", got, "b")
+	}
+}
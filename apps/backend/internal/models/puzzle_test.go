@@ -0,0 +1,65 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWeakPuzzleThemes(t *testing.T) {
+	stats := []PuzzleThemeStat{
+		{Theme: "fork", Attempts: 10, Solved: 9},
+		{Theme: "pin", Attempts: 10, Solved: 3},
+		{Theme: "skewer", Attempts: 2, Solved: 0},
+		{Theme: "mateIn2", Attempts: 8, Solved: 4},
+		{Theme: "deflection", Attempts: 20, Solved: 6},
+	}
+
+	got := WeakPuzzleThemes(stats, 5, 0.6, 3)
+	want := []string{"deflection", "pin", "mateIn2"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("WeakPuzzleThemes() = %v, want %v", got, want)
+	}
+
+	if got := WeakPuzzleThemes(stats, 5, 0.6, 1); !reflect.DeepEqual(got, []string{"deflection"}) {
+		t.Errorf("WeakPuzzleThemes() with limit 1 = %v, want [deflection]", got)
+	}
+
+	if got := WeakPuzzleThemes(nil, 5, 0.6, 3); len(got) != 0 {
+		t.Errorf("WeakPuzzleThemes(nil) = %v, want empty", got)
+	}
+}
+
+func TestPuzzleSelectionPlan(t *testing.T) {
+	plan := PuzzleSelectionPlan(1500, []string{"pin"})
+	if len(plan) != 2*len(puzzleSelectionRadii)+1 {
+		t.Fatalf("len(plan) = %d, want %d", len(plan), 2*len(puzzleSelectionRadii)+1)
+	}
+
+	first := plan[0]
+	if first.MinRating != 1400 || first.MaxRating != 1600 || !reflect.DeepEqual(first.AnyThemes, []string{"pin"}) {
+		t.Errorf("plan[0] = %+v, want 1400-1600 with weak themes", first)
+	}
+	if plan[1].AnyThemes != nil || plan[1].MinRating != 1400 {
+		t.Errorf("plan[1] = %+v, want 1400-1600 without theme filter", plan[1])
+	}
+
+	last := plan[len(plan)-1]
+	if last.MinRating != 0 || last.MaxRating != 0 || last.AnyThemes != nil {
+		t.Errorf("last query = %+v, want unfiltered", last)
+	}
+}
+
+func TestPuzzleSelectionPlanWithoutWeakThemes(t *testing.T) {
+	plan := PuzzleSelectionPlan(50, nil)
+	if len(plan) != len(puzzleSelectionRadii)+1 {
+		t.Fatalf("len(plan) = %d, want %d", len(plan), len(puzzleSelectionRadii)+1)
+	}
+	for i, q := range plan {
+		if q.MinRating < 0 {
+			t.Errorf("plan[%d].MinRating = %d, want >= 0", i, q.MinRating)
+		}
+		if q.AnyThemes != nil {
+			t.Errorf("plan[%d] has theme filter %v, want none", i, q.AnyThemes)
+		}
+	}
+}
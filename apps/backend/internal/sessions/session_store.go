@@ -54,25 +54,6 @@ func GenerateSessionToken() (string, error) {
 	return base64.URLEncoding.EncodeToString(b), nil
 }
 
-func StoreSession(token, userID string) error {
-	err := rdb.Set(ctx, token, userID, 24*time.Hour).Err()
-	if err != nil {
-		logger.Error("Failed to store session", logger.F("error", err.Error()))
-	}
-	return err
-}
-
-func GetSessionUserID(token string) (string, bool) {
-	userID, err := rdb.Get(ctx, token).Result()
-	if err == redis.Nil {
-		return "", false
-	} else if err != nil {
-		logger.Error("Failed to get session from Redis", logger.F("error", err.Error()))
-		return "", false
-	}
-	return userID, true
-}
-
 // Close closes the Redis client connection
 func Close() error {
 	if rdb != nil {
@@ -90,10 +71,26 @@ func Ping() error {
 	return rdb.Ping(ctx).Err()
 }
 
-// DeleteSession removes a session token from Redis
-func DeleteSession(token string) error {
-	if rdb == nil {
-		return fmt.Errorf("redis not initialized")
+// linkRequestTTL bounds how long an account link may take to complete at the provider
+const linkRequestTTL = 10 * time.Minute
+
+// StoreLinkRequest remembers which user started linking an account under an OAuth state
+func StoreLinkRequest(state, userID string) error {
+	err := rdb.Set(ctx, "link:"+state, userID, linkRequestTTL).Err()
+	if err != nil {
+		logger.Error("Failed to store link request", logger.F("error", err.Error()))
 	}
-	return rdb.Del(ctx, token).Err()
+	return err
+}
+
+// TakeLinkRequest returns and forgets the user who started linking under state
+func TakeLinkRequest(state string) (string, bool) {
+	userID, err := rdb.GetDel(ctx, "link:"+state).Result()
+	if err == redis.Nil {
+		return "", false
+	} else if err != nil {
+		logger.Error("Failed to get link request from Redis", logger.F("error", err.Error()))
+		return "", false
+	}
+	return userID, true
 }
@@ -0,0 +1,177 @@
+package sessions
+
+import (
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/logger"
+)
+
+const (
+	// AccessTokenTTL is how long a mobile access token is valid before it must be refreshed
+	AccessTokenTTL = time.Hour
+	// authCodeTTL bounds how long the app may take to exchange a one-time code
+	authCodeTTL = time.Minute
+	// pkceTTL bounds how long a mobile login may take to complete at the provider
+	pkceTTL = 10 * time.Minute
+)
+
+// TokenPair is the credentials of a mobile session. The access token is sent as
+// Authorization: Bearer; the refresh token is exchanged for a new pair when it expires.
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+// Tokens are stored by their hash, like sessions, so Redis never holds a usable token
+func accessKey(token string) string    { return accessHashKey(SessionID(token)) }
+func accessHashKey(hash string) string { return "access:" + hash }
+func refreshKey(token string) string   { return "refresh:" + SessionID(token) }
+func authCodeKey(code string) string   { return "authcode:" + SessionID(code) }
+func pkceKey(state string) string      { return "pkce:" + state }
+
+// StorePKCEChallenge remembers the PKCE code challenge of a mobile login under its OAuth state
+func StorePKCEChallenge(state, challenge string) error {
+	err := rdb.Set(ctx, pkceKey(state), challenge, pkceTTL).Err()
+	if err != nil {
+		logger.Error("Failed to store PKCE challenge", logger.F("error", err.Error()))
+	}
+	return err
+}
+
+// TakePKCEChallenge returns and forgets the PKCE code challenge stored under state
+func TakePKCEChallenge(state string) (string, bool) {
+	challenge, err := rdb.GetDel(ctx, pkceKey(state)).Result()
+	if err == redis.Nil {
+		return "", false
+	} else if err != nil {
+		logger.Error("Failed to get PKCE challenge from Redis", logger.F("error", err.Error()))
+		return "", false
+	}
+	return challenge, true
+}
+
+// StoreAuthCode stores a one-time code the app exchanges for a token pair by proving
+// it holds the verifier of challenge
+func StoreAuthCode(code, userID, challenge string) error {
+	_, err := rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, authCodeKey(code), "user_id", userID, "challenge", challenge)
+		pipe.Expire(ctx, authCodeKey(code), authCodeTTL)
+		return nil
+	})
+	if err != nil {
+		logger.Error("Failed to store authorization code", logger.F("error", err.Error()))
+	}
+	return err
+}
+
+// TakeAuthCode returns the user and PKCE challenge of a one-time code. The code is
+// consumed whether or not the exchange then succeeds.
+func TakeAuthCode(code string) (userID, challenge string, ok bool) {
+	var get *redis.MapStringStringCmd
+	_, err := rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		get = pipe.HGetAll(ctx, authCodeKey(code))
+		pipe.Del(ctx, authCodeKey(code))
+		return nil
+	})
+	if err != nil {
+		logger.Error("Failed to get authorization code from Redis", logger.F("error", err.Error()))
+		return "", "", false
+	}
+	vals := get.Val()
+	if vals["user_id"] == "" {
+		return "", "", false
+	}
+	return vals["user_id"], vals["challenge"], true
+}
+
+// CreateTokenSession starts a session for a user signing in with bearer tokens and
+// returns its first token pair
+func CreateTokenSession(userID string, meta Metadata) (TokenPair, error) {
+	secret, err := GenerateSessionToken()
+	if err != nil {
+		return TokenPair{}, err
+	}
+	// The session ID is not derived from any token the client holds, so rotating
+	// tokens keeps the session
+	id := SessionID(secret)
+	if err := createSession(id, userID, meta); err != nil {
+		return TokenPair{}, err
+	}
+	return issueTokens(id)
+}
+
+// RefreshTokens exchanges a refresh token for a new pair. Refresh tokens are single
+// use, and the access token issued with one stops working when it is exchanged; it
+// reports false if the token is unknown, already used or its session ended.
+func RefreshTokens(refreshToken string) (TokenPair, bool, error) {
+	var get *redis.MapStringStringCmd
+	_, err := rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		get = pipe.HGetAll(ctx, refreshKey(refreshToken))
+		pipe.Del(ctx, refreshKey(refreshToken))
+		return nil
+	})
+	if err != nil {
+		logger.Error("Failed to get refresh token from Redis", logger.F("error", err.Error()))
+		return TokenPair{}, false, err
+	}
+	vals := get.Val()
+	id := vals["session"]
+	if id == "" {
+		return TokenPair{}, false, nil
+	}
+	if err := rdb.Del(ctx, accessHashKey(vals["access"])).Err(); err != nil {
+		logger.Error("Failed to revoke rotated access token", logger.F("error", err.Error()))
+		return TokenPair{}, false, err
+	}
+	if _, ok := lookupSession(id); !ok {
+		return TokenPair{}, false, nil
+	}
+	pair, err := issueTokens(id)
+	return pair, err == nil, err
+}
+
+// GetAccessTokenSession returns the user and session ID of a bearer access token
+func GetAccessTokenSession(accessToken string) (userID, sessionID string, ok bool) {
+	id, err := rdb.Get(ctx, accessKey(accessToken)).Result()
+	if err != nil {
+		if err != redis.Nil {
+			logger.Error("Failed to get access token from Redis", logger.F("error", err.Error()))
+		}
+		return "", "", false
+	}
+	userID, ok = lookupSession(id)
+	return userID, id, ok
+}
+
+func issueTokens(sessionID string) (TokenPair, error) {
+	access, err := GenerateSessionToken()
+	if err != nil {
+		return TokenPair{}, err
+	}
+	refresh, err := GenerateSessionToken()
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	_, err = rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, accessKey(access), sessionID, AccessTokenTTL)
+		// The refresh token remembers its access token (by hash) to revoke it on rotation
+		pipe.HSet(ctx, refreshKey(refresh), "session", sessionID, "access", SessionID(access))
+		pipe.Expire(ctx, refreshKey(refresh), MaxLifetime)
+		return nil
+	})
+	if err != nil {
+		logger.Error("Failed to store tokens", logger.F("error", err.Error()))
+		return TokenPair{}, err
+	}
+
+	return TokenPair{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(AccessTokenTTL.Seconds()),
+	}, nil
+}
@@ -0,0 +1,293 @@
+package sessions
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/logger"
+)
+
+const (
+	// IdleTimeout is how long a session lives without being used
+	IdleTimeout = 7 * 24 * time.Hour
+	// MaxLifetime caps how long a session lives however often it is used
+	MaxLifetime = 30 * 24 * time.Hour
+	// touchInterval limits how often use of a session is written back to Redis
+	touchInterval = time.Minute
+)
+
+// Platforms a session was created from
+const (
+	PlatformWeb    = "web"
+	PlatformMobile = "mobile"
+)
+
+// Metadata describes where a session was created
+type Metadata struct {
+	IP        string
+	UserAgent string
+	Platform  string
+}
+
+// Session is a signed-in device as shown to its user. ID identifies the session
+// without revealing its token.
+type Session struct {
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	LastSeen  time.Time `json:"last_seen"`
+	ExpiresAt time.Time `json:"expires_at"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+	Platform  string    `json:"platform"`
+}
+
+// revokeHook is told about revoked sessions so live connections can be closed. A
+// nil sessionIDs stands for all of the user's connections.
+var revokeHook func(userID string, sessionIDs []string)
+
+// OnRevoke registers fn to be called with the IDs of sessions after they are revoked
+// or logged out, and with nil when all of a user's connections must close
+func OnRevoke(fn func(userID string, sessionIDs []string)) {
+	revokeHook = fn
+}
+
+// SessionID returns the ID of a session token. Sessions are stored under their ID,
+// so a leaked Redis key or session listing never reveals a usable token.
+func SessionID(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// sessionKey is the Redis hash holding a session. The key changed when sessions
+// gained IDs, so older sessions (keyed by the token) are no longer found.
+func sessionKey(id string) string {
+	return "session:" + id
+}
+
+// userSessionsKey is the Redis set of a user's session IDs
+func userSessionsKey(userID string) string {
+	return "user_sessions:" + userID
+}
+
+// expiresAt is when a session expires if it is not used again
+func expiresAt(createdAt, lastSeen time.Time) time.Time {
+	idle, capped := lastSeen.Add(IdleTimeout), createdAt.Add(MaxLifetime)
+	if capped.Before(idle) {
+		return capped
+	}
+	return idle
+}
+
+// StoreSession creates a session for token and adds it to the user's sessions
+func StoreSession(token, userID string, meta Metadata) error {
+	return createSession(SessionID(token), userID, meta)
+}
+
+func createSession(id, userID string, meta Metadata) error {
+	now := time.Now().Unix()
+
+	_, err := rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, sessionKey(id), map[string]interface{}{
+			"user_id":    userID,
+			"created_at": now,
+			"last_seen":  now,
+			"ip":         meta.IP,
+			"user_agent": meta.UserAgent,
+			"platform":   meta.Platform,
+		})
+		pipe.Expire(ctx, sessionKey(id), IdleTimeout)
+		pipe.SAdd(ctx, userSessionsKey(userID), id)
+		pipe.Expire(ctx, userSessionsKey(userID), MaxLifetime)
+		return nil
+	})
+	if err != nil {
+		logger.Error("Failed to store session", logger.F("error", err.Error()))
+	}
+	return err
+}
+
+// GetSessionUserID returns the user of a session token. Using a session slides its
+// expiry forward, up to MaxLifetime after it was created.
+func GetSessionUserID(token string) (string, bool) {
+	return lookupSession(SessionID(token))
+}
+
+func lookupSession(id string) (string, bool) {
+	vals, err := rdb.HGetAll(ctx, sessionKey(id)).Result()
+	if err != nil {
+		logger.Error("Failed to get session from Redis", logger.F("error", err.Error()))
+		return "", false
+	}
+	userID := vals["user_id"]
+	if userID == "" {
+		return "", false
+	}
+
+	now := time.Now()
+	createdAt, lastSeen := unixField(vals, "created_at"), unixField(vals, "last_seen")
+	if now.Sub(lastSeen) < touchInterval {
+		return userID, true
+	}
+
+	ttl := expiresAt(createdAt, now).Sub(now)
+	if ttl <= 0 {
+		rdb.Del(ctx, sessionKey(id))
+		return "", false
+	}
+	_, err = rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, sessionKey(id), "last_seen", now.Unix())
+		pipe.Expire(ctx, sessionKey(id), ttl)
+		return nil
+	})
+	if err != nil {
+		logger.Warn("Failed to refresh session", logger.F("error", err.Error()))
+	}
+	return userID, true
+}
+
+// ListUserSessions returns the user's live sessions, most recently used first
+func ListUserSessions(userID string) ([]Session, error) {
+	ids, err := rdb.SMembers(ctx, userSessionsKey(userID)).Result()
+	if err != nil {
+		logger.Error("Failed to list sessions", logger.F("userID", userID, "error", err.Error()))
+		return nil, err
+	}
+
+	cmds := make([]*redis.MapStringStringCmd, len(ids))
+	_, err = rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, id := range ids {
+			cmds[i] = pipe.HGetAll(ctx, sessionKey(id))
+		}
+		return nil
+	})
+	if err != nil {
+		logger.Error("Failed to load sessions", logger.F("userID", userID, "error", err.Error()))
+		return nil, err
+	}
+
+	result := []Session{}
+	var expired []interface{}
+	for i, cmd := range cmds {
+		vals := cmd.Val()
+		if vals["user_id"] != userID {
+			expired = append(expired, ids[i])
+			continue
+		}
+		createdAt, lastSeen := unixField(vals, "created_at"), unixField(vals, "last_seen")
+		result = append(result, Session{
+			ID:        ids[i],
+			CreatedAt: createdAt,
+			LastSeen:  lastSeen,
+			ExpiresAt: expiresAt(createdAt, lastSeen),
+			IP:        vals["ip"],
+			UserAgent: vals["user_agent"],
+			Platform:  vals["platform"],
+		})
+	}
+	if len(expired) > 0 {
+		rdb.SRem(ctx, userSessionsKey(userID), expired...)
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].LastSeen.After(result[j].LastSeen) })
+	return result, nil
+}
+
+// RevokeSession ends one of the user's sessions. It reports false if the user has no
+// session with that ID.
+func RevokeSession(userID, sessionID string) (bool, error) {
+	owner, err := rdb.HGet(ctx, sessionKey(sessionID), "user_id").Result()
+	if err == redis.Nil || (err == nil && owner != userID) {
+		return false, nil
+	}
+	if err != nil {
+		logger.Error("Failed to get session from Redis", logger.F("error", err.Error()))
+		return false, err
+	}
+	if err := deleteSessions(userID, []string{sessionID}); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// RevokeUserSessions ends every session of the user except keepID (when set) and
+// returns how many were ended. With no keepID this signs the user out everywhere,
+// e.g. when they are banned.
+func RevokeUserSessions(userID, keepID string) (int, error) {
+	ids, err := rdb.SMembers(ctx, userSessionsKey(userID)).Result()
+	if err != nil {
+		logger.Error("Failed to list sessions", logger.F("userID", userID, "error", err.Error()))
+		return 0, err
+	}
+	revoke := ids[:0]
+	for _, id := range ids {
+		if id != keepID {
+			revoke = append(revoke, id)
+		}
+	}
+	if len(revoke) == 0 {
+		return 0, nil
+	}
+	if err := deleteSessions(userID, revoke); err != nil {
+		return 0, err
+	}
+	return len(revoke), nil
+}
+
+// DisconnectUser closes every live connection of the user, bot streams opened with
+// an API token included, e.g. after their tokens are revoked
+func DisconnectUser(userID string) {
+	if revokeHook != nil {
+		revokeHook(userID, nil)
+	}
+}
+
+// DeleteSession ends the session of a token, e.g. on logout
+func DeleteSession(token string) error {
+	if rdb == nil {
+		return fmt.Errorf("redis not initialized")
+	}
+	id := SessionID(token)
+	userID, err := rdb.HGet(ctx, sessionKey(id), "user_id").Result()
+	if err == redis.Nil {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return deleteSessions(userID, []string{id})
+}
+
+func deleteSessions(userID string, ids []string) error {
+	keys := make([]string, len(ids))
+	members := make([]interface{}, len(ids))
+	for i, id := range ids {
+		keys[i] = sessionKey(id)
+		members[i] = id
+	}
+
+	_, err := rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, keys...)
+		pipe.SRem(ctx, userSessionsKey(userID), members...)
+		return nil
+	})
+	if err != nil {
+		logger.Error("Failed to delete sessions", logger.F("userID", userID, "error", err.Error()))
+		return err
+	}
+
+	logger.Info("Sessions revoked", logger.F("userID", userID, "count", len(ids)))
+	if revokeHook != nil {
+		revokeHook(userID, ids)
+	}
+	return nil
+}
+
+func unixField(vals map[string]string, field string) time.Time {
+	n, _ := strconv.ParseInt(vals[field], 10, 64)
+	return time.Unix(n, 0)
+}
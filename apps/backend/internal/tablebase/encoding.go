@@ -0,0 +1,137 @@
+package tablebase
+
+// Index tables for the Syzygy position encoding. Squares are numbered a1=0 ... h8=63
+// and pieces use the generator's codes: 1-6 for white P N B R Q K, 9-14 for black.
+var (
+	// mapPawns maps a2-h7 to 0..47: the number of squares left for the other
+	// leading pawns when the leading one stands on the square
+	mapPawns [64]int
+	// mapB1H1H7 maps the squares below the a1-h8 diagonal to 0..27
+	mapB1H1H7 [64]int
+	// mapA1D1D4 maps the a1-d1-d4 triangle to 0..9, diagonal squares last
+	mapA1D1D4 [64]int
+	// mapKK encodes the 462 legal placements of two kings, the first in the triangle
+	mapKK [10][64]int
+	// binomial[k][n] is the number of ways to choose k of n squares
+	binomial [maxPieces][64]uint64
+	// leadPawnIdx and leadPawnsSize encode the leading pawns group per pawn count
+	leadPawnIdx   [maxPieces][64]uint64
+	leadPawnsSize [maxPieces][4]uint64
+)
+
+func init() {
+	code := 0
+	for sq := 0; sq < 64; sq++ {
+		if offDiag(sq) < 0 {
+			mapB1H1H7[sq] = code
+			code++
+		}
+	}
+
+	code = 0
+	var diagonal []int
+	for sq := 0; sq <= 27; sq++ {
+		switch {
+		case offDiag(sq) < 0 && fileOf(sq) <= 3:
+			mapA1D1D4[sq] = code
+			code++
+		case offDiag(sq) == 0 && fileOf(sq) <= 3:
+			diagonal = append(diagonal, sq)
+		}
+	}
+	for _, sq := range diagonal {
+		mapA1D1D4[sq] = code
+		code++
+	}
+
+	// Placements with both kings on the diagonal are encoded last
+	type kk struct{ idx, sq int }
+	var bothOnDiagonal []kk
+	code = 0
+	for idx := 0; idx < 10; idx++ {
+		for s1 := 0; s1 <= 27; s1++ {
+			if mapA1D1D4[s1] != idx || (idx == 0 && s1 != 1) {
+				continue
+			}
+			for s2 := 0; s2 < 64; s2++ {
+				switch {
+				case kingDistance(s1, s2) <= 1:
+					// Adjacent kings
+				case offDiag(s1) == 0 && offDiag(s2) > 0:
+					// First on the diagonal, second above it
+				case offDiag(s1) == 0 && offDiag(s2) == 0:
+					bothOnDiagonal = append(bothOnDiagonal, kk{idx, s2})
+				default:
+					mapKK[idx][s2] = code
+					code++
+				}
+			}
+		}
+	}
+	for _, p := range bothOnDiagonal {
+		mapKK[p.idx][p.sq] = code
+		code++
+	}
+
+	binomial[0][0] = 1
+	for n := 1; n < 64; n++ {
+		for k := 0; k < maxPieces && k <= n; k++ {
+			if k > 0 {
+				binomial[k][n] += binomial[k-1][n-1]
+			}
+			if k < n {
+				binomial[k][n] += binomial[k][n-1]
+			}
+		}
+	}
+
+	available := 47
+	for count := 1; count < maxPieces-1; count++ {
+		for f := 0; f < 4; f++ {
+			var idx uint64
+			for r := 1; r <= 6; r++ {
+				sq := r*8 + f
+				if count == 1 {
+					mapPawns[sq] = available
+					available--
+					mapPawns[flipFile(sq)] = available
+					available--
+				}
+				leadPawnIdx[count][sq] = idx
+				idx += binomial[count-1][mapPawns[sq]]
+			}
+			leadPawnsSize[count][f] = idx
+		}
+	}
+}
+
+func fileOf(sq int) int { return sq & 7 }
+func rankOf(sq int) int { return sq >> 3 }
+
+// offDiag is negative below the a1-h8 diagonal, zero on it and positive above it
+func offDiag(sq int) int { return rankOf(sq) - fileOf(sq) }
+
+func flipFile(sq int) int { return sq ^ 7 }
+func flipRank(sq int) int { return sq ^ 56 }
+func flipDiag(sq int) int { return ((sq >> 3) | (sq << 3)) & 63 }
+
+func edgeDistance(file int) int {
+	if file > 7-file {
+		return 7 - file
+	}
+	return file
+}
+
+func kingDistance(a, b int) int {
+	df, dr := fileOf(a)-fileOf(b), rankOf(a)-rankOf(b)
+	if df < 0 {
+		df = -df
+	}
+	if dr < 0 {
+		dr = -dr
+	}
+	if df > dr {
+		return df
+	}
+	return dr
+}
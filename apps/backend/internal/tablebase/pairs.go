@@ -0,0 +1,271 @@
+package tablebase
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// Flags of a compressed subtable
+const (
+	flagSTM         = 1
+	flagMapped      = 2
+	flagWinPlies    = 4
+	flagLossPlies   = 8
+	flagWide        = 16
+	flagSingleValue = 128
+)
+
+var errCorrupt = errors.New("tablebase: corrupt table file")
+
+// pairsData describes one compressed subtable: the values for one side to move
+// (and, with pawns, one leading pawn file). Offsets point into the table file.
+type pairsData struct {
+	flags     byte
+	minSymLen int
+	maxSymLen int
+
+	blockSize       int
+	span            int
+	numBlocks       int
+	blockLengthSize int
+	sparseIndexSize int
+
+	lowestSym   int // offset of the lowest symbol per code length
+	btree       int // offset of the symbol pair tree
+	blockLength int // offset of the per-block value counts
+	sparseIndex int // offset of the sparse block index
+	data        int // offset of the compressed blocks
+
+	// base64[l] is the lowest code of length l+minSymLen, left-aligned in 64 bits
+	base64 []uint64
+	// symlen[s] is the number of values symbol s expands to, minus one
+	symlen []int
+
+	pieces   [maxPieces]int
+	groupIdx [maxPieces + 1]uint64
+	groupLen [maxPieces + 1]int
+	// mapIdx locates the DTZ value maps for win, loss, cursed win and blessed loss
+	mapIdx [4]int
+}
+
+// setSizes reads the subtable header at off and returns the offset after it
+func (d *pairsData) setSizes(buf []byte, off int) (int, error) {
+	if off+1 > len(buf) {
+		return 0, errCorrupt
+	}
+	d.flags = buf[off]
+	off++
+	if d.flags&flagSingleValue != 0 {
+		if off+1 > len(buf) {
+			return 0, errCorrupt
+		}
+		// The single value every position stores
+		d.minSymLen = int(buf[off])
+		return off + 1, nil
+	}
+
+	if off+10 > len(buf) {
+		return 0, errCorrupt
+	}
+	size := uint64(0)
+	for i, l := range d.groupLen {
+		if l == 0 {
+			size = d.groupIdx[i]
+			break
+		}
+	}
+
+	d.blockSize = 1 << buf[off]
+	d.span = 1 << buf[off+1]
+	d.sparseIndexSize = int((size + uint64(d.span) - 1) / uint64(d.span))
+	padding := int(buf[off+2])
+	d.numBlocks = int(binary.LittleEndian.Uint32(buf[off+3:]))
+	d.blockLengthSize = d.numBlocks + padding
+	d.maxSymLen = int(buf[off+7])
+	d.minSymLen = int(buf[off+8])
+	off += 9
+	if d.maxSymLen < d.minSymLen {
+		return 0, errCorrupt
+	}
+
+	d.lowestSym = off
+	n := d.maxSymLen - d.minSymLen + 1
+	if off+2*n+2 > len(buf) {
+		return 0, errCorrupt
+	}
+	// Canonical codes: longer codes have lower values, so base64 decreases with length
+	d.base64 = make([]uint64, n)
+	for i := n - 2; i >= 0; i-- {
+		d.base64[i] = (d.base64[i+1] + uint64(le16(buf, d.lowestSym+2*i)) - uint64(le16(buf, d.lowestSym+2*(i+1)))) / 2
+	}
+	for i := range d.base64 {
+		d.base64[i] <<= uint(64 - i - d.minSymLen)
+	}
+	off += 2 * n
+
+	numSyms := int(le16(buf, off))
+	off += 2
+	d.btree = off
+	if off+3*numSyms > len(buf) {
+		return 0, errCorrupt
+	}
+	d.symlen = make([]int, numSyms)
+	visited := make([]bool, numSyms)
+	for s := 0; s < numSyms; s++ {
+		if !visited[s] {
+			if err := d.setSymlen(buf, s, visited); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return off + 3*numSyms + numSyms&1, nil
+}
+
+// setSymlen computes how many values symbol s expands to; the pair tree is acyclic
+func (d *pairsData) setSymlen(buf []byte, s int, visited []bool) error {
+	visited[s] = true
+	right := d.right(buf, s)
+	if right == 0xFFF {
+		return nil
+	}
+	left := d.left(buf, s)
+	if left >= len(d.symlen) || right >= len(d.symlen) {
+		return errCorrupt
+	}
+	if !visited[left] {
+		if err := d.setSymlen(buf, left, visited); err != nil {
+			return err
+		}
+	}
+	if !visited[right] {
+		if err := d.setSymlen(buf, right, visited); err != nil {
+			return err
+		}
+	}
+	d.symlen[s] = d.symlen[left] + d.symlen[right] + 1
+	return nil
+}
+
+func (d *pairsData) left(buf []byte, s int) int {
+	p := d.btree + 3*s
+	return int(buf[p+1]&0xF)<<8 | int(buf[p])
+}
+
+func (d *pairsData) right(buf []byte, s int) int {
+	p := d.btree + 3*s
+	return int(buf[p+2])<<4 | int(buf[p+1]>>4)
+}
+
+func (d *pairsData) blockLen(buf []byte, block int) int {
+	return int(le16(buf, d.blockLength+2*block))
+}
+
+// decompress returns the value stored at index idx
+func (d *pairsData) decompress(buf []byte, idx uint64) (int, error) {
+	if d.flags&flagSingleValue != 0 {
+		return d.minSymLen, nil
+	}
+
+	// The sparse index gives the block and offset of every span-th value, counted
+	// from the middle of the span; walk the block lengths from there
+	k := int(idx / uint64(d.span))
+	if k >= d.sparseIndexSize {
+		return 0, errCorrupt
+	}
+	entry := d.sparseIndex + 6*k
+	block := int(le32(buf, entry))
+	offset := int(le16(buf, entry+4))
+	offset += int(idx%uint64(d.span)) - d.span/2
+
+	for offset < 0 {
+		block--
+		if block < 0 {
+			return 0, errCorrupt
+		}
+		offset += d.blockLen(buf, block) + 1
+	}
+	for offset > d.blockLen(buf, block) {
+		offset -= d.blockLen(buf, block) + 1
+		block++
+		if block >= d.blockLengthSize {
+			return 0, errCorrupt
+		}
+	}
+
+	// Read canonical Huffman symbols from the start of the block until the one
+	// covering offset
+	ptr := d.data + block*d.blockSize
+	buf64 := be64(buf, ptr)
+	ptr += 8
+	bufSize := 64
+	var sym int
+	for {
+		l := 0
+		for buf64 < d.base64[l] {
+			l++
+			if l >= len(d.base64) {
+				return 0, errCorrupt
+			}
+		}
+		sym = int((buf64-d.base64[l])>>uint(64-l-d.minSymLen)) + int(le16(buf, d.lowestSym+2*l))
+		if sym >= len(d.symlen) {
+			return 0, errCorrupt
+		}
+		if offset < d.symlen[sym]+1 {
+			break
+		}
+		offset -= d.symlen[sym] + 1
+		l += d.minSymLen
+		buf64 <<= uint(l)
+		bufSize -= l
+		if bufSize <= 32 {
+			bufSize += 32
+			buf64 |= uint64(be32(buf, ptr)) << uint(64-bufSize)
+			ptr += 4
+		}
+	}
+
+	// Expand the symbol through the pair tree down to a single value
+	for d.symlen[sym] != 0 {
+		left := d.left(buf, sym)
+		if offset < d.symlen[left]+1 {
+			sym = left
+		} else {
+			offset -= d.symlen[left] + 1
+			sym = d.right(buf, sym)
+		}
+	}
+	return d.left(buf, sym), nil
+}
+
+// Readers past the end of the file return zero bits, like the padding of the last block
+
+func le16(buf []byte, off int) uint16 {
+	if off < 0 || off+2 > len(buf) {
+		return 0
+	}
+	return binary.LittleEndian.Uint16(buf[off:])
+}
+
+func le32(buf []byte, off int) uint32 {
+	if off < 0 || off+4 > len(buf) {
+		return 0
+	}
+	return binary.LittleEndian.Uint32(buf[off:])
+}
+
+func be32(buf []byte, off int) uint32 {
+	var b [4]byte
+	if off >= 0 && off < len(buf) {
+		copy(b[:], buf[off:])
+	}
+	return binary.BigEndian.Uint32(b[:])
+}
+
+func be64(buf []byte, off int) uint64 {
+	var b [8]byte
+	if off >= 0 && off < len(buf) {
+		copy(b[:], buf[off:])
+	}
+	return binary.BigEndian.Uint64(b[:])
+}
@@ -0,0 +1,282 @@
+package tablebase
+
+import (
+	"github.com/notnil/chess"
+)
+
+type probeState int
+
+const (
+	stateOK probeState = iota
+	// stateZeroingBest: the best move is a capture or pawn move, whose result the
+	// tables do not store for this position
+	stateZeroingBest
+	// stateChangeSTM: the DTZ table stores the other side to move
+	stateChangeSTM
+)
+
+// search returns the WDL value of pos. The tables may store a "don't care" value
+// where the side to move has a winning capture, and only a lower bound where it has
+// a drawing one, so captures (and pawn moves with zeroing set, for DTZ) are searched
+// and the best of them and the stored value wins.
+func (p *Prober) search(pos *chess.Position, zeroing bool) (WDL, probeState, error) {
+	best := Loss
+	moves := pos.ValidMoves()
+	searched := 0
+	for _, m := range moves {
+		if !isCapture(m) && (!zeroing || !isPawnMove(pos, m)) {
+			continue
+		}
+		searched++
+
+		v, _, err := p.search(pos.Update(m), false)
+		if err != nil {
+			return Draw, stateOK, err
+		}
+		if -v > best {
+			best = -v
+			if best >= Win {
+				return best, stateZeroingBest, nil
+			}
+		}
+	}
+
+	// With every legal move searched the stored value is not needed (and may be
+	// wrong, e.g. tables ignore en passant rights)
+	noMoreMoves := searched > 0 && searched == len(moves)
+	value := best
+	if !noMoreMoves {
+		stored, _, err := p.probeTable(pos, kindWDL, Draw)
+		if err != nil {
+			return Draw, stateOK, err
+		}
+		value = WDL(stored)
+	}
+
+	if best >= value {
+		if best > Draw || noMoreMoves {
+			return best, stateZeroingBest, nil
+		}
+		return best, stateOK, nil
+	}
+	return value, stateOK, nil
+}
+
+// probeDTZ returns the DTZ of pos in plies, signed by the result for the side to move
+func (p *Prober) probeDTZ(pos *chess.Position) (int, error) {
+	wdl, state, err := p.search(pos, true)
+	if err != nil || wdl == Draw {
+		return 0, err
+	}
+	if state == stateZeroingBest {
+		return dtzBeforeZeroing(wdl), nil
+	}
+
+	dtz, state, err := p.probeTable(pos, kindDTZ, wdl)
+	if err != nil {
+		return 0, err
+	}
+	if state != stateChangeSTM {
+		if wdl == CursedWin || wdl == BlessedLoss {
+			dtz += 100
+		}
+		return dtz * sign(int(wdl)), nil
+	}
+
+	// The table stores the other side to move: take the best DTZ over all moves
+	minDTZ := 0xFFFF
+	for _, m := range pos.ValidMoves() {
+		zeroingMove := isCapture(m) || isPawnMove(pos, m)
+		next := pos.Update(m)
+
+		var v int
+		if zeroingMove {
+			// The DTZ before the move; the search after it gives the sign
+			w, _, err := p.search(next, false)
+			if err != nil {
+				return 0, err
+			}
+			v = -dtzBeforeZeroing(w)
+		} else {
+			d, err := p.probeDTZ(next)
+			if err != nil {
+				return 0, err
+			}
+			v = -d
+		}
+
+		if v == 1 && next.Status() == chess.Checkmate {
+			minDTZ = 1
+		}
+		if !zeroingMove {
+			v += sign(v)
+		}
+		if v < minDTZ && sign(v) == sign(int(wdl)) {
+			minDTZ = v
+		}
+	}
+	if minDTZ == 0xFFFF {
+		// No legal moves: mated
+		return -1, nil
+	}
+	return minDTZ, nil
+}
+
+// probeTable looks pos up in its WDL or DTZ table. WDL values come back as WDL;
+// DTZ values need the position's WDL value and come back in plies.
+func (p *Prober) probeTable(pos *chess.Position, kind tableKind, wdl WDL) (int, probeState, error) {
+	board := pos.Board().SquareMap()
+	if len(board) == 2 {
+		return int(Draw), stateOK, nil
+	}
+
+	key := materialKey(board)
+	index := p.wdl
+	if kind == kindDTZ {
+		index = p.dtz
+	}
+	t := index[key]
+	if t == nil {
+		return 0, stateOK, ErrNotAvailable
+	}
+	if err := t.load(); err != nil {
+		return 0, stateOK, err
+	}
+
+	// Tables store the first side of their name as white. Positions with the
+	// colours the other way round, and black to move in symmetric material, are
+	// looked up with colours swapped and the board mirrored vertically.
+	stm := 0
+	if pos.Turn() == chess.Black {
+		stm = 1
+	}
+	flip := key != t.key || (t.key == t.key2 && stm == 1)
+	flipColor, flipSquares := 0, 0
+	if flip {
+		flipColor, flipSquares = 8, 56
+		stm ^= 1
+	}
+
+	squares := make([]int, 0, len(board))
+	pieces := make([]int, 0, len(board))
+	for sq := chess.A1; sq <= chess.H8; sq++ {
+		pc, ok := board[sq]
+		if !ok {
+			continue
+		}
+		squares = append(squares, int(sq)^flipSquares)
+		pieces = append(pieces, code(pc)^flipColor)
+	}
+
+	if kind == kindDTZ {
+		file := 0
+		if t.hasPawns {
+			file = leadingPawnFile(t, squares, pieces)
+		}
+		if !t.dtzStored(stm, file) {
+			return 0, stateChangeSTM, nil
+		}
+	}
+
+	d, file, idx := t.encode(squares, pieces, stm)
+	value, err := d.decompress(t.data, idx)
+	if err != nil {
+		return 0, stateOK, err
+	}
+	if kind == kindWDL {
+		return value - 2, stateOK, nil
+	}
+	return t.mapDTZ(file, value, wdl), stateOK, nil
+}
+
+// leadingPawnFile returns the subtable file of a position with pawns: the file of
+// the leading pawn, folded onto a-d
+func leadingPawnFile(t *table, squares, pieces []int) int {
+	lead := t.get(0, 0).pieces[0]
+	best := -1
+	for i, pc := range pieces {
+		if pc == lead && (best < 0 || mapPawns[squares[i]] > mapPawns[squares[best]]) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return 0
+	}
+	return edgeDistance(fileOf(squares[best]))
+}
+
+var pieceLetter = map[chess.PieceType]byte{
+	chess.King: 'K', chess.Queen: 'Q', chess.Rook: 'R', chess.Bishop: 'B', chess.Knight: 'N', chess.Pawn: 'P',
+}
+
+// materialKey returns the material as in table names, white first: "KRPvKR"
+func materialKey(board map[chess.Square]chess.Piece) string {
+	var white, black []byte
+	for _, pc := range board {
+		letter := pieceLetter[pc.Type()]
+		if pc.Color() == chess.White {
+			white = append(white, letter)
+		} else {
+			black = append(black, letter)
+		}
+	}
+	return normalizeSide(string(white)) + "v" + normalizeSide(string(black))
+}
+
+// code returns the generator's code for a piece: 1-6 white P N B R Q K, 9-14 black
+func code(pc chess.Piece) int {
+	var c int
+	switch pc.Type() {
+	case chess.Pawn:
+		c = 1
+	case chess.Knight:
+		c = 2
+	case chess.Bishop:
+		c = 3
+	case chess.Rook:
+		c = 4
+	case chess.Queen:
+		c = 5
+	case chess.King:
+		c = 6
+	}
+	if pc.Color() == chess.Black {
+		c |= 8
+	}
+	return c
+}
+
+func isCapture(m *chess.Move) bool {
+	return m.HasTag(chess.Capture) || m.HasTag(chess.EnPassant)
+}
+
+func isPawnMove(pos *chess.Position, m *chess.Move) bool {
+	return pos.Board().Piece(m.S1()).Type() == chess.Pawn
+}
+
+// dtzBeforeZeroing is the DTZ of a position whose best move zeroes the counter
+func dtzBeforeZeroing(wdl WDL) int {
+	switch wdl {
+	case Win:
+		return 1
+	case CursedWin:
+		return 101
+	case BlessedLoss:
+		return -101
+	case Loss:
+		return -1
+	default:
+		return 0
+	}
+}
+
+func sign(v int) int {
+	switch {
+	case v > 0:
+		return 1
+	case v < 0:
+		return -1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,364 @@
+package tablebase
+
+import (
+	"strings"
+	"testing"
+)
+
+// solvedTable is an independent retrograde solution of KQvK or KRvK with white
+// holding the piece. Positions are indexed by white king, piece, black king and side
+// to move; a distance is the number of plies to mate with best play, the winner
+// hurrying and the loser delaying. Without pawns or captures for the winner, DTZ
+// tables store the same distance.
+type solvedTable struct {
+	piece byte // 'Q' or 'R'
+	state []int8
+	dist  []int8
+}
+
+const (
+	solvedIllegal int8 = iota
+	solvedDraw
+	solvedWon  // white to move wins
+	solvedLost // black to move loses
+)
+
+func solvedIndex(wk, pc, bk, stm int) int { return ((wk*64+pc)*64+bk)*2 + stm }
+
+var (
+	kingSteps = [][2]int{{-1, -1}, {-1, 0}, {-1, 1}, {0, -1}, {0, 1}, {1, -1}, {1, 0}, {1, 1}}
+	rookSteps = [][2]int{{-1, 0}, {0, -1}, {0, 1}, {1, 0}}
+)
+
+func stepFrom(sq int, d [2]int) (int, bool) {
+	f, r := sq%8+d[0], sq/8+d[1]
+	if f < 0 || f > 7 || r < 0 || r > 7 {
+		return 0, false
+	}
+	return r*8 + f, true
+}
+
+func adjacent(a, b int) bool {
+	df, dr := a%8-b%8, a/8-b/8
+	return df >= -1 && df <= 1 && dr >= -1 && dr <= 1
+}
+
+func (s *solvedTable) steps() [][2]int {
+	if s.piece == 'Q' {
+		return kingSteps
+	}
+	return rookSteps
+}
+
+// slides calls fn for each square the piece on pc reaches, stopping at blocker
+func (s *solvedTable) slides(pc, blocker int, fn func(to int)) {
+	for _, d := range s.steps() {
+		for sq, ok := stepFrom(pc, d); ok && sq != blocker; sq, ok = stepFrom(sq, d) {
+			fn(sq)
+		}
+	}
+}
+
+// attacks reports whether the piece on pc attacks sq, with the white king on wk
+// the only blocker
+func (s *solvedTable) attacks(pc, wk, sq int) bool {
+	hit := false
+	s.slides(pc, wk, func(to int) { hit = hit || to == sq })
+	return hit
+}
+
+// blackMoves returns the black king's legal moves, a capture of an unprotected piece
+// included
+func (s *solvedTable) blackMoves(wk, pc, bk int) (to []int, capture bool) {
+	for _, d := range kingSteps {
+		sq, ok := stepFrom(bk, d)
+		if !ok || adjacent(sq, wk) {
+			continue
+		}
+		if sq == pc {
+			capture = true
+		} else if !s.attacks(pc, wk, sq) {
+			to = append(to, sq)
+		}
+	}
+	return to, capture
+}
+
+func solve(piece byte) *solvedTable {
+	s := &solvedTable{piece: piece, state: make([]int8, 64*64*64*2), dist: make([]int8, 64*64*64*2)}
+	remaining := make([]int8, len(s.state))
+	var queue []int
+
+	for wk := 0; wk < 64; wk++ {
+		for pc := 0; pc < 64; pc++ {
+			for bk := 0; bk < 64; bk++ {
+				if pc == wk || bk == wk || bk == pc || adjacent(wk, bk) {
+					continue
+				}
+				check := s.attacks(pc, wk, bk)
+				if !check {
+					s.state[solvedIndex(wk, pc, bk, 0)] = solvedDraw
+				}
+				btm := solvedIndex(wk, pc, bk, 1)
+				s.state[btm] = solvedDraw
+				to, capture := s.blackMoves(wk, pc, bk)
+				remaining[btm] = int8(len(to))
+				if capture {
+					remaining[btm]++ // the capture draws, so it is never resolved
+				}
+				if remaining[btm] == 0 && check {
+					s.state[btm], queue = solvedLost, append(queue, btm)
+				}
+			}
+		}
+	}
+
+	// Breadth-first from the mates: a white-to-move position is won at the first
+	// lost child found, a black-to-move position lost once its last child is won
+	for len(queue) > 0 {
+		i := queue[0]
+		queue = queue[1:]
+		wk, pc, bk := i/2/64/64, i/2/64%64, i/2%64
+		d := s.dist[i] + 1
+
+		if i%2 == 1 {
+			// White moved last: unmove the king or the piece
+			win := func(wk, pc int) {
+				j := solvedIndex(wk, pc, bk, 0)
+				if s.state[j] == solvedDraw {
+					s.state[j], s.dist[j], queue = solvedWon, d, append(queue, j)
+				}
+			}
+			for _, step := range kingSteps {
+				if from, ok := stepFrom(wk, step); ok && from != pc && from != bk && !adjacent(from, bk) {
+					win(from, pc)
+				}
+			}
+			for _, step := range s.steps() {
+				for from, ok := stepFrom(pc, step); ok && from != wk && from != bk; from, ok = stepFrom(from, step) {
+					win(wk, from)
+				}
+			}
+			continue
+		}
+
+		// Black moved last: unmove the king
+		for _, step := range kingSteps {
+			from, ok := stepFrom(bk, step)
+			if !ok || from == wk || from == pc || adjacent(from, wk) {
+				continue
+			}
+			j := solvedIndex(wk, pc, from, 1)
+			if s.state[j] != solvedDraw {
+				continue
+			}
+			if remaining[j]--; remaining[j] == 0 {
+				s.state[j], s.dist[j], queue = solvedLost, d, append(queue, j)
+			}
+		}
+	}
+	return s
+}
+
+// lookup returns the solution for fen, which must have white holding the piece
+func (s *solvedTable) lookup(t *testing.T, fen string) (WDL, int) {
+	t.Helper()
+	sq := map[byte]int{}
+	fields := strings.Fields(fen)
+	for r, row := range strings.Split(fields[0], "/") {
+		f := 0
+		for _, c := range []byte(row) {
+			if c >= '1' && c <= '8' {
+				f += int(c - '0')
+				continue
+			}
+			sq[c] = (7-r)*8 + f
+			f++
+		}
+	}
+	pc, ok := sq[s.piece]
+	if !ok || len(sq) != 3 {
+		t.Fatalf("%s is not K%cvK with white holding the piece", fen, s.piece)
+	}
+	stm := 0
+	if fields[1] == "b" {
+		stm = 1
+	}
+	i := solvedIndex(sq['K'], pc, sq['k'], stm)
+	switch s.state[i] {
+	case solvedWon:
+		return Win, int(s.dist[i])
+	case solvedLost:
+		return Loss, -int(s.dist[i])
+	case solvedDraw:
+		return Draw, 0
+	}
+	t.Fatalf("%s is illegal", fen)
+	return 0, 0
+}
+
+// fen returns the position at index i
+func (s *solvedTable) fen(i int) string {
+	wk, pc, bk := i/2/64/64, i/2/64%64, i/2%64
+	var b strings.Builder
+	for r := 7; r >= 0; r-- {
+		empty := 0
+		for f := 0; f < 8; f++ {
+			var c byte
+			switch r*8 + f {
+			case wk:
+				c = 'K'
+			case pc:
+				c = s.piece
+			case bk:
+				c = 'k'
+			default:
+				empty++
+				continue
+			}
+			if empty > 0 {
+				b.WriteByte(byte('0' + empty))
+				empty = 0
+			}
+			b.WriteByte(c)
+		}
+		if empty > 0 {
+			b.WriteByte(byte('0' + empty))
+		}
+		if r > 0 {
+			b.WriteByte('/')
+		}
+	}
+	if i%2 == 1 {
+		return b.String() + " b - - 0 1"
+	}
+	return b.String() + " w - - 0 1"
+}
+
+// swapColours mirrors fen's board vertically and swaps the colours of the pieces and
+// the side to move, which leaves the result unchanged
+func swapColours(fen string) string {
+	fields := strings.Fields(fen)
+	rows := strings.Split(fields[0], "/")
+	for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+		rows[i], rows[j] = rows[j], rows[i]
+	}
+	board := []byte(strings.Join(rows, "/"))
+	for i, c := range board {
+		if c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' {
+			board[i] = c ^ 0x20
+		}
+	}
+	side := "w"
+	if fields[1] == "w" {
+		side = "b"
+	}
+	return string(board) + " " + side + " - - 0 1"
+}
+
+var solvedTables = map[byte]*solvedTable{}
+
+func solved(piece byte) *solvedTable {
+	if solvedTables[piece] == nil {
+		solvedTables[piece] = solve(piece)
+	}
+	return solvedTables[piece]
+}
+
+// solvedFEN returns fen, with colours swapped if black holds the piece, and its
+// solution; ok is false for material other than KQvK and KRvK
+func solvedFEN(fen string) (string, *solvedTable, bool) {
+	board := strings.Fields(fen)[0]
+	if strings.ContainsAny(board, "qr") {
+		fen, board = swapColours(fen), strings.Fields(swapColours(fen))[0]
+	}
+	pieces := strings.Map(func(c rune) rune {
+		if c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' {
+			return c
+		}
+		return -1
+	}, board)
+	for _, piece := range []byte{'Q', 'R'} {
+		if len(pieces) == 3 && strings.Count(pieces, string(piece)) == 1 {
+			return fen, solved(piece), true
+		}
+	}
+	return fen, nil, false
+}
+
+func TestSolve(t *testing.T) {
+	tests := []struct {
+		piece            byte
+		longWin, longest int
+	}{
+		// Mate takes at most 10 moves with the queen and 16 with the rook
+		{'Q', 19, 20},
+		{'R', 31, 32},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.piece), func(t *testing.T) {
+			s := solved(tt.piece)
+			var longWin, longest int
+			for i, st := range s.state {
+				switch {
+				case st == solvedWon && int(s.dist[i]) > longWin:
+					longWin = int(s.dist[i])
+				case st == solvedLost && int(s.dist[i]) > longest:
+					longest = int(s.dist[i])
+				}
+			}
+			if longWin != tt.longWin || longest != tt.longest {
+				t.Errorf("longest win, loss = %d, %d plies; want %d, %d", longWin, longest, tt.longWin, tt.longest)
+			}
+		})
+	}
+
+	// The expectations TestRealTables holds the official tables to
+	for _, tt := range realTablePositions {
+		fen, s, ok := solvedFEN(tt.fen)
+		if !ok {
+			continue
+		}
+		t.Run(tt.name, func(t *testing.T) {
+			wdl, dist := s.lookup(t, fen)
+			if wdl != tt.wdl || dist < tt.minDTZ || dist > tt.maxDTZ {
+				t.Errorf("solution = %v in %d plies; want %v, %d to %d", wdl, dist, tt.wdl, tt.minDTZ, tt.maxDTZ)
+			}
+		})
+	}
+}
+
+// TestRealTables_Solved compares a sample of the official KQvK and KRvK tables, in
+// both colours, with the retrograde solution
+func TestRealTables_Solved(t *testing.T) {
+	p := openRealTables(t)
+
+	const stride = 127 // prime, so the sample covers every square for each piece
+	for _, piece := range []byte{'Q', 'R'} {
+		s := solved(piece)
+		checked := 0
+		for i := 0; i < len(s.state); i += stride {
+			if s.state[i] == solvedIllegal {
+				continue
+			}
+			fen := s.fen(i)
+			wantWDL, wantDist := s.lookup(t, fen)
+			for _, fen := range []string{fen, swapColours(fen)} {
+				wdl, err := p.ProbeWDL(fen)
+				if err != nil || wdl != wantWDL {
+					t.Fatalf("ProbeWDL(%s) = %v, %v; want %v", fen, wdl, err, wantWDL)
+				}
+				if wantWDL == Loss && wantDist == 0 {
+					continue // mated
+				}
+				// DTZ tables may round a distance up by one ply
+				dtz, err := p.ProbeDTZ(fen)
+				if err != nil || dtz < wantDist-1 || dtz > wantDist+1 || (dtz == 0) != (wantDist == 0) {
+					t.Fatalf("ProbeDTZ(%s) = %d, %v; want %d", fen, dtz, err, wantDist)
+				}
+			}
+			checked++
+		}
+		t.Logf("K%cvK: %d positions agree", piece, checked)
+	}
+}
@@ -0,0 +1,474 @@
+package tablebase
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+type tableKind int
+
+const (
+	kindWDL tableKind = iota
+	kindDTZ
+)
+
+var tableMagic = map[tableKind][4]byte{
+	kindWDL: {0x71, 0xE8, 0x23, 0x5D},
+	kindDTZ: {0xD7, 0x66, 0x0C, 0xA5},
+}
+
+// pieceOrder is the order of piece letters in material keys and file names
+const pieceOrder = "KQRBNP"
+
+// table is one Syzygy file, e.g. KRPvKR.rtbw. Its data is mapped on first use.
+type table struct {
+	kind tableKind
+	path string
+
+	// key is the material with the first side of the file name as white, key2
+	// with colours swapped; they are equal for symmetric material
+	key, key2       string
+	pieceCount      int
+	hasPawns        bool
+	hasUniquePieces bool
+	// pawnCount[0] is the leading (fewer pawns) side's pawns
+	pawnCount [2]int
+
+	once    sync.Once
+	initErr error
+	data    []byte
+	unmap   func() error
+	items   [2][4]pairsData
+	dtzMap  int
+}
+
+// newTable describes the table for a file name code like "KRPvKR"
+func newTable(kind tableKind, code, path string) (*table, error) {
+	sides := strings.Split(code, "v")
+	if len(sides) != 2 || sides[0] == "" || sides[1] == "" {
+		return nil, fmt.Errorf("tablebase: invalid table name %q", code)
+	}
+	white, black := normalizeSide(sides[0]), normalizeSide(sides[1])
+	if strings.Count(white, "K") != 1 || strings.Count(black, "K") != 1 ||
+		len(white) != len(sides[0]) || len(black) != len(sides[1]) {
+		return nil, fmt.Errorf("tablebase: invalid table name %q", code)
+	}
+
+	t := &table{
+		kind:       kind,
+		path:       path,
+		key:        white + "v" + black,
+		key2:       black + "v" + white,
+		pieceCount: len(white) + len(black),
+	}
+	if t.pieceCount > maxPieces {
+		return nil, fmt.Errorf("tablebase: table %q has more than %d pieces", code, maxPieces)
+	}
+
+	for _, side := range []string{white, black} {
+		for _, p := range "QRBNP" {
+			if strings.Count(side, string(p)) == 1 {
+				t.hasUniquePieces = true
+			}
+		}
+	}
+
+	// The leading side is the one with fewer pawns, as it compresses better
+	wp, bp := strings.Count(white, "P"), strings.Count(black, "P")
+	t.hasPawns = wp+bp > 0
+	if bp == 0 || (wp > 0 && bp >= wp) {
+		t.pawnCount = [2]int{wp, bp}
+	} else {
+		t.pawnCount = [2]int{bp, wp}
+	}
+	return t, nil
+}
+
+// normalizeSide orders piece letters as in file names, dropping unknown letters
+func normalizeSide(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(pieceOrder); i++ {
+		b.WriteString(strings.Repeat(string(pieceOrder[i]), strings.Count(s, string(pieceOrder[i]))))
+	}
+	return b.String()
+}
+
+// sides is the number of subtables per file: WDL tables store both sides to move
+// unless the material is symmetric, DTZ tables only one
+func (t *table) sides() int {
+	if t.kind == kindWDL && t.key != t.key2 {
+		return 2
+	}
+	return 1
+}
+
+func (t *table) get(stm, file int) *pairsData {
+	if !t.hasPawns {
+		file = 0
+	}
+	if t.kind == kindDTZ {
+		stm = 0
+	}
+	return &t.items[stm%2][file]
+}
+
+// load maps the file and parses its headers once
+func (t *table) load() error {
+	t.once.Do(func() {
+		data, unmap, err := mapFile(t.path)
+		if err != nil {
+			t.initErr = fmt.Errorf("tablebase: %w", err)
+			return
+		}
+		t.data, t.unmap = data, unmap
+		if err := t.parse(); err != nil {
+			t.initErr = fmt.Errorf("%s: %w", t.path, err)
+		}
+	})
+	return t.initErr
+}
+
+func (t *table) close() error {
+	if t.unmap == nil {
+		return nil
+	}
+	return t.unmap()
+}
+
+// parse reads the headers of all subtables
+func (t *table) parse() error {
+	buf := t.data
+	magic := tableMagic[t.kind]
+	if len(buf) < 6 || buf[0] != magic[0] || buf[1] != magic[1] || buf[2] != magic[2] || buf[3] != magic[3] {
+		return errCorrupt
+	}
+
+	const (
+		split    = 1
+		hasPawns = 2
+	)
+	if (buf[4]&hasPawns != 0) != t.hasPawns || (t.kind == kindWDL && (buf[4]&split != 0) != (t.key != t.key2)) {
+		return errCorrupt
+	}
+	off := 5
+
+	sides := t.sides()
+	maxFile := 0
+	if t.hasPawns {
+		maxFile = 3
+	}
+	pp := t.hasPawns && t.pawnCount[1] > 0
+
+	for f := 0; f <= maxFile; f++ {
+		if off+2+t.pieceCount > len(buf) {
+			return errCorrupt
+		}
+		order := [2][2]int{{int(buf[off] & 0xF), 0xF}, {int(buf[off] >> 4), 0xF}}
+		if pp {
+			order[0][1] = int(buf[off+1] & 0xF)
+			order[1][1] = int(buf[off+1] >> 4)
+			off++
+		}
+		off++
+
+		for k := 0; k < t.pieceCount; k++ {
+			for i := 0; i < sides; i++ {
+				p := int(buf[off] & 0xF)
+				if i == 1 {
+					p = int(buf[off] >> 4)
+				}
+				t.items[i][f].pieces[k] = p
+			}
+			off++
+		}
+		for i := 0; i < sides; i++ {
+			t.setGroups(&t.items[i][f], order[i], f)
+		}
+	}
+	off += off & 1
+
+	var err error
+	for f := 0; f <= maxFile; f++ {
+		for i := 0; i < sides; i++ {
+			if off, err = t.items[i][f].setSizes(buf, off); err != nil {
+				return err
+			}
+		}
+	}
+
+	if t.kind == kindDTZ {
+		t.dtzMap = off
+		for f := 0; f <= maxFile; f++ {
+			d := &t.items[0][f]
+			if d.flags&flagMapped == 0 {
+				continue
+			}
+			if d.flags&flagWide != 0 {
+				off += off & 1
+				for i := 0; i < 4; i++ {
+					d.mapIdx[i] = (off-t.dtzMap)/2 + 1
+					off += 2*int(le16(buf, off)) + 2
+				}
+			} else {
+				for i := 0; i < 4 && off < len(buf); i++ {
+					d.mapIdx[i] = off - t.dtzMap + 1
+					off += int(buf[off]) + 1
+				}
+			}
+		}
+		off += off & 1
+	}
+
+	for f := 0; f <= maxFile; f++ {
+		for i := 0; i < sides; i++ {
+			t.items[i][f].sparseIndex = off
+			off += 6 * t.items[i][f].sparseIndexSize
+		}
+	}
+	for f := 0; f <= maxFile; f++ {
+		for i := 0; i < sides; i++ {
+			t.items[i][f].blockLength = off
+			off += 2 * t.items[i][f].blockLengthSize
+		}
+	}
+	for f := 0; f <= maxFile; f++ {
+		for i := 0; i < sides; i++ {
+			off = (off + 0x3F) &^ 0x3F
+			t.items[i][f].data = off
+			off += t.items[i][f].numBlocks * t.items[i][f].blockSize
+		}
+	}
+	if off > len(buf) {
+		return errCorrupt
+	}
+	return nil
+}
+
+// setGroups splits the pieces into the groups that are encoded together. Pieces of
+// one type and colour form a group, except the leading group: pawns when there are
+// any, otherwise three unique pieces or the two kings. order gives the position of
+// the leading group and the remaining pawns in the encoding.
+func (t *table) setGroups(d *pairsData, order [2]int, file int) {
+	n := 0
+	firstLen := 2
+	if t.hasPawns {
+		firstLen = 0
+	} else if t.hasUniquePieces {
+		firstLen = 3
+	}
+	d.groupLen[0] = 1
+	for i := 1; i < t.pieceCount; i++ {
+		firstLen--
+		if firstLen > 0 || d.pieces[i] == d.pieces[i-1] {
+			d.groupLen[n]++
+		} else {
+			n++
+			d.groupLen[n] = 1
+		}
+	}
+	n++
+	d.groupLen[n] = 0
+
+	pp := t.hasPawns && t.pawnCount[1] > 0
+	next := 1
+	freeSquares := 64 - d.groupLen[0]
+	if pp {
+		next = 2
+		freeSquares -= d.groupLen[1]
+	}
+
+	idx := uint64(1)
+	for k := 0; next < n || k == order[0] || k == order[1]; k++ {
+		switch {
+		case k == order[0]:
+			d.groupIdx[0] = idx
+			switch {
+			case t.hasPawns:
+				idx *= leadPawnsSize[d.groupLen[0]][file]
+			case t.hasUniquePieces:
+				idx *= 31332
+			default:
+				idx *= 462
+			}
+		case k == order[1]:
+			d.groupIdx[1] = idx
+			idx *= binomial[d.groupLen[1]][48-d.groupLen[0]]
+		default:
+			d.groupIdx[next] = idx
+			idx *= binomial[d.groupLen[next]][freeSquares]
+			freeSquares -= d.groupLen[next]
+			next++
+		}
+	}
+	d.groupIdx[n] = idx
+}
+
+// encode returns the subtable and index of a position. squares and pieces list the
+// occupied squares and their pieces, already coloured so that the table's first side
+// is white; stm is the side to move in the same terms (0 white).
+func (t *table) encode(squares, pieces []int, stm int) (*pairsData, int, uint64) {
+	size := len(squares)
+	leadPawns := 0
+	file := 0
+
+	if t.hasPawns {
+		// The pawns of the leading colour come first; the leading pawn is the one
+		// with the highest mapPawns value
+		lead := t.get(0, 0).pieces[0]
+		for i := 0; i < size; i++ {
+			if pieces[i] == lead {
+				squares[i], squares[leadPawns] = squares[leadPawns], squares[i]
+				pieces[i], pieces[leadPawns] = pieces[leadPawns], pieces[i]
+				leadPawns++
+			}
+		}
+		best := 0
+		for i := 1; i < leadPawns; i++ {
+			if mapPawns[squares[i]] > mapPawns[squares[best]] {
+				best = i
+			}
+		}
+		squares[0], squares[best] = squares[best], squares[0]
+		file = edgeDistance(fileOf(squares[0]))
+	}
+
+	d := t.get(stm, file)
+
+	// Order the remaining pieces as the table lists them
+	for i := leadPawns; i < size-1; i++ {
+		for j := i + 1; j < size; j++ {
+			if d.pieces[i] == pieces[j] {
+				pieces[i], pieces[j] = pieces[j], pieces[i]
+				squares[i], squares[j] = squares[j], squares[i]
+				break
+			}
+		}
+	}
+
+	// Mirror so the leading piece is on files a-d
+	if fileOf(squares[0]) > 3 {
+		for i := range squares {
+			squares[i] = flipFile(squares[i])
+		}
+	}
+
+	var idx uint64
+	if t.hasPawns {
+		idx = leadPawnIdx[leadPawns][squares[0]]
+		rest := squares[1:leadPawns]
+		sort.SliceStable(rest, func(a, b int) bool { return mapPawns[rest[a]] < mapPawns[rest[b]] })
+		for i := 1; i < leadPawns; i++ {
+			idx += binomial[i][mapPawns[squares[i]]]
+		}
+	} else {
+		idx = t.encodeLeadingPieces(d, squares)
+	}
+
+	idx *= d.groupIdx[0]
+	start := d.groupLen[0]
+	remainingPawns := t.hasPawns && t.pawnCount[1] > 0
+	for next := 1; d.groupLen[next] != 0; next++ {
+		group := squares[start : start+d.groupLen[next]]
+		sort.Ints(group)
+		var n uint64
+		for i, sq := range group {
+			// Skip the squares taken by earlier groups
+			adjust := 0
+			for _, prev := range squares[:start] {
+				if sq > prev {
+					adjust++
+				}
+			}
+			s := sq - adjust
+			if remainingPawns {
+				s -= 8
+			}
+			n += binomial[i+1][s]
+		}
+		remainingPawns = false
+		idx += n * d.groupIdx[next]
+		start += d.groupLen[next]
+	}
+	return d, file, idx
+}
+
+// encodeLeadingPieces maps the leading group of a pawnless position, folding the
+// board's eight symmetries into the a1-d1-d4 triangle
+func (t *table) encodeLeadingPieces(d *pairsData, squares []int) uint64 {
+	if rankOf(squares[0]) > 3 {
+		for i := range squares {
+			squares[i] = flipRank(squares[i])
+		}
+	}
+	// The first leading piece off the a1-h8 diagonal goes below it
+	for i := 0; i < d.groupLen[0]; i++ {
+		if offDiag(squares[i]) == 0 {
+			continue
+		}
+		if offDiag(squares[i]) > 0 {
+			for j := i; j < len(squares); j++ {
+				squares[j] = flipDiag(squares[j])
+			}
+		}
+		break
+	}
+
+	if !t.hasUniquePieces {
+		return uint64(mapKK[mapA1D1D4[squares[0]]][squares[1]])
+	}
+
+	s0, s1, s2 := squares[0], squares[1], squares[2]
+	adjust1 := 0
+	if s1 > s0 {
+		adjust1 = 1
+	}
+	adjust2 := 0
+	if s2 > s0 {
+		adjust2++
+	}
+	if s2 > s1 {
+		adjust2++
+	}
+
+	var idx int
+	switch {
+	case offDiag(s0) != 0:
+		idx = (mapA1D1D4[s0]*63+(s1-adjust1))*62 + s2 - adjust2
+	case offDiag(s1) != 0:
+		idx = (6*63+rankOf(s0)*28+mapB1H1H7[s1])*62 + s2 - adjust2
+	case offDiag(s2) != 0:
+		idx = 6*63*62 + 4*28*62 + rankOf(s0)*7*28 + (rankOf(s1)-adjust1)*28 + mapB1H1H7[s2]
+	default:
+		idx = 6*63*62 + 4*28*62 + 4*7*28 + rankOf(s0)*7*6 + (rankOf(s1)-adjust1)*6 + (rankOf(s2) - adjust2)
+	}
+	return uint64(idx)
+}
+
+// dtzStored reports whether the DTZ table stores positions with stm to move
+func (t *table) dtzStored(stm, file int) bool {
+	flags := t.get(stm, file).flags
+	return int(flags&flagSTM) == stm || (t.key == t.key2 && !t.hasPawns)
+}
+
+// mapDTZ converts a stored DTZ value to plies for a position with result wdl
+func (t *table) mapDTZ(file, value int, wdl WDL) int {
+	d := t.get(0, file)
+	if d.flags&flagMapped != 0 {
+		// Map order is win, loss, cursed win, blessed loss
+		slot := map[WDL]int{Win: 0, Loss: 1, CursedWin: 2, BlessedLoss: 3}[wdl]
+		if d.flags&flagWide != 0 {
+			value = int(le16(t.data, t.dtzMap+2*(d.mapIdx[slot]+value)))
+		} else if p := t.dtzMap + d.mapIdx[slot] + value; p < len(t.data) {
+			value = int(t.data[p])
+		}
+	}
+
+	if (wdl == Win && d.flags&flagWinPlies == 0) ||
+		(wdl == Loss && d.flags&flagLossPlies == 0) ||
+		wdl == CursedWin || wdl == BlessedLoss {
+		value *= 2
+	}
+	return value + 1
+}
@@ -0,0 +1,253 @@
+// Package tablebase probes Syzygy endgame tablebases: WDL (.rtbw) tables for the
+// game-theoretic result of a position and DTZ (.rtbz) tables for the distance to
+// the next capture or pawn move. Files are read from a local directory and mapped
+// into memory when first needed.
+package tablebase
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/notnil/chess"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/logger"
+)
+
+// maxPieces is the largest piece count Syzygy tables exist for
+const maxPieces = 7
+
+// WDL is a tablebase result for the side to move. CursedWin and BlessedLoss are
+// wins and losses that the fifty-move rule turns into draws.
+type WDL int
+
+const (
+	Loss        WDL = -2
+	BlessedLoss WDL = -1
+	Draw        WDL = 0
+	CursedWin   WDL = 1
+	Win         WDL = 2
+)
+
+func (w WDL) String() string {
+	switch w {
+	case Loss:
+		return "loss"
+	case BlessedLoss:
+		return "blessed_loss"
+	case CursedWin:
+		return "cursed_win"
+	case Win:
+		return "win"
+	default:
+		return "draw"
+	}
+}
+
+// Outcome returns 1 for a win, -1 for a loss and 0 for a draw under the fifty-move rule
+func (w WDL) Outcome() int {
+	switch w {
+	case Win:
+		return 1
+	case Loss:
+		return -1
+	default:
+		return 0
+	}
+}
+
+// Result names the outcome as endgame positions record it: "win", "draw" or "loss"
+func (w WDL) Result() string {
+	switch w.Outcome() {
+	case 1:
+		return "win"
+	case -1:
+		return "loss"
+	default:
+		return "draw"
+	}
+}
+
+var (
+	// ErrNotAvailable is returned when no table covers the position's material
+	ErrNotAvailable = errors.New("tablebase: no table for this material")
+	// ErrTooManyPieces is returned for positions with more than seven pieces
+	ErrTooManyPieces = errors.New("tablebase: more than 7 pieces")
+	// ErrCastling is returned for positions with castling rights, which tables exclude
+	ErrCastling = errors.New("tablebase: position has castling rights")
+)
+
+// Default is the process-wide prober set by Init; nil when probing is disabled
+var Default *Prober
+
+// Init opens the tables in dir as Default. An empty dir disables probing.
+func Init(dir string) {
+	if dir == "" {
+		logger.Info("Syzygy tablebases disabled (SYZYGY_PATH not set)")
+		return
+	}
+	p, err := Open(dir)
+	if err != nil {
+		logger.Error("Failed to open Syzygy tablebases", logger.F("dir", dir, "error", err.Error()))
+		return
+	}
+	Default = p
+	logger.Info("Syzygy tablebases loaded", logger.F(
+		"dir", dir,
+		"wdlTables", p.wdlCount,
+		"dtzTables", p.dtzCount,
+		"maxPieces", p.MaxPieces(),
+	))
+}
+
+// Prober probes the tables found in one directory. It is safe for concurrent use.
+type Prober struct {
+	// wdl and dtz index every table by both of its material keys
+	wdl, dtz           map[string]*table
+	wdlCount, dtzCount int
+	maxPieces          int
+}
+
+// Open indexes the .rtbw and .rtbz files in dir (subdirectories are searched too,
+// as tables are often shipped split by piece count). Files are opened lazily.
+func Open(dir string) (*Prober, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("tablebase: %s is not a directory", dir)
+	}
+
+	p := &Prober{wdl: make(map[string]*table), dtz: make(map[string]*table)}
+	err = filepath.WalkDir(dir, func(path string, e os.DirEntry, err error) error {
+		if err != nil || e.IsDir() {
+			return err
+		}
+		name := e.Name()
+		var kind tableKind
+		switch strings.ToLower(filepath.Ext(name)) {
+		case ".rtbw":
+			kind = kindWDL
+		case ".rtbz":
+			kind = kindDTZ
+		default:
+			return nil
+		}
+
+		t, err := newTable(kind, strings.TrimSuffix(name, filepath.Ext(name)), path)
+		if err != nil {
+			logger.Warn("Skipping tablebase file", logger.F("file", path, "error", err.Error()))
+			return nil
+		}
+		index := p.wdl
+		if kind == kindDTZ {
+			index = p.dtz
+		}
+		if _, dup := index[t.key]; dup {
+			return nil
+		}
+		index[t.key], index[t.key2] = t, t
+		if kind == kindWDL {
+			p.wdlCount++
+			if t.pieceCount > p.maxPieces {
+				p.maxPieces = t.pieceCount
+			}
+		} else {
+			p.dtzCount++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// MaxPieces returns the largest piece count with a WDL table, 0 without tables
+func (p *Prober) MaxPieces() int {
+	if p == nil {
+		return 0
+	}
+	return p.maxPieces
+}
+
+// Close unmaps all opened tables
+func (p *Prober) Close() error {
+	var first error
+	for _, index := range []map[string]*table{p.wdl, p.dtz} {
+		for key, t := range index {
+			if key == t.key {
+				if err := t.close(); err != nil && first == nil {
+					first = err
+				}
+			}
+		}
+	}
+	return first
+}
+
+// ProbeWDL returns the result of the position in fen for the side to move
+func (p *Prober) ProbeWDL(fen string) (WDL, error) {
+	pos, err := probePosition(fen)
+	if err != nil {
+		return Draw, err
+	}
+	wdl, _, err := p.search(pos, false)
+	return wdl, err
+}
+
+// ProbeDTZ returns the distance to zeroing of the position in fen: plies to the
+// next capture or pawn move with optimal play, positive when the side to move wins,
+// negative when it loses and 0 for draws. Results near the fifty-move boundary
+// may be off by one.
+func (p *Prober) ProbeDTZ(fen string) (int, error) {
+	pos, err := probePosition(fen)
+	if err != nil {
+		return 0, err
+	}
+	return p.probeDTZ(pos)
+}
+
+// Value gives the outcome of fen for the side to move as 1, 0 or -1, and false when
+// the tables cannot tell. A nil Prober knows nothing.
+func (p *Prober) Value(fen string) (int, bool) {
+	if p == nil {
+		return 0, false
+	}
+	wdl, err := p.ProbeWDL(fen)
+	if err != nil {
+		return 0, false
+	}
+	return wdl.Outcome(), true
+}
+
+// Distance gives the DTZ of fen for the side to move, and false when the tables
+// cannot tell. A nil Prober knows nothing.
+func (p *Prober) Distance(fen string) (int, bool) {
+	if p == nil {
+		return 0, false
+	}
+	dtz, err := p.ProbeDTZ(fen)
+	if err != nil {
+		return 0, false
+	}
+	return dtz, true
+}
+
+// probePosition parses fen and checks that tables can cover it
+func probePosition(fen string) (*chess.Position, error) {
+	opt, err := chess.FEN(fen)
+	if err != nil {
+		return nil, err
+	}
+	pos := chess.NewGame(opt).Position()
+	if len(pos.Board().SquareMap()) > maxPieces {
+		return nil, ErrTooManyPieces
+	}
+	if pos.CastleRights().String() != "-" {
+		return nil, ErrCastling
+	}
+	return pos, nil
+}
@@ -0,0 +1,341 @@
+package tablebase
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/notnil/chess"
+)
+
+// writeSingleValueTable writes a KQvK table whose subtables store one value each,
+// exercising the header parsing and position encoding without real table data
+func writeSingleValueTable(t *testing.T, dir string, kind tableKind, values ...byte) {
+	t.Helper()
+	magic := tableMagic[kind]
+	buf := append([]byte{}, magic[:]...)
+	if kind == kindWDL {
+		buf = append(buf, 0x01) // split: both sides to move
+	} else {
+		buf = append(buf, 0x00)
+	}
+	buf = append(buf, 0x00)             // group order
+	buf = append(buf, 0x66, 0x55, 0xEE) // white king, white queen, black king
+	buf = append(buf, 0x00)             // alignment
+	for _, v := range values {
+		buf = append(buf, flagSingleValue, v)
+	}
+	for len(buf) < 64 {
+		buf = append(buf, 0)
+	}
+
+	name := "KQvK.rtbw"
+	if kind == kindDTZ {
+		name = "KQvK.rtbz"
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), buf, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func openTestProber(t *testing.T, withDTZ bool) *Prober {
+	t.Helper()
+	dir := t.TempDir()
+	// White to move wins (stored 4 = WDL 2), black to move loses (stored 0 = WDL -2)
+	writeSingleValueTable(t, dir, kindWDL, 4, 0)
+	if withDTZ {
+		// White to move stored; 5 moves to zeroing
+		writeSingleValueTable(t, dir, kindDTZ, 5)
+	}
+	p, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { p.Close() })
+	return p
+}
+
+func TestProbeWDL(t *testing.T) {
+	p := openTestProber(t, false)
+
+	tests := []struct {
+		name string
+		fen  string
+		want WDL
+	}{
+		{"white to move", "8/8/8/4k3/8/8/1Q6/K7 w - - 0 1", Win},
+		{"black to move", "8/8/8/4k3/8/8/1Q6/K7 b - - 0 1", Loss},
+		{"queen on the diagonal", "8/8/8/4k3/8/8/8/K6Q w - - 0 1", Win},
+		{"all on the diagonal", "8/8/8/8/3k4/8/1Q6/K7 b - - 0 1", Loss},
+		{"colours swapped", "8/8/8/4K3/8/8/1q6/k7 b - - 0 1", Win},
+		{"hanging queen", "8/8/8/8/8/3k4/3Q4/7K b - - 0 1", Draw},
+		{"bare kings", "8/8/8/4k3/8/8/8/K7 w - - 0 1", Draw},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := p.ProbeWDL(tt.fen)
+			if err != nil {
+				t.Fatalf("ProbeWDL() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ProbeWDL() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProbeWDL_Errors(t *testing.T) {
+	p := openTestProber(t, false)
+
+	tests := []struct {
+		name string
+		fen  string
+		want error
+	}{
+		{"missing table", "8/8/8/4k3/8/8/1R6/K7 w - - 0 1", ErrNotAvailable},
+		{"castling rights", "r3k3/8/8/8/8/8/8/4K3 b q - 0 1", ErrCastling},
+		{"too many pieces", "4k3/pppppppp/8/8/8/8/8/4K3 w - - 0 1", ErrTooManyPieces},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := p.ProbeWDL(tt.fen); !errors.Is(err, tt.want) {
+				t.Errorf("ProbeWDL() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestProbeDTZ(t *testing.T) {
+	p := openTestProber(t, true)
+
+	// Stored in moves for white to move: 5 moves is 10 plies, plus the move itself
+	if got, err := p.ProbeDTZ("8/8/8/4k3/8/8/1Q6/K7 w - - 0 1"); err != nil || got != 11 {
+		t.Errorf("ProbeDTZ(white) = %d, %v, want 11", got, err)
+	}
+	// Black to move is not stored: one ply further from the other side
+	if got, err := p.ProbeDTZ("8/8/8/4k3/8/8/1Q6/K7 b - - 0 1"); err != nil || got != -12 {
+		t.Errorf("ProbeDTZ(black) = %d, %v, want -12", got, err)
+	}
+
+	noDTZ := openTestProber(t, false)
+	if _, err := noDTZ.ProbeDTZ("8/8/8/4k3/8/8/1Q6/K7 w - - 0 1"); !errors.Is(err, ErrNotAvailable) {
+		t.Errorf("ProbeDTZ() without DTZ table error = %v, want ErrNotAvailable", err)
+	}
+}
+
+func TestValue(t *testing.T) {
+	var nilProber *Prober
+	if _, ok := nilProber.Value("8/8/8/4k3/8/8/1Q6/K7 w - - 0 1"); ok {
+		t.Error("nil Prober Value() ok = true, want false")
+	}
+
+	p := openTestProber(t, false)
+	if v, ok := p.Value("8/8/8/4k3/8/8/1Q6/K7 b - - 0 1"); !ok || v != -1 {
+		t.Errorf("Value() = %d, %v, want -1, true", v, ok)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"KRvK.rtbw", "KRvK.rtbz", "KPvKP.rtbw", "readme.txt", "KKvK.rtbw"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	p, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.wdlCount != 2 || p.dtzCount != 1 {
+		t.Errorf("tables = %d WDL, %d DTZ, want 2, 1", p.wdlCount, p.dtzCount)
+	}
+	if p.MaxPieces() != 4 {
+		t.Errorf("MaxPieces() = %d, want 4", p.MaxPieces())
+	}
+	if p.wdl["KvKR"] == nil || p.wdl["KvKR"] != p.wdl["KRvK"] {
+		t.Error("table not indexed by both material keys")
+	}
+
+	if _, err := Open(filepath.Join(dir, "missing")); err == nil {
+		t.Error("Open() of a missing directory succeeded")
+	}
+}
+
+func TestNewTable(t *testing.T) {
+	tests := []struct {
+		code      string
+		key2      string
+		unique    bool
+		pawnCount [2]int
+		wantErr   bool
+	}{
+		{code: "KRvK", key2: "KvKR", unique: true},
+		{code: "KRRvKBB", key2: "KBBvKRR"},
+		{code: "KPPvKP", key2: "KPvKPP", pawnCount: [2]int{1, 2}, unique: true},
+		{code: "KPvKPP", key2: "KPPvKP", pawnCount: [2]int{1, 2}, unique: true},
+		{code: "KRv", wantErr: true},
+		{code: "KRvKXQ", wantErr: true},
+		{code: "KQQQQQQvK", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			tb, err := newTable(kindWDL, tt.code, "")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newTable() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if tb.key2 != tt.key2 || tb.hasUniquePieces != tt.unique || tb.pawnCount != tt.pawnCount {
+				t.Errorf("newTable() = key2 %q, unique %v, pawns %v; want %q, %v, %v",
+					tb.key2, tb.hasUniquePieces, tb.pawnCount, tt.key2, tt.unique, tt.pawnCount)
+			}
+		})
+	}
+}
+
+func TestEncodingTables(t *testing.T) {
+	seen := make(map[int]bool)
+	for idx := 0; idx < 10; idx++ {
+		for sq := 0; sq < 64; sq++ {
+			if mapKK[idx][sq] != 0 {
+				seen[mapKK[idx][sq]] = true
+			}
+		}
+	}
+	// 462 king placements, code 0 being the only one not counted above
+	if len(seen) != 461 {
+		t.Errorf("mapKK has %d non-zero codes, want 461", len(seen))
+	}
+
+	if mapPawns[8] != 47 || mapPawns[15] != 46 || mapPawns[9] != 35 || mapPawns[52] != 0 {
+		t.Errorf("mapPawns a2, h2, b2, e7 = %d, %d, %d, %d; want 47, 46, 35, 0",
+			mapPawns[8], mapPawns[15], mapPawns[9], mapPawns[52])
+	}
+	if binomial[2][5] != 10 || binomial[0][63] != 1 || binomial[3][48] != 17296 {
+		t.Errorf("binomial = %d, %d, %d; want 10, 1, 17296", binomial[2][5], binomial[0][63], binomial[3][48])
+	}
+	if leadPawnsSize[1][0] != 6 {
+		t.Errorf("leadPawnsSize[1][a] = %d, want 6", leadPawnsSize[1][0])
+	}
+}
+
+// realTableFiles are the official tables TestRealTables needs in testdata/syzygy
+var realTableFiles = []string{
+	"KQvK.rtbw", "KQvK.rtbz", "KRvK.rtbw", "KRvK.rtbz", "KRPvKR.rtbw", "KRPvKR.rtbz",
+}
+
+// openRealTables opens the official tables in testdata/syzygy. Locally the test is
+// skipped when they have not been downloaded (see the README there); in CI, which
+// downloads them, it fails.
+func openRealTables(t *testing.T) *Prober {
+	t.Helper()
+	dir := filepath.Join("testdata", "syzygy")
+	for _, name := range realTableFiles {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			if os.Getenv("CI") != "" {
+				t.Fatalf("%s not found; run %s/fetch.sh", name, dir)
+			}
+			t.Skipf("%s not found; see %s/README.md", name, dir)
+		}
+	}
+	p, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { p.Close() })
+	return p
+}
+
+// realTablePositions are known results; TestSolve checks those without pawns
+// against an independent solution
+var realTablePositions = []struct {
+	name    string
+	fen     string
+	wdl     WDL
+	minDTZ  int
+	maxDTZ  int
+	playOut bool
+}{
+	{"KQvK mate in one", "k7/8/1K6/8/8/8/8/6Q1 w - - 0 1", Win, 1, 1, true},
+	{"KQvK win", "8/8/8/4k3/8/8/2Q5/K7 w - - 0 1", Win, 1, 19, true},
+	{"KQvK loss", "8/8/8/4k3/8/8/2Q5/K7 b - - 0 1", Loss, -20, -1, false},
+	{"KQvK colours swapped", "k7/2q5/8/8/4K3/8/8/8 b - - 0 1", Win, 1, 19, true},
+	{"KQvK stalemate", "k7/2Q5/1K6/8/8/8/8/8 b - - 0 1", Draw, 0, 0, false},
+	{"KQvK hanging queen", "8/8/8/8/8/3k4/3Q4/7K b - - 0 1", Draw, 0, 0, false},
+	{"KRvK mate in one", "k7/8/1K6/8/8/8/8/7R w - - 0 1", Win, 1, 1, true},
+	{"KRvK win", "8/8/8/4k3/8/8/8/R3K3 w - - 0 1", Win, 1, 31, true},
+	{"KRvK protected rook", "8/8/8/8/8/3k4/3R4/3K4 b - - 0 1", Loss, -32, -1, false},
+	{"KRvK hanging rook", "8/8/8/8/8/3k4/3R4/7K b - - 0 1", Draw, 0, 0, false},
+	// The Lucena position: the defending king is cut off, so the pawn promotes
+	{"KRPvKR Lucena", "1K6/1P2k3/8/8/8/8/r7/3R4 w - - 0 1", Win, 1, 100, false},
+	// The Philidor defence: the rook holds the sixth rank until the pawn advances
+	{"KRPvKR Philidor", "4k3/8/r7/4P3/3K4/8/8/7R b - - 0 1", Draw, 0, 0, false},
+}
+
+func TestRealTables(t *testing.T) {
+	p := openRealTables(t)
+
+	for _, tt := range realTablePositions {
+		t.Run(tt.name, func(t *testing.T) {
+			wdl, err := p.ProbeWDL(tt.fen)
+			if err != nil || wdl != tt.wdl {
+				t.Fatalf("ProbeWDL() = %v, %v; want %v", wdl, err, tt.wdl)
+			}
+			dtz, err := p.ProbeDTZ(tt.fen)
+			if err != nil || dtz < tt.minDTZ || dtz > tt.maxDTZ {
+				t.Fatalf("ProbeDTZ() = %d, %v; want %d to %d", dtz, err, tt.minDTZ, tt.maxDTZ)
+			}
+			if tt.playOut {
+				playOut(t, p, tt.fen, dtz)
+			}
+		})
+	}
+}
+
+// playOut follows the tables from a won position, the winner taking the move with
+// the shortest DTZ and the loser the longest, and checks that it ends in mate
+// within the position's DTZ (give or take the one-ply rounding DTZ tables allow)
+func playOut(t *testing.T, p *Prober, fen string, dtz int) {
+	t.Helper()
+	opt, err := chess.FEN(fen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pos := chess.NewGame(opt).Position()
+	for ply := 0; ply <= dtz+1; ply++ {
+		if pos.Status() == chess.Checkmate {
+			if ply%2 == 0 {
+				t.Fatalf("the winning side was mated after %d plies", ply)
+			}
+			return
+		}
+
+		var (
+			next     *chess.Position
+			nextDTZ  int
+			winnerUp = ply%2 == 0
+		)
+		for _, m := range pos.ValidMoves() {
+			child := pos.Update(m)
+			d, err := p.probeDTZ(child)
+			if err != nil {
+				t.Fatalf("probeDTZ(%s) error = %v", child, err)
+			}
+			if winnerUp && d >= 0 {
+				continue // lets the loser off
+			}
+			// Shortest for the winner (the loser's DTZ closest to zero), longest for the loser
+			if next == nil || d > nextDTZ {
+				next, nextDTZ = child, d
+			}
+		}
+		if next == nil {
+			t.Fatalf("no winning move from %s after %d plies", pos, ply)
+		}
+		pos = next
+	}
+	t.Fatalf("no mate within %d plies of %s", dtz+1, fen)
+}
@@ -0,0 +1,9 @@
+#!/bin/sh
+# Downloads the official tables TestRealTables probes into this directory
+set -eu
+cd "$(dirname "$0")"
+for f in KQvK KRvK KRPvKR; do
+  for ext in rtbw rtbz; do
+    [ -s "$f.$ext" ] || curl -fsSO "https://tablebase.lichess.ovh/tables/standard/3-4-5/$f.$ext"
+  done
+done
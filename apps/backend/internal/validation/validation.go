@@ -41,6 +41,7 @@ var reservedUsernames = map[string]bool{
 	"null":        true,
 	"undefined":   true,
 	"api":         true,
+	"myprofile":   true,
 	"www":         true,
 	"mail":        true,
 	"email":       true,
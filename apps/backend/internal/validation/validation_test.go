@@ -60,6 +60,7 @@ func TestValidateUsername(t *testing.T) {
 		{"reserved nxtchess", "nxtchess", true},
 		{"reserved chess", "chess", true},
 		{"reserved test", "test", true},
+		{"reserved myprofile", "MyProfile", true},
 
 		// Offensive content (case-insensitive, substring match)
 		{"offensive fuck", "fuck", true},
@@ -0,0 +1,322 @@
+package ws
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/chess"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/database"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/httpx"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/logger"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/middleware"
+)
+
+// Blank lines keep idle bot streams open through proxies
+const botKeepAlivePeriod = 30 * time.Second
+
+// newBotClient creates a client without a WebSocket connection. Its messages are
+// written to the bot's NDJSON event stream instead.
+func newBotClient(userID, username string, hub *Hub) *Client {
+	client := NewClient(generateClientID(), userID, hub, nil)
+	client.Username = username
+	client.IsBot = true
+	return client
+}
+
+// registerBot records the bot's event stream. A bot may only have one stream open.
+func (h *Hub) registerBot(client *Client) bool {
+	h.botsMu.Lock()
+	defer h.botsMu.Unlock()
+	if _, exists := h.bots[client.UserID]; exists {
+		return false
+	}
+	h.bots[client.UserID] = client
+	return true
+}
+
+// unregisterBot removes the bot's event stream if it is still the registered one
+func (h *Hub) unregisterBot(client *Client) {
+	h.botsMu.Lock()
+	defer h.botsMu.Unlock()
+	if h.bots[client.UserID] == client {
+		delete(h.bots, client.UserID)
+	}
+}
+
+// GetBot returns the client of a bot with an open event stream, or nil
+func (h *Hub) GetBot(userID string) *Client {
+	h.botsMu.RLock()
+	defer h.botsMu.RUnlock()
+	return h.bots[userID]
+}
+
+// GetBotByUsername returns the client of an online bot by username, or nil
+func (h *Hub) GetBotByUsername(username string) *Client {
+	h.botsMu.RLock()
+	defer h.botsMu.RUnlock()
+	for _, client := range h.bots {
+		if strings.EqualFold(client.Username, username) {
+			return client
+		}
+	}
+	return nil
+}
+
+// BotHandler serves the bot API. Bots receive challenges and game events on an NDJSON
+// stream carrying the same messages as the WebSocket protocol, and act through HTTP
+// calls whose results arrive on the stream.
+type BotHandler struct {
+	hub *Hub
+}
+
+// NewBotHandler creates a bot API handler
+func NewBotHandler(hub *Hub) *BotHandler {
+	return &BotHandler{hub: hub}
+}
+
+// UpgradeAccount turns the caller's account into a bot account
+// POST /api/bot/account/upgrade
+func (h *BotHandler) UpgradeAccount(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok || userID == "" {
+		httpx.WriteJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	isBot, err := database.IsBotAccount(userID)
+	if err != nil {
+		httpx.WriteJSONError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	if !isBot {
+		if err := database.UpgradeToBot(userID); err != nil {
+			if errors.Is(err, database.ErrBotHasGames) {
+				httpx.WriteJSONError(w, http.StatusConflict, "Accounts that have played games cannot become bots")
+				return
+			}
+			httpx.WriteJSONError(w, http.StatusInternalServerError, "Internal server error")
+			return
+		}
+		logger.Info("Account upgraded to bot", logger.F("userId", userID))
+	}
+
+	httpx.WriteJSON(w, http.StatusOK, map[string]string{
+		"message": "Account is a bot account",
+	})
+}
+
+// Stream sends the bot's challenges and game events as newline-delimited JSON until
+// the bot disconnects. Games left by a dropped stream are resumed on the next one.
+// GET /api/bot/stream
+func (h *BotHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.requireBot(w, r)
+	if !ok {
+		return
+	}
+	username, err := database.GetUsernameByID(userID)
+	if err != nil {
+		httpx.WriteJSONError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	client := newBotClient(userID, username, h.hub)
+	if !h.hub.registerBot(client) {
+		httpx.WriteJSONError(w, http.StatusConflict, "A bot stream is already open")
+		return
+	}
+	defer h.closeStream(client)
+
+	// The stream outlives the server's write timeout, so each write gets its own deadline
+	rc := http.NewResponseController(w)
+	rc.SetWriteDeadline(time.Now().Add(writeWait))
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		logger.Error("Bot stream flush unsupported", logger.F("userId", userID, "error", err.Error()))
+		return
+	}
+
+	logger.Info("Bot stream opened", logger.F("userId", userID, "clientId", client.ID))
+
+	for _, gameID := range h.hub.games.GameIDsForUser(userID) {
+		h.hub.games.HandleReconnect(client, gameID)
+	}
+
+	keepAlive := time.NewTicker(botKeepAlivePeriod)
+	defer keepAlive.Stop()
+
+	for {
+		var line []byte
+		select {
+		case <-r.Context().Done():
+			return
+		case <-client.done:
+			return
+		case message := <-client.Send:
+			line = append(message, '\n')
+		case <-keepAlive.C:
+			line = []byte("\n")
+		}
+
+		rc.SetWriteDeadline(time.Now().Add(writeWait))
+		if _, err := w.Write(line); err != nil {
+			return
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// closeStream treats the bot as disconnected from its games, which start their
+// reconnection grace period, and declines the challenges it can no longer answer
+func (h *BotHandler) closeStream(client *Client) {
+	h.hub.unregisterBot(client)
+	client.Close()
+
+	for _, gameID := range h.hub.games.GameIDsForUser(client.UserID) {
+		h.hub.games.HandleDisconnect(client, gameID)
+	}
+	for _, gameID := range h.hub.games.ChallengeIDsForUser(client.UserID) {
+		h.hub.games.DeclineChallenge(client, gameID)
+	}
+
+	logger.Info("Bot stream closed", logger.F("userId", client.UserID, "clientId", client.ID))
+}
+
+// Seek creates a game in the lobby with the bot as white. Humans and other bots may
+// join it.
+// POST /api/bot/seek
+func (h *BotHandler) Seek(w http.ResponseWriter, r *http.Request) {
+	client, ok := h.streamClient(w, r)
+	if !ok {
+		return
+	}
+
+	var data GameCreateData
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+			httpx.WriteJSONError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+
+	h.hub.games.CreateGame(client, &data)
+	writeAccepted(w)
+}
+
+// AcceptChallenge starts a game the bot was challenged to
+// POST /api/bot/challenge/{gameID}/accept
+func (h *BotHandler) AcceptChallenge(w http.ResponseWriter, r *http.Request) {
+	client, ok := h.streamClient(w, r)
+	if !ok {
+		return
+	}
+
+	if !h.hub.games.AcceptChallenge(client, chi.URLParam(r, "gameID")) {
+		httpx.WriteJSONError(w, http.StatusNotFound, "Challenge not found")
+		return
+	}
+	writeAccepted(w)
+}
+
+// DeclineChallenge cancels a game the bot was challenged to
+// POST /api/bot/challenge/{gameID}/decline
+func (h *BotHandler) DeclineChallenge(w http.ResponseWriter, r *http.Request) {
+	client, ok := h.streamClient(w, r)
+	if !ok {
+		return
+	}
+
+	if !h.hub.games.DeclineChallenge(client, chi.URLParam(r, "gameID")) {
+		httpx.WriteJSONError(w, http.StatusNotFound, "Challenge not found")
+		return
+	}
+	httpx.WriteJSON(w, http.StatusOK, map[string]string{
+		"message": "Challenge declined",
+	})
+}
+
+// Move plays a move in UCI notation (e.g. e2e4, e7e8q). It is validated like any other
+// move, and MOVE_ACCEPTED or MOVE_REJECTED arrives on the stream.
+// POST /api/bot/game/{gameID}/move/{move}
+func (h *BotHandler) Move(w http.ResponseWriter, r *http.Request) {
+	client, ok := h.streamClient(w, r)
+	if !ok {
+		return
+	}
+
+	from, to, promotion, err := chess.ParseUCI(chi.URLParam(r, "move"))
+	if err != nil {
+		httpx.WriteJSONError(w, http.StatusBadRequest, "Invalid UCI move")
+		return
+	}
+
+	h.hub.games.HandleMove(client, &MoveData{
+		GameID:    chi.URLParam(r, "gameID"),
+		From:      from,
+		To:        to,
+		Promotion: promotion,
+	})
+	writeAccepted(w)
+}
+
+// Resign resigns one of the bot's games
+// POST /api/bot/game/{gameID}/resign
+func (h *BotHandler) Resign(w http.ResponseWriter, r *http.Request) {
+	client, ok := h.streamClient(w, r)
+	if !ok {
+		return
+	}
+
+	h.hub.games.HandleResign(client, chi.URLParam(r, "gameID"))
+	writeAccepted(w)
+}
+
+// requireBot returns the caller's user ID if the account is a bot
+func (h *BotHandler) requireBot(w http.ResponseWriter, r *http.Request) (string, bool) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok || userID == "" {
+		httpx.WriteJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return "", false
+	}
+
+	isBot, err := database.IsBotAccount(userID)
+	if err != nil {
+		httpx.WriteJSONError(w, http.StatusInternalServerError, "Internal server error")
+		return "", false
+	}
+	if !isBot {
+		httpx.WriteJSONError(w, http.StatusForbidden, "Only bot accounts can use the bot API")
+		return "", false
+	}
+	return userID, true
+}
+
+// streamClient returns the client of the caller's open event stream. Bot actions
+// report their results on the stream, so one must be open.
+func (h *BotHandler) streamClient(w http.ResponseWriter, r *http.Request) (*Client, bool) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok || userID == "" {
+		httpx.WriteJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return nil, false
+	}
+
+	client := h.hub.GetBot(userID)
+	if client == nil {
+		httpx.WriteJSONError(w, http.StatusConflict, "Open the bot event stream first")
+		return nil, false
+	}
+	return client, true
+}
+
+func writeAccepted(w http.ResponseWriter) {
+	httpx.WriteJSON(w, http.StatusAccepted, map[string]string{
+		"message": "Accepted; the result is sent on the event stream",
+	})
+}
@@ -0,0 +1,271 @@
+package ws
+
+import (
+	"testing"
+
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/database"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/database/dbtest"
+)
+
+func TestBotRegistry(t *testing.T) {
+	hub := NewHub(nil)
+	bot := newBotClient("user-1", "StockBot", hub)
+
+	if !hub.registerBot(bot) {
+		t.Fatal("expected first stream to register")
+	}
+	if hub.registerBot(newBotClient("user-1", "StockBot", hub)) {
+		t.Error("expected second stream for the same bot to be rejected")
+	}
+	if got := hub.GetBotByUsername("stockbot"); got != bot {
+		t.Errorf("expected case-insensitive username lookup to find the bot, got %v", got)
+	}
+	if !bot.IsBot {
+		t.Error("expected bot client to be flagged as a bot")
+	}
+
+	// A stale stream must not unregister the current one
+	hub.unregisterBot(newBotClient("user-1", "StockBot", hub))
+	if hub.GetBot("user-1") != bot {
+		t.Error("expected unregistering another client to keep the registered stream")
+	}
+
+	hub.unregisterBot(bot)
+	if hub.GetBot("user-1") != nil {
+		t.Error("expected bot to be unregistered")
+	}
+}
+
+func TestDisconnectSessions_BotStream(t *testing.T) {
+	hub := NewHub(nil)
+	bot := onlineBot(t, hub, "user-1", "StockBot")
+	closed := func() bool {
+		select {
+		case <-bot.done:
+			return true
+		default:
+			return false
+		}
+	}
+
+	// A bot stream is opened with an API token, not a session
+	hub.DisconnectSessions("user-1", []string{"session-1"})
+	if closed() {
+		t.Fatal("expected revoking sessions to leave the bot stream open")
+	}
+
+	hub.DisconnectSessions("user-1", nil)
+	if !closed() {
+		t.Fatal("expected disconnecting the user to close the bot stream")
+	}
+	expectError(t, bot, "SESSION_REVOKED")
+}
+
+// useRecordedDB swaps the database for a recording connection so that rating lookups
+// and game results can be inspected without Postgres
+func useRecordedDB(t *testing.T) *dbtest.Recorder {
+	t.Helper()
+	db, rec := dbtest.Open()
+	prev := database.DB
+	database.DB = db
+	t.Cleanup(func() {
+		db.Close()
+		database.DB = prev
+	})
+	return rec
+}
+
+// onlineBot registers a bot with an open event stream
+func onlineBot(t *testing.T, hub *Hub, userID, username string) *Client {
+	t.Helper()
+	bot := newBotClient(userID, username, hub)
+	if !hub.registerBot(bot) {
+		t.Fatalf("failed to register %s", username)
+	}
+	return bot
+}
+
+func newHumanClient(hub *Hub, id, userID string) *Client {
+	client := NewClient(id, userID, hub, nil)
+	client.Username = id
+	client.IP = "203.0.113.7"
+	return client
+}
+
+// createdGameID reads the GAME_CREATED message sent to the creator
+func createdGameID(t *testing.T, client *Client) string {
+	t.Helper()
+	msg := expectMessage(t, client, MsgTypeGameCreated)
+	return msg.Data.(map[string]interface{})["gameId"].(string)
+}
+
+func expectError(t *testing.T, client *Client, code string) {
+	t.Helper()
+	msg := expectMessage(t, client, MsgTypeError)
+	if got := msg.Data.(map[string]interface{})["code"]; got != code {
+		t.Fatalf("expected error %s, got %v", code, got)
+	}
+}
+
+func TestJoinGame_Challenge(t *testing.T) {
+	useRecordedDB(t)
+	hub := NewHub(nil)
+	bot := onlineBot(t, hub, "bot-1", "StockBot")
+	otherBot := onlineBot(t, hub, "bot-2", "OtherBot")
+	challenger := newHumanClient(hub, "human-1", "")
+	other := newHumanClient(hub, "human-2", "user-2")
+
+	hub.games.ChallengeBot(challenger, &BotChallengeData{Bot: "stockbot"})
+	gameID := createdGameID(t, challenger)
+	challenge := expectMessage(t, bot, MsgTypeChallenge)
+	if challenge.Data.(map[string]interface{})["gameId"] != gameID {
+		t.Fatalf("expected the bot to be challenged to %s, got %v", gameID, challenge.Data)
+	}
+
+	// Only the challenged bot may take the seat
+	hub.games.JoinGame(other, gameID)
+	expectMessage(t, other, MsgTypeGameFull)
+	hub.games.JoinGame(otherBot, gameID)
+	expectMessage(t, otherBot, MsgTypeGameFull)
+	if hub.games.AcceptChallenge(otherBot, gameID) {
+		t.Error("expected another bot to be unable to accept the challenge")
+	}
+
+	if !hub.games.AcceptChallenge(bot, gameID) {
+		t.Fatal("expected the challenged bot to accept")
+	}
+	expectMessage(t, bot, MsgTypeGameJoined)
+	started := expectMessage(t, challenger, MsgTypeGameStarted)
+	black := started.Data.(map[string]interface{})["blackPlayer"].(map[string]interface{})
+	if black["id"] != "bot-1" || black["isBot"] != true {
+		t.Errorf("expected the bot to play black, got %v", black)
+	}
+}
+
+func TestJoinGame_BotSeats(t *testing.T) {
+	tests := []struct {
+		name      string
+		botSeek   bool
+		joinAsBot bool
+		wantError string
+	}{
+		{name: "bot joins a human's seek", joinAsBot: true, wantError: "BOT_NOT_ALLOWED"},
+		{name: "bot joins a bot's seek", botSeek: true, joinAsBot: true},
+		{name: "human joins a bot's seek", botSeek: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useRecordedDB(t)
+			hub := NewHub(nil)
+
+			creator := newHumanClient(hub, "human-1", "user-1")
+			if tt.botSeek {
+				creator = onlineBot(t, hub, "bot-1", "StockBot")
+			}
+			hub.games.CreateGame(creator, &GameCreateData{})
+			gameID := createdGameID(t, creator)
+
+			joiner := newHumanClient(hub, "human-2", "user-2")
+			if tt.joinAsBot {
+				joiner = onlineBot(t, hub, "bot-2", "OtherBot")
+			}
+			hub.games.JoinGame(joiner, gameID)
+
+			if tt.wantError != "" {
+				expectError(t, joiner, tt.wantError)
+				if game := hub.games.GetGame(gameID); game.Status != "waiting" {
+					t.Errorf("expected the seek to stay open, got %s", game.Status)
+				}
+				return
+			}
+			expectMessage(t, joiner, MsgTypeGameJoined)
+			game := hub.games.GetGame(gameID)
+			game.mu.RLock()
+			defer game.mu.RUnlock()
+			if game.Status != "active" || game.blackIsBot != tt.joinAsBot {
+				t.Errorf("expected an active game with blackIsBot %v, got %s, %v", tt.joinAsBot, game.Status, game.blackIsBot)
+			}
+		})
+	}
+}
+
+func TestDeclineChallenge(t *testing.T) {
+	useRecordedDB(t)
+	hub := NewHub(nil)
+	bot := onlineBot(t, hub, "bot-1", "StockBot")
+	otherBot := onlineBot(t, hub, "bot-2", "OtherBot")
+	challenger := newHumanClient(hub, "human-1", "")
+
+	hub.games.ChallengeBot(challenger, &BotChallengeData{Bot: "StockBot"})
+	gameID := createdGameID(t, challenger)
+	expectMessage(t, bot, MsgTypeChallenge)
+
+	if hub.games.DeclineChallenge(otherBot, gameID) {
+		t.Fatal("expected another bot to be unable to decline the challenge")
+	}
+	if !hub.games.DeclineChallenge(bot, gameID) {
+		t.Fatal("expected the challenged bot to decline")
+	}
+
+	declined := expectMessage(t, challenger, MsgTypeChallengeDeclined)
+	if declined.Data.(map[string]interface{})["gameId"] != gameID {
+		t.Errorf("expected the decline of %s, got %v", gameID, declined.Data)
+	}
+	if challenger.GetGameID() != "" {
+		t.Errorf("expected the challenger to leave the game, still in %s", challenger.GetGameID())
+	}
+	if hub.games.GetGame(gameID) != nil {
+		t.Error("expected the declined game to be removed")
+	}
+	if hub.games.DeclineChallenge(bot, gameID) {
+		t.Error("expected a declined challenge to be gone")
+	}
+}
+
+func TestFinalizeGame_Pool(t *testing.T) {
+	tests := []struct {
+		name       string
+		info       gameEndInfo
+		wantColumn string
+	}{
+		{
+			name:       "between humans",
+			info:       gameEndInfo{whiteUID: "user-1", blackUID: "user-2", rated: true},
+			wantColumn: "SELECT rating FROM profiles",
+		},
+		{
+			name:       "against a bot",
+			info:       gameEndInfo{whiteUID: "user-1", blackUID: "bot-1", blackIsBot: true, rated: true},
+			wantColumn: "SELECT bot_rating FROM profiles",
+		},
+		{
+			name:       "between bots",
+			info:       gameEndInfo{whiteUID: "bot-1", blackUID: "bot-2", whiteIsBot: true, blackIsBot: true, rated: true},
+			wantColumn: "SELECT bot_rating FROM profiles",
+		},
+		{
+			name: "unrated",
+			info: gameEndInfo{whiteUID: "user-1", blackUID: "bot-1", blackIsBot: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := useRecordedDB(t)
+			hub := NewHub(nil)
+
+			tt.info.gameID, tt.info.result = "game-1", "white"
+			hub.games.finalizeGame(tt.info)
+
+			statements := rec.Statements()
+			if tt.wantColumn == "" {
+				if len(statements) != 0 {
+					t.Errorf("expected an unrated game to store nothing, got %v", statements)
+				}
+				return
+			}
+			if _, ok := rec.Find(tt.wantColumn); !ok {
+				t.Errorf("expected the rating to be read with %q, got %v", tt.wantColumn, statements)
+			}
+		})
+	}
+}
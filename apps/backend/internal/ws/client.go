@@ -110,7 +110,9 @@ func (r *MessageRateLimiter) IsBlocked() bool {
 type Client struct {
 	ID        string
 	UserID    string // Empty for anonymous users
+	SessionID string // Session the client signed in with; empty for anonymous users
 	Username  string // Empty for anonymous users
+	IsBot     bool   // Bot accounts play through the bot API event stream
 	IP        string // Client IP for connection limiting
 	Hub       *Hub
 	Conn      *websocket.Conn
@@ -0,0 +1,301 @@
+package ws
+
+import (
+	"context"
+	"crypto/rand"
+	"strings"
+	"time"
+
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/achievements"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/analysis"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/chess"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/computer"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/database"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/elo"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/logger"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/metrics"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/models"
+)
+
+// Time allowed for the computer to choose a move, including waiting for a free engine
+const computerMoveTimeout = 15 * time.Second
+
+// newComputerClient creates the client playing the computer's side. It has no
+// connection and is closed from the start, so messages sent to it are dropped.
+func newComputerClient(hub *Hub) *Client {
+	client := NewClient(generateClientID(), "", hub, nil)
+	client.Username = "Computer"
+	client.Close()
+	return client
+}
+
+// CreateComputerGame starts a game between the client and the computer. The
+// computer's moves are played through HandleMove like any player's, so the game
+// follows the same rules, clocks and endings as games between players.
+func (gm *GameManager) CreateComputerGame(client *Client, data *ComputerGameCreateData) {
+	opponent := computer.Default
+	if opponent == nil {
+		client.SendMessage(NewErrorMessage("COMPUTER_UNAVAILABLE", "Games against the computer are not available"))
+		return
+	}
+	level, ok := computer.GetLevel(data.Level)
+	if !ok {
+		client.SendMessage(NewErrorMessage("INVALID_DATA", "Difficulty level must be between 1 and 10"))
+		return
+	}
+	playerWhite, ok := pickColor(data.Color)
+	if !ok {
+		client.SendMessage(NewErrorMessage("INVALID_DATA", "Color must be white, black or random"))
+		return
+	}
+
+	identity, byUserID, ok := gm.admitGame(client, data.TimeControl)
+	if !ok {
+		return
+	}
+
+	username := client.Username
+	if username == "" {
+		username = "Anonymous"
+	}
+	rating := computerPlayerRating(client)
+	cpu := newComputerClient(gm.hub)
+
+	game := &GameState{
+		ID:               generateGameID(),
+		FEN:              initialFEN,
+		MoveHistory:      make([]string, 0),
+		MoveNum:          1,
+		Status:           "active",
+		CreatedAt:        time.Now(),
+		LastMoveAt:       time.Now(),
+		Rated:            data.Rated && client.UserID != "",
+		CreatorUsername:  username,
+		CreatorRating:    rating,
+		chessGame:        chess.NewGame(),
+		computerOpponent: opponent,
+		computerPlayer:   cpu,
+		computerLevel:    data.Level,
+	}
+	player := PlayerInfo{ID: client.ID, Username: username, Rating: rating}
+	if client.UserID != "" {
+		player.ID = client.UserID
+	}
+	cpuInfo := PlayerInfo{ID: cpu.ID, Username: cpu.Username, Rating: level.Elo, ComputerLevel: data.Level}
+
+	color := "white"
+	whiteInfo, blackInfo := player, cpuInfo
+	if playerWhite {
+		game.WhitePlayer, game.BlackPlayer = client, cpu
+		game.whiteUserID = client.UserID
+	} else {
+		color = "black"
+		game.WhitePlayer, game.BlackPlayer = cpu, client
+		game.blackUserID = client.UserID
+		whiteInfo, blackInfo = cpuInfo, player
+	}
+	if data.TimeControl != nil {
+		game.TimeControl = data.TimeControl
+		game.WhiteTimeMs = int64(data.TimeControl.InitialTime) * 1000
+		game.BlackTimeMs = int64(data.TimeControl.InitialTime) * 1000
+	}
+
+	if !gm.addGame(client, game, identity, byUserID) {
+		return
+	}
+	client.SetGameID(game.ID)
+	metrics.WSGamesActive.Inc()
+
+	game.mu.Lock()
+	gm.startClock(game)
+	game.mu.Unlock()
+
+	logger.Info("Computer game created", logger.F(
+		"gameId", game.ID, "clientId", client.ID, "level", data.Level, "color", color, "rated", game.Rated,
+	))
+
+	client.SendMessage(NewServerMessage(MsgTypeGameCreated, GameCreatedData{
+		GameID: game.ID,
+		Color:  color,
+	}))
+	client.SendMessage(NewServerMessage(MsgTypeGameStarted, GameStartedData{
+		GameID:      game.ID,
+		FEN:         initialFEN,
+		WhitePlayer: whiteInfo,
+		BlackPlayer: blackInfo,
+		TimeControl: game.TimeControl,
+		WhiteTimeMs: int(game.WhiteTimeMs),
+		BlackTimeMs: int(game.BlackTimeMs),
+	}))
+
+	if !playerWhite {
+		go gm.playComputerMove(game)
+	}
+}
+
+// pickColor reports whether the player takes white, choosing at random unless a
+// color was asked for
+func pickColor(color string) (white bool, ok bool) {
+	switch strings.ToLower(color) {
+	case "white":
+		return true, true
+	case "black":
+		return false, true
+	case "", "random":
+		var b [1]byte
+		rand.Read(b[:])
+		return b[0]&1 == 0, true
+	default:
+		return false, false
+	}
+}
+
+// playComputerMove asks the opponent for the computer's move and plays it. If the
+// opponent fails, a random legal move is played so the game does not stall, and a
+// rated game is no longer rated: it would rate the player against random moves.
+func (gm *GameManager) playComputerMove(game *GameState) {
+	game.mu.RLock()
+	if game.Status != "active" {
+		game.mu.RUnlock()
+		return
+	}
+	gameID := game.ID
+	moves := make([]string, len(game.MoveHistory))
+	copy(moves, game.MoveHistory)
+	opponent := game.computerOpponent
+	cpu := game.computerPlayer
+	level := game.computerLevel
+	game.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), computerMoveTimeout)
+	move, err := opponent.Move(ctx, moves, level)
+	cancel()
+	if err != nil {
+		logger.Warn("Computer failed to choose a move, playing a random one", logger.F("gameId", gameID, "error", err.Error()))
+		unrateComputerGame(game)
+		if move, err = (computer.RandomOpponent{}).Move(context.Background(), moves, level); err != nil {
+			return
+		}
+	}
+
+	from, to, promotion, err := chess.ParseUCI(move)
+	if err != nil {
+		logger.Error("Computer chose an invalid move", logger.F("gameId", gameID, "move", move))
+		return
+	}
+	gm.HandleMove(cpu, &MoveData{GameID: gameID, From: from, To: to, Promotion: promotion})
+}
+
+// unrateComputerGame makes a rated game against the computer unrated and tells the
+// player why
+func unrateComputerGame(game *GameState) {
+	game.mu.Lock()
+	if !game.Rated {
+		game.mu.Unlock()
+		return
+	}
+	game.Rated = false
+	player := game.WhitePlayer
+	if player == game.computerPlayer {
+		player = game.BlackPlayer
+	}
+	gameID := game.ID
+	game.mu.Unlock()
+
+	logger.Info("Computer game unrated after engine failure", logger.F("gameId", gameID))
+	player.SendMessage(NewErrorMessage("GAME_UNRATED", "The computer engine failed, so this game is no longer rated."))
+}
+
+// computerPlayerRating is the rating shown for a player in games against the
+// computer: their computer rating. Anonymous players have none.
+func computerPlayerRating(client *Client) int {
+	if client.UserID == "" {
+		return 0
+	}
+	rating, _, err := database.GetPlayerRatingInfo(client.UserID, models.RatingPoolComputer)
+	if err != nil {
+		return 0
+	}
+	return rating
+}
+
+// finalizeComputerGame stores a game against the computer for a signed-in player,
+// rated or not, and updates their computer rating and achievements. Games of
+// anonymous players are not stored.
+func (gm *GameManager) finalizeComputerGame(info gameEndInfo, endedData *GameEndedData) {
+	userID, color := info.whiteUID, "white"
+	if info.computerColor == "white" {
+		userID, color = info.blackUID, "black"
+	}
+	if userID == "" {
+		return
+	}
+
+	rating, gamesPlayed, err := database.GetPlayerRatingInfo(userID, models.RatingPoolComputer)
+	if err != nil {
+		return
+	}
+	level, _ := computer.GetLevel(info.computerLevel)
+	opening, _ := chess.ClassifyOpening(info.moveHistory)
+
+	g := database.ComputerGame{
+		UserID:         userID,
+		Color:          color,
+		Level:          info.computerLevel,
+		ComputerRating: level.Elo,
+		Rated:          info.rated,
+		PGN:            strings.Join(info.moveHistory, " "),
+		Result:         elo.ResultToPGN(info.result),
+		ECO:            opening.ECO,
+		Opening:        opening.Name,
+		OldRating:      rating,
+		NewRating:      rating,
+	}
+	var delta int
+	if info.rated {
+		// Only the player's side of the calculation is used; the computer's rating is fixed
+		result := elo.ResultFromWinner(info.result)
+		if color == "white" {
+			rc := elo.Calculate(rating, level.Elo, result, gamesPlayed, gamesPlayed)
+			g.NewRating, delta = rc.WhiteNew, rc.WhiteDelta
+		} else {
+			rc := elo.Calculate(level.Elo, rating, result, gamesPlayed, gamesPlayed)
+			g.NewRating, delta = rc.BlackNew, rc.BlackDelta
+		}
+	}
+
+	storedGameID, err := database.FinalizeComputerGame(g)
+	if err != nil {
+		logger.Error("Failed to store computer game", logger.F("gameId", info.gameID, "error", err.Error()))
+		return
+	}
+	analysis.Enqueue(storedGameID)
+
+	if info.rated {
+		if color == "white" {
+			endedData.WhiteRating, endedData.WhiteRatingDelta = &g.NewRating, &delta
+		} else {
+			endedData.BlackRating, endedData.BlackRatingDelta = &g.NewRating, &delta
+		}
+	}
+
+	logger.Info("Computer game finalized", logger.F(
+		"gameId", info.gameID, "userId", userID, "level", info.computerLevel, "eco", opening.ECO,
+		"rated", info.rated, "ratingOld", rating, "ratingNew", g.NewRating,
+	))
+
+	ctx := achievements.ComputerContext{
+		Level: info.computerLevel,
+		Won:   info.result == color,
+		Drew:  info.result == "draw",
+	}
+	if info.rated {
+		ctx.NewRating = g.NewRating
+	}
+	unlocked := achievements.CheckComputerAchievements(userID, ctx)
+	if color == "white" {
+		endedData.WhiteNewAchievements = unlocked
+	} else {
+		endedData.BlackNewAchievements = unlocked
+	}
+}
@@ -0,0 +1,168 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/computer"
+)
+
+// useComputer replaces the computer's opponent for the duration of a test
+func useComputer(t *testing.T, opponent computer.Opponent) {
+	prev := computer.Default
+	computer.Default = opponent
+	t.Cleanup(func() { computer.Default = prev })
+}
+
+func newComputerTestClient(hub *Hub) *Client {
+	client := NewClient("client-1", "", hub, nil)
+	client.IP = "203.0.113.7"
+	return client
+}
+
+// nextMessage reads the client's next message, failing after a timeout
+func nextMessage(t *testing.T, client *Client) ServerMessage {
+	t.Helper()
+	select {
+	case data := <-client.Send:
+		var msg ServerMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			t.Fatalf("failed to decode message: %v", err)
+		}
+		return msg
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for a message")
+		return ServerMessage{}
+	}
+}
+
+func expectMessage(t *testing.T, client *Client, msgType string) ServerMessage {
+	t.Helper()
+	msg := nextMessage(t, client)
+	if msg.Type != msgType {
+		t.Fatalf("expected %s, got %s (%v)", msgType, msg.Type, msg.Data)
+	}
+	return msg
+}
+
+func TestCreateComputerGame_PlaysReplies(t *testing.T) {
+	useComputer(t, computer.MaterialOpponent{})
+	hub := NewHub(nil)
+	client := newComputerTestClient(hub)
+
+	hub.games.CreateComputerGame(client, &ComputerGameCreateData{Level: 3, Color: "white"})
+
+	created := expectMessage(t, client, MsgTypeGameCreated)
+	gameID := created.Data.(map[string]interface{})["gameId"].(string)
+	if color := created.Data.(map[string]interface{})["color"]; color != "white" {
+		t.Fatalf("expected to play white, got %v", color)
+	}
+	started := expectMessage(t, client, MsgTypeGameStarted)
+	black := started.Data.(map[string]interface{})["blackPlayer"].(map[string]interface{})
+	if black["username"] != "Computer" || black["computerLevel"] != float64(3) {
+		t.Errorf("expected the computer at level 3 as black, got %v", black)
+	}
+
+	hub.games.HandleMove(client, &MoveData{GameID: gameID, From: "e2", To: "e4"})
+	expectMessage(t, client, MsgTypeMoveAccepted)
+	reply := expectMessage(t, client, MsgTypeOpponentMove)
+	if reply.Data.(map[string]interface{})["moveNum"] != float64(2) {
+		t.Errorf("expected the computer's reply as move 2, got %v", reply.Data)
+	}
+
+	game := hub.games.GetGame(gameID)
+	game.mu.RLock()
+	defer game.mu.RUnlock()
+	if len(game.MoveHistory) != 2 {
+		t.Errorf("expected two moves played, got %v", game.MoveHistory)
+	}
+}
+
+func TestCreateComputerGame_ComputerMovesFirstAsWhite(t *testing.T) {
+	useComputer(t, computer.RandomOpponent{})
+	hub := NewHub(nil)
+	client := newComputerTestClient(hub)
+
+	hub.games.CreateComputerGame(client, &ComputerGameCreateData{Level: 1, Color: "black"})
+
+	expectMessage(t, client, MsgTypeGameCreated)
+	expectMessage(t, client, MsgTypeGameStarted)
+	expectMessage(t, client, MsgTypeOpponentMove)
+}
+
+// failingOpponent stands for an engine that cannot answer
+type failingOpponent struct{}
+
+func (failingOpponent) Move(context.Context, []string, int) (string, error) {
+	return "", errors.New("engine crashed")
+}
+
+func TestPlayComputerMove_EngineFailure(t *testing.T) {
+	tests := []struct {
+		name  string
+		rated bool
+	}{
+		{"rated game is unrated", true},
+		{"casual game goes on", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useRecordedDB(t)
+			useComputer(t, failingOpponent{})
+			hub := NewHub(nil)
+			client := newComputerTestClient(hub)
+			client.UserID = "user-1"
+
+			hub.games.CreateComputerGame(client, &ComputerGameCreateData{Level: 5, Color: "black", Rated: tt.rated})
+
+			created := expectMessage(t, client, MsgTypeGameCreated)
+			expectMessage(t, client, MsgTypeGameStarted)
+			if tt.rated {
+				expectError(t, client, "GAME_UNRATED")
+			}
+			// A random move keeps the game going
+			expectMessage(t, client, MsgTypeOpponentMove)
+
+			game := hub.games.GetGame(created.Data.(map[string]interface{})["gameId"].(string))
+			game.mu.RLock()
+			defer game.mu.RUnlock()
+			if game.Rated {
+				t.Error("expected the game to be unrated")
+			}
+		})
+	}
+}
+
+func TestCreateComputerGame_Rejects(t *testing.T) {
+	tests := []struct {
+		name      string
+		opponent  computer.Opponent
+		data      ComputerGameCreateData
+		wantError string
+	}{
+		{"unavailable", nil, ComputerGameCreateData{Level: 3}, "COMPUTER_UNAVAILABLE"},
+		{"level too low", computer.RandomOpponent{}, ComputerGameCreateData{Level: 0}, "INVALID_DATA"},
+		{"level too high", computer.RandomOpponent{}, ComputerGameCreateData{Level: 11}, "INVALID_DATA"},
+		{"unknown color", computer.RandomOpponent{}, ComputerGameCreateData{Level: 3, Color: "green"}, "INVALID_DATA"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useComputer(t, tt.opponent)
+			hub := NewHub(nil)
+			client := newComputerTestClient(hub)
+
+			hub.games.CreateComputerGame(client, &tt.data)
+
+			msg := expectMessage(t, client, MsgTypeError)
+			if code := msg.Data.(map[string]interface{})["code"]; code != tt.wantError {
+				t.Errorf("expected %s, got %v", tt.wantError, code)
+			}
+			if n := len(hub.games.games); n != 0 {
+				t.Errorf("expected no game created, got %d", n)
+			}
+		})
+	}
+}
@@ -9,11 +9,14 @@ import (
 	"time"
 
 	"github.com/tmcarmichael/nxtchess/apps/backend/internal/achievements"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/analysis"
 	"github.com/tmcarmichael/nxtchess/apps/backend/internal/chess"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/computer"
 	"github.com/tmcarmichael/nxtchess/apps/backend/internal/database"
 	"github.com/tmcarmichael/nxtchess/apps/backend/internal/elo"
 	"github.com/tmcarmichael/nxtchess/apps/backend/internal/logger"
 	"github.com/tmcarmichael/nxtchess/apps/backend/internal/metrics"
+	"github.com/tmcarmichael/nxtchess/apps/backend/internal/models"
 )
 
 // GameState represents the state of an active game
@@ -39,12 +42,18 @@ type GameState struct {
 	stopClock       chan struct{} // signal to stop the clock goroutine
 	clockRunning    bool          // whether the clock is currently ticking
 
-	whiteUserID          string      // persistent user identity for reconnection
-	blackUserID          string      // persistent user identity for reconnection
-	whiteDisconnected    bool        // whether white player is disconnected
-	blackDisconnected    bool        // whether black player is disconnected
-	whiteDisconnectTimer *time.Timer // grace period timer for white
-	blackDisconnectTimer *time.Timer // grace period timer for black
+	whiteUserID          string            // persistent user identity for reconnection
+	blackUserID          string            // persistent user identity for reconnection
+	whiteIsBot           bool              // whether white is a bot account
+	blackIsBot           bool              // whether black is a bot account
+	challengedUserID     string            // for bot challenges, the only user who may join
+	computerOpponent     computer.Opponent // chooses the computer's moves in games against it
+	computerPlayer       *Client           // the computer's seat in games against it
+	computerLevel        int               // difficulty level in games against the computer
+	whiteDisconnected    bool              // whether white player is disconnected
+	blackDisconnected    bool              // whether black player is disconnected
+	whiteDisconnectTimer *time.Timer       // grace period timer for white
+	blackDisconnectTimer *time.Timer       // grace period timer for black
 
 	mu sync.RWMutex
 }
@@ -282,14 +291,18 @@ func (game *GameState) stopDisconnectTimers() {
 // gameEndInfo captures the state needed for post-game finalization
 // (rating updates, achievements) without holding the game mutex.
 type gameEndInfo struct {
-	gameID       string
-	result       string
-	resultReason string
-	rated        bool
-	whiteUID     string
-	blackUID     string
-	moveHistory  []string
-	chessGame    *chess.Game
+	gameID        string
+	result        string
+	resultReason  string
+	rated         bool
+	whiteUID      string
+	blackUID      string
+	whiteIsBot    bool
+	blackIsBot    bool
+	computerLevel int    // set for games against the computer
+	computerColor string // the computer's color in games against it
+	moveHistory   []string
+	chessGame     *chess.Game
 }
 
 // captureGameEndInfo snapshots game state for finalization.
@@ -305,6 +318,15 @@ func captureGameEndInfo(game *GameState) gameEndInfo {
 	}
 	info.whiteUID = game.whiteUserID
 	info.blackUID = game.blackUserID
+	info.whiteIsBot = game.whiteIsBot
+	info.blackIsBot = game.blackIsBot
+	if game.computerPlayer != nil {
+		info.computerLevel = game.computerLevel
+		info.computerColor = "black"
+		if game.WhitePlayer == game.computerPlayer {
+			info.computerColor = "white"
+		}
+	}
 	info.moveHistory = make([]string, len(game.MoveHistory))
 	copy(info.moveHistory, game.MoveHistory)
 	return info
@@ -329,17 +351,28 @@ func (gm *GameManager) finalizeGame(info gameEndInfo) GameEndedData {
 		Reason: info.resultReason,
 	}
 
+	if info.computerLevel > 0 {
+		gm.finalizeComputerGame(info, &endedData)
+		return endedData
+	}
+
 	if info.whiteUID == "" || info.blackUID == "" || !info.rated {
 		return endedData
 	}
 
-	whiteRating, whiteGames, err := database.GetPlayerRatingInfo(info.whiteUID)
+	// Games involving a bot are rated in their own pool
+	pool := models.RatingPoolStandard
+	if info.whiteIsBot || info.blackIsBot {
+		pool = models.RatingPoolBot
+	}
+
+	whiteRating, whiteGames, err := database.GetPlayerRatingInfo(info.whiteUID, pool)
 	if err != nil {
 		logger.Error("Failed to get white player rating", logger.F("userID", info.whiteUID, "error", err.Error()))
 		return endedData
 	}
 
-	blackRating, blackGames, err := database.GetPlayerRatingInfo(info.blackUID)
+	blackRating, blackGames, err := database.GetPlayerRatingInfo(info.blackUID, pool)
 	if err != nil {
 		logger.Error("Failed to get black player rating", logger.F("userID", info.blackUID, "error", err.Error()))
 		return endedData
@@ -351,10 +384,14 @@ func (gm *GameManager) finalizeGame(info gameEndInfo) GameEndedData {
 	pgn := strings.Join(info.moveHistory, " ")
 	resultPGN := elo.ResultToPGN(info.result)
 
-	if err := database.FinalizeGameResult(info.whiteUID, info.blackUID, pgn, resultPGN, rc.WhiteOld, rc.BlackOld, rc.WhiteNew, rc.BlackNew); err != nil {
+	opening, _ := chess.ClassifyOpening(info.moveHistory)
+
+	storedGameID, err := database.FinalizeGameResult(info.whiteUID, info.blackUID, pool, pgn, resultPGN, opening.ECO, opening.Name, rc.WhiteOld, rc.BlackOld, rc.WhiteNew, rc.BlackNew)
+	if err != nil {
 		logger.Error("Failed to finalize game result", logger.F("gameId", info.gameID, "error", err.Error()))
 		return endedData
 	}
+	analysis.Enqueue(storedGameID)
 
 	endedData.WhiteRating = &rc.WhiteNew
 	endedData.BlackRating = &rc.BlackNew
@@ -363,6 +400,8 @@ func (gm *GameManager) finalizeGame(info gameEndInfo) GameEndedData {
 
 	logger.Info("Game finalized with ratings", logger.F(
 		"gameId", info.gameID,
+		"pool", pool,
+		"eco", opening.ECO,
 		"whiteOld", rc.WhiteOld, "whiteNew", rc.WhiteNew,
 		"blackOld", rc.BlackOld, "blackNew", rc.BlackNew,
 	))
@@ -373,33 +412,71 @@ func (gm *GameManager) finalizeGame(info gameEndInfo) GameEndedData {
 
 	moveCount := len(info.moveHistory)
 
-	whiteCtx := achievements.GameContext{
-		InnerGame:   info.chessGame.InnerGame(),
-		Result:      info.result,
-		Reason:      info.resultReason,
-		PlayerColor: "white",
-		MoveCount:   moveCount,
-		NewRating:   rc.WhiteNew,
-		Won:         whiteWon,
-		Drew:        isDraw,
-	}
-	endedData.WhiteNewAchievements = achievements.CheckGameAchievements(info.whiteUID, whiteCtx)
-
-	blackCtx := achievements.GameContext{
-		InnerGame:   info.chessGame.InnerGame(),
-		Result:      info.result,
-		Reason:      info.resultReason,
-		PlayerColor: "black",
-		MoveCount:   moveCount,
-		NewRating:   rc.BlackNew,
-		Won:         blackWon,
-		Drew:        isDraw,
-	}
-	endedData.BlackNewAchievements = achievements.CheckGameAchievements(info.blackUID, blackCtx)
+	// Bots do not earn achievements
+	if !info.whiteIsBot {
+		whiteCtx := achievements.GameContext{
+			InnerGame:   info.chessGame.InnerGame(),
+			Result:      info.result,
+			Reason:      info.resultReason,
+			PlayerColor: "white",
+			MoveCount:   moveCount,
+			NewRating:   achievementRating(info.whiteUID, pool, rc.WhiteNew),
+			Won:         whiteWon,
+			Drew:        isDraw,
+		}
+		endedData.WhiteNewAchievements = achievements.CheckGameAchievements(info.whiteUID, whiteCtx)
+	}
+
+	if !info.blackIsBot {
+		blackCtx := achievements.GameContext{
+			InnerGame:   info.chessGame.InnerGame(),
+			Result:      info.result,
+			Reason:      info.resultReason,
+			PlayerColor: "black",
+			MoveCount:   moveCount,
+			NewRating:   achievementRating(info.blackUID, pool, rc.BlackNew),
+			Won:         blackWon,
+			Drew:        isDraw,
+		}
+		endedData.BlackNewAchievements = achievements.CheckGameAchievements(info.blackUID, blackCtx)
+	}
 
 	return endedData
 }
 
+// achievementRating is the rating achievements are checked against: the new
+// rating for standard games, and the unchanged standard rating for games against bots
+func achievementRating(userID, pool string, newRating int) int {
+	if pool == models.RatingPoolStandard {
+		return newRating
+	}
+	rating, err := database.GetRatingByID(userID)
+	if err != nil {
+		return 0
+	}
+	return rating
+}
+
+// playerRating is the rating shown for a player: a bot's rating comes from the bot
+// pool, everyone else's is their standard rating. Anonymous players have none.
+func playerRating(client *Client) int {
+	if client.UserID == "" {
+		return 0
+	}
+	if client.IsBot {
+		rating, _, err := database.GetPlayerRatingInfo(client.UserID, models.RatingPoolBot)
+		if err != nil {
+			return 0
+		}
+		return rating
+	}
+	rating, err := database.GetRatingByID(client.UserID)
+	if err != nil {
+		return 0
+	}
+	return rating
+}
+
 // countActiveGamesByIdentity counts waiting/active games for a given user identity.
 // For authenticated users, identity is the UserID; for anonymous users, it's the IP.
 func (gm *GameManager) countActiveGamesByIdentity(identity string, byUserID bool) int {
@@ -433,40 +510,133 @@ func (gm *GameManager) countActiveGamesLocked(identity string, byUserID bool) in
 
 // CreateGame creates a new game and adds the creator as white
 func (gm *GameManager) CreateGame(client *Client, data *GameCreateData) {
-	// Per-client cooldown: max 1 game creation per 10 seconds
-	if time.Since(client.GetLastGameCreatedAt()) < gameCreateCooldown {
-		client.SendMessage(NewErrorMessage("GAME_CREATE_COOLDOWN", "Please wait before creating another game"))
+	gm.createGame(client, data, nil)
+}
+
+// ChallengeBot creates a game only the challenged bot may join and sends the bot the
+// challenge. The challenger plays white.
+func (gm *GameManager) ChallengeBot(client *Client, data *BotChallengeData) {
+	bot := gm.hub.GetBotByUsername(data.Bot)
+	if bot == nil {
+		client.SendMessage(NewErrorMessage("BOT_OFFLINE", "That bot is not online"))
+		return
+	}
+	if client.UserID != "" && client.UserID == bot.UserID {
+		client.SendMessage(NewErrorMessage("SAME_PLAYER", "Cannot challenge yourself"))
 		return
 	}
 
-	// Per-user active game limit: max 2 waiting/active games
-	identity := client.IP
-	byUserID := false
+	game := gm.createGame(client, &GameCreateData{TimeControl: data.TimeControl, Rated: data.Rated}, bot)
+	if game == nil {
+		return
+	}
+
+	challenger := PlayerInfo{ID: client.ID, Username: game.CreatorUsername, Rating: game.CreatorRating, IsBot: client.IsBot}
 	if client.UserID != "" {
-		identity = client.UserID
-		byUserID = true
+		challenger.ID = client.UserID
 	}
-	if gm.countActiveGamesByIdentity(identity, byUserID) >= maxActiveGamesPerUser {
-		client.SendMessage(NewErrorMessage("GAME_LIMIT_REACHED", "You already have the maximum number of active games"))
-		return
+	bot.SendMessage(NewServerMessage(MsgTypeChallenge, ChallengeData{
+		GameID:      game.ID,
+		Challenger:  challenger,
+		TimeControl: game.TimeControl,
+		Rated:       game.Rated,
+	}))
+}
+
+// AcceptChallenge joins a bot to a game it was challenged to. It reports false if
+// there is no such waiting challenge.
+func (gm *GameManager) AcceptChallenge(bot *Client, gameID string) bool {
+	if !gm.isChallengeTo(bot, gameID) {
+		return false
 	}
+	gm.JoinGame(bot, gameID)
+	return true
+}
 
-	// Validate time control early (before expensive ops)
-	if data != nil && data.TimeControl != nil {
-		tc := data.TimeControl
-		if tc.InitialTime < 60 || tc.InitialTime > 10800 || tc.Increment < 0 || tc.Increment > 300 {
-			client.SendMessage(NewErrorMessage("INVALID_TIME_CONTROL", "Time control out of valid range"))
-			return
+// DeclineChallenge cancels a game a bot was challenged to and tells the challenger.
+// It reports false if there is no such waiting challenge.
+func (gm *GameManager) DeclineChallenge(bot *Client, gameID string) bool {
+	gm.mu.Lock()
+	game, exists := gm.games[gameID]
+	if !exists {
+		gm.mu.Unlock()
+		return false
+	}
+	game.mu.Lock()
+	if game.Status != "waiting" || game.challengedUserID == "" || game.challengedUserID != bot.UserID {
+		game.mu.Unlock()
+		gm.mu.Unlock()
+		return false
+	}
+	delete(gm.games, gameID)
+	challenger := game.WhitePlayer
+	game.mu.Unlock()
+	gm.mu.Unlock()
+
+	logger.Info("Bot challenge declined", logger.F("gameId", gameID, "botId", bot.UserID))
+	if challenger != nil {
+		challenger.SetGameID("")
+		challenger.SendMessage(NewServerMessage(MsgTypeChallengeDeclined, map[string]string{
+			"gameId": gameID,
+		}))
+	}
+	return true
+}
+
+func (gm *GameManager) isChallengeTo(bot *Client, gameID string) bool {
+	game := gm.GetGame(gameID)
+	if game == nil {
+		return false
+	}
+	game.mu.RLock()
+	defer game.mu.RUnlock()
+	return game.Status == "waiting" && game.challengedUserID != "" && game.challengedUserID == bot.UserID
+}
+
+// GameIDsForUser returns the waiting and active games the user plays in
+func (gm *GameManager) GameIDsForUser(userID string) []string {
+	gm.mu.RLock()
+	defer gm.mu.RUnlock()
+
+	var ids []string
+	for id, game := range gm.games {
+		game.mu.RLock()
+		if (game.Status == "waiting" || game.Status == "active") &&
+			(game.whiteUserID == userID || game.blackUserID == userID) {
+			ids = append(ids, id)
 		}
+		game.mu.RUnlock()
 	}
+	return ids
+}
 
-	// Check game ceiling early to avoid wasted work when at capacity
+// ChallengeIDsForUser returns the open challenges to the user
+func (gm *GameManager) ChallengeIDsForUser(userID string) []string {
 	gm.mu.RLock()
-	atCapacity := len(gm.games) >= maxGames
-	gm.mu.RUnlock()
-	if atCapacity {
-		client.SendMessage(NewErrorMessage("SERVER_FULL", "Server is at capacity, please try again later"))
-		return
+	defer gm.mu.RUnlock()
+
+	var ids []string
+	for id, game := range gm.games {
+		game.mu.RLock()
+		if game.Status == "waiting" && game.challengedUserID != "" && game.challengedUserID == userID {
+			ids = append(ids, id)
+		}
+		game.mu.RUnlock()
+	}
+	return ids
+}
+
+// createGame creates a waiting game with client as white. Games with a challenged
+// player stay out of the lobby and only that player may join them. It returns nil
+// after telling the client why if the game could not be created.
+func (gm *GameManager) createGame(client *Client, data *GameCreateData, challenged *Client) *GameState {
+	var timeControl *TimeControl
+	if data != nil {
+		timeControl = data.TimeControl
+	}
+	identity, byUserID, ok := gm.admitGame(client, timeControl)
+	if !ok {
+		return nil
 	}
 
 	gameID := generateGameID()
@@ -479,12 +649,7 @@ func (gm *GameManager) CreateGame(client *Client, data *GameCreateData) {
 		creatorUsername = "Anonymous"
 	}
 
-	var creatorRating int
-	if client.UserID != "" {
-		if r, err := database.GetRatingByID(client.UserID); err == nil {
-			creatorRating = r
-		}
-	}
+	creatorRating := playerRating(client)
 
 	rated := false
 	if data != nil && data.Rated && client.UserID != "" {
@@ -502,9 +667,13 @@ func (gm *GameManager) CreateGame(client *Client, data *GameCreateData) {
 		Rated:           rated,
 		CreatorUsername: creatorUsername,
 		CreatorRating:   creatorRating,
-		whiteUserID:    client.UserID,
+		whiteUserID:     client.UserID,
+		whiteIsBot:      client.IsBot,
 		chessGame:       chessGame,
 	}
+	if challenged != nil {
+		game.challengedUserID = challenged.UserID
+	}
 
 	if data != nil && data.TimeControl != nil {
 		game.TimeControl = data.TimeControl
@@ -512,26 +681,14 @@ func (gm *GameManager) CreateGame(client *Client, data *GameCreateData) {
 		game.BlackTimeMs = int64(data.TimeControl.InitialTime) * 1000
 	}
 
-	// Double-check ceiling under write lock to prevent TOCTOU race
-	gm.mu.Lock()
-	if len(gm.games) >= maxGames {
-		gm.mu.Unlock()
-		client.SendMessage(NewErrorMessage("SERVER_FULL", "Server is at capacity, please try again later"))
-		return
+	if !gm.addGame(client, game, identity, byUserID) {
+		return nil
 	}
-	if gm.countActiveGamesLocked(identity, byUserID) >= maxActiveGamesPerUser {
-		gm.mu.Unlock()
-		client.SendMessage(NewErrorMessage("GAME_LIMIT_REACHED", "You already have the maximum number of active games"))
-		return
-	}
-	gm.games[gameID] = game
-	client.SetLastGameCreatedAt(time.Now())
-	gm.mu.Unlock()
 
 	// Associate client with game
 	client.SetGameID(gameID)
 
-	logger.Info("Game created", logger.F("gameId", gameID, "clientId", client.ID, "rated", rated))
+	logger.Info("Game created", logger.F("gameId", gameID, "clientId", client.ID, "rated", rated, "challenge", challenged != nil))
 
 	// Send confirmation to creator
 	client.SendMessage(NewServerMessage(MsgTypeGameCreated, GameCreatedData{
@@ -540,17 +697,82 @@ func (gm *GameManager) CreateGame(client *Client, data *GameCreateData) {
 	}))
 
 	// Broadcast to lobby subscribers
-	gm.hub.BroadcastLobbyUpdate(LobbyUpdateData{
-		Action: "added",
-		Game: &LobbyGameInfo{
-			GameID:        gameID,
-			Creator:       creatorUsername,
-			CreatorRating: creatorRating,
-			TimeControl:   game.TimeControl,
-			Rated:         rated,
-			CreatedAt:     game.CreatedAt.UnixMilli(),
-		},
-	})
+	if challenged == nil {
+		gm.hub.BroadcastLobbyUpdate(LobbyUpdateData{
+			Action: "added",
+			Game: &LobbyGameInfo{
+				GameID:        gameID,
+				Creator:       creatorUsername,
+				CreatorRating: creatorRating,
+				CreatorIsBot:  client.IsBot,
+				TimeControl:   game.TimeControl,
+				Rated:         rated,
+				CreatedAt:     game.CreatedAt.UnixMilli(),
+			},
+		})
+	}
+	return game
+}
+
+// admitGame checks the limits on creating a game before any work is done, telling
+// the client why it may not create one. It returns the identity the client's games
+// are counted by.
+func (gm *GameManager) admitGame(client *Client, tc *TimeControl) (identity string, byUserID bool, ok bool) {
+	// Per-client cooldown: max 1 game creation per 10 seconds
+	if time.Since(client.GetLastGameCreatedAt()) < gameCreateCooldown {
+		client.SendMessage(NewErrorMessage("GAME_CREATE_COOLDOWN", "Please wait before creating another game"))
+		return "", false, false
+	}
+
+	// Per-user active game limit: max 2 waiting/active games
+	identity = client.IP
+	if client.UserID != "" {
+		identity = client.UserID
+		byUserID = true
+	}
+	if gm.countActiveGamesByIdentity(identity, byUserID) >= maxActiveGamesPerUser {
+		client.SendMessage(NewErrorMessage("GAME_LIMIT_REACHED", "You already have the maximum number of active games"))
+		return "", false, false
+	}
+
+	// Validate time control early (before expensive ops)
+	if tc != nil {
+		if tc.InitialTime < 60 || tc.InitialTime > 10800 || tc.Increment < 0 || tc.Increment > 300 {
+			client.SendMessage(NewErrorMessage("INVALID_TIME_CONTROL", "Time control out of valid range"))
+			return "", false, false
+		}
+	}
+
+	// Check game ceiling early to avoid wasted work when at capacity
+	gm.mu.RLock()
+	atCapacity := len(gm.games) >= maxGames
+	gm.mu.RUnlock()
+	if atCapacity {
+		client.SendMessage(NewErrorMessage("SERVER_FULL", "Server is at capacity, please try again later"))
+		return "", false, false
+	}
+	return identity, byUserID, true
+}
+
+// addGame registers a game created by client, unless the server or the client
+// reached its game limit since admitGame, telling the client why not
+func (gm *GameManager) addGame(client *Client, game *GameState, identity string, byUserID bool) bool {
+	// Double-check ceiling under write lock to prevent TOCTOU race
+	gm.mu.Lock()
+	if len(gm.games) >= maxGames {
+		gm.mu.Unlock()
+		client.SendMessage(NewErrorMessage("SERVER_FULL", "Server is at capacity, please try again later"))
+		return false
+	}
+	if gm.countActiveGamesLocked(identity, byUserID) >= maxActiveGamesPerUser {
+		gm.mu.Unlock()
+		client.SendMessage(NewErrorMessage("GAME_LIMIT_REACHED", "You already have the maximum number of active games"))
+		return false
+	}
+	gm.games[game.ID] = game
+	client.SetLastGameCreatedAt(time.Now())
+	gm.mu.Unlock()
+	return true
 }
 
 // JoinGame adds a player to an existing game
@@ -598,9 +820,24 @@ func (gm *GameManager) JoinGame(client *Client, gameID string) {
 		return
 	}
 
+	// Challenges are private to the challenged bot, and bots only join games offered to
+	// bots: challenges and other bots' seeks
+	if game.challengedUserID != "" && game.challengedUserID != client.UserID {
+		game.mu.Unlock()
+		client.SendMessage(NewServerMessage(MsgTypeGameFull, nil))
+		return
+	}
+	if client.IsBot && game.challengedUserID == "" && !game.whiteIsBot {
+		game.mu.Unlock()
+		client.SendMessage(NewErrorMessage("BOT_NOT_ALLOWED", "Bots can only join challenges and games created by bots"))
+		return
+	}
+
 	// Transition state under lock
 	game.BlackPlayer = client
 	game.blackUserID = client.UserID
+	game.blackIsBot = client.IsBot
+	game.challengedUserID = ""
 	game.Status = "active"
 	game.LastMoveAt = time.Now()
 	metrics.WSGamesActive.Inc()
@@ -611,7 +848,7 @@ func (gm *GameManager) JoinGame(client *Client, gameID string) {
 
 	// Capture data for messages before releasing lock
 	whitePlayer := game.WhitePlayer
-	whiteInfo := PlayerInfo{ID: whitePlayer.ID, Username: game.CreatorUsername, Rating: game.CreatorRating}
+	whiteInfo := PlayerInfo{ID: whitePlayer.ID, Username: game.CreatorUsername, Rating: game.CreatorRating, IsBot: game.whiteIsBot}
 	if whitePlayer.UserID != "" {
 		whiteInfo.ID = whitePlayer.UserID
 	}
@@ -634,13 +871,8 @@ func (gm *GameManager) JoinGame(client *Client, gameID string) {
 	if joinerUsername == "" {
 		joinerUsername = "Anonymous"
 	}
-	var joinerRating int
-	if client.UserID != "" {
-		if r, err := database.GetRatingByID(client.UserID); err == nil {
-			joinerRating = r
-		}
-	}
-	blackInfo := PlayerInfo{ID: client.ID, Username: joinerUsername, Rating: joinerRating}
+	joinerRating := playerRating(client)
+	blackInfo := PlayerInfo{ID: client.ID, Username: joinerUsername, Rating: joinerRating, IsBot: client.IsBot}
 	if client.UserID != "" {
 		blackInfo.ID = client.UserID
 	}
@@ -772,6 +1004,7 @@ func (gm *GameManager) HandleMove(client *Client, data *MoveData) {
 	} else {
 		opponent = game.WhitePlayer
 	}
+	computerToMove := opponent != nil && opponent == game.computerPlayer
 
 	var gameOver bool
 	var info gameEndInfo
@@ -801,6 +1034,10 @@ func (gm *GameManager) HandleMove(client *Client, data *MoveData) {
 		opponent.SendMessage(NewServerMessage(MsgTypeOpponentMove, opponentMoveData))
 	}
 
+	if computerToMove && !gameOver {
+		go gm.playComputerMove(game)
+	}
+
 	if gameOver {
 		logger.Info("Game ended", logger.F(
 			"gameId", data.GameID,
@@ -1183,8 +1420,10 @@ func (gm *GameManager) HandleReconnect(client *Client, gameID string) {
 	opponentInfo := PlayerInfo{}
 	if isWhite {
 		opponent = game.BlackPlayer
+		opponentInfo.IsBot = game.blackIsBot
 	} else {
 		opponent = game.WhitePlayer
+		opponentInfo.IsBot = game.whiteIsBot
 	}
 	if opponent != nil {
 		opponentInfo.Username = opponent.Username
@@ -1193,6 +1432,9 @@ func (gm *GameManager) HandleReconnect(client *Client, gameID string) {
 		} else {
 			opponentInfo.ID = opponent.ID
 		}
+		if opponent == game.computerPlayer {
+			opponentInfo.ComputerLevel = game.computerLevel
+		}
 	}
 
 	moveHistory := make([]string, len(game.MoveHistory))
@@ -1245,11 +1487,12 @@ func (gm *GameManager) GetWaitingGames() []LobbyGameInfo {
 	games := make([]LobbyGameInfo, 0)
 	for _, game := range gm.games {
 		game.mu.RLock()
-		if game.Status == "waiting" {
+		if game.Status == "waiting" && game.challengedUserID == "" {
 			games = append(games, LobbyGameInfo{
 				GameID:        game.ID,
 				Creator:       game.CreatorUsername,
 				CreatorRating: game.CreatorRating,
+				CreatorIsBot:  game.whiteIsBot,
 				TimeControl:   game.TimeControl,
 				Rated:         game.Rated,
 				CreatedAt:     game.CreatedAt.UnixMilli(),
@@ -154,16 +154,23 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Try to get user ID from session cookie (optional - allows anonymous play)
-	var userID string
-	var username string
-	if cookie, err := r.Cookie("session_token"); err == nil {
+	// Try to get user ID from a bearer token (mobile) or the session cookie
+	// (optional - allows anonymous play)
+	var userID, username, sessionID string
+	var isBot bool
+	if token, ok := httpx.BearerToken(r); ok {
+		userID, sessionID, _ = sessions.GetAccessTokenSession(token)
+	} else if cookie, err := r.Cookie("session_token"); err == nil {
 		if id, ok := sessions.GetSessionUserID(cookie.Value); ok {
 			userID = id
-			if uname, err := database.GetUsernameByID(id); err == nil {
-				username = uname
-			}
+			sessionID = sessions.SessionID(cookie.Value)
+		}
+	}
+	if userID != "" {
+		if uname, err := database.GetUsernameByID(userID); err == nil {
+			username = uname
 		}
+		isBot, _ = database.IsBotAccount(userID)
 	}
 
 	// Upgrade to WebSocket
@@ -178,6 +185,8 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	clientID := generateClientID()
 	client := NewClient(clientID, userID, h.hub, conn)
 	client.Username = username
+	client.SessionID = sessionID
+	client.IsBot = isBot
 	client.IP = clientIP // Store IP for disconnection tracking
 
 	// Register client with hub
@@ -28,6 +28,10 @@ type Hub struct {
 	lobbySubscribers map[string]*Client
 	lobbyMu          sync.RWMutex
 
+	// Bot event streams by user ID
+	bots   map[string]*Client
+	botsMu sync.RWMutex
+
 	// Connection limiter callback for cleanup
 	onDisconnect func(ip string)
 
@@ -43,6 +47,7 @@ func NewHub(onDisconnect func(ip string)) *Hub {
 		Register:         make(chan *Client),
 		Unregister:       make(chan *Client),
 		lobbySubscribers: make(map[string]*Client),
+		bots:             make(map[string]*Client),
 		lobbyUpdateCh:    make(chan LobbyUpdateData, 100),
 		stopBatcher:      make(chan struct{}),
 		onDisconnect:     onDisconnect,
@@ -109,6 +114,37 @@ func (h *Hub) GetClientCount() int {
 	return len(h.clients)
 }
 
+// DisconnectSessions closes the user's clients signed in with any of sessionIDs, or
+// all of the user's clients and bot stream when sessionIDs is nil. It is called when
+// sessions are revoked so signed-out devices cannot keep playing over an open
+// connection.
+func (h *Hub) DisconnectSessions(userID string, sessionIDs []string) {
+	revoked := make(map[string]bool, len(sessionIDs))
+	for _, id := range sessionIDs {
+		revoked[id] = true
+	}
+
+	h.mu.RLock()
+	var targets []*Client
+	for _, client := range h.clients {
+		if client.UserID == userID && (sessionIDs == nil || revoked[client.SessionID]) {
+			targets = append(targets, client)
+		}
+	}
+	h.mu.RUnlock()
+	if bot := h.GetBot(userID); bot != nil && sessionIDs == nil {
+		targets = append(targets, bot)
+	}
+
+	for _, client := range targets {
+		client.SendMessage(NewErrorMessage("SESSION_REVOKED", "Your session has ended. Please sign in again."))
+		client.Close()
+	}
+	if len(targets) > 0 {
+		logger.Info("Disconnected revoked sessions", logger.F("userId", userID, "clients", len(targets)))
+	}
+}
+
 // SubscribeLobby adds a client to the lobby subscriber list and sends the current game list
 func (h *Hub) SubscribeLobby(client *Client) {
 	h.lobbyMu.Lock()
@@ -255,6 +291,22 @@ func (h *Hub) HandleMessage(client *Client, msg *ClientMessage) {
 		}
 		h.games.HandleReconnect(client, data.GameID)
 
+	case MsgTypeBotChallenge:
+		var data BotChallengeData
+		if err := json.Unmarshal(msg.Data, &data); err != nil || data.Bot == "" {
+			client.SendMessage(NewErrorMessage("INVALID_DATA", "Invalid bot challenge data"))
+			return
+		}
+		h.games.ChallengeBot(client, &data)
+
+	case MsgTypeComputerGameCreate:
+		var data ComputerGameCreateData
+		if err := json.Unmarshal(msg.Data, &data); err != nil {
+			client.SendMessage(NewErrorMessage("INVALID_DATA", "Invalid computer game data"))
+			return
+		}
+		h.games.CreateComputerGame(client, &data)
+
 	case MsgTypeLobbySubscribe:
 		h.SubscribeLobby(client)
 
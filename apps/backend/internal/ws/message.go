@@ -23,6 +23,12 @@ const (
 	// Reconnection
 	MsgTypeGameReconnect = "GAME_RECONNECT"
 
+	// Bots
+	MsgTypeBotChallenge = "BOT_CHALLENGE"
+
+	// Games against the computer
+	MsgTypeComputerGameCreate = "COMPUTER_GAME_CREATE"
+
 	// Lobby
 	MsgTypeLobbySubscribe   = "LOBBY_SUBSCRIBE"
 	MsgTypeLobbyUnsubscribe = "LOBBY_UNSUBSCRIBE"
@@ -57,6 +63,10 @@ const (
 	MsgTypeOpponentDisconnected = "OPPONENT_DISCONNECTED"
 	MsgTypeOpponentReconnected  = "OPPONENT_RECONNECTED"
 
+	// Bot challenges (CHALLENGE is sent to the bot's event stream)
+	MsgTypeChallenge         = "CHALLENGE"
+	MsgTypeChallengeDeclined = "CHALLENGE_DECLINED"
+
 	// Lobby responses
 	MsgTypeLobbyList   = "LOBBY_LIST"
 	MsgTypeLobbyUpdate = "LOBBY_UPDATE"
@@ -123,9 +133,11 @@ type GameStartedData struct {
 
 // PlayerInfo contains info about a player
 type PlayerInfo struct {
-	ID       string `json:"id"`
-	Username string `json:"username,omitempty"`
-	Rating   int    `json:"rating,omitempty"`
+	ID            string `json:"id"`
+	Username      string `json:"username,omitempty"`
+	Rating        int    `json:"rating,omitempty"`
+	IsBot         bool   `json:"isBot,omitempty"`
+	ComputerLevel int    `json:"computerLevel,omitempty"` // difficulty level when the player is the computer
 }
 
 // GameEndedData is sent when game ends
@@ -141,6 +153,33 @@ type GameEndedData struct {
 	BlackNewAchievements  []achievements.AchievementUnlock `json:"blackNewAchievements,omitempty"`
 }
 
+// Bot payloads
+
+// BotChallengeData is sent by client to challenge an online bot
+type BotChallengeData struct {
+	Bot         string       `json:"bot"` // bot username
+	TimeControl *TimeControl `json:"timeControl,omitempty"`
+	Rated       bool         `json:"rated,omitempty"`
+}
+
+// ChallengeData is sent to a bot when it is challenged
+type ChallengeData struct {
+	GameID      string       `json:"gameId"`
+	Challenger  PlayerInfo   `json:"challenger"`
+	TimeControl *TimeControl `json:"timeControl,omitempty"`
+	Rated       bool         `json:"rated"`
+}
+
+// Computer payloads
+
+// ComputerGameCreateData is sent by client to start a game against the computer
+type ComputerGameCreateData struct {
+	Level       int          `json:"level"`           // difficulty, 1-10
+	Color       string       `json:"color,omitempty"` // "white", "black" or "random" (default)
+	TimeControl *TimeControl `json:"timeControl,omitempty"`
+	Rated       bool         `json:"rated,omitempty"` // counts towards the player's computer rating
+}
+
 // Gameplay payloads
 
 // MoveData is sent by client to make a move
@@ -248,6 +287,7 @@ type LobbyGameInfo struct {
 	GameID        string       `json:"gameId"`
 	Creator       string       `json:"creator"`
 	CreatorRating int          `json:"creatorRating,omitempty"`
+	CreatorIsBot  bool         `json:"creatorIsBot,omitempty"`
 	TimeControl   *TimeControl `json:"timeControl,omitempty"`
 	Rated         bool         `json:"rated"`
 	CreatedAt     int64        `json:"createdAt"`
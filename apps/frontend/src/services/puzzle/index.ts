@@ -1,5 +1,7 @@
 export { getRandomPuzzle, uciToFromTo } from './puzzleData';
 export type { PuzzleDefinition } from './puzzleData';
+export { fetchRatedPuzzle, submitPuzzleMoves } from './puzzleApi';
+export type { PuzzleSubmitResult } from './puzzleApi';
 export { computeSetupMove } from './setupMoveComputer';
 export type { SetupMoveResult } from './setupMoveComputer';
 export { puzzleHistory, solvedPuzzleTracker } from './puzzleHistory';
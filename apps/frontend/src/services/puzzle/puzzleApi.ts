@@ -0,0 +1,88 @@
+import { BACKEND_URL } from '../../shared/config/env';
+import type { AchievementUnlock } from '../../types/achievements';
+import type { PuzzleCategory, Side } from '../../types/game';
+import type { PuzzleDefinition } from './puzzleData';
+
+type MateCategory = Exclude<PuzzleCategory, 'random'>;
+
+const CATEGORY_THEMES: Record<MateCategory, string> = {
+  'mate-in-1': 'mateIn1',
+  'mate-in-2': 'mateIn2',
+  'mate-in-3': 'mateIn3',
+};
+
+interface ServerPuzzle {
+  puzzle_id: string;
+  fen: string;
+  rating: number;
+  themes: string[];
+  solution_length: number;
+}
+
+/** Response of POST /api/puzzle/submit; rating fields are only set once a rated attempt ends */
+export interface PuzzleSubmitResult {
+  status: 'continue' | 'solved' | 'failed';
+  reply?: string;
+  solved?: boolean;
+  solution?: string[];
+  rated?: boolean;
+  new_rating?: number;
+  rating_delta?: number;
+  old_rating?: number;
+  new_achievements?: AchievementUnlock[];
+}
+
+/**
+ * Fetches the next rated puzzle for the signed-in user from the server's puzzle pool.
+ * The solution stays on the server, so solutionUci is empty and moves are checked
+ * one at a time with submitPuzzleMoves.
+ */
+export async function fetchRatedPuzzle(category: PuzzleCategory): Promise<PuzzleDefinition> {
+  const categories = Object.keys(CATEGORY_THEMES) as MateCategory[];
+  const mateCategory =
+    category === 'random' ? categories[Math.floor(Math.random() * categories.length)] : category;
+
+  const url = new URL(`${BACKEND_URL}/api/puzzle/next`);
+  url.searchParams.set('theme', CATEGORY_THEMES[mateCategory]);
+  const res = await fetch(url.toString(), {
+    headers: { Accept: 'application/json' },
+    credentials: 'include',
+  });
+  if (!res.ok) {
+    throw new Error(
+      res.status === 404 ? 'No new rated puzzles available' : 'Failed to load rated puzzle'
+    );
+  }
+
+  const data: ServerPuzzle = await res.json();
+  return {
+    id: data.puzzle_id,
+    category: mateCategory,
+    fen: data.fen,
+    solutionUci: [],
+    playerSide: data.fen.split(' ')[1] as Side,
+    rating: data.rating,
+  };
+}
+
+/**
+ * Submits the solver's moves so far (UCI, opponent replies excluded). Returns null
+ * when the server could not be reached or refused the request.
+ */
+export async function submitPuzzleMoves(
+  puzzleId: string,
+  moves: string[]
+): Promise<PuzzleSubmitResult | null> {
+  try {
+    const res = await fetch(`${BACKEND_URL}/api/puzzle/submit`, {
+      method: 'POST',
+      credentials: 'include',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ puzzle_id: puzzleId, moves }),
+    });
+    if (!res.ok) return null;
+    return await res.json();
+  } catch {
+    return null;
+  }
+}
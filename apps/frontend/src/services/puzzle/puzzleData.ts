@@ -1,4 +1,3 @@
-import { solvedPuzzleTracker } from './puzzleHistory';
 import type { Side, PuzzleCategory } from '../../types/game';
 
 export interface PuzzleDefinition {
@@ -612,13 +611,8 @@ function shuffle<T>(arr: T[]): T[] {
 
 const categoryQueues = new Map<string, PuzzleDefinition[]>();
 
-export function getRandomPuzzle(
-  category: PuzzleCategory,
-  excludeId?: string,
-  rated?: boolean
-): PuzzleDefinition {
-  const key = rated ? `${category}:rated` : `${category}:casual`;
-  let queue = categoryQueues.get(key);
+export function getRandomPuzzle(category: PuzzleCategory, excludeId?: string): PuzzleDefinition {
+  let queue = categoryQueues.get(category);
 
   if (!queue || queue.length === 0) {
     const source =
@@ -626,28 +620,14 @@ export function getRandomPuzzle(
         ? [...MATE_IN_1_PUZZLES, ...MATE_IN_2_PUZZLES, ...MATE_IN_3_PUZZLES]
         : ALL_PUZZLES[category];
 
-    let candidates = shuffle(source);
-
-    if (rated) {
-      const ratedSolved = solvedPuzzleTracker.getSolvedIds(true);
-      const casualSolved = solvedPuzzleTracker.getSolvedIds(false);
-      const allSolved = new Set([...ratedSolved, ...casualSolved]);
-      if (allSolved.size > 0) {
-        const unseen = candidates.filter((p) => !allSolved.has(p.id));
-        if (unseen.length > 0) {
-          candidates = unseen;
-        }
-      }
-    }
-
-    queue = candidates;
-    categoryQueues.set(key, queue);
+    queue = shuffle(source);
+    categoryQueues.set(category, queue);
   }
 
   if (excludeId && queue.length > 1 && queue[0].id === excludeId) {
     const [first, second, ...rest] = queue;
     queue = [second, first, ...rest];
-    categoryQueues.set(key, queue);
+    categoryQueues.set(category, queue);
   }
 
   return queue.shift()!;
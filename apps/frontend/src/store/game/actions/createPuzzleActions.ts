@@ -5,13 +5,14 @@ import { getOpponentSide } from '../../../services/game/chessGameService';
 import { transition } from '../../../services/game/gameLifecycle';
 import {
   getRandomPuzzle,
+  fetchRatedPuzzle,
+  submitPuzzleMoves,
   uciToFromTo,
   computeSetupMove,
   puzzleHistory,
   solvedPuzzleTracker,
   type PuzzleDefinition,
 } from '../../../services/puzzle';
-import { BACKEND_URL } from '../../../shared/config/env';
 import type { Square, PromotionPiece } from '../../../types/chess';
 import type { Side, StartGameOptions, PuzzleCategory } from '../../../types/game';
 import type { ChessStore } from '../stores/createChessStore';
@@ -46,51 +47,54 @@ export const createPuzzleActions = (
   let currentPuzzle: PuzzleDefinition | null = null;
   let currentGameGeneration = 0;
   let hasRecordedResult = false;
+  // Rated puzzles are judged by the server: the solver's moves so far, or null for a
+  // casual puzzle checked against its local solution
+  let verifiedMoves: string[] | null = null;
+  let awaitingVerdict = false;
 
-  const submitPuzzleResult = async (
-    solved: boolean
-  ): Promise<{ new_rating: number; rating_delta: number; old_rating: number } | null> => {
-    if (!currentPuzzle || !chess.state.puzzleRated) return null;
-    try {
-      const res = await fetch(`${BACKEND_URL}/api/puzzle/result`, {
-        method: 'POST',
-        credentials: 'include',
-        headers: { 'Content-Type': 'application/json' },
-        body: JSON.stringify({
-          puzzle_id: currentPuzzle.id,
-          category: currentPuzzle.category,
-          solved,
-        }),
-      });
-      if (res.ok) {
-        const data = await res.json();
-        if (data.new_achievements && data.new_achievements.length > 0) {
-          pushAchievementToasts(data.new_achievements);
-        }
-        return data;
+  const queueEvaluation = (moveIndex: number, fenBefore: string, side: Side) => {
+    const fenAfter = chess.state.fen;
+    const san = chess.state.moveHistory[moveIndex] || '';
+    moveEvalService.queueMoveEvaluation(
+      {
+        moveIndex,
+        san,
+        fenBefore,
+        fenAfter,
+        side,
+        isPlayerMove: side === chess.state.playerColor,
+      },
+      (evaluation) => {
+        chess.updateMoveEvaluation(evaluation);
       }
-      return null;
-    } catch {
-      return null;
-    }
+    );
   };
 
-  const performOpponentMove = async (generation: number) => {
-    if (!currentPuzzle) return;
-
-    const solIdx = chess.state.puzzleSolutionIndex;
-    if (solIdx >= currentPuzzle.solutionUci.length) return;
+  const recordAttempt = (result: 'pass' | 'fail') => {
+    if (hasRecordedResult || !currentPuzzle) return;
+    hasRecordedResult = true;
+    const isRated = chess.state.puzzleRated;
+    puzzleHistory.record({
+      puzzleId: currentPuzzle.id,
+      category: currentPuzzle.category,
+      fen: currentPuzzle.fen,
+      playerSide: chess.state.playerColor,
+      result,
+      rated: isRated,
+      timestamp: Date.now(),
+    });
+    solvedPuzzleTracker.markSolved(currentPuzzle.id, isRated);
+  };
 
+  const playOpponentMove = async (generation: number, uci: string) => {
     await randomDelay(OPPONENT_MOVE_DELAY.min, OPPONENT_MOVE_DELAY.max);
 
     if (generation !== currentGameGeneration) return;
     if (chess.state.isGameOver || chess.state.lifecycle !== 'playing') return;
 
-    const uci = currentPuzzle.solutionUci[solIdx];
     const move = uciToFromTo(uci);
     const fenBefore = chess.state.fen;
     const moveIndex = chess.state.moveHistory.length;
-    const opponentSide = getOpponentSide(chess.state.playerColor);
 
     chess.applyMove(
       move.from as Square,
@@ -98,23 +102,84 @@ export const createPuzzleActions = (
       move.promotion as PromotionPiece | undefined
     );
 
-    chess.setPuzzleSolutionIndex(solIdx + 1);
+    chess.setPuzzleSolutionIndex(chess.state.puzzleSolutionIndex + 1);
+    queueEvaluation(moveIndex, fenBefore, getOpponentSide(chess.state.playerColor));
+  };
 
-    const fenAfter = chess.state.fen;
-    const san = chess.state.moveHistory[moveIndex] || '';
-    moveEvalService.queueMoveEvaluation(
-      {
-        moveIndex,
-        san,
-        fenBefore,
-        fenAfter,
-        side: opponentSide,
-        isPlayerMove: false,
-      },
-      (evaluation) => {
-        chess.updateMoveEvaluation(evaluation);
-      }
-    );
+  const performOpponentMove = async (generation: number) => {
+    if (!currentPuzzle) return;
+
+    const solIdx = chess.state.puzzleSolutionIndex;
+    if (solIdx >= currentPuzzle.solutionUci.length) return;
+
+    await playOpponentMove(generation, currentPuzzle.solutionUci[solIdx]);
+  };
+
+  const completePuzzle = (ratingDelta?: number, newRating?: number) => {
+    const winnerSide = chess.state.playerColor;
+    const winnerName = winnerSide === 'w' ? 'White' : 'Black';
+    chess.endGame('checkmate', winnerSide);
+    chess.setPuzzleFeedback({
+      type: 'complete',
+      message: `${winnerName} wins by checkmate.`,
+      ratingDelta,
+      newRating,
+    });
+    recordAttempt('pass');
+  };
+
+  /**
+   * Plays a move of a rated puzzle and lets the server judge it. The server replies
+   * with the opponent's answer while the line is unfinished, and rates the attempt
+   * once it is solved or a wrong move is played.
+   */
+  const applyVerifiedMove = async (
+    moves: string[],
+    from: Square,
+    to: Square,
+    promotion?: PromotionPiece
+  ) => {
+    if (!currentPuzzle || awaitingVerdict) return;
+
+    const fenBefore = chess.state.fen;
+    const moveIndex = chess.state.moveHistory.length;
+    if (!chess.applyMove(from, to, promotion)) return;
+
+    const san = chess.state.moveHistory[moveIndex] || `${from}${to}`;
+    chess.setPuzzleSolutionIndex(chess.state.puzzleSolutionIndex + 1);
+    queueEvaluation(moveIndex, fenBefore, chess.state.playerColor);
+
+    const generation = currentGameGeneration;
+    moves.push(`${from}${to}${promotion ?? ''}`);
+    awaitingVerdict = true;
+    const data = await submitPuzzleMoves(currentPuzzle.id, moves);
+    if (generation !== currentGameGeneration) return;
+    awaitingVerdict = false;
+
+    if (data?.new_achievements && data.new_achievements.length > 0) {
+      pushAchievementToasts(data.new_achievements);
+    }
+
+    if (data?.status === 'continue' && data.reply) {
+      await playOpponentMove(generation, data.reply);
+      return;
+    }
+    if (data?.status === 'solved') {
+      completePuzzle(data.rating_delta, data.new_rating);
+      return;
+    }
+
+    chess.endGame('checkmate', getOpponentSide(chess.state.playerColor));
+    chess.setPuzzleFeedback({
+      type: 'incorrect',
+      message: data
+        ? `${san} is not the correct move.`
+        : 'Your move could not be checked. Please try another puzzle.',
+      incorrectMoveSan: san,
+      ratingDelta: data?.rating_delta,
+      newRating: data?.new_rating,
+    });
+    if (data) recordAttempt('fail');
   };
 
   const startNewGame = async (options: StartGameOptions) => {
@@ -130,16 +195,20 @@ export const createPuzzleActions = (
     const { puzzleCategory = 'mate-in-1' } = options;
     const category = puzzleCategory as PuzzleCategory;
 
-    const puzzle = getRandomPuzzle(
-      category,
-      chess.state.puzzleId ?? undefined,
-      options.puzzleRated
-    );
-    currentPuzzle = puzzle;
+    currentPuzzle = null;
+    verifiedMoves = null;
+    awaitingVerdict = false;
 
     chess.setLifecycle(transition('idle', 'START_GAME'));
 
     try {
+      const puzzle = options.puzzleRated
+        ? await fetchRatedPuzzle(category)
+        : getRandomPuzzle(category, chess.state.puzzleId ?? undefined);
+      if (thisGeneration !== currentGameGeneration) return;
+      currentPuzzle = puzzle;
+      verifiedMoves = options.puzzleRated ? [] : null;
+
       const fenTurn = puzzle.fen.split(' ')[1] as Side;
       const isOpponentFirst = fenTurn !== puzzle.playerSide;
 
@@ -209,6 +278,10 @@ export const createPuzzleActions = (
 
   const applyPlayerMove = (from: Square, to: Square, promotion?: PromotionPiece) => {
     if (!currentPuzzle) return;
+    if (verifiedMoves) {
+      void applyVerifiedMove(verifiedMoves, from, to, promotion);
+      return;
+    }
 
     const solIdx = chess.state.puzzleSolutionIndex;
     if (solIdx >= currentPuzzle.solutionUci.length) return;
@@ -228,39 +301,12 @@ export const createPuzzleActions = (
         // Use UCI notation as fallback
       }
 
-      if (chess.state.puzzleRated) {
-        chess.endGame('checkmate', getOpponentSide(chess.state.playerColor));
-        submitPuzzleResult(false).then((data) => {
-          chess.setPuzzleFeedback({
-            type: 'incorrect',
-            message: `${incorrectSan} is not the correct move.`,
-            incorrectMoveSan: incorrectSan,
-            ratingDelta: data?.rating_delta,
-            newRating: data?.new_rating,
-          });
-        });
-      } else {
-        chess.setPuzzleFeedback({
-          type: 'incorrect',
-          message: `${incorrectSan} is not the correct move. Try again!`,
-          incorrectMoveSan: incorrectSan,
-        });
-      }
-
-      if (!hasRecordedResult && currentPuzzle) {
-        hasRecordedResult = true;
-        const isRated = chess.state.puzzleRated;
-        puzzleHistory.record({
-          puzzleId: currentPuzzle.id,
-          category: currentPuzzle.category,
-          fen: currentPuzzle.fen,
-          playerSide: chess.state.playerColor,
-          result: 'fail',
-          rated: isRated,
-          timestamp: Date.now(),
-        });
-        solvedPuzzleTracker.markSolved(currentPuzzle.id, isRated);
-      }
+      chess.setPuzzleFeedback({
+        type: 'incorrect',
+        message: `${incorrectSan} is not the correct move. Try again!`,
+        incorrectMoveSan: incorrectSan,
+      });
+      recordAttempt('fail');
       return;
     }
 
@@ -275,58 +321,11 @@ export const createPuzzleActions = (
     chess.setPuzzleSolutionIndex(solIdx + 1);
 
     // Queue evaluation for player move
-    const fenAfter = chess.state.fen;
-    const san = chess.state.moveHistory[moveIndex] || '';
-    moveEvalService.queueMoveEvaluation(
-      {
-        moveIndex,
-        san,
-        fenBefore,
-        fenAfter,
-        side: playerSide,
-        isPlayerMove: true,
-      },
-      (evaluation) => {
-        chess.updateMoveEvaluation(evaluation);
-      }
-    );
+    queueEvaluation(moveIndex, fenBefore, playerSide);
 
     const newSolIdx = solIdx + 1;
     if (newSolIdx >= currentPuzzle.solutionUci.length) {
-      const winnerSide = chess.state.playerColor;
-      const winnerName = winnerSide === 'w' ? 'White' : 'Black';
-      chess.endGame('checkmate', winnerSide);
-
-      if (chess.state.puzzleRated) {
-        submitPuzzleResult(true).then((data) => {
-          chess.setPuzzleFeedback({
-            type: 'complete',
-            message: `${winnerName} wins by checkmate.`,
-            ratingDelta: data?.rating_delta,
-            newRating: data?.new_rating,
-          });
-        });
-      } else {
-        chess.setPuzzleFeedback({
-          type: 'complete',
-          message: `${winnerName} wins by checkmate.`,
-        });
-      }
-
-      if (!hasRecordedResult && currentPuzzle) {
-        hasRecordedResult = true;
-        const isRated = chess.state.puzzleRated;
-        puzzleHistory.record({
-          puzzleId: currentPuzzle.id,
-          category: currentPuzzle.category,
-          fen: currentPuzzle.fen,
-          playerSide: chess.state.playerColor,
-          result: 'pass',
-          rated: isRated,
-          timestamp: Date.now(),
-        });
-        solvedPuzzleTracker.markSolved(currentPuzzle.id, isRated);
-      }
+      completePuzzle();
       return;
     }
 
@@ -369,6 +368,7 @@ export const createPuzzleActions = (
       engine.release(chess.state.sessionId);
     }
     currentPuzzle = null;
+    verifiedMoves = null;
     coreActions.exitGame();
   };
 
@@ -1,14 +1,13 @@
 import 'dart:math';
 
-import 'package:flutter/foundation.dart';
 import 'package:flutter_riverpod/flutter_riverpod.dart';
 
-import '../models/achievement.dart';
 import '../models/game_types.dart';
 import '../providers/chess/chess_provider.dart';
 import '../services/api/api_client.dart' show apiClientProvider;
 import '../services/audio/audio_service.dart';
 import '../services/haptics/haptics_service.dart';
+import '../services/puzzle/puzzle_api.dart';
 import '../services/puzzle/puzzle_data.dart';
 import '../utils/uci_utils.dart';
 import 'game_controller.dart';
@@ -21,6 +20,11 @@ class PuzzleGameController extends GameController {
   bool _disposed = false;
   bool _hasRecordedResult = false;
 
+  /// Rated puzzles are judged by the server: the solver's moves so far, or null
+  /// for a casual puzzle checked against its local solution
+  List<String>? _verifiedMoves;
+  bool _awaitingVerdict = false;
+
   PuzzleGameController(this.ref);
 
   @override
@@ -41,23 +45,27 @@ class PuzzleGameController extends GameController {
     final thisGeneration = _gameGeneration;
     _currentPuzzle = null;
     _hasRecordedResult = false;
+    _verifiedMoves = null;
+    _awaitingVerdict = false;
 
     _chess.setLifecycle(GameLifecycle.initializing);
 
-    final puzzle = getRandomPuzzle(
-      category,
-      excludeId: excludeId,
-      rated: rated,
-      tracker: ref.read(solvedPuzzleTrackerProvider),
-    );
+    final puzzle = rated
+        ? await fetchRatedPuzzle(ref.read(apiClientProvider), category)
+        : getRandomPuzzle(category, excludeId: excludeId);
+    if (thisGeneration != _gameGeneration || _disposed) return;
     if (puzzle == null) {
-      _chess.setInitError('No puzzles available for this category');
+      _chess.setInitError(
+        rated
+            ? 'No rated puzzles available right now'
+            : 'No puzzles available for this category',
+      );
       _chess.setLifecycle(GameLifecycle.error);
       return;
     }
 
-    if (thisGeneration != _gameGeneration || _disposed) return;
     _currentPuzzle = puzzle;
+    _verifiedMoves = rated ? [] : null;
 
     // Determine if opponent moves first
     final fenParts = puzzle.fen.split(' ');
@@ -119,6 +127,10 @@ class PuzzleGameController extends GameController {
     if (state.lifecycle != GameLifecycle.playing) return;
     if (state.currentTurn != state.playerColor) return;
     if (_currentPuzzle == null) return;
+    if (_verifiedMoves != null) {
+      _onVerifiedMove(_verifiedMoves!, from, to, promotion);
+      return;
+    }
 
     final solIdx = state.puzzle.solutionIndex;
     if (solIdx >= _currentPuzzle!.solutionUci.length) return;
@@ -127,24 +139,14 @@ class PuzzleGameController extends GameController {
     final isCorrect = matchesSolution(expectedUci, from, to, promotion);
 
     if (!isCorrect) {
-      // Wrong move
+      // Wrong move: casual puzzles allow a retry
       _recordResult(false);
 
       _audio.playIllegalMove();
       _haptics.onPuzzleIncorrect();
-      if (state.puzzle.rated) {
-        // Rated: end immediately
-        _chess.endGame(GameOverReason.checkmate, null);
-        _submitPuzzleResult(false);
-        _chess.setPuzzleFeedback(
-          const PuzzleFeedback(correct: false, message: 'Incorrect'),
-        );
-      } else {
-        // Casual: allow retry
-        _chess.setPuzzleFeedback(
-          const PuzzleFeedback(correct: false, message: 'Try again'),
-        );
-      }
+      _chess.setPuzzleFeedback(
+        const PuzzleFeedback(correct: false, message: 'Try again'),
+      );
       return;
     }
 
@@ -157,38 +159,105 @@ class PuzzleGameController extends GameController {
     _chess.setPuzzleSolutionIndex(nextSolIdx);
 
     if (nextSolIdx >= _currentPuzzle!.solutionUci.length) {
-      // Puzzle complete!
-      _recordResult(true);
-      _chess.endGame(GameOverReason.checkmate, null);
-      _submitPuzzleResult(true);
-      _audio.playGameEnd();
-      _haptics.onPuzzleCorrect();
-      _chess.setPuzzleFeedback(
-        const PuzzleFeedback(correct: true, message: 'Correct!'),
-      );
+      _completePuzzle();
       return;
     }
 
     // More moves: play opponent's response
-    _performOpponentMove(nextSolIdx);
+    _playOpponentMove(_currentPuzzle!.solutionUci[nextSolIdx]);
   }
 
-  Future<void> _performOpponentMove(int solIdx) async {
+  /// Plays a move of a rated puzzle and lets the server judge it. The server
+  /// replies with the opponent's answer while the line is unfinished, and rates
+  /// the attempt once it is solved or a wrong move is played.
+  Future<void> _onVerifiedMove(
+    List<String> moves,
+    String from,
+    String to,
+    String? promotion,
+  ) async {
+    if (_awaitingVerdict) return;
     final thisGeneration = _gameGeneration;
-    if (_currentPuzzle == null ||
-        solIdx >= _currentPuzzle!.solutionUci.length) {
+
+    _chess.applyMove(from, to, promotion: promotion);
+    _audio.playMoveSound();
+    _haptics.onMove();
+    _chess.setPuzzleSolutionIndex(
+      ref.read(chessProvider).puzzle.solutionIndex + 1,
+    );
+
+    moves.add('$from$to${promotion ?? ''}');
+    _awaitingVerdict = true;
+    final verdict = await submitPuzzleMoves(
+      ref.read(apiClientProvider),
+      _currentPuzzle!.id,
+      moves,
+    );
+    if (thisGeneration != _gameGeneration || _disposed) return;
+    _awaitingVerdict = false;
+
+    if (verdict == null) {
+      _chess.endGame(GameOverReason.checkmate, null);
+      _chess.setPuzzleFeedback(
+        const PuzzleFeedback(
+          correct: false,
+          message: 'Your move could not be checked',
+        ),
+      );
       return;
     }
 
+    if (verdict.newRating != null) {
+      _chess.setRatingChange(
+        RatingChange(
+          delta: verdict.ratingDelta,
+          newRating: verdict.newRating!,
+        ),
+      );
+    }
+    if (verdict.achievements.isNotEmpty) {
+      _chess.setPuzzleAchievements(verdict.achievements);
+    }
+
+    switch (verdict.status) {
+      case PuzzleVerdictStatus.continues:
+        if (verdict.reply != null) _playOpponentMove(verdict.reply!);
+      case PuzzleVerdictStatus.solved:
+        _completePuzzle();
+      case PuzzleVerdictStatus.failed:
+        _recordResult(false);
+        _chess.endGame(GameOverReason.checkmate, null);
+        _audio.playIllegalMove();
+        _haptics.onPuzzleIncorrect();
+        _chess.setPuzzleFeedback(
+          const PuzzleFeedback(correct: false, message: 'Incorrect'),
+        );
+    }
+  }
+
+  void _completePuzzle() {
+    _recordResult(true);
+    _chess.endGame(GameOverReason.checkmate, null);
+    _audio.playGameEnd();
+    _haptics.onPuzzleCorrect();
+    _chess.setPuzzleFeedback(
+      const PuzzleFeedback(correct: true, message: 'Correct!'),
+    );
+  }
+
+  Future<void> _playOpponentMove(String uci) async {
+    final thisGeneration = _gameGeneration;
+
     await Future.delayed(Duration(milliseconds: 300 + Random().nextInt(300)));
     if (thisGeneration != _gameGeneration || _disposed) return;
 
-    final opponentUci = _currentPuzzle!.solutionUci[solIdx];
-    final move = parseUciMove(opponentUci);
+    final move = parseUciMove(uci);
     _chess.applyMove(move.from, move.to, promotion: move.promotion);
     _audio.playMoveSound();
     _haptics.onMove();
-    _chess.setPuzzleSolutionIndex(solIdx + 1);
+    _chess.setPuzzleSolutionIndex(
+      ref.read(chessProvider).puzzle.solutionIndex + 1,
+    );
   }
 
   void _recordResult(bool solved) {
@@ -218,61 +287,6 @@ class PuzzleGameController extends GameController {
     }
   }
 
-  Future<void> _submitPuzzleResult(bool solved) async {
-    if (_currentPuzzle == null) return;
-    final state = ref.read(chessProvider);
-    if (!state.puzzle.rated) return;
-
-    try {
-      final response = await ref
-          .read(apiClientProvider)
-          .post(
-            '/api/puzzle/result',
-            data: {
-              'puzzle_id': _currentPuzzle!.id,
-              'category': _categoryString(_currentPuzzle!.category),
-              'solved': solved,
-            },
-          );
-
-      if (response.data != null && response.data is Map) {
-        final data = response.data as Map;
-        if (data['new_rating'] != null) {
-          _chess.setRatingChange(
-            RatingChange(
-              delta: (data['rating_delta'] as num?)?.toInt() ?? 0,
-              newRating: (data['new_rating'] as num).toInt(),
-            ),
-          );
-        }
-        final rawAchievements = data['new_achievements'] as List<dynamic>?;
-        if (rawAchievements != null && rawAchievements.isNotEmpty) {
-          final achievements = rawAchievements
-              .map((e) => AchievementUnlock.fromJson(e as Map<String, dynamic>))
-              .toList();
-          _chess.setPuzzleAchievements(achievements);
-        }
-      }
-    } catch (e) {
-      if (kDebugMode) {
-        debugPrint('PuzzleGameController rating submit error: $e');
-      }
-    }
-  }
-
-  String _categoryString(PuzzleCategory category) {
-    switch (category) {
-      case PuzzleCategory.mateIn1:
-        return 'mate-in-1';
-      case PuzzleCategory.mateIn2:
-        return 'mate-in-2';
-      case PuzzleCategory.mateIn3:
-        return 'mate-in-3';
-      case PuzzleCategory.random:
-        return 'random';
-    }
-  }
-
   void loadNextPuzzle() {
     final state = ref.read(chessProvider);
     _chess.setPuzzleFeedback(null);
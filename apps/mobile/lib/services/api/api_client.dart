@@ -1,14 +1,49 @@
 import 'package:cookie_jar/cookie_jar.dart';
 import 'package:dio/dio.dart';
 import 'package:dio_cookie_manager/dio_cookie_manager.dart';
+import 'package:flutter/foundation.dart';
 import 'package:flutter_riverpod/flutter_riverpod.dart';
 import 'package:path_provider/path_provider.dart';
+import 'package:shared_preferences/shared_preferences.dart';
 import '../../config/env.dart';
 
+/// The bearer credentials of a mobile session, as returned by POST /auth/token
+class AuthTokens {
+  final String accessToken;
+  final String refreshToken;
+  final DateTime expiresAt;
+
+  const AuthTokens({
+    required this.accessToken,
+    required this.refreshToken,
+    required this.expiresAt,
+  });
+
+  factory AuthTokens.fromResponse(Map<String, dynamic> json) {
+    final expiresIn = (json['expires_in'] as num?)?.toInt() ?? 0;
+    return AuthTokens(
+      accessToken: json['access_token'] as String,
+      refreshToken: json['refresh_token'] as String,
+      expiresAt: DateTime.now().add(Duration(seconds: expiresIn)),
+    );
+  }
+
+  /// Refresh a little early so a request never goes out with a token about to lapse
+  bool get isExpired =>
+      DateTime.now().isAfter(expiresAt.subtract(const Duration(seconds: 30)));
+}
+
 class ApiClient {
+  static const _accessTokenKey = 'auth_access_token';
+  static const _refreshTokenKey = 'auth_refresh_token';
+  static const _expiresAtKey = 'auth_expires_at';
+
   late final Dio dio;
   late final PersistCookieJar cookieJar;
 
+  AuthTokens? _tokens;
+  Future<AuthTokens?>? _refreshing;
+
   Future<void> init() async {
     final dir = await getApplicationDocumentsDirectory();
     cookieJar = PersistCookieJar(storage: FileStorage('${dir.path}/.cookies/'));
@@ -24,6 +59,10 @@ class ApiClient {
       ),
     );
     dio.interceptors.add(CookieManager(cookieJar));
+    dio.interceptors.add(
+      InterceptorsWrapper(onRequest: _authorize, onError: _retryUnauthorized),
+    );
+    await _loadTokens();
   }
 
   Future<Response<T>> get<T>(
@@ -45,11 +84,142 @@ class ApiClient {
     return dio.delete<T>(path);
   }
 
-  Future<String?> getSessionToken() async {
-    final uri = Uri.parse(Env.backendUrl);
-    final cookies = await cookieJar.loadForRequest(uri);
-    final session = cookies.where((c) => c.name == 'session_token').firstOrNull;
-    return session?.value;
+  /// Returns a current access token, refreshing it first if it has expired, or null
+  /// when signed out
+  Future<String?> getAccessToken() async {
+    var tokens = _tokens;
+    if (tokens != null && tokens.isExpired) {
+      tokens = await _refresh();
+    }
+    return tokens?.accessToken;
+  }
+
+  /// Exchanges the one-time code of a mobile login for tokens, proving with the
+  /// PKCE verifier that this app started the login
+  Future<bool> exchangeCode(String code, String codeVerifier) async {
+    final response = await _tokenRequest({
+      'grant_type': 'authorization_code',
+      'code': code,
+      'code_verifier': codeVerifier,
+    });
+    if (response == null) return false;
+    await _saveTokens(AuthTokens.fromResponse(response));
+    return true;
+  }
+
+  Future<void> clearTokens() => _saveTokens(null);
+
+  Future<void> _authorize(
+    RequestOptions options,
+    RequestInterceptorHandler handler,
+  ) async {
+    if (options.path != '/auth/token') {
+      String? token;
+      try {
+        token = await getAccessToken();
+      } on DioException {
+        // Offline: send the old token and let the server decide
+        token = _tokens?.accessToken;
+      }
+      if (token != null) {
+        options.headers['Authorization'] = 'Bearer $token';
+      }
+    }
+    handler.next(options);
+  }
+
+  /// Retries a request once with refreshed tokens when the access token was rejected
+  Future<void> _retryUnauthorized(
+    DioException err,
+    ErrorInterceptorHandler handler,
+  ) async {
+    final options = err.requestOptions;
+    if (err.response?.statusCode != 401 ||
+        _tokens == null ||
+        options.extra['retried'] == true ||
+        options.path == '/auth/token') {
+      return handler.next(err);
+    }
+
+    try {
+      final tokens = await _refresh();
+      if (tokens == null) return handler.next(err);
+      options.extra['retried'] = true;
+      options.headers['Authorization'] = 'Bearer ${tokens.accessToken}';
+      handler.resolve(await dio.fetch(options));
+    } on DioException catch (e) {
+      handler.next(e);
+    }
+  }
+
+  /// Rotates the tokens. Refresh tokens are single use, so concurrent callers share
+  /// one request. Signs out when the refresh token is no longer accepted.
+  Future<AuthTokens?> _refresh() {
+    return _refreshing ??= () async {
+      try {
+        final refreshToken = _tokens?.refreshToken;
+        if (refreshToken == null) return null;
+        final response = await _tokenRequest({
+          'grant_type': 'refresh_token',
+          'refresh_token': refreshToken,
+        });
+        await _saveTokens(
+          response == null ? null : AuthTokens.fromResponse(response),
+        );
+        return _tokens;
+      } finally {
+        _refreshing = null;
+      }
+    }();
+  }
+
+  /// Posts to /auth/token, returning the token pair or null if the grant was refused
+  Future<Map<String, dynamic>?> _tokenRequest(Map<String, String> body) async {
+    try {
+      final response = await dio.post<Map<String, dynamic>>(
+        '/auth/token',
+        data: body,
+      );
+      return response.data;
+    } on DioException catch (e) {
+      final status = e.response?.statusCode;
+      if (status == 400 || status == 401) {
+        if (kDebugMode) debugPrint('ApiClient: token grant refused ($status)');
+        return null;
+      }
+      rethrow;
+    }
+  }
+
+  Future<void> _loadTokens() async {
+    final prefs = await SharedPreferences.getInstance();
+    final access = prefs.getString(_accessTokenKey);
+    final refresh = prefs.getString(_refreshTokenKey);
+    final expiresAt = prefs.getInt(_expiresAtKey);
+    if (access == null || refresh == null || expiresAt == null) return;
+    _tokens = AuthTokens(
+      accessToken: access,
+      refreshToken: refresh,
+      expiresAt: DateTime.fromMillisecondsSinceEpoch(expiresAt),
+    );
+  }
+
+  Future<void> _saveTokens(AuthTokens? tokens) async {
+    _tokens = tokens;
+    final prefs = await SharedPreferences.getInstance();
+    if (tokens == null) {
+      await Future.wait([
+        prefs.remove(_accessTokenKey),
+        prefs.remove(_refreshTokenKey),
+        prefs.remove(_expiresAtKey),
+      ]);
+      return;
+    }
+    await Future.wait([
+      prefs.setString(_accessTokenKey, tokens.accessToken),
+      prefs.setString(_refreshTokenKey, tokens.refreshToken),
+      prefs.setInt(_expiresAtKey, tokens.expiresAt.millisecondsSinceEpoch),
+    ]);
   }
 }
 
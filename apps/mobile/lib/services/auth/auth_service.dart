@@ -1,36 +1,38 @@
-import 'dart:io';
-
 import 'package:flutter/foundation.dart';
 import 'package:flutter_riverpod/flutter_riverpod.dart';
 import 'package:flutter_web_auth_2/flutter_web_auth_2.dart';
 import '../../config/env.dart' show Env;
 import '../api/api_client.dart';
+import 'pkce.dart';
 
 class AuthService {
   final ApiClient _api;
 
   AuthService(this._api);
 
+  /// Signs in through the provider's web login. The backend redirects back with a
+  /// one-time code, which is exchanged for tokens with the PKCE verifier of this login.
   Future<({bool success, bool hasUsername})> signInWithProvider(
     String provider,
   ) async {
     try {
-      final authUrl = '${Env.authUrl}/$provider/login?mobile=true';
+      final pkce = Pkce.generate();
+      final authUrl = Uri.parse('${Env.authUrl}/$provider/login').replace(
+        queryParameters: {
+          'mobile': 'true',
+          'code_challenge': pkce.challenge,
+          'code_challenge_method': Pkce.method,
+        },
+      );
 
       final result = await FlutterWebAuth2.authenticate(
-        url: authUrl,
+        url: authUrl.toString(),
         callbackUrlScheme: Env.callbackUrlScheme,
       );
 
       final uri = Uri.parse(result);
-      final token = uri.queryParameters['token'];
-      if (token != null) {
-        final cookieUri = Uri.parse(Env.backendUrl);
-        final cookie = Cookie('session_token', token)
-          ..domain = cookieUri.host
-          ..path = '/'
-          ..secure = cookieUri.scheme == 'https';
-        await _api.cookieJar.saveFromResponse(cookieUri, [cookie]);
+      final code = uri.queryParameters['code'];
+      if (code != null && await _api.exchangeCode(code, pkce.verifier)) {
         final hasUsername = uri.queryParameters['has_username'] == 'true';
         return (success: true, hasUsername: hasUsername);
       }
@@ -57,6 +59,7 @@ class AuthService {
     } catch (e) {
       if (kDebugMode) debugPrint('AuthService.logout: $e');
     }
+    await _api.clearTokens();
     await _api.cookieJar.deleteAll();
   }
 }
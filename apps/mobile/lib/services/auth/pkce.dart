@@ -0,0 +1,30 @@
+import 'dart:convert';
+import 'dart:math';
+
+import 'package:crypto/crypto.dart';
+
+/// A PKCE (RFC 7636) verifier and its S256 challenge. The challenge goes with the
+/// login request; the verifier proves the app started that login when it exchanges
+/// the one-time code for tokens.
+class Pkce {
+  final String verifier;
+  final String challenge;
+
+  const Pkce._(this.verifier, this.challenge);
+
+  static const method = 'S256';
+
+  factory Pkce.generate([Random? random]) {
+    final rng = random ?? Random.secure();
+    final bytes = List<int>.generate(32, (_) => rng.nextInt(256));
+    return Pkce.fromVerifier(_base64Url(bytes));
+  }
+
+  factory Pkce.fromVerifier(String verifier) {
+    final digest = sha256.convert(ascii.encode(verifier));
+    return Pkce._(verifier, _base64Url(digest.bytes));
+  }
+
+  static String _base64Url(List<int> bytes) =>
+      base64Url.encode(bytes).replaceAll('=', '');
+}
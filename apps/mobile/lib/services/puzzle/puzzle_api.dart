@@ -0,0 +1,108 @@
+import 'dart:math';
+
+import 'package:flutter/foundation.dart';
+
+import '../../models/achievement.dart';
+import '../../models/game_types.dart';
+import '../api/api_client.dart' show ApiClient;
+import 'puzzle_data.dart';
+
+const _categoryThemes = {
+  PuzzleCategory.mateIn1: 'mateIn1',
+  PuzzleCategory.mateIn2: 'mateIn2',
+  PuzzleCategory.mateIn3: 'mateIn3',
+};
+
+enum PuzzleVerdictStatus { continues, solved, failed }
+
+/// The server's judgement of the moves played so far in a rated puzzle
+class PuzzleVerdict {
+  final PuzzleVerdictStatus status;
+
+  /// The opponent's answer while the line is unfinished
+  final String? reply;
+
+  /// Set once a rated attempt ends; null for repeats, which are not rated
+  final int? newRating;
+  final int ratingDelta;
+  final List<AchievementUnlock> achievements;
+
+  const PuzzleVerdict({
+    required this.status,
+    this.reply,
+    this.newRating,
+    this.ratingDelta = 0,
+    this.achievements = const [],
+  });
+
+  factory PuzzleVerdict.fromJson(Map<String, dynamic> json) {
+    final status = switch (json['status']) {
+      'continue' => PuzzleVerdictStatus.continues,
+      'solved' => PuzzleVerdictStatus.solved,
+      _ => PuzzleVerdictStatus.failed,
+    };
+    final rawAchievements = json['new_achievements'] as List<dynamic>?;
+    return PuzzleVerdict(
+      status: status,
+      reply: json['reply'] as String?,
+      newRating: (json['new_rating'] as num?)?.toInt(),
+      ratingDelta: (json['rating_delta'] as num?)?.toInt() ?? 0,
+      achievements: [
+        for (final e in rawAchievements ?? const [])
+          AchievementUnlock.fromJson(e as Map<String, dynamic>),
+      ],
+    );
+  }
+}
+
+/// Fetches the next rated puzzle from the server's pool. The solution stays on
+/// the server, so [PuzzleDefinition.solutionUci] is empty and moves are judged
+/// one at a time by [submitPuzzleMoves]. Returns null when no puzzle is
+/// available.
+Future<PuzzleDefinition?> fetchRatedPuzzle(
+  ApiClient api,
+  PuzzleCategory category,
+) async {
+  final categories = _categoryThemes.keys.toList();
+  final mateCategory = category == PuzzleCategory.random
+      ? categories[Random().nextInt(categories.length)]
+      : category;
+  try {
+    final response = await api.get(
+      '/api/puzzle/next',
+      queryParameters: {'theme': _categoryThemes[mateCategory]},
+    );
+    final data = response.data as Map<String, dynamic>;
+    final fen = data['fen'] as String;
+    return PuzzleDefinition(
+      id: data['puzzle_id'] as String,
+      category: mateCategory,
+      fen: fen,
+      solutionUci: const [],
+      playerSide: fen.split(' ')[1] == 'b' ? Side.b : Side.w,
+      rating: (data['rating'] as num?)?.toInt(),
+    );
+  } catch (e) {
+    if (kDebugMode) debugPrint('fetchRatedPuzzle error: $e');
+    return null;
+  }
+}
+
+/// Submits the solver's moves so far (UCI, without the opponent's replies).
+/// Returns null when the server could not be reached or refused the request.
+Future<PuzzleVerdict?> submitPuzzleMoves(
+  ApiClient api,
+  String puzzleId,
+  List<String> moves,
+) async {
+  try {
+    final response = await api.post(
+      '/api/puzzle/submit',
+      data: {'puzzle_id': puzzleId, 'moves': moves},
+    );
+    return PuzzleVerdict.fromJson(response.data as Map<String, dynamic>);
+  } catch (e) {
+    if (kDebugMode) debugPrint('submitPuzzleMoves error: $e');
+    return null;
+  }
+}
@@ -91,8 +91,6 @@ final _puzzleRandom = Random();
 PuzzleDefinition? getRandomPuzzle(
   PuzzleCategory category, {
   String? excludeId,
-  bool rated = false,
-  required SolvedPuzzleTracker tracker,
 }) {
   final actualCategory = category == PuzzleCategory.random
       ? [
@@ -107,10 +105,6 @@ PuzzleDefinition? getRandomPuzzle(
     final source = allPuzzles
         .where((p) => p.category == actualCategory)
         .toList();
-    if (rated) {
-      final solved = tracker.getSolvedIds(true);
-      source.removeWhere((p) => solved.contains(p.id));
-    }
     source.shuffle(_puzzleRandom);
     _categoryQueues[actualCategory] = source;
   }
@@ -34,9 +34,9 @@ class GameSyncService {
     if (serverUrl != null) {
       _socket.setUrl(serverUrl);
     }
-    final token = await _api.getSessionToken();
+    final token = await _api.getAccessToken();
     if (token != null) {
-      _socket.setHeaders({'Cookie': 'session_token=$token'});
+      _socket.setHeaders({'Authorization': 'Bearer $token'});
     }
     unawaited(_socket.connect());
   }
@@ -0,0 +1,26 @@
+import 'dart:math';
+
+import 'package:flutter_test/flutter_test.dart';
+import 'package:nxtchess/services/auth/pkce.dart';
+
+void main() {
+  group('Pkce', () {
+    test('matches the RFC 7636 example', () {
+      final pkce = Pkce.fromVerifier(
+        'dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk',
+      );
+      expect(pkce.challenge, 'E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM');
+    });
+
+    test('generates verifiers the backend accepts', () {
+      final pkce = Pkce.generate(Random(1));
+      expect(pkce.verifier, matches(RegExp(r'^[A-Za-z0-9\-._~]{43,128}$')));
+      expect(pkce.challenge, hasLength(43));
+      expect(Pkce.fromVerifier(pkce.verifier).challenge, pkce.challenge);
+    });
+
+    test('generates a new verifier each time', () {
+      expect(Pkce.generate().verifier, isNot(Pkce.generate().verifier));
+    });
+  });
+}
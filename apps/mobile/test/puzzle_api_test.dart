@@ -0,0 +1,54 @@
+import 'package:flutter_test/flutter_test.dart';
+import 'package:nxtchess/services/puzzle/puzzle_api.dart';
+
+void main() {
+  group('PuzzleVerdict.fromJson', () {
+    test('parses an unfinished line with the reply', () {
+      final verdict = PuzzleVerdict.fromJson({
+        'status': 'continue',
+        'reply': 'd7d8',
+      });
+      expect(verdict.status, PuzzleVerdictStatus.continues);
+      expect(verdict.reply, 'd7d8');
+      expect(verdict.newRating, isNull);
+      expect(verdict.achievements, isEmpty);
+    });
+
+    test('parses a rated solve', () {
+      final verdict = PuzzleVerdict.fromJson({
+        'status': 'solved',
+        'solved': true,
+        'rated': true,
+        'new_rating': 1512,
+        'rating_delta': 12,
+        'old_rating': 1500,
+        'new_achievements': [
+          {
+            'id': 'puzzles_10',
+            'name': 'Puzzle Solver',
+            'description': 'Solve 10 puzzles',
+            'rarity': 'common',
+            'points': 5,
+            'icon': 'puzzle',
+          },
+        ],
+      });
+      expect(verdict.status, PuzzleVerdictStatus.solved);
+      expect(verdict.newRating, 1512);
+      expect(verdict.ratingDelta, 12);
+      expect(verdict.achievements.single.id, 'puzzles_10');
+    });
+
+    test('treats a repeat as unrated', () {
+      final verdict = PuzzleVerdict.fromJson({
+        'status': 'failed',
+        'solved': false,
+        'rated': false,
+        'solution': ['a2e6', 'd7d8', 'f7f8'],
+      });
+      expect(verdict.status, PuzzleVerdictStatus.failed);
+      expect(verdict.newRating, isNull);
+      expect(verdict.ratingDelta, 0);
+    });
+  });
+}
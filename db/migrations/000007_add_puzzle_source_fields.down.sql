@@ -0,0 +1,3 @@
+ALTER TABLE puzzles DROP COLUMN IF EXISTS opening_tags;
+ALTER TABLE puzzles DROP COLUMN IF EXISTS game_url;
+ALTER TABLE puzzles DROP COLUMN IF EXISTS rating_deviation;
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS puzzle_attempts;
@@ -0,0 +1,21 @@
+-- Migration: Add timed puzzle sprints
+
+CREATE TABLE IF NOT EXISTS puzzle_sprints (
+    id           SERIAL PRIMARY KEY,
+    user_id      TEXT NOT NULL REFERENCES profiles(user_id) ON DELETE CASCADE,
+    score        INT NOT NULL DEFAULT 0,
+    mistakes     INT NOT NULL DEFAULT 0,
+    puzzle_ids   TEXT[] NOT NULL DEFAULT '{}',
+    started_at   TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    ends_at      TIMESTAMP WITH TIME ZONE NOT NULL,
+    finished_at  TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS puzzle_sprints_user_started_idx ON puzzle_sprints(user_id, started_at DESC);
+CREATE INDEX IF NOT EXISTS puzzle_sprints_score_idx ON puzzle_sprints(score DESC, started_at);
+
+COMMENT ON COLUMN puzzle_sprints.puzzle_ids IS 'Puzzles handed out in order; the last one is the current puzzle';
+COMMENT ON COLUMN puzzle_sprints.finished_at IS 'Set when the sprint ends by mistakes or an answer after the timer; NULL sprints past ends_at are also over';
+
+GRANT SELECT, INSERT, UPDATE ON puzzle_sprints TO anon;
+GRANT USAGE, SELECT ON SEQUENCE puzzle_sprints_id_seq TO anon;
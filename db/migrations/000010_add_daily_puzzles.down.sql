@@ -0,0 +1,4 @@
+ALTER TABLE profiles DROP COLUMN IF EXISTS last_daily_puzzle_solved;
+ALTER TABLE profiles DROP COLUMN IF EXISTS daily_puzzle_streak;
+DROP TABLE IF EXISTS daily_puzzle_results;
+DROP TABLE IF EXISTS daily_puzzles;
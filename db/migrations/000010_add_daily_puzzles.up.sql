@@ -0,0 +1,25 @@
+-- Migration: Add the daily puzzle and per-user daily results
+
+-- One puzzle per UTC date, fixed once chosen so later imports do not change past days
+CREATE TABLE IF NOT EXISTS daily_puzzles (
+    puzzle_date  DATE PRIMARY KEY,
+    puzzle_id    TEXT NOT NULL REFERENCES puzzles(puzzle_id) ON DELETE CASCADE,
+    overridden   BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at   TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS daily_puzzle_results (
+    user_id      TEXT NOT NULL REFERENCES profiles(user_id) ON DELETE CASCADE,
+    puzzle_date  DATE NOT NULL,
+    solved       BOOLEAN NOT NULL,
+    created_at   TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, puzzle_date)
+);
+
+ALTER TABLE profiles ADD COLUMN IF NOT EXISTS daily_puzzle_streak INT NOT NULL DEFAULT 0;
+ALTER TABLE profiles ADD COLUMN IF NOT EXISTS last_daily_puzzle_solved DATE;
+
+COMMENT ON COLUMN daily_puzzles.overridden IS 'Set when an admin chose the puzzle instead of the deterministic pick';
+
+GRANT SELECT, INSERT, UPDATE ON daily_puzzles TO anon;
+GRANT SELECT, INSERT, UPDATE ON daily_puzzle_results TO anon;
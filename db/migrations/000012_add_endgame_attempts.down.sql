@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS endgame_reviews;
+DROP TABLE IF EXISTS endgame_attempts;
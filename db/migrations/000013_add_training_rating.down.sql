@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS endgame_attempts_user_position_idx;
+ALTER TABLE endgame_attempts DROP COLUMN IF EXISTS rating_after;
+ALTER TABLE endgame_attempts DROP COLUMN IF EXISTS rating_before;
+ALTER TABLE endgame_attempts DROP COLUMN IF EXISTS outcome;
+ALTER TABLE profiles DROP COLUMN IF EXISTS training_rating;
+ALTER TABLE endgame_positions DROP COLUMN IF EXISTS expected_result;
@@ -0,0 +1,4 @@
+-- Migration: Track username changes for the username change policy
+
+-- When the username was last changed; NULL until the first change after the initial pick
+ALTER TABLE profiles ADD COLUMN IF NOT EXISTS username_changed_at TIMESTAMP WITH TIME ZONE;
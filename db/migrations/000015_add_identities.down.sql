@@ -0,0 +1,12 @@
+-- Restore provider subjects as user IDs (the oldest identity of each user)
+UPDATE profiles p
+SET user_id = i.subject
+FROM (
+    SELECT DISTINCT ON (user_id) user_id, subject
+    FROM identities
+    ORDER BY user_id, created_at
+) i
+WHERE p.user_id = i.user_id;
+
+DROP INDEX IF EXISTS identities_user_id_idx;
+DROP TABLE IF EXISTS identities;
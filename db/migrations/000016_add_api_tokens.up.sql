@@ -0,0 +1,19 @@
+-- Migration: Personal API tokens
+--
+-- Users create tokens for their own bots and tools. Only the SHA-256 hash of a token
+-- is stored; the token itself is shown once when it is created.
+
+CREATE TABLE IF NOT EXISTS api_tokens (
+    token_id     TEXT PRIMARY KEY DEFAULT gen_random_uuid()::text,
+    user_id      TEXT NOT NULL REFERENCES profiles(user_id) ON UPDATE CASCADE ON DELETE CASCADE,
+    name         TEXT NOT NULL,
+    token_hash   TEXT NOT NULL UNIQUE,
+    token_prefix TEXT NOT NULL,
+    scopes       TEXT[] NOT NULL DEFAULT '{}',
+    created_at   TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    expires_at   TIMESTAMP WITH TIME ZONE,
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    revoked_at   TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS api_tokens_user_id_idx ON api_tokens(user_id);
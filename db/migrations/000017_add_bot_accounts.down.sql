@@ -0,0 +1,4 @@
+ALTER TABLE rating_history DROP COLUMN IF EXISTS pool;
+ALTER TABLE games DROP COLUMN IF EXISTS rating_pool;
+ALTER TABLE profiles DROP COLUMN IF EXISTS bot_rating;
+ALTER TABLE profiles DROP COLUMN IF EXISTS is_bot;
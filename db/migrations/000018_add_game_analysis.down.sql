@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS game_analysis_unfinished_idx;
+DROP TABLE IF EXISTS game_analysis;
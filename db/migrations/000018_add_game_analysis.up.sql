@@ -0,0 +1,27 @@
+-- Migration: Computer analysis of finished games
+--
+-- Rated games are queued for analysis when they finish. Each ply is evaluated with
+-- the server's UCI engine; moves holds the per-move evaluations and classifications.
+
+CREATE TABLE IF NOT EXISTS game_analysis (
+    game_id            UUID PRIMARY KEY REFERENCES games(game_id) ON DELETE CASCADE,
+    status             TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'running', 'done', 'failed')),
+    depth              INT,
+    white_accuracy     REAL,
+    black_accuracy     REAL,
+    white_acpl         INT,
+    black_acpl         INT,
+    white_inaccuracies INT,
+    white_mistakes     INT,
+    white_blunders     INT,
+    black_inaccuracies INT,
+    black_mistakes     INT,
+    black_blunders     INT,
+    moves              JSONB,
+    error              TEXT,
+    created_at         TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    completed_at       TIMESTAMP WITH TIME ZONE
+);
+
+-- Unfinished analyses are picked up again when the server restarts
+CREATE INDEX IF NOT EXISTS game_analysis_unfinished_idx ON game_analysis(created_at) WHERE status IN ('pending', 'running');
@@ -0,0 +1,2 @@
+ALTER TABLE games DROP COLUMN IF EXISTS rated;
+ALTER TABLE profiles DROP COLUMN IF EXISTS computer_rating;
@@ -0,0 +1,2 @@
+ALTER TABLE games DROP COLUMN IF EXISTS opening;
+ALTER TABLE games DROP COLUMN IF EXISTS eco;
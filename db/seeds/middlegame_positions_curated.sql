@@ -0,0 +1,68 @@
+-- Curated Middlegame Training Positions
+-- Typical middlegame positions reached from well-known opening lines
+--
+-- Each position is the board after the main-line moves of the opening named in
+-- its description; the theme is the task for the side to move.
+--
+-- Run with: psql -d chess_db -f middlegame_positions_curated.sql
+-- Larger sets can be loaded with: go run ./cmd/importmiddlegames -file <csv>
+
+BEGIN;
+
+-- ============================================================================
+-- ATTACK
+-- ============================================================================
+
+INSERT INTO middlegame_positions (position_id, fen, moves, rating, themes, description, source)
+VALUES
+  ('mg_attack_01', '2rq1rk1/pp1bppb1/3p1np1/4n2p/3NP2P/1BN1BP2/PPPQ2P1/2KR3R w - h6 0 13', '', 1700, ARRAY['attack'], 'Yugoslav Attack against the Dragon - open the h-file', 'curated'),
+  ('mg_attack_02', 'r2q1rk1/3nbppp/p2pbn2/4p1P1/1p2P3/1NN1BP2/PPPQ3P/2KR1B1R w - - 0 13', '', 1800, ARRAY['attack'], 'English Attack - pawn storm against the castled king', 'curated'),
+  ('mg_attack_03', 'r2qkb1r/3b1ppp/p1nppn2/1p4B1/3NPP2/2N5/PPPQ2PP/2KR1B1R w kq b6 0 10', '', 1600, ARRAY['attack'], 'Richter-Rauzer - opposite-side castling race', 'curated'),
+  ('mg_attack_04', 'r1b1k1nr/pppp1ppp/2n3q1/b3P3/2B5/1Qp2N2/P4PPP/RNB2RK1 w kq - 1 10', '', 1500, ARRAY['attack'], 'Evans Gambit - development lead against the uncastled king', 'curated'),
+  ('mg_attack_05', 'r1bq1rk1/ppp1n1bp/3p1np1/3Pp3/2P1PpP1/2NN1P2/PP1BB2P/R2Q1RK1 b - g3 0 13', '', 1900, ARRAY['attack'], 'King''s Indian Mar del Plata - kingside pawn storm', 'curated'),
+  ('mg_attack_06', 'r1bqrnk1/pp2bppp/2p2n2/3p2B1/3P4/2NBP3/PPQ1NPPP/2KR3R w - - 8 11', '', 1400, ARRAY['attack'], 'Carlsbad with opposite-side castling - kingside pawn storm', 'curated')
+ON CONFLICT (position_id) DO NOTHING;
+
+-- ============================================================================
+-- DEFENCE
+-- ============================================================================
+
+INSERT INTO middlegame_positions (position_id, fen, moves, rating, themes, description, source)
+VALUES
+  ('mg_defence_01', 'r1bqk2r/pppp1ppp/2n5/3P4/2B1n3/2b2N2/PP3PPP/R1BQ1RK1 b kq - 0 9', '', 1500, ARRAY['defence'], 'Moller Attack - hold the centre under fire', 'curated'),
+  ('mg_defence_02', 'r1bq1b1r/ppp3pp/2n1k3/3np3/2B5/2N2Q2/PPPP1PPP/R1B1K2R b KQ - 3 8', '', 1300, ARRAY['defence'], 'Fried Liver - survive with the king in the centre', 'curated'),
+  ('mg_defence_03', 'rn2k2r/pp3ppp/2p1pn2/q3Nb2/1bBP4/2N5/PPPBQPPP/R3K2R b KQkq - 3 9', '', 1400, ARRAY['defence'], 'Scandinavian - neutralise the knight on e5', 'curated'),
+  ('mg_defence_04', 'r1bq1rk1/p4ppp/1pnbpn2/2ppN3/3P4/2PBP1B1/PP1N1PPP/R2QK2R b KQ - 1 9', '', 1600, ARRAY['defence'], 'London System - meet the e5 outpost', 'curated'),
+  ('mg_defence_05', '2rq1rk1/pp1bppb1/3p1np1/4n1Bp/3NP2P/1BN2P2/PPPQ2P1/2KR3R b - - 1 13', '', 1700, ARRAY['defence'], 'Dragon - defend against the h-file attack', 'curated'),
+  ('mg_defence_06', 'rnb1k1r1/ppq1np1Q/4p3/3pP3/3p4/P1P5/2P1NPPP/R1B1KB1R b KQq - 1 10', '', 1800, ARRAY['defence'], 'French Winawer - hold the kingside after Qg4', 'curated')
+ON CONFLICT (position_id) DO NOTHING;
+
+-- ============================================================================
+-- PAWN STRUCTURE
+-- ============================================================================
+
+INSERT INTO middlegame_positions (position_id, fen, moves, rating, themes, description, source)
+VALUES
+  ('mg_pawn_01', 'r1bqrnk1/pp2bppp/2p2n2/3p2B1/3P4/2NBP3/PPQ1NPPP/1R3RK1 b - - 9 11', '', 1600, ARRAY['pawnStructure'], 'Carlsbad structure - prepare the minority attack', 'curated'),
+  ('mg_pawn_02', 'r1bq1rk1/pp2bppp/2n1p3/3n4/3P4/2NB1N2/PP3PPP/R1BQR1K1 b - - 4 10', '', 1500, ARRAY['pawnStructure'], 'Isolated queen pawn - blockade d5', 'curated'),
+  ('mg_pawn_03', 'r1b1kbnr/pp3ppp/1q2p3/n2pP3/2pP4/P1P2N2/1P1N1PPP/R1BQKB1R w KQkq - 2 8', '', 1400, ARRAY['pawnStructure'], 'French Advance - attack the pawn chain at its base', 'curated'),
+  ('mg_pawn_04', 'rnbqk2r/pp3ppp/4p3/2Pn4/8/P1P2P2/4P1PP/R1BQKBNR b KQkq - 0 8', '', 1700, ARRAY['pawnStructure'], 'Nimzo-Indian Saemisch - target the doubled c-pawns', 'curated'),
+  ('mg_pawn_05', 'r1bqr1k1/pp3pbp/n2p1np1/2pP4/4P3/2N5/PP1NBPPP/R1BQ1RK1 w - - 8 11', '', 1800, ARRAY['pawnStructure'], 'Modern Benoni - play for the e5 break', 'curated'),
+  ('mg_pawn_06', 'r2qkb1r/pp1nnppp/4p3/2ppPb2/2PP4/4BN2/PP2BPPP/RN1Q1RK1 b kq c3 0 8', '', 1300, ARRAY['pawnStructure'], 'Caro-Kann Advance - undermine the centre', 'curated')
+ON CONFLICT (position_id) DO NOTHING;
+
+-- ============================================================================
+-- PIECE ACTIVITY
+-- ============================================================================
+
+INSERT INTO middlegame_positions (position_id, fen, moves, rating, themes, description, source)
+VALUES
+  ('mg_activity_01', 'r1b2rk1/2q1bppp/p2p1n2/npp1p3/3PP3/2P2N1P/PPBN1PP1/R1BQR1K1 b - - 2 12', '', 1600, ARRAY['pieceActivity'], 'Closed Ruy Lopez - reroute the knight', 'curated'),
+  ('mg_activity_02', 'rn1q1rk1/1bp1bppp/p3pn2/1p6/3P4/5NP1/PPQBPPBP/RN3RK1 b - - 3 10', '', 1700, ARRAY['pieceActivity'], 'Open Catalan - activate the queenside pieces', 'curated'),
+  ('mg_activity_03', 'r3k2r/1bqnbppp/pp1ppn2/8/2PQP3/1PN2NP1/P4PBP/R1BR2K1 w kq - 1 12', '', 1900, ARRAY['pieceActivity'], 'Hedgehog - squeeze the cramped position', 'curated'),
+  ('mg_activity_04', 'r1b1kb1r/p1ppqppp/1np5/4P3/2P5/8/PP2QPPP/RNB1KB1R w KQkq - 1 9', '', 1400, ARRAY['pieceActivity'], 'Scotch - find squares for the pieces', 'curated'),
+  ('mg_activity_05', 'rnb2rk1/pp2q1pp/2pbpn2/3p1p2/2PP4/1P3NP1/PB2PPBP/RNQ2RK1 b - - 4 9', '', 1500, ARRAY['pieceActivity'], 'Stonewall Dutch - solve the light-squared bishop', 'curated'),
+  ('mg_activity_06', 'r1bq1rk1/pp2ppbp/2n3p1/2p5/2BPP3/2P1B3/P3NPPP/R2Q1RK1 b - - 5 10', '', 1800, ARRAY['pieceActivity'], 'Grunfeld Exchange - pressure on d4', 'curated')
+ON CONFLICT (position_id) DO NOTHING;
+
+COMMIT;